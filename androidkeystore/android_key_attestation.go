@@ -22,8 +22,6 @@ import (
 	"bytes"
 	"crypto/x509"
 	"encoding/asn1"
-	"encoding/pem"
-	"errors"
 	"fmt"
 	"reflect"
 
@@ -32,8 +30,7 @@ import (
 )
 
 /*
- * Android Keystore Root Certificate extracted by herrjemand.
- * The last certificate in x5c must match this certificate.
+ * Android Keystore Software Attestation Root Certificate extracted by herrjemand.
  * Date: 2019
  * Availability: https://gist.github.com/herrjemand/c5a84de5c04ef41b3ac7fd12d0cbceae#file-verify-androidkey-webauthn-js
  */
@@ -56,7 +53,6 @@ C/NR2TB8fVvaNTQdqEcbY6WFZTytTySn502vQX3xvw==
 -----END CERTIFICATE-----`
 
 var (
-	androidKeyStoreRootCert     *x509.Certificate
 	oidAndroidKeyCertificateExt = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 1, 17}
 )
 
@@ -65,6 +61,13 @@ type androidKeyAttestationStatement struct {
 	sig      []byte
 	credCert *x509.Certificate
 	caCerts  []*x509.Certificate
+	details  *AttestationDetails
+}
+
+// AttestationDetails represents android-key specific details of a verified attestation statement.
+type AttestationDetails struct {
+	KeyDescription *KeyDescription // Key description in the attestation certificate extension.
+	AppIdentity    *AppIdentity    // App identity allowed by AppIdentityPolicy, or nil if the policy is not set.
 }
 
 func parseAttestation(data []byte) (webauthn.AttestationStatement, error) {
@@ -116,20 +119,23 @@ func parseAttestation(data []byte) (webauthn.AttestationStatement, error) {
 // android-key attestation statement verification procedure defined in
 // http://w3c.github.io/webauthn/#sctn-android-key-attestation
 func (attStmt *androidKeyAttestationStatement) Verify(clientDataHash []byte, authnData *webauthn.AuthenticatorData) (attType webauthn.AttestationType, trustPath interface{}, err error) {
-	// Verify that root certificate is set to Android KeyStore Root certificate to detect fake attestations.
-	if len(attStmt.caCerts) == 0 {
-		err = &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate", Msg: "certificate chain is empty"}
-		return
-	}
-	if !attStmt.caCerts[len(attStmt.caCerts)-1].Equal(androidKeyStoreRootCert) {
-		err = &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate", Msg: "Android Keystore root certificate doesn't match received attestation certificate"}
+	// Verify leaf certificate by building certificate chain to a trusted Android attestation root
+	// certificate to detect fake attestations.
+	var trusted bool
+	if trustPath, trusted, err = webauthn.VerifyAttestationCertificateWithRoots(attStmt.credCert, attStmt.caCerts, DefaultRoots.Certificates()); err != nil {
+		err = &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate", Msg: err.Error()}
 		return
 	}
 
-	// Verify leaf certificate by building certificate chain.
-	if trustPath, err = verifyAttestationCert(attStmt.credCert, attStmt.caCerts); err != nil {
-		err = &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate", Msg: err.Error()}
-		return
+	// Verify that no certificate in x5c is revoked or suspended.
+	if DefaultPolicy.Revocation != nil {
+		certs := append([]*x509.Certificate{attStmt.credCert}, attStmt.caCerts...)
+		if err = DefaultPolicy.Revocation.Check(certs); err != nil {
+			if _, ok := err.(*CertificateRevokedError); !ok {
+				err = &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate revocation status", Msg: err.Error()}
+			}
+			return
+		}
 	}
 
 	// Verify that sig is a valid signature over the concatenation of authenticatorData and clientDataHash
@@ -151,7 +157,7 @@ func (attStmt *androidKeyAttestationStatement) Verify(clientDataHash []byte, aut
 		return
 	}
 
-	attestationChallenge, softwareEnforced, teeEnforced, err := parseAndroidKeyCertExtension(attStmt.credCert)
+	keyDescription, err := ParseKeyDescription(attStmt.credCert)
 	if err != nil {
 		err = &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate extension " + oidAndroidKeyCertificateExt.String(), Msg: err.Error()}
 		return
@@ -159,7 +165,7 @@ func (attStmt *androidKeyAttestationStatement) Verify(clientDataHash []byte, aut
 
 	// Verify that the attestationChallenge field in the attestation certificate extension data is
 	// identical to clientDataHash.
-	if !bytes.Equal(attestationChallenge, clientDataHash[:]) {
+	if !bytes.Equal(keyDescription.AttestationChallenge, clientDataHash[:]) {
 		err = &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate extension attestationChallenge", Msg: "attestationChallenge does not match clientDataHash"}
 		return
 	}
@@ -167,144 +173,136 @@ func (attStmt *androidKeyAttestationStatement) Verify(clientDataHash []byte, aut
 	// Verify the following using the appropriate authorization list from the attestatoin certificate extension data:
 	// - The AuthorizationList.allApplications field is not present on either authorization list
 	//   (softwareEnforced nor teeEnfored), since PublicKeyCredential must be scoped to the RP ID.
-	if softwareEnforced.allApplications {
+	if keyDescription.SoftwareEnforced.AllApplications {
 		err = &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate extension softwareEnforced", Msg: "softwareEnforced has allApplications set"}
 		return
 	}
-	if teeEnforced.allApplications {
+	if keyDescription.TeeEnforced.AllApplications {
 		err = &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate extension teeEnforced", Msg: "teeEnforced has allApplications set"}
 		return
 	}
 	// - For the following, use only the teeEnforced authorization list if the RP wants to accept only keys from a
 	//   trusted execution environment, otherwise use the union of teeEnfored and softwareEnfored.
 	//   * The value in the AuthorizationList.origin field is equal to KM_ORIGIN_GENERATED.
-	kmOriginGenerated := 0
-	if softwareEnforced.origin != kmOriginGenerated && teeEnforced.origin != kmOriginGenerated {
-		err = &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate extension softwareEnforced and teeEnfored", Msg: "origin is not KM_ORIGIN_GENERATED"}
-		return
-	}
 	//   * The value in the AuthorizationList.purpose field is equal to KM_PURPOSE_SIGN.
-	kmPurposeSign := 2
-	if (len(softwareEnforced.purpose) != 1 || softwareEnforced.purpose[0] != kmPurposeSign) &&
-		(len(teeEnforced.purpose) != 1 || teeEnforced.purpose[0] != kmPurposeSign) {
-		err = &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate extension softwareEnforced and teeEnfored", Msg: "purpose is not KM_PURPOSE_SIGN"}
+	if err = DefaultPolicy.verify(keyDescription); err != nil {
 		return
 	}
 
+	// Verify that the app that created the credential is allowed.
+	details := &AttestationDetails{KeyDescription: keyDescription}
+	if DefaultPolicy.AppIdentity != nil {
+		// attestationApplicationId is in softwareEnforced, or teeEnforced in newer KeyMint versions.
+		appID := keyDescription.TeeEnforced.AttestationApplicationID
+		if appID == nil {
+			appID = keyDescription.SoftwareEnforced.AttestationApplicationID
+		}
+		if details.AppIdentity, err = DefaultPolicy.AppIdentity.match(appID); err != nil {
+			return
+		}
+	}
+	attStmt.details = details
+
+	// Without a trusted Android attestation root certificate, attestation type is uncertain.
+	if !trusted {
+		return webauthn.AttestationTypeUncertain, trustPath, nil
+	}
+
 	// If successful, return implementation-specific values representing attestation type Basic and
 	// attestation trust path x5c.
 	return webauthn.AttestationTypeBasic, trustPath, nil
 }
 
-type authorizationList struct {
-	allApplications bool
-	purpose         []int
-	origin          int
+// AttestationDetails implements the webauthn.AttestationDetailer interface.  It returns *AttestationDetails
+// after successful verification.
+func (attStmt *androidKeyAttestationStatement) AttestationDetails() interface{} {
+	return attStmt.details
 }
 
-func parseAuthorizationList(data []byte) (*authorizationList, error) {
-	authList := &authorizationList{}
+// Policy specifies which Android Keystore attestations are accepted in addition to the android-key
+// attestation statement verification procedure.
+type Policy struct {
+	// MinSecurityLevel is the minimum attestation and Keymaster security level.  If it is
+	// SecurityLevelTrustedEnvironment or higher, only the teeEnforced authorization list is used.
+	MinSecurityLevel SecurityLevel
 
-	// purpose [1] EXPLICIT SET OF INTEGER OPTIONAL
-	if _, err := asn1.UnmarshalWithParams(data, &authList.purpose, "explicit,set,optional,tag:1"); err != nil {
-		return nil, errors.New("failed to unmarshal AuthorizationList purpose: " + err.Error())
-	}
+	// RequireVerifiedBoot requires a hardware enforced root of trust with locked bootloader and
+	// verified boot state Verified.
+	RequireVerifiedBoot bool
 
-	// origin [702] EXPLICIT INTEGER OPTIONAL
-	if _, err := asn1.UnmarshalWithParams(data, &authList.origin, "explicit,optional,tag:702"); err != nil {
-		return nil, errors.New("failed to unmarshal AuthorizationList origin: " + err.Error())
-	}
-
-	// allApplications [600] EXPLICIT NULL OPTIONAL
-	if _, err := asn1.UnmarshalWithParams(data, &authList.allApplications, "explicit,optional,tag:600"); err != nil {
-		return nil, errors.New("failed to unmarshal AuthorizationList allApplications: " + err.Error())
-	}
+	// Revocation checks certificates in x5c against Android attestation revocation status list.
+	// Certificates are not checked if it is nil.
+	Revocation *RevocationChecker
 
-	return authList, nil
+	// AppIdentity specifies Android apps allowed to create credentials.  Any app is allowed if it is nil.
+	AppIdentity *AppIdentityPolicy
 }
 
-func parseAndroidKeyCertExtension(cert *x509.Certificate) (attestationChallenge []byte, softwareEnforced *authorizationList, teeEnforced *authorizationList, err error) {
-	var extValue []byte
-	for _, ext := range cert.Extensions {
-		if ext.Id.Equal(oidAndroidKeyCertificateExt) {
-			extValue = ext.Value
-			break
-		}
+// DefaultPolicy is the policy used to verify android-key attestation statements.  It accepts
+// software keys and any boot state.  Modify it during initialization, before verifying attestations.
+var DefaultPolicy = &Policy{}
+
+func (policy *Policy) verify(keyDescription *KeyDescription) error {
+	if keyDescription.AttestationSecurityLevel < policy.MinSecurityLevel {
+		return &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate extension attestationSecurityLevel", Msg: "expected " + policy.MinSecurityLevel.String() + " or higher, got " + keyDescription.AttestationSecurityLevel.String()}
 	}
-	if len(extValue) == 0 {
-		return nil, nil, nil, errors.New("missing certificate extension")
+	if keyDescription.KeymasterSecurityLevel < policy.MinSecurityLevel {
+		return &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate extension keymasterSecurityLevel", Msg: "expected " + policy.MinSecurityLevel.String() + " or higher, got " + keyDescription.KeymasterSecurityLevel.String()}
 	}
 
-	var seq asn1.RawValue
-	var rest []byte
-	rest, err = asn1.Unmarshal(extValue, &seq)
-	if err != nil {
-		return nil, nil, nil, errors.New("failed to unmarshal certificate extension: " + err.Error())
-	} else if len(rest) != 0 {
-		return nil, nil, nil, errors.New("trailing data after certificate extension")
-	}
-	if !seq.IsCompound || seq.Tag != asn1.TagSequence || seq.Class != asn1.ClassUniversal {
-		return nil, nil, nil, errors.New("bad data")
+	authLists := []*AuthorizationList{keyDescription.SoftwareEnforced, keyDescription.TeeEnforced}
+	field := "certificate extension softwareEnforced and teeEnforced"
+	if policy.MinSecurityLevel >= SecurityLevelTrustedEnvironment {
+		authLists = authLists[1:]
+		field = "certificate extension teeEnforced"
 	}
 
-	rest = seq.Bytes
-	for i := 0; len(rest) > 0; i++ {
-		var v asn1.RawValue
-		rest, err = asn1.Unmarshal(rest, &v)
-		if err != nil {
-			return nil, nil, nil, errors.New("failed to unmarshal certificate extension element: " + err.Error())
+	originGenerated, purposeSign := false, false
+	for _, authList := range authLists {
+		if authList.Origin != nil && *authList.Origin == kmOriginGenerated {
+			originGenerated = true
 		}
-		if i == 4 {
-			attestationChallenge = v.Bytes
-		} else if i == 6 {
-			if softwareEnforced, err = parseAuthorizationList(v.Bytes); err != nil {
-				return nil, nil, nil, err
-			}
-		} else if i == 7 {
-			if teeEnforced, err = parseAuthorizationList(v.Bytes); err != nil {
-				return nil, nil, nil, err
-			}
+		if len(authList.Purpose) == 1 && authList.Purpose[0] == kmPurposeSign {
+			purposeSign = true
 		}
 	}
-	return attestationChallenge, softwareEnforced, teeEnforced, nil
-}
-
-func verifyAttestationCert(attestnCert *x509.Certificate, caCerts []*x509.Certificate) (trustPath []*x509.Certificate, err error) {
-	var verifyOptions x509.VerifyOptions
-
-	if len(caCerts) > 0 {
-		lastCert := caCerts[len(caCerts)-1]
-		if bytes.Equal(lastCert.RawIssuer, lastCert.RawSubject) && lastCert.IsCA {
-			caCerts = caCerts[:len(caCerts)-1]
+	if !originGenerated {
+		return &webauthn.VerificationError{Type: "Android key attestation", Field: field, Msg: "origin is not KM_ORIGIN_GENERATED"}
+	}
+	if !purposeSign {
+		return &webauthn.VerificationError{Type: "Android key attestation", Field: field, Msg: "purpose is not KM_PURPOSE_SIGN"}
+	}
 
-			verifyOptions.Roots = x509.NewCertPool()
-			verifyOptions.Roots.AddCert(lastCert)
+	if policy.RequireVerifiedBoot {
+		rootOfTrust := keyDescription.TeeEnforced.RootOfTrust
+		if rootOfTrust == nil {
+			return &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate extension teeEnforced rootOfTrust", Msg: "rootOfTrust is missing"}
 		}
-		if len(caCerts) > 0 {
-			verifyOptions.Intermediates = x509.NewCertPool()
-			for _, c := range caCerts {
-				verifyOptions.Intermediates.AddCert(c)
-			}
+		if !rootOfTrust.DeviceLocked {
+			return &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate extension teeEnforced rootOfTrust", Msg: "device is not locked"}
+		}
+		if rootOfTrust.VerifiedBootState != VerifiedBootStateVerified {
+			return &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate extension teeEnforced rootOfTrust", Msg: "verified boot state is " + rootOfTrust.VerifiedBootState.String()}
 		}
 	}
-
-	var chains [][]*x509.Certificate
-	if chains, err = attestnCert.Verify(verifyOptions); err != nil {
-		return nil, err
-	}
-	return chains[0], nil
+	return nil
 }
 
 func init() {
-	block, _ := pem.Decode([]byte(androidKeyStoreRootCertPem))
-	if block == nil {
-		panic("failed to PEM decode Android KeyStore root certificate")
+	for _, rootPem := range []string{
+		googleHardwareAttestationRSARoot2019Pem,
+		googleHardwareAttestationECDSARootPem,
+	} {
+		if err := DefaultRoots.AddPEM([]byte(rootPem)); err != nil {
+			panic("failed to parse Android attestation root certificate: " + err.Error())
+		}
 	}
 
-	var err error
-	if androidKeyStoreRootCert, err = x509.ParseCertificate(block.Bytes); err != nil {
-		panic("failed to parse Android KeyStore root certificate: " + err.Error())
+	certs, err := webauthn.ParseCertificatesPEM([]byte(androidKeyStoreRootCertPem))
+	if err != nil {
+		panic("failed to parse Android Keystore software attestation root certificate: " + err.Error())
 	}
+	SoftwareAttestationRoot = certs[0]
 
 	webauthn.RegisterAttestationFormat("android-key", parseAttestation)
 }
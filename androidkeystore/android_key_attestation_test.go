@@ -24,7 +24,9 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/kappapay/webauthn"
 )
@@ -191,10 +193,12 @@ type parseTest struct {
 }
 
 type verifyTest struct {
-	name          string
-	attestation   []byte
-	wantAttType   webauthn.AttestationType
-	wantTrustPath interface{}
+	name              string
+	attestation       []byte
+	trustSoftwareRoot bool
+	wantAttType       webauthn.AttestationType
+	wantTrustPath     interface{}
+	wantErrorMsg      string
 }
 
 var parseTests = []parseTest{
@@ -210,10 +214,20 @@ var parseTests = []parseTest{
 
 var verifyTests = []verifyTest{
 	{
-		"attestation 1",
+		"attestation 1 chaining to untrusted software attestation root",
 		[]byte(attestation1),
+		false,
+		0,
+		nil,
+		"android_key_attestation: failed to verify certificate: x509: certificate signed by unknown authority",
+	},
+	{
+		"attestation 1 with trusted software attestation root",
+		[]byte(attestation1),
+		true,
 		webauthn.AttestationTypeBasic,
 		[]*x509.Certificate{parseCertificate(attestation1CredCert), parseCertificate(attestation1CACert0), parseCertificate(attestation1CACert1)},
+		"",
 	},
 }
 
@@ -269,13 +283,32 @@ func TestParseAndroidKeyAttestation(t *testing.T) {
 }
 
 func TestVerifyAndroidKeyAttestation(t *testing.T) {
+	// Verify certificates at a time when the intermediate certificate of attestation 1 was valid.
+	savedNow := webauthn.DefaultCertificatePolicy.Now
+	webauthn.DefaultCertificatePolicy.Now = func() time.Time { return time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC) }
+	defer func() { webauthn.DefaultCertificatePolicy.Now = savedNow }()
+
 	for _, tc := range verifyTests {
 		t.Run(tc.name, func(t *testing.T) {
+			if tc.trustSoftwareRoot {
+				savedRoots := DefaultRoots.Certificates()
+				DefaultRoots.Add(SoftwareAttestationRoot)
+				defer DefaultRoots.Set(savedRoots)
+			}
+
 			var credentialAttestation webauthn.PublicKeyCredentialAttestation
 			if err := json.Unmarshal(tc.attestation, &credentialAttestation); err != nil {
 				t.Fatalf("failed to unmarshal attestation %s: %q", string(tc.attestation), err)
 			}
 			attType, trustPath, err := credentialAttestation.VerifyAttestationStatement()
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyAttestationStatement() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyAttestationStatement() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
 			if err != nil {
 				t.Fatalf("VerifyAttestationStatement() returns error %q", err)
 			}
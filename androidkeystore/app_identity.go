@@ -0,0 +1,73 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package androidkeystore
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/internal/appidentity"
+)
+
+// AppIdentityPolicy specifies Android apps allowed to create credentials, identified by
+// attestationApplicationId in Android Keystore attestation.
+type AppIdentityPolicy struct {
+	PackageNames       []string // Allowed package names.  Any package name is allowed if empty.
+	SigningCertDigests [][]byte // Allowed SHA-256 digests of app signing certificates.  Any signing certificate is allowed if empty.
+}
+
+// AppIdentity identifies the Android app that created the credential.
+type AppIdentity struct {
+	PackageName       string // Package name of the app.
+	Version           int64  // Version code of the app.
+	SigningCertDigest []byte // SHA-256 digest of the app signing certificate.
+}
+
+// match returns the app identity in attestationApplicationId that is allowed by policy.
+func (policy *AppIdentityPolicy) match(appID *AttestationApplicationID) (*AppIdentity, error) {
+	if appID == nil {
+		return nil, &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate extension attestationApplicationId", Msg: "attestationApplicationId is missing"}
+	}
+
+	var identity *AppIdentity
+	for _, info := range appID.PackageInfos {
+		if appidentity.AllowsName(policy.PackageNames, info.PackageName) {
+			identity = &AppIdentity{PackageName: info.PackageName, Version: info.Version}
+			break
+		}
+	}
+	if identity == nil {
+		var names []string
+		for _, info := range appID.PackageInfos {
+			names = append(names, info.PackageName)
+		}
+		return nil, &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate extension attestationApplicationId", Msg: "package name " + strings.Join(names, ", ") + " is not allowed"}
+	}
+
+	if digest, ok := appidentity.MatchDigest(policy.SigningCertDigests, appID.SignatureDigests); ok {
+		identity.SigningCertDigest = digest
+		return identity, nil
+	}
+	var digests []string
+	for _, digest := range appID.SignatureDigests {
+		digests = append(digests, hex.EncodeToString(digest))
+	}
+	return nil, &webauthn.VerificationError{Type: "Android key attestation", Field: "certificate extension attestationApplicationId", Msg: "signing certificate digest " + strings.Join(digests, ", ") + " is not allowed"}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package androidkeystore
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAppIdentityPolicy(t *testing.T) {
+	digest1 := bytes.Repeat([]byte{0x01}, 32)
+	digest2 := bytes.Repeat([]byte{0x02}, 32)
+	appID := &AttestationApplicationID{
+		PackageInfos: []AttestationPackageInfo{
+			{PackageName: "com.example.shared", Version: 3},
+			{PackageName: "com.example.app", Version: 7},
+		},
+		SignatureDigests: [][]byte{digest1},
+	}
+
+	testCases := []struct {
+		name         string
+		policy       *AppIdentityPolicy
+		appID        *AttestationApplicationID
+		wantIdentity *AppIdentity
+		wantErrorMsg string
+	}{
+		{
+			name:         "allowed package and signing certificate",
+			policy:       &AppIdentityPolicy{PackageNames: []string{"com.example.app"}, SigningCertDigests: [][]byte{digest2, digest1}},
+			appID:        appID,
+			wantIdentity: &AppIdentity{PackageName: "com.example.app", Version: 7, SigningCertDigest: digest1},
+		},
+		{
+			name:         "any package",
+			policy:       &AppIdentityPolicy{SigningCertDigests: [][]byte{digest1}},
+			appID:        appID,
+			wantIdentity: &AppIdentity{PackageName: "com.example.shared", Version: 3, SigningCertDigest: digest1},
+		},
+		{
+			name:         "any signing certificate",
+			policy:       &AppIdentityPolicy{PackageNames: []string{"com.example.app"}},
+			appID:        &AttestationApplicationID{PackageInfos: appID.PackageInfos},
+			wantIdentity: &AppIdentity{PackageName: "com.example.app", Version: 7},
+		},
+		{
+			name:         "package not allowed",
+			policy:       &AppIdentityPolicy{PackageNames: []string{"com.example.other"}},
+			appID:        appID,
+			wantErrorMsg: "failed to verify certificate extension attestationApplicationId: package name com.example.shared, com.example.app is not allowed",
+		},
+		{
+			name:         "signing certificate not allowed",
+			policy:       &AppIdentityPolicy{PackageNames: []string{"com.example.app"}, SigningCertDigests: [][]byte{digest2}},
+			appID:        appID,
+			wantErrorMsg: "signing certificate digest 0101010101010101010101010101010101010101010101010101010101010101 is not allowed",
+		},
+		{
+			name:         "missing attestationApplicationId",
+			policy:       &AppIdentityPolicy{},
+			appID:        nil,
+			wantErrorMsg: "attestationApplicationId is missing",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			identity, err := tc.policy.match(tc.appID)
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("match() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("match() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("match() returns error %q", err)
+			}
+			if identity.PackageName != tc.wantIdentity.PackageName || identity.Version != tc.wantIdentity.Version || !bytes.Equal(identity.SigningCertDigest, tc.wantIdentity.SigningCertDigest) {
+				t.Errorf("match() returns %+v, want %+v", identity, tc.wantIdentity)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package androidkeystore
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"strconv"
+)
+
+// SecurityLevel identifies where a key or its attestation is enforced, as defined in
+// https://source.android.com/security/keystore/attestation#schema
+type SecurityLevel int
+
+// Security levels defined in Android Keystore attestation schema.
+const (
+	SecurityLevelSoftware           SecurityLevel = 0
+	SecurityLevelTrustedEnvironment SecurityLevel = 1
+	SecurityLevelStrongBox          SecurityLevel = 2
+)
+
+func (level SecurityLevel) String() string {
+	switch level {
+	case SecurityLevelSoftware:
+		return "Software"
+	case SecurityLevelTrustedEnvironment:
+		return "TrustedEnvironment"
+	case SecurityLevelStrongBox:
+		return "StrongBox"
+	default:
+		return "SecurityLevel(" + strconv.Itoa(int(level)) + ")"
+	}
+}
+
+// VerifiedBootState identifies the state of Verified Boot on the device, as defined in
+// https://source.android.com/security/keystore/attestation#schema
+type VerifiedBootState int
+
+// Verified boot states defined in Android Keystore attestation schema.
+const (
+	VerifiedBootStateVerified   VerifiedBootState = 0
+	VerifiedBootStateSelfSigned VerifiedBootState = 1
+	VerifiedBootStateUnverified VerifiedBootState = 2
+	VerifiedBootStateFailed     VerifiedBootState = 3
+)
+
+func (state VerifiedBootState) String() string {
+	switch state {
+	case VerifiedBootStateVerified:
+		return "Verified"
+	case VerifiedBootStateSelfSigned:
+		return "SelfSigned"
+	case VerifiedBootStateUnverified:
+		return "Unverified"
+	case VerifiedBootStateFailed:
+		return "Failed"
+	default:
+		return "VerifiedBootState(" + strconv.Itoa(int(state)) + ")"
+	}
+}
+
+// Keymaster tag values used by WebAuthn verification.
+const (
+	kmOriginGenerated = 0
+	kmPurposeSign     = 2
+)
+
+// RootOfTrust represents the RootOfTrust structure of Android Keystore attestation.
+type RootOfTrust struct {
+	VerifiedBootKey   []byte            // Hash of the public key used to verify the system image.
+	DeviceLocked      bool              // Whether the bootloader is locked.
+	VerifiedBootState VerifiedBootState // State of Verified Boot.
+	VerifiedBootHash  []byte            // Digest of all data protected by Verified Boot (attestation version 3 and later).
+}
+
+// AttestationPackageInfo represents the AttestationPackageInfo structure of Android Keystore attestation.
+type AttestationPackageInfo struct {
+	PackageName string
+	Version     int64
+}
+
+// AttestationApplicationID represents the AttestationApplicationId structure of Android Keystore
+// attestation.  It identifies the apps allowed to use the key.
+type AttestationApplicationID struct {
+	PackageInfos     []AttestationPackageInfo // Package names and versions sharing the same UID.
+	SignatureDigests [][]byte                 // SHA-256 digests of the apps' signing certificates.
+}
+
+// AuthorizationList represents the AuthorizationList structure of Android Keystore attestation.
+// Absent integer fields are 0 and absent NULL fields are false.
+type AuthorizationList struct {
+	Purpose                     []int
+	Algorithm                   int
+	KeySize                     int
+	Digest                      []int
+	Padding                     []int
+	ECCurve                     int
+	RSAPublicExponent           int64
+	RollbackResistance          bool
+	ActiveDateTime              int64
+	OriginationExpireDateTime   int64
+	UsageExpireDateTime         int64
+	NoAuthRequired              bool
+	UserAuthType                int
+	AuthTimeout                 int
+	AllowWhileOnBody            bool
+	TrustedUserPresenceRequired bool
+	TrustedConfirmationRequired bool
+	UnlockedDeviceRequired      bool
+	AllApplications             bool
+	CreationDateTime            int64
+	Origin                      *int // Origin of the key, or nil if absent.
+	RootOfTrust                 *RootOfTrust
+	OSVersion                   int
+	OSPatchLevel                int
+	AttestationApplicationID    *AttestationApplicationID
+	VendorPatchLevel            int
+	BootPatchLevel              int
+}
+
+// KeyDescription represents the KeyDescription structure in Android Keystore attestation certificate
+// extension, as defined in https://source.android.com/security/keystore/attestation#schema
+type KeyDescription struct {
+	AttestationVersion       int
+	AttestationSecurityLevel SecurityLevel
+	KeymasterVersion         int
+	KeymasterSecurityLevel   SecurityLevel
+	AttestationChallenge     []byte
+	UniqueID                 []byte
+	SoftwareEnforced         *AuthorizationList
+	TeeEnforced              *AuthorizationList
+}
+
+// ParseKeyDescription parses KeyDescription in Android Keystore attestation certificate extension
+// with OID 1.3.6.1.4.1.11129.2.1.17.
+func ParseKeyDescription(cert *x509.Certificate) (*KeyDescription, error) {
+	var extValue []byte
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidAndroidKeyCertificateExt) {
+			extValue = ext.Value
+			break
+		}
+	}
+	if len(extValue) == 0 {
+		return nil, errors.New("missing certificate extension")
+	}
+
+	type rawKeyDescription struct {
+		AttestationVersion       int
+		AttestationSecurityLevel asn1.Enumerated
+		KeymasterVersion         int
+		KeymasterSecurityLevel   asn1.Enumerated
+		AttestationChallenge     []byte
+		UniqueID                 []byte
+		SoftwareEnforced         asn1.RawValue
+		TeeEnforced              asn1.RawValue
+	}
+	var raw rawKeyDescription
+	if rest, err := asn1.Unmarshal(extValue, &raw); err != nil {
+		return nil, errors.New("failed to unmarshal certificate extension: " + err.Error())
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after certificate extension")
+	}
+
+	keyDescription := &KeyDescription{
+		AttestationVersion:       raw.AttestationVersion,
+		AttestationSecurityLevel: SecurityLevel(raw.AttestationSecurityLevel),
+		KeymasterVersion:         raw.KeymasterVersion,
+		KeymasterSecurityLevel:   SecurityLevel(raw.KeymasterSecurityLevel),
+		AttestationChallenge:     raw.AttestationChallenge,
+		UniqueID:                 raw.UniqueID,
+	}
+	var err error
+	if keyDescription.SoftwareEnforced, err = parseAuthorizationList(raw.SoftwareEnforced); err != nil {
+		return nil, errors.New("failed to unmarshal softwareEnforced: " + err.Error())
+	}
+	if keyDescription.TeeEnforced, err = parseAuthorizationList(raw.TeeEnforced); err != nil {
+		return nil, errors.New("failed to unmarshal teeEnforced: " + err.Error())
+	}
+	return keyDescription, nil
+}
+
+// parseAuthorizationList parses AuthorizationList elements one by one, so unknown tags added by
+// newer Keymaster versions are skipped.
+func parseAuthorizationList(seq asn1.RawValue) (*AuthorizationList, error) {
+	if !seq.IsCompound || seq.Tag != asn1.TagSequence || seq.Class != asn1.ClassUniversal {
+		return nil, errors.New("bad data")
+	}
+
+	authList := &AuthorizationList{}
+	rest := seq.Bytes
+	for len(rest) > 0 {
+		var v asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &v); err != nil {
+			return nil, err
+		}
+		if v.Class != asn1.ClassContextSpecific || !v.IsCompound {
+			return nil, errors.New("element is not explicitly tagged")
+		}
+
+		// Tags are defined in https://source.android.com/security/keystore/tags
+		switch v.Tag {
+		case 1:
+			err = unmarshalElement(v.Bytes, &authList.Purpose, "set")
+		case 2:
+			err = unmarshalElement(v.Bytes, &authList.Algorithm, "")
+		case 3:
+			err = unmarshalElement(v.Bytes, &authList.KeySize, "")
+		case 5:
+			err = unmarshalElement(v.Bytes, &authList.Digest, "set")
+		case 6:
+			err = unmarshalElement(v.Bytes, &authList.Padding, "set")
+		case 10:
+			err = unmarshalElement(v.Bytes, &authList.ECCurve, "")
+		case 200:
+			err = unmarshalElement(v.Bytes, &authList.RSAPublicExponent, "")
+		case 303:
+			authList.RollbackResistance = true
+		case 400:
+			err = unmarshalElement(v.Bytes, &authList.ActiveDateTime, "")
+		case 401:
+			err = unmarshalElement(v.Bytes, &authList.OriginationExpireDateTime, "")
+		case 402:
+			err = unmarshalElement(v.Bytes, &authList.UsageExpireDateTime, "")
+		case 503:
+			authList.NoAuthRequired = true
+		case 504:
+			err = unmarshalElement(v.Bytes, &authList.UserAuthType, "")
+		case 505:
+			err = unmarshalElement(v.Bytes, &authList.AuthTimeout, "")
+		case 506:
+			authList.AllowWhileOnBody = true
+		case 507:
+			authList.TrustedUserPresenceRequired = true
+		case 508:
+			authList.TrustedConfirmationRequired = true
+		case 509:
+			authList.UnlockedDeviceRequired = true
+		case 600:
+			authList.AllApplications = true
+		case 701:
+			err = unmarshalElement(v.Bytes, &authList.CreationDateTime, "")
+		case 702:
+			var origin int
+			if err = unmarshalElement(v.Bytes, &origin, ""); err == nil {
+				authList.Origin = &origin
+			}
+		case 704:
+			authList.RootOfTrust, err = parseRootOfTrust(v.Bytes)
+		case 705:
+			err = unmarshalElement(v.Bytes, &authList.OSVersion, "")
+		case 706:
+			err = unmarshalElement(v.Bytes, &authList.OSPatchLevel, "")
+		case 709:
+			authList.AttestationApplicationID, err = parseAttestationApplicationID(v.Bytes)
+		case 718:
+			err = unmarshalElement(v.Bytes, &authList.VendorPatchLevel, "")
+		case 719:
+			err = unmarshalElement(v.Bytes, &authList.BootPatchLevel, "")
+		}
+		if err != nil {
+			return nil, errors.New("failed to unmarshal tag " + strconv.Itoa(v.Tag) + ": " + err.Error())
+		}
+	}
+	return authList, nil
+}
+
+func unmarshalElement(data []byte, v interface{}, params string) error {
+	rest, err := asn1.UnmarshalWithParams(data, v, params)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return errors.New("trailing data")
+	}
+	return nil
+}
+
+func parseRootOfTrust(data []byte) (*RootOfTrust, error) {
+	type rawRootOfTrust struct {
+		VerifiedBootKey   []byte
+		DeviceLocked      bool
+		VerifiedBootState asn1.Enumerated
+		VerifiedBootHash  []byte `asn1:"optional"`
+	}
+	var raw rawRootOfTrust
+	if err := unmarshalElement(data, &raw, ""); err != nil {
+		return nil, err
+	}
+	return &RootOfTrust{
+		VerifiedBootKey:   raw.VerifiedBootKey,
+		DeviceLocked:      raw.DeviceLocked,
+		VerifiedBootState: VerifiedBootState(raw.VerifiedBootState),
+		VerifiedBootHash:  raw.VerifiedBootHash,
+	}, nil
+}
+
+func parseAttestationApplicationID(data []byte) (*AttestationApplicationID, error) {
+	// attestationApplicationId is an OCTET STRING containing DER encoded AttestationApplicationId.
+	var octets []byte
+	if err := unmarshalElement(data, &octets, ""); err != nil {
+		return nil, err
+	}
+	type rawPackageInfo struct {
+		PackageName []byte
+		Version     int64
+	}
+	type rawAttestationApplicationID struct {
+		PackageInfos     []rawPackageInfo `asn1:"set"`
+		SignatureDigests [][]byte         `asn1:"set"`
+	}
+	var raw rawAttestationApplicationID
+	if err := unmarshalElement(octets, &raw, ""); err != nil {
+		return nil, err
+	}
+	appID := &AttestationApplicationID{SignatureDigests: raw.SignatureDigests}
+	for _, info := range raw.PackageInfos {
+		appID.PackageInfos = append(appID.PackageInfos, AttestationPackageInfo{PackageName: string(info.PackageName), Version: info.Version})
+	}
+	return appID, nil
+}
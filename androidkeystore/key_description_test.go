@@ -0,0 +1,204 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package androidkeystore
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseKeyDescription(t *testing.T) {
+	origin := kmOriginGenerated
+	wantSignatureDigest, _ := hex.DecodeString("5ad05ec221c8f83a226127dec557500c3e574bc60125a9dc21cb0be4a0066095")
+	wantChallenge, _ := hex.DecodeString("2a4382d7bbd89d8b5bdf1772cfecca14392487b9fd571f2eb72bdf97de06d4b6")
+	want := &KeyDescription{
+		AttestationVersion:       2,
+		AttestationSecurityLevel: SecurityLevelSoftware,
+		KeymasterVersion:         1,
+		KeymasterSecurityLevel:   SecurityLevelTrustedEnvironment,
+		AttestationChallenge:     wantChallenge,
+		UniqueID:                 []byte{},
+		SoftwareEnforced: &AuthorizationList{
+			ActiveDateTime:            1543741825392,
+			OriginationExpireDateTime: 1859361025392,
+			UsageExpireDateTime:       1859361025392,
+			CreationDateTime:          1543741825000,
+			AttestationApplicationID: &AttestationApplicationID{
+				PackageInfos:     []AttestationPackageInfo{{PackageName: "com.google.attestationexample", Version: 1}},
+				SignatureDigests: [][]byte{wantSignatureDigest},
+			},
+		},
+		TeeEnforced: &AuthorizationList{
+			Purpose:      []int{kmPurposeSign},
+			Algorithm:    3,
+			KeySize:      256,
+			Digest:       []int{4},
+			ECCurve:      1,
+			UserAuthType: 23,
+			AuthTimeout:  30,
+			Origin:       &origin,
+		},
+	}
+
+	keyDescription, err := ParseKeyDescription(parseCertificate(attestation1CredCert))
+	if err != nil {
+		t.Fatalf("ParseKeyDescription() returns error %q", err)
+	}
+	if !reflect.DeepEqual(keyDescription, want) {
+		t.Errorf("ParseKeyDescription() returns %+v, want %+v", keyDescription, want)
+		t.Errorf("softwareEnforced %+v, want %+v", keyDescription.SoftwareEnforced, want.SoftwareEnforced)
+		t.Errorf("teeEnforced %+v, want %+v", keyDescription.TeeEnforced, want.TeeEnforced)
+	}
+}
+
+func TestParseKeyDescriptionError(t *testing.T) {
+	_, err := ParseKeyDescription(parseCertificate(attestation1CACert0))
+	wantErrorMsg := "missing certificate extension"
+	if err == nil {
+		t.Errorf("ParseKeyDescription() returns no error, want error containing substring %q", wantErrorMsg)
+	} else if !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("ParseKeyDescription() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	generated, imported := kmOriginGenerated, 2
+	newKeyDescription := func(securityLevel SecurityLevel, softwareEnforced *AuthorizationList, teeEnforced *AuthorizationList) *KeyDescription {
+		return &KeyDescription{
+			AttestationSecurityLevel: securityLevel,
+			KeymasterSecurityLevel:   securityLevel,
+			SoftwareEnforced:         softwareEnforced,
+			TeeEnforced:              teeEnforced,
+		}
+	}
+	signingKey := &AuthorizationList{Purpose: []int{kmPurposeSign}, Origin: &generated}
+	lockedDevice := &AuthorizationList{
+		Purpose:     []int{kmPurposeSign},
+		Origin:      &generated,
+		RootOfTrust: &RootOfTrust{DeviceLocked: true, VerifiedBootState: VerifiedBootStateVerified},
+	}
+	unlockedDevice := &AuthorizationList{
+		Purpose:     []int{kmPurposeSign},
+		Origin:      &generated,
+		RootOfTrust: &RootOfTrust{DeviceLocked: false, VerifiedBootState: VerifiedBootStateUnverified},
+	}
+	selfSignedBoot := &AuthorizationList{
+		Purpose:     []int{kmPurposeSign},
+		Origin:      &generated,
+		RootOfTrust: &RootOfTrust{DeviceLocked: true, VerifiedBootState: VerifiedBootStateSelfSigned},
+	}
+
+	testCases := []struct {
+		name           string
+		policy         *Policy
+		keyDescription *KeyDescription
+		wantErrorMsg   string
+	}{
+		{
+			name:           "default policy accepts software enforced key",
+			policy:         &Policy{},
+			keyDescription: newKeyDescription(SecurityLevelSoftware, signingKey, &AuthorizationList{}),
+		},
+		{
+			name:           "default policy rejects imported key",
+			policy:         &Policy{},
+			keyDescription: newKeyDescription(SecurityLevelSoftware, &AuthorizationList{Purpose: []int{kmPurposeSign}, Origin: &imported}, &AuthorizationList{}),
+			wantErrorMsg:   "failed to verify certificate extension softwareEnforced and teeEnforced: origin is not KM_ORIGIN_GENERATED",
+		},
+		{
+			name:           "default policy rejects missing origin",
+			policy:         &Policy{},
+			keyDescription: newKeyDescription(SecurityLevelSoftware, &AuthorizationList{Purpose: []int{kmPurposeSign}}, &AuthorizationList{}),
+			wantErrorMsg:   "origin is not KM_ORIGIN_GENERATED",
+		},
+		{
+			name:           "default policy rejects encryption key",
+			policy:         &Policy{},
+			keyDescription: newKeyDescription(SecurityLevelSoftware, &AuthorizationList{Purpose: []int{0, 1}, Origin: &generated}, &AuthorizationList{}),
+			wantErrorMsg:   "purpose is not KM_PURPOSE_SIGN",
+		},
+		{
+			name:           "TEE policy accepts TEE key",
+			policy:         &Policy{MinSecurityLevel: SecurityLevelTrustedEnvironment},
+			keyDescription: newKeyDescription(SecurityLevelTrustedEnvironment, &AuthorizationList{}, signingKey),
+		},
+		{
+			name:           "TEE policy accepts StrongBox key",
+			policy:         &Policy{MinSecurityLevel: SecurityLevelTrustedEnvironment},
+			keyDescription: newKeyDescription(SecurityLevelStrongBox, &AuthorizationList{}, signingKey),
+		},
+		{
+			name:           "TEE policy rejects software attestation",
+			policy:         &Policy{MinSecurityLevel: SecurityLevelTrustedEnvironment},
+			keyDescription: newKeyDescription(SecurityLevelSoftware, &AuthorizationList{}, signingKey),
+			wantErrorMsg:   "failed to verify certificate extension attestationSecurityLevel: expected TrustedEnvironment or higher, got Software",
+		},
+		{
+			name:           "TEE policy ignores softwareEnforced",
+			policy:         &Policy{MinSecurityLevel: SecurityLevelTrustedEnvironment},
+			keyDescription: newKeyDescription(SecurityLevelTrustedEnvironment, signingKey, &AuthorizationList{}),
+			wantErrorMsg:   "failed to verify certificate extension teeEnforced: origin is not KM_ORIGIN_GENERATED",
+		},
+		{
+			name:           "StrongBox policy rejects TEE key",
+			policy:         &Policy{MinSecurityLevel: SecurityLevelStrongBox},
+			keyDescription: newKeyDescription(SecurityLevelTrustedEnvironment, &AuthorizationList{}, signingKey),
+			wantErrorMsg:   "expected StrongBox or higher, got TrustedEnvironment",
+		},
+		{
+			name:           "verified boot policy accepts locked device",
+			policy:         &Policy{MinSecurityLevel: SecurityLevelTrustedEnvironment, RequireVerifiedBoot: true},
+			keyDescription: newKeyDescription(SecurityLevelTrustedEnvironment, &AuthorizationList{}, lockedDevice),
+		},
+		{
+			name:           "verified boot policy rejects missing root of trust",
+			policy:         &Policy{MinSecurityLevel: SecurityLevelTrustedEnvironment, RequireVerifiedBoot: true},
+			keyDescription: newKeyDescription(SecurityLevelTrustedEnvironment, &AuthorizationList{}, signingKey),
+			wantErrorMsg:   "failed to verify certificate extension teeEnforced rootOfTrust: rootOfTrust is missing",
+		},
+		{
+			name:           "verified boot policy rejects unlocked device",
+			policy:         &Policy{MinSecurityLevel: SecurityLevelTrustedEnvironment, RequireVerifiedBoot: true},
+			keyDescription: newKeyDescription(SecurityLevelTrustedEnvironment, &AuthorizationList{}, unlockedDevice),
+			wantErrorMsg:   "device is not locked",
+		},
+		{
+			name:           "verified boot policy rejects self-signed boot",
+			policy:         &Policy{MinSecurityLevel: SecurityLevelTrustedEnvironment, RequireVerifiedBoot: true},
+			keyDescription: newKeyDescription(SecurityLevelTrustedEnvironment, &AuthorizationList{}, selfSignedBoot),
+			wantErrorMsg:   "verified boot state is SelfSigned",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.verify(tc.keyDescription)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("verify() returns error %q", err)
+			} else if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("verify() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("verify() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package androidkeystore
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// RevocationStatusListURL is the URL of Google's revocation status list for Android attestation certificates.
+// See https://developer.android.com/privacy-and-security/security-key-attestation#certificate_status
+const RevocationStatusListURL = "https://android.googleapis.com/attestation/status"
+
+// RevocationStatus is the status of a revoked or suspended attestation certificate.
+type RevocationStatus string
+
+// Revocation statuses in Android attestation revocation status list.
+const (
+	RevocationStatusRevoked   RevocationStatus = "REVOKED"
+	RevocationStatusSuspended RevocationStatus = "SUSPENDED"
+)
+
+// RevocationReason is the reason an attestation certificate is revoked or suspended.
+type RevocationReason string
+
+// Revocation reasons in Android attestation revocation status list.
+const (
+	RevocationReasonUnspecified   RevocationReason = "UNSPECIFIED"
+	RevocationReasonKeyCompromise RevocationReason = "KEY_COMPROMISE"
+	RevocationReasonCACompromise  RevocationReason = "CA_COMPROMISE"
+	RevocationReasonSuperseded    RevocationReason = "SUPERSEDED"
+	RevocationReasonSoftwareFlaw  RevocationReason = "SOFTWARE_FLAW"
+)
+
+// RevocationEntry is an entry of Android attestation revocation status list.
+type RevocationEntry struct {
+	Status  RevocationStatus `json:"status"`
+	Expires string           `json:"expires,omitempty"` // Date after which a SUSPENDED certificate is no longer suspended, in YYYY-MM-DD format.
+	Reason  RevocationReason `json:"reason,omitempty"`
+	Comment string           `json:"comment,omitempty"`
+}
+
+// RevocationList is Android attestation revocation status list, keyed by lowercase hexadecimal
+// certificate serial number.
+type RevocationList struct {
+	Entries map[string]RevocationEntry `json:"entries"`
+}
+
+// ParseRevocationList parses JSON encoded Android attestation revocation status list.
+func ParseRevocationList(data []byte) (*RevocationList, error) {
+	var list RevocationList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, errors.New("failed to unmarshal revocation status list: " + err.Error())
+	}
+	if list.Entries == nil {
+		return nil, errors.New("revocation status list is missing entries")
+	}
+	return &list, nil
+}
+
+// Lookup returns revocation entry of certificate at time now, or nil if certificate is not in the list
+// or its suspension has expired.
+func (list *RevocationList) Lookup(cert *x509.Certificate, now time.Time) *RevocationEntry {
+	entry, ok := list.Entries[cert.SerialNumber.Text(16)]
+	if !ok || entry.suspensionExpired(now) {
+		return nil
+	}
+	return &entry
+}
+
+// suspensionExpired returns true if entry is SUSPENDED and now is after its expiration date.
+// Suspensions with a malformed expiration date don't expire.
+func (entry *RevocationEntry) suspensionExpired(now time.Time) bool {
+	if entry.Status != RevocationStatusSuspended || entry.Expires == "" {
+		return false
+	}
+	expires, err := time.Parse("2006-01-02", entry.Expires)
+	if err != nil {
+		return false
+	}
+	// Certificate is suspended until the end of the expiration date.
+	return !now.Before(expires.AddDate(0, 0, 1))
+}
+
+// RevocationListFetcher returns JSON encoded Android attestation revocation status list and the time
+// it expires, such as from an HTTP response and its Cache-Control max-age.
+type RevocationListFetcher func() (data []byte, expires time.Time, err error)
+
+// FileRevocationListFetcher returns RevocationListFetcher that reads revocation status list from file,
+// and caches it for maxAge.
+func FileRevocationListFetcher(filename string, maxAge time.Duration) RevocationListFetcher {
+	return func() ([]byte, time.Time, error) {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		return data, time.Now().Add(maxAge), nil
+	}
+}
+
+// RevocationChecker checks attestation certificates against Android attestation revocation status list.
+// The list is fetched on first use and fetched again after it expires.  It is safe for concurrent use.
+type RevocationChecker struct {
+	fetch RevocationListFetcher
+	now   func() time.Time
+
+	mu      sync.Mutex
+	list    *RevocationList
+	expires time.Time
+}
+
+// NewRevocationChecker returns RevocationChecker using fetch to get revocation status list.
+func NewRevocationChecker(fetch RevocationListFetcher) *RevocationChecker {
+	return &RevocationChecker{fetch: fetch, now: time.Now}
+}
+
+// revocationList returns cached revocation status list, or fetches it if it is missing or expired.
+func (c *RevocationChecker) revocationList() (*RevocationList, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.list != nil && c.now().Before(c.expires) {
+		return c.list, nil
+	}
+	data, expires, err := c.fetch()
+	if err != nil {
+		return nil, errors.New("failed to fetch revocation status list: " + err.Error())
+	}
+	list, err := ParseRevocationList(data)
+	if err != nil {
+		return nil, err
+	}
+	c.list, c.expires = list, expires
+	return list, nil
+}
+
+// Check returns CertificateRevokedError if any certificate is revoked or suspended.
+func (c *RevocationChecker) Check(certs []*x509.Certificate) error {
+	list, err := c.revocationList()
+	if err != nil {
+		return err
+	}
+	for _, cert := range certs {
+		if entry := list.Lookup(cert, c.now()); entry != nil {
+			return &CertificateRevokedError{SerialNumber: cert.SerialNumber.Text(16), Entry: *entry}
+		}
+	}
+	return nil
+}
+
+// CertificateRevokedError results when an attestation certificate is in Android attestation
+// revocation status list.
+type CertificateRevokedError struct {
+	SerialNumber string
+	Entry        RevocationEntry
+}
+
+func (e *CertificateRevokedError) Error() string {
+	s := "webauthn/android_key_attestation: certificate " + e.SerialNumber + " is " + string(e.Entry.Status)
+	if e.Entry.Reason != "" {
+		s += " (" + string(e.Entry.Reason) + ")"
+	}
+	return s
+}
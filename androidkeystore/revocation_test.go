@@ -0,0 +1,202 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package androidkeystore
+
+import (
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRevocationList = `{
+	"entries": {
+		"2c8cdddfd5e03bfc": {"status": "REVOKED", "expires": "2020-11-13", "reason": "KEY_COMPROMISE", "comment": "Key stored on unsecure system"},
+		"1": {"status": "SUSPENDED", "reason": "SOFTWARE_FLAW"}
+	}
+}`
+
+func TestParseRevocationList(t *testing.T) {
+	list, err := ParseRevocationList([]byte(testRevocationList))
+	if err != nil {
+		t.Fatalf("ParseRevocationList() returns error %q", err)
+	}
+	want := RevocationEntry{Status: RevocationStatusRevoked, Expires: "2020-11-13", Reason: RevocationReasonKeyCompromise, Comment: "Key stored on unsecure system"}
+	if entry := list.Entries["2c8cdddfd5e03bfc"]; entry != want {
+		t.Errorf("entry %+v, want %+v", entry, want)
+	}
+
+	// attestation1CredCert has serial number 1.
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if entry := list.Lookup(parseCertificate(attestation1CredCert), now); entry == nil || entry.Status != RevocationStatusSuspended {
+		t.Errorf("Lookup() returns %+v, want SUSPENDED entry", entry)
+	}
+	if entry := list.Lookup(parseCertificate(attestation1CACert0), now); entry != nil {
+		t.Errorf("Lookup() returns %+v, want nil", entry)
+	}
+}
+
+func TestRevocationListLookupSuspensionExpires(t *testing.T) {
+	cert := parseCertificate(attestation1CredCert) // Serial number 1.
+
+	testCases := []struct {
+		name       string
+		entry      RevocationEntry
+		now        time.Time
+		wantStatus RevocationStatus
+	}{
+		{"suspended until expiration date", RevocationEntry{Status: RevocationStatusSuspended, Expires: "2026-01-01"}, time.Date(2026, 1, 1, 23, 59, 0, 0, time.UTC), RevocationStatusSuspended},
+		{"suspension expired", RevocationEntry{Status: RevocationStatusSuspended, Expires: "2026-01-01"}, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), ""},
+		{"suspended without expiration date", RevocationEntry{Status: RevocationStatusSuspended}, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), RevocationStatusSuspended},
+		{"suspended with malformed expiration date", RevocationEntry{Status: RevocationStatusSuspended, Expires: "1 January 2026"}, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), RevocationStatusSuspended},
+		{"revoked after expiration date", RevocationEntry{Status: RevocationStatusRevoked, Expires: "2026-01-01"}, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), RevocationStatusRevoked},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			list := &RevocationList{Entries: map[string]RevocationEntry{"1": tc.entry}}
+			entry := list.Lookup(cert, tc.now)
+			if tc.wantStatus == "" {
+				if entry != nil {
+					t.Errorf("Lookup() returns %+v, want nil", entry)
+				}
+				return
+			}
+			if entry == nil || entry.Status != tc.wantStatus {
+				t.Errorf("Lookup() returns %+v, want %s entry", entry, tc.wantStatus)
+			}
+		})
+	}
+
+	// RevocationChecker accepts certificates whose suspension has expired.
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	c := NewRevocationChecker(func() ([]byte, time.Time, error) {
+		return []byte(`{"entries": {"1": {"status": "SUSPENDED", "expires": "2026-01-01"}}}`), now.Add(time.Hour), nil
+	})
+	c.now = func() time.Time { return now }
+	if err := c.Check([]*x509.Certificate{cert}); err != nil {
+		t.Errorf("Check() returns error %q, want no error after suspension expired", err)
+	}
+}
+
+func TestParseRevocationListError(t *testing.T) {
+	testCases := []struct {
+		name         string
+		data         string
+		wantErrorMsg string
+	}{
+		{"not json", "revoked", "failed to unmarshal revocation status list"},
+		{"missing entries", "{}", "revocation status list is missing entries"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseRevocationList([]byte(tc.data)); err == nil {
+				t.Errorf("ParseRevocationList() returns no error, want error containing substring %q", tc.wantErrorMsg)
+			} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+				t.Errorf("ParseRevocationList() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+			}
+		})
+	}
+}
+
+func TestRevocationChecker(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fetches := 0
+	list := testRevocationList
+	c := NewRevocationChecker(func() ([]byte, time.Time, error) {
+		fetches++
+		return []byte(list), now.Add(time.Hour), nil
+	})
+	c.now = func() time.Time { return now }
+
+	credCert := parseCertificate(attestation1CredCert)
+	caCert := parseCertificate(attestation1CACert0)
+
+	err := c.Check(nil)
+	if err != nil {
+		t.Fatalf("Check() returns error %q", err)
+	}
+	err = c.Check([]*x509.Certificate{credCert, caCert})
+	revokedErr, ok := err.(*CertificateRevokedError)
+	if !ok {
+		t.Fatalf("Check() returns error %v (%T), want *CertificateRevokedError", err, err)
+	}
+	if revokedErr.SerialNumber != "1" || revokedErr.Entry.Status != RevocationStatusSuspended {
+		t.Errorf("Check() returns error %+v, want SUSPENDED certificate 1", revokedErr)
+	}
+	wantErrorMsg := "webauthn/android_key_attestation: certificate 1 is SUSPENDED (SOFTWARE_FLAW)"
+	if err.Error() != wantErrorMsg {
+		t.Errorf("error %q, want %q", err, wantErrorMsg)
+	}
+	if fetches != 1 {
+		t.Errorf("revocation status list fetched %d times, want 1", fetches)
+	}
+
+	// Cached list is used until it expires.
+	list = `{"entries": {}}`
+	now = now.Add(30 * time.Minute)
+	if err = c.Check([]*x509.Certificate{credCert}); err == nil {
+		t.Errorf("Check() returns no error with cached list, want *CertificateRevokedError")
+	}
+	now = now.Add(time.Hour)
+	if err = c.Check([]*x509.Certificate{credCert}); err != nil {
+		t.Errorf("Check() returns error %q with fetched list", err)
+	}
+	if fetches != 2 {
+		t.Errorf("revocation status list fetched %d times, want 2", fetches)
+	}
+}
+
+func TestRevocationCheckerFetchError(t *testing.T) {
+	c := NewRevocationChecker(func() ([]byte, time.Time, error) {
+		return nil, time.Time{}, errors.New("connection refused")
+	})
+	wantErrorMsg := "failed to fetch revocation status list: connection refused"
+	if err := c.Check(nil); err == nil {
+		t.Errorf("Check() returns no error, want error containing substring %q", wantErrorMsg)
+	} else if !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("Check() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	}
+}
+
+func TestFileRevocationListFetcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "androidkeystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "status.json")
+	if err = ioutil.WriteFile(filename, []byte(testRevocationList), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewRevocationChecker(FileRevocationListFetcher(filename, time.Hour))
+	if err = c.Check([]*x509.Certificate{parseCertificate(attestation1CredCert)}); err == nil {
+		t.Errorf("Check() returns no error, want *CertificateRevokedError")
+	}
+
+	c = NewRevocationChecker(FileRevocationListFetcher(filepath.Join(dir, "missing.json"), time.Hour))
+	if err = c.Check(nil); err == nil {
+		t.Errorf("Check() returns no error for missing file, want error")
+	}
+}
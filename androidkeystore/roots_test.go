@@ -0,0 +1,135 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package androidkeystore
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/internal/testcert"
+)
+
+func TestDefaultRoots(t *testing.T) {
+	wantSubjects := []string{
+		"SERIALNUMBER=f92009e853b6b045",
+		"CN=Key Attestation CA1,OU=Android,O=Google LLC,C=US",
+	}
+	roots := DefaultRoots.Certificates()
+	if len(roots) != len(wantSubjects) {
+		t.Fatalf("default roots has %d certificates, want %d", len(roots), len(wantSubjects))
+	}
+	for i, c := range roots {
+		if c.Subject.String() != wantSubjects[i] {
+			t.Errorf("default root %d subject %q, want %q", i, c.Subject, wantSubjects[i])
+		}
+		if err := c.CheckSignatureFrom(c); err != nil {
+			t.Errorf("default root %d self signature: %q", i, err)
+		}
+		if c.NotAfter.Before(time.Date(2026, time.May, 25, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("default root %d expired at %v", i, c.NotAfter)
+		}
+	}
+}
+
+func TestSoftwareAttestationRoot(t *testing.T) {
+	want := "CN=Android Keystore Software Attestation Root,OU=Android,O=Google\\, Inc.,L=Mountain View,ST=California,C=US"
+	if s := SoftwareAttestationRoot.Subject.String(); s != want {
+		t.Errorf("software attestation root subject %q, want %q", s, want)
+	}
+	for _, c := range DefaultRoots.Certificates() {
+		if c.Equal(SoftwareAttestationRoot) {
+			t.Errorf("default roots contain software attestation root")
+		}
+	}
+}
+
+func TestRoots(t *testing.T) {
+	root1 := testcert.NewCA("Test Root CA 1", nil)
+	root2 := testcert.NewCA("Test Root CA 2", nil)
+
+	roots := NewRoots(root1.Cert)
+	roots.Add(root1.Cert)
+	if n := len(roots.Certificates()); n != 1 {
+		t.Errorf("roots has %d certificates after adding duplicate, want 1", n)
+	}
+
+	if err := roots.AddPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root2.Cert.Raw})); err != nil {
+		t.Fatalf("AddPEM() returns error %q", err)
+	}
+	if certs := roots.Certificates(); len(certs) != 2 || !certs[1].Equal(root2.Cert) {
+		t.Errorf("roots %v, want root1 and root2", certs)
+	}
+
+	wantErrorMsg := "no PEM encoded certificate"
+	if err := roots.AddPEM([]byte("not pem")); err == nil {
+		t.Errorf("AddPEM() returns no error, want error containing substring %q", wantErrorMsg)
+	} else if !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("AddPEM() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	}
+
+	roots.Set([]*x509.Certificate{root2.Cert})
+	if certs := roots.Certificates(); len(certs) != 1 || !certs[0].Equal(root2.Cert) {
+		t.Errorf("roots %v, want root2", certs)
+	}
+}
+
+func TestVerifyAttestationCertRoots(t *testing.T) {
+	root := testcert.NewCA("Test Root CA", nil)
+	intermediate := testcert.NewCA("Test Intermediate CA", root)
+	leaf := testcert.NewCA("Test Leaf", intermediate)
+	otherRoot := testcert.NewCA("Other Root CA", nil)
+	otherIntermediate := testcert.NewCA("Other Intermediate CA", otherRoot)
+	otherLeaf := testcert.NewCA("Other Leaf", otherIntermediate)
+
+	savedRoots := DefaultRoots.Certificates()
+	DefaultRoots.Set([]*x509.Certificate{root.Cert})
+	defer DefaultRoots.Set(savedRoots)
+
+	testCases := []struct {
+		name         string
+		leaf         *x509.Certificate
+		caCerts      []*x509.Certificate
+		wantErrorMsg string
+	}{
+		{"chain with root", leaf.Cert, []*x509.Certificate{intermediate.Cert, root.Cert}, ""},
+		{"chain without root", leaf.Cert, []*x509.Certificate{intermediate.Cert}, ""},
+		{"self-signed untrusted root", otherLeaf.Cert, []*x509.Certificate{otherIntermediate.Cert, otherRoot.Cert}, "certificate signed by unknown authority"},
+		{"missing intermediate", leaf.Cert, nil, "certificate signed by unknown authority"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			trustPath, trusted, err := webauthn.VerifyAttestationCertificateWithRoots(tc.leaf, tc.caCerts, DefaultRoots.Certificates())
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("VerifyAttestationCertificateWithRoots() returns error %q", err)
+			} else if tc.wantErrorMsg == "" && (!trusted || len(trustPath) != 3 || !trustPath[2].Equal(root.Cert)) {
+				t.Errorf("trust path %v, want chain to trusted root", trustPath)
+			} else if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyAttestationCertificateWithRoots() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyAttestationCertificateWithRoots() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+			}
+		})
+	}
+}
@@ -26,6 +26,8 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/fxamacker/cbor/v2"
 	"github.com/kappapay/webauthn"
@@ -94,7 +96,133 @@ type androidSafetyNetAttestationStatement struct {
 	rawSignature []byte
 	*header
 	*payload
-	sig []byte
+	sig     []byte
+	details *AttestationDetails
+}
+
+// AttestationDetails represents android-safetynet specific details of a verified attestation statement.
+type AttestationDetails struct {
+	CTSProfileMatch bool         // Device passed Android compatibility testing.
+	BasicIntegrity  bool         // Device passed basic integrity checks.
+	AppIdentity     *AppIdentity // App identity allowed by AppIdentityPolicy, or nil if the policy is not set.
+}
+
+// IntegrityRequirement specifies which SafetyNet device integrity verdict is required.
+type IntegrityRequirement int
+
+// Device integrity requirements.
+const (
+	// RequireCTSProfileMatch requires ctsProfileMatch, as specified by WebAuthn.
+	RequireCTSProfileMatch IntegrityRequirement = iota
+	// RequireBasicIntegrity requires basicIntegrity, and accepts devices failing Android compatibility testing.
+	RequireBasicIntegrity
+	// RequireNoIntegrity accepts any device integrity verdict.
+	RequireNoIntegrity
+)
+
+// Policy specifies which SafetyNet attestations are accepted in addition to the android-safetynet
+// attestation statement verification procedure.
+type Policy struct {
+	// Now returns current time, used to verify timestampMs, and the attestation certificate chain unless
+	// CertificatePolicy is set.  time.Now is used if it is nil.
+	Now func() time.Time
+
+	// CertificatePolicy is the policy used to verify the attestation certificate chain, including the
+	// time it is verified at, which is independent of Now.  If it is nil, webauthn.DefaultCertificatePolicy
+	// is used with its time replaced by Now, if Now is set.
+	CertificatePolicy *webauthn.CertificatePolicy
+
+	// MaxAge is the maximum age of SafetyNet response.  timestampMs is not verified if it is 0.
+	MaxAge time.Duration
+
+	// ClockSkew is the allowed difference between the clocks of the device and the server.
+	ClockSkew time.Duration
+
+	// MinVersion is the minimum Google Play Services version ver.  Any version is accepted if it is 0.
+	MinVersion uint64
+
+	// Integrity specifies the required device integrity verdict.
+	Integrity IntegrityRequirement
+
+	// AppIdentity specifies Android apps allowed to create credentials.  Any app is allowed if it is nil.
+	AppIdentity *AppIdentityPolicy
+}
+
+// DefaultPolicy is the policy used to verify android-safetynet attestation statements.  It requires
+// ctsProfileMatch, and doesn't verify timestampMs.  Modify it during initialization, before verifying
+// attestations.
+var DefaultPolicy = &Policy{}
+
+func (policy *Policy) now() time.Time {
+	if policy.Now != nil {
+		return policy.Now()
+	}
+	return time.Now()
+}
+
+func (policy *Policy) certificatePolicy() *webauthn.CertificatePolicy {
+	if policy.CertificatePolicy != nil {
+		return policy.CertificatePolicy
+	}
+	if policy.Now == nil {
+		return webauthn.DefaultCertificatePolicy
+	}
+	// Verify certificates at the same time as timestampMs, so that one clock is set for tests and replays.
+	certificatePolicy := *webauthn.DefaultCertificatePolicy
+	certificatePolicy.Now = policy.Now
+	return &certificatePolicy
+}
+
+// verifyVersion verifies that ver is a Google Play Services version number not less than MinVersion.
+func (policy *Policy) verifyVersion(ver string) error {
+	if ver == "" {
+		return &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "ver", Msg: "ver is empty"}
+	}
+	version, err := strconv.ParseUint(ver, 10, 64)
+	if err != nil {
+		return &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "ver", Msg: "ver " + strconv.Quote(ver) + " is not a version number"}
+	}
+	if version < policy.MinVersion {
+		return &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "ver", Msg: "ver " + ver + " is less than minimum version " + strconv.FormatUint(policy.MinVersion, 10)}
+	}
+	return nil
+}
+
+// verifyTimestamp verifies that SafetyNet response was generated within MaxAge, allowing ClockSkew.
+func (policy *Policy) verifyTimestamp(timestampMS uint64) error {
+	if policy.MaxAge == 0 {
+		return nil
+	}
+	if timestampMS == 0 {
+		return &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "payload.timestampMs", Msg: "timestampMs is missing"}
+	}
+	timestamp := time.Unix(0, 0).Add(time.Duration(timestampMS) * time.Millisecond)
+	now := policy.now()
+	if timestamp.After(now.Add(policy.ClockSkew)) {
+		return &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "payload.timestampMs", Msg: "timestamp " + timestamp.UTC().Format(time.RFC3339) + " is in the future"}
+	}
+	if now.Sub(timestamp) > policy.MaxAge+policy.ClockSkew {
+		return &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "payload.timestampMs", Msg: "timestamp " + timestamp.UTC().Format(time.RFC3339) + " is older than " + policy.MaxAge.String()}
+	}
+	return nil
+}
+
+// verifyIntegrity verifies device integrity verdict in SafetyNet response.
+func (policy *Policy) verifyIntegrity(p *payload) error {
+	switch policy.Integrity {
+	case RequireCTSProfileMatch:
+		if !p.CTSProfileMatch {
+			return &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "payload.ctsProfileMatch", Msg: "ctsProfileMatch is false"}
+		}
+	case RequireBasicIntegrity:
+		if !p.BasicIntegrity {
+			return &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "payload.basicIntegrity", Msg: "basicIntegrity is false"}
+		}
+	case RequireNoIntegrity:
+	default:
+		return &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "integrity requirement", Msg: "unknown integrity requirement " + strconv.Itoa(int(policy.Integrity))}
+	}
+	return nil
 }
 
 func parseAttestation(data []byte) (webauthn.AttestationStatement, error) {
@@ -192,7 +320,10 @@ func parseHeader(data []byte) (h *header, err error) {
 // android-key attestation statement verification procedure defined in
 // http://w3c.github.io/webauthn/#sctn-android-safetynet-attestation
 func (attStmt *androidSafetyNetAttestationStatement) Verify(clientDataHash []byte, authnData *webauthn.AuthenticatorData) (attType webauthn.AttestationType, trustPath interface{}, err error) {
-	// todo: Verify that response is a valid SafetyNet response of version ver.
+	// Verify that response is a valid SafetyNet response of version ver.
+	if err = DefaultPolicy.verifyVersion(attStmt.ver); err != nil {
+		return
+	}
 
 	// Verify that the nonce in the response is identical to the Base64 encoding of the SHA-256
 	// hash of the concatenation of authenticatorData and clientDataHash.
@@ -214,14 +345,19 @@ func (attStmt *androidSafetyNetAttestationStatement) Verify(clientDataHash []byt
 		return
 	}
 
-	// Verify that the ctsProfileMatch attribute in the payload of response is true.
-	if !attStmt.CTSProfileMatch {
-		err = &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "payload.ctsProfileMatch ", Msg: "ctsProfileMatch is false"}
+	// Verify that the ctsProfileMatch attribute in the payload of response is true, or the device
+	// integrity verdict required by policy.
+	if err = DefaultPolicy.verifyIntegrity(attStmt.payload); err != nil {
+		return
+	}
+
+	// Verify that response is fresh.
+	if err = DefaultPolicy.verifyTimestamp(attStmt.TimestampMS); err != nil {
 		return
 	}
 
-	// Verify attestation certificate by building certificate chain.
-	if trustPath, err = verifyAttestationCert(attStmt.attestnCert, attStmt.caCerts); err != nil {
+	// Verify attestation certificate by building certificate chain to Google GlobalSign Root CA R2.
+	if trustPath, _, err = DefaultPolicy.certificatePolicy().VerifyAttestationCertificateWithRoots(attStmt.attestnCert, attStmt.caCerts, []*x509.Certificate{googleGlobalSignRootCAR2Cert}); err != nil {
 		err = &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "certificate", Msg: err.Error()}
 		return
 	}
@@ -245,30 +381,24 @@ func (attStmt *androidSafetyNetAttestationStatement) Verify(clientDataHash []byt
 		return
 	}
 
+	// Verify that the app that created the credential is allowed.
+	details := &AttestationDetails{CTSProfileMatch: attStmt.CTSProfileMatch, BasicIntegrity: attStmt.BasicIntegrity}
+	if DefaultPolicy.AppIdentity != nil {
+		if details.AppIdentity, err = DefaultPolicy.AppIdentity.match(attStmt.payload); err != nil {
+			return
+		}
+	}
+	attStmt.details = details
+
 	// If successful, return implementation-specific values representing attestation type Basic and
 	// attestation trust path attestationCert.
 	return webauthn.AttestationTypeBasic, trustPath, nil
 }
 
-func verifyAttestationCert(attestnCert *x509.Certificate, caCerts []*x509.Certificate) (trustPath []*x509.Certificate, err error) {
-	var verifyOptions x509.VerifyOptions
-
-	verifyOptions.Roots = x509.NewCertPool()
-	verifyOptions.Roots.AddCert(googleGlobalSignRootCAR2Cert)
-
-	if len(caCerts) > 0 {
-		verifyOptions.Intermediates = x509.NewCertPool()
-		for _, c := range caCerts {
-			verifyOptions.Intermediates.AddCert(c)
-		}
-	}
-
-	var chains [][]*x509.Certificate
-	chains, err = attestnCert.Verify(verifyOptions)
-	if err != nil {
-		return nil, err
-	}
-	return chains[0], nil
+// AttestationDetails implements the webauthn.AttestationDetailer interface.  It returns *AttestationDetails
+// after successful verification.
+func (attStmt *androidSafetyNetAttestationStatement) AttestationDetails() interface{} {
+	return attStmt.details
 }
 
 func init() {
@@ -0,0 +1,78 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package androidsafetynet
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/internal/appidentity"
+)
+
+// AppIdentityPolicy specifies Android apps allowed to create credentials, identified by
+// apkPackageName, apkCertificateDigestSha256, and apkDigestSha256 in SafetyNet response.
+type AppIdentityPolicy struct {
+	PackageNames       []string // Allowed package names.  Any package name is allowed if empty.
+	SigningCertDigests [][]byte // Allowed SHA-256 digests of app signing certificates.  Any signing certificate is allowed if empty.
+	APKDigests         [][]byte // Allowed SHA-256 digests of APK files.  Any APK is allowed if empty.
+}
+
+// AppIdentity identifies the Android app that created the credential.
+type AppIdentity struct {
+	PackageName       string // Package name of the app.
+	SigningCertDigest []byte // SHA-256 digest of the app signing certificate.
+	APKDigest         []byte // SHA-256 digest of the APK file.
+}
+
+// match returns the app identity in SafetyNet response that is allowed by policy.
+func (policy *AppIdentityPolicy) match(p *payload) (*AppIdentity, error) {
+	if p.APKPackageName == "" {
+		return nil, &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "payload.apkPackageName", Msg: "apkPackageName is missing"}
+	}
+	if !appidentity.AllowsName(policy.PackageNames, p.APKPackageName) {
+		return nil, &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "payload.apkPackageName", Msg: "package name " + p.APKPackageName + " is not allowed"}
+	}
+	identity := &AppIdentity{PackageName: p.APKPackageName}
+
+	digests := make([][]byte, len(p.APKCertificateDigestSHA256))
+	for i, encodedDigest := range p.APKCertificateDigestSHA256 {
+		digest, err := base64.StdEncoding.DecodeString(encodedDigest)
+		if err != nil {
+			return nil, &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "payload.apkCertificateDigestSha256", Msg: "failed to base64 decode digest: " + err.Error()}
+		}
+		digests[i] = digest
+	}
+	var ok bool
+	if identity.SigningCertDigest, ok = appidentity.MatchDigest(policy.SigningCertDigests, digests); !ok {
+		return nil, &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "payload.apkCertificateDigestSha256", Msg: "signing certificate digest " + strings.Join(p.APKCertificateDigestSHA256, ", ") + " is not allowed"}
+	}
+
+	if p.APKDigestSHA256 != "" {
+		digest, err := base64.StdEncoding.DecodeString(p.APKDigestSHA256)
+		if err != nil {
+			return nil, &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "payload.apkDigestSha256", Msg: "failed to base64 decode digest: " + err.Error()}
+		}
+		identity.APKDigest = digest
+	}
+	if !appidentity.AllowsDigest(policy.APKDigests, identity.APKDigest) {
+		return nil, &webauthn.VerificationError{Type: "Android safetynet attestation", Field: "payload.apkDigestSha256", Msg: "APK digest " + p.APKDigestSHA256 + " is not allowed"}
+	}
+	return identity, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package androidsafetynet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestAppIdentityPolicy(t *testing.T) {
+	signingCertDigest := sha256.Sum256([]byte("signing certificate"))
+	otherDigest := sha256.Sum256([]byte("other"))
+	apkDigest := sha256.Sum256([]byte("apk"))
+	p := &payload{
+		APKPackageName:             "com.example.app",
+		APKCertificateDigestSHA256: []string{base64.StdEncoding.EncodeToString(signingCertDigest[:])},
+		APKDigestSHA256:            base64.StdEncoding.EncodeToString(apkDigest[:]),
+	}
+
+	testCases := []struct {
+		name         string
+		policy       *AppIdentityPolicy
+		payload      *payload
+		wantIdentity *AppIdentity
+		wantErrorMsg string
+	}{
+		{
+			name: "allowed app",
+			policy: &AppIdentityPolicy{
+				PackageNames:       []string{"com.example.app"},
+				SigningCertDigests: [][]byte{otherDigest[:], signingCertDigest[:]},
+				APKDigests:         [][]byte{apkDigest[:]},
+			},
+			payload:      p,
+			wantIdentity: &AppIdentity{PackageName: "com.example.app", SigningCertDigest: signingCertDigest[:], APKDigest: apkDigest[:]},
+		},
+		{
+			name:         "any app",
+			policy:       &AppIdentityPolicy{},
+			payload:      p,
+			wantIdentity: &AppIdentity{PackageName: "com.example.app", SigningCertDigest: signingCertDigest[:], APKDigest: apkDigest[:]},
+		},
+		{
+			name:         "package not allowed",
+			policy:       &AppIdentityPolicy{PackageNames: []string{"com.example.other"}},
+			payload:      p,
+			wantErrorMsg: "webauthn/android_safetynet_attestation: failed to verify payload.apkPackageName: package name com.example.app is not allowed",
+		},
+		{
+			name:         "signing certificate not allowed",
+			policy:       &AppIdentityPolicy{SigningCertDigests: [][]byte{otherDigest[:]}},
+			payload:      p,
+			wantErrorMsg: "failed to verify payload.apkCertificateDigestSha256: signing certificate digest " + p.APKCertificateDigestSHA256[0] + " is not allowed",
+		},
+		{
+			name:         "APK not allowed",
+			policy:       &AppIdentityPolicy{APKDigests: [][]byte{otherDigest[:]}},
+			payload:      p,
+			wantErrorMsg: "failed to verify payload.apkDigestSha256: APK digest " + p.APKDigestSHA256 + " is not allowed",
+		},
+		{
+			name:         "missing package name",
+			policy:       &AppIdentityPolicy{},
+			payload:      &payload{},
+			wantErrorMsg: "apkPackageName is missing",
+		},
+		{
+			name:         "invalid signing certificate digest",
+			policy:       &AppIdentityPolicy{},
+			payload:      &payload{APKPackageName: "com.example.app", APKCertificateDigestSHA256: []string{"not base64!"}},
+			wantErrorMsg: "failed to verify payload.apkCertificateDigestSha256: failed to base64 decode digest",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			identity, err := tc.policy.match(tc.payload)
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("match() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("match() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("match() returns error %q", err)
+			}
+			if identity.PackageName != tc.wantIdentity.PackageName || !bytes.Equal(identity.SigningCertDigest, tc.wantIdentity.SigningCertDigest) || !bytes.Equal(identity.APKDigest, tc.wantIdentity.APKDigest) {
+				t.Errorf("match() returns %+v, want %+v", identity, tc.wantIdentity)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package androidsafetynet
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kappapay/webauthn"
+)
+
+// attestation1Time is timestampMs of SafetyNet response in attestation1.
+var attestation1Time = time.Unix(0, 1541336729930*int64(time.Millisecond))
+
+// attestation1CertificatePolicy verifies certificates of attestation1 at the time of SafetyNet response.
+var attestation1CertificatePolicy = &webauthn.CertificatePolicy{Now: func() time.Time { return attestation1Time }}
+
+func TestVerifyAndroidSafetyNetAttestationPolicy(t *testing.T) {
+	testCases := []struct {
+		name         string
+		policy       *Policy
+		wantErrorMsg string
+	}{
+		{
+			name:   "fresh response",
+			policy: &Policy{Now: func() time.Time { return attestation1Time.Add(30 * time.Second) }, MaxAge: time.Minute, CertificatePolicy: attestation1CertificatePolicy},
+		},
+		{
+			name:   "response within clock skew",
+			policy: &Policy{Now: func() time.Time { return attestation1Time.Add(-10 * time.Second) }, MaxAge: time.Minute, ClockSkew: 30 * time.Second, CertificatePolicy: attestation1CertificatePolicy},
+		},
+		{
+			name:         "stale response",
+			policy:       &Policy{Now: func() time.Time { return attestation1Time.Add(2 * time.Minute) }, MaxAge: time.Minute, CertificatePolicy: attestation1CertificatePolicy},
+			wantErrorMsg: "failed to verify payload.timestampMs: timestamp 2018-11-04T13:05:29Z is older than 1m0s",
+		},
+		{
+			name:         "response from the future",
+			policy:       &Policy{Now: func() time.Time { return attestation1Time.Add(-time.Minute) }, MaxAge: time.Minute, ClockSkew: 30 * time.Second, CertificatePolicy: attestation1CertificatePolicy},
+			wantErrorMsg: "failed to verify payload.timestampMs: timestamp 2018-11-04T13:05:29Z is in the future",
+		},
+		{
+			name:         "old Google Play Services",
+			policy:       &Policy{Now: func() time.Time { return attestation1Time }, MinVersion: 15000000, CertificatePolicy: attestation1CertificatePolicy},
+			wantErrorMsg: "failed to verify ver: ver 14366019 is less than minimum version 15000000",
+		},
+		{
+			name:   "basic integrity",
+			policy: &Policy{Now: func() time.Time { return attestation1Time }, Integrity: RequireBasicIntegrity, CertificatePolicy: attestation1CertificatePolicy},
+		},
+		{
+			name:   "certificate verified at Now",
+			policy: &Policy{Now: func() time.Time { return attestation1Time }},
+		},
+		{
+			name:         "certificate verified at time of certificate policy",
+			policy:       &Policy{Now: func() time.Time { return attestation1Time }, CertificatePolicy: &webauthn.CertificatePolicy{}},
+			wantErrorMsg: "failed to verify certificate: x509: certificate has expired or is not yet valid",
+		},
+		{
+			name:         "expired certificate",
+			policy:       &Policy{},
+			wantErrorMsg: "failed to verify certificate: x509: certificate has expired or is not yet valid",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			savedPolicy := DefaultPolicy
+			DefaultPolicy = tc.policy
+			defer func() { DefaultPolicy = savedPolicy }()
+
+			var credentialAttestation webauthn.PublicKeyCredentialAttestation
+			if err := json.Unmarshal([]byte(attestation1), &credentialAttestation); err != nil {
+				t.Fatalf("failed to unmarshal attestation %s: %q", attestation1, err)
+			}
+			attType, trustPath, err := credentialAttestation.VerifyAttestationStatement()
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyAttestationStatement() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyAttestationStatement() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyAttestationStatement() returns error %q", err)
+			}
+			if attType != verifyTests[0].wantAttType {
+				t.Errorf("attestation type %v, want %v", attType, verifyTests[0].wantAttType)
+			}
+			if !reflect.DeepEqual(trustPath, verifyTests[0].wantTrustPath) {
+				t.Errorf("trust path %v, want %v", trustPath, verifyTests[0].wantTrustPath)
+			}
+			details, ok := credentialAttestation.AttStmt.(webauthn.AttestationDetailer).AttestationDetails().(*AttestationDetails)
+			if !ok || !details.CTSProfileMatch || !details.BasicIntegrity {
+				t.Errorf("attestation details %+v, want ctsProfileMatch and basicIntegrity", details)
+			}
+		})
+	}
+}
+
+func TestPolicyVerifyVersion(t *testing.T) {
+	testCases := []struct {
+		name         string
+		ver          string
+		wantErrorMsg string
+	}{
+		{"version", "14366019", ""},
+		{"empty version", "", "failed to verify ver: ver is empty"},
+		{"not a version number", "14.3.66", "failed to verify ver: ver \"14.3.66\" is not a version number"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := (&Policy{}).verifyVersion(tc.ver)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("verifyVersion() returns error %q", err)
+			} else if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("verifyVersion() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("verifyVersion() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestPolicyVerifyIntegrity(t *testing.T) {
+	testCases := []struct {
+		name         string
+		integrity    IntegrityRequirement
+		payload      *payload
+		wantErrorMsg string
+	}{
+		{"cts profile match", RequireCTSProfileMatch, &payload{CTSProfileMatch: true, BasicIntegrity: true}, ""},
+		{"cts profile mismatch", RequireCTSProfileMatch, &payload{BasicIntegrity: true}, "failed to verify payload.ctsProfileMatch: ctsProfileMatch is false"},
+		{"basic integrity", RequireBasicIntegrity, &payload{BasicIntegrity: true}, ""},
+		{"no basic integrity", RequireBasicIntegrity, &payload{}, "failed to verify payload.basicIntegrity: basicIntegrity is false"},
+		{"no integrity", RequireNoIntegrity, &payload{}, ""},
+		{"unknown requirement", IntegrityRequirement(9), &payload{CTSProfileMatch: true, BasicIntegrity: true}, "unknown integrity requirement 9"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := (&Policy{Integrity: tc.integrity}).verifyIntegrity(tc.payload)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("verifyIntegrity() returns error %q", err)
+			} else if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("verifyIntegrity() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("verifyIntegrity() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+			}
+		})
+	}
+}
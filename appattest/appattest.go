@@ -0,0 +1,353 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+/*
+Package appattest verifies attestations and assertions of Apple App Attest keys, generated by the
+DeviceCheck App Attest service on iOS, so apps and web clients can share one library for device binding.
+See https://developer.apple.com/documentation/devicecheck/validating_apps_that_connect_to_your_server
+*/
+package appattest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/internal/applenonce"
+)
+
+// Apple App Attestation Root CA certificate is available at https://www.apple.com/certificateauthority/private/
+const appleAppAttestationRootCACertPem = `-----BEGIN CERTIFICATE-----
+MIICITCCAaegAwIBAgIQC/O+DvHN0uD7jG5yH2IXmDAKBggqhkjOPQQDAzBSMSYw
+JAYDVQQDDB1BcHBsZSBBcHAgQXR0ZXN0YXRpb24gUm9vdCBDQTETMBEGA1UECgwK
+QXBwbGUgSW5jLjETMBEGA1UECAwKQ2FsaWZvcm5pYTAeFw0yMDAzMTgxODMyNTNa
+Fw00NTAzMTUwMDAwMDBaMFIxJjAkBgNVBAMMHUFwcGxlIEFwcCBBdHRlc3RhdGlv
+biBSb290IENBMRMwEQYDVQQKDApBcHBsZSBJbmMuMRMwEQYDVQQIDApDYWxpZm9y
+bmlhMHYwEAYHKoZIzj0CAQYFK4EEACIDYgAERTHhmLW07ATaFQIEVwTtT4dyctdh
+NbJhFs/Ii2FdCgAHGbpphY3+d8qjuDngIN3WVhQUBHAoMeQ/cLiP1sOUtgjqK9au
+Yen1mMEvRq9Sk3Jm5X8U62H+xTD3FE9TgS41o0IwQDAPBgNVHRMBAf8EBTADAQH/
+MB0GA1UdDgQWBBSskRBTM72+aEH/pwyp5frq5eWKoTAOBgNVHQ8BAf8EBAMCAQYw
+CgYIKoZIzj0EAwMDaAAwZQIwQgFGnByvsiVbpTKwSga0kP0e8EeDS4+sQmTvb7vn
+53O5+FRXgeLhpJ06ysC5PrOyAjEAp5U4xDgEgllF7En3VcE3iexZZtKeYnpqtijV
+oyFraWVIyd/dganmrduC1bmTBGwD
+-----END CERTIFICATE-----`
+
+var (
+	appleAppAttestationRootCACert *x509.Certificate
+	aaguidAppAttestProduction     = []byte("appattest\x00\x00\x00\x00\x00\x00\x00")
+	aaguidAppAttestDevelopment    = []byte("appattestdevelop")
+)
+
+// Environment identifies the App Attest environment in which a key was attested.
+type Environment int
+
+// App Attest environments.
+const (
+	EnvironmentProduction Environment = iota + 1
+	EnvironmentDevelopment
+)
+
+func (env Environment) String() string {
+	switch env {
+	case EnvironmentProduction:
+		return "production"
+	case EnvironmentDevelopment:
+		return "development"
+	default:
+		return "Undefined"
+	}
+}
+
+// AttestationExpectedData represents data needed to verify App Attest attestations.
+type AttestationExpectedData struct {
+	AppID            string // Team identifier and bundle identifier of the app, such as "0352187391.com.example.app".
+	Challenge        []byte // One-time challenge the server sent to the app for attestation.
+	KeyID            []byte // Key identifier generated by the app.
+	AllowDevelopment bool   // Accept keys attested in the development environment.
+}
+
+// Key represents an App Attest key verified by attestation.  The app server stores Credential
+// and a counter starting at 0 with the key identifier to verify later assertions.
+type Key struct {
+	ID          []byte               // Key identifier.
+	Credential  *webauthn.Credential // Algorithm and public key of the key.
+	Environment Environment          // Environment in which the key was attested.
+	Receipt     []byte               // Receipt for obtaining fraud risk metric from Apple.
+	TrustPath   []*x509.Certificate  // Attestation certificate chain to Apple App Attestation Root CA.
+}
+
+// AssertionExpectedData represents data needed to verify App Attest assertions.
+type AssertionExpectedData struct {
+	AppID       string               // Team identifier and bundle identifier of the app.
+	Credential  *webauthn.Credential // Credential of the key, returned by VerifyAttestation.
+	PrevCounter uint32               // Counter of the key returned by the previous assertion, or 0.
+}
+
+type appAttestAttestationStatement struct {
+	credCert *x509.Certificate   // Credential certificate.
+	caCerts  []*x509.Certificate // Credential certificate chain.
+	receipt  []byte              // Receipt.
+}
+
+// parseAttestationObject parses CBOR encoded App Attest attestation object.  The apple-appattest format
+// isn't registered with webauthn.RegisterAttestationFormat, so App Attest attestation objects are never
+// accepted as WebAuthn registrations.
+func parseAttestationObject(data []byte) (*webauthn.AuthenticatorData, *appAttestAttestationStatement, error) {
+	type rawAttestationObject struct {
+		AuthnData []byte          `cbor:"authData"`
+		Fmt       string          `cbor:"fmt"`
+		AttStmt   cbor.RawMessage `cbor:"attStmt"`
+	}
+	var raw rawAttestationObject
+	if err := cbor.Unmarshal(data, &raw); err != nil {
+		return nil, nil, &webauthn.UnmarshalSyntaxError{Type: "app attest attestation object", Msg: err.Error()}
+	}
+	if len(raw.AuthnData) == 0 {
+		return nil, nil, &webauthn.UnmarshalMissingFieldError{Type: "app attest attestation object", Field: "authenticator data"}
+	}
+	if raw.Fmt != "apple-appattest" {
+		return nil, nil, &webauthn.UnmarshalBadDataError{Type: "app attest attestation object", Msg: "expected attestation statement format \"apple-appattest\", got \"" + raw.Fmt + "\""}
+	}
+
+	authnData, err := webauthn.ParseAuthenticatorData(raw.AuthnData)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Verify that credential id and credential are not empty.
+	if len(authnData.CredentialID) == 0 || authnData.Credential == nil {
+		return nil, nil, &webauthn.UnmarshalMissingFieldError{Type: "app attest attestation object", Field: "credential data"}
+	}
+	attStmt, err := parseAttestation(raw.AttStmt)
+	if err != nil {
+		return nil, nil, err
+	}
+	return authnData, attStmt, nil
+}
+
+func parseAttestation(data []byte) (*appAttestAttestationStatement, error) {
+	type rawAttStmt struct {
+		X5C     [][]byte `cbor:"x5c"`
+		Receipt []byte   `cbor:"receipt"`
+	}
+
+	var raw rawAttStmt
+	if err := cbor.Unmarshal(data, &raw); err != nil {
+		return nil, &webauthn.UnmarshalSyntaxError{Type: "app attest attestation", Msg: err.Error()}
+	}
+
+	if len(raw.X5C) == 0 {
+		return nil, &webauthn.UnmarshalMissingFieldError{Type: "app attest attestation", Field: "x5c"}
+	}
+
+	attStmt := &appAttestAttestationStatement{receipt: raw.Receipt}
+	for i := 0; i < len(raw.X5C); i++ {
+		c, err := x509.ParseCertificate(raw.X5C[i])
+		if err != nil {
+			return nil, &webauthn.UnmarshalSyntaxError{Type: "app attest attestation", Field: fmt.Sprintf("x5c[%d]", i), Msg: err.Error()}
+		}
+		if i == 0 {
+			attStmt.credCert = c
+		} else {
+			attStmt.caCerts = append(attStmt.caCerts, c)
+		}
+	}
+
+	return attStmt, nil
+}
+
+// Verify implements the webauthn.AttestationStatement interface.  It follows App Attest attestation
+// verification procedure defined in
+// https://developer.apple.com/documentation/devicecheck/validating_apps_that_connect_to_your_server
+// except for App ID and key identifier, which are verified by VerifyAttestation.
+func (attStmt *appAttestAttestationStatement) Verify(clientDataHash []byte, authnData *webauthn.AuthenticatorData) (attType webauthn.AttestationType, trustPath interface{}, err error) {
+	// Verify that credCert chains to Apple App Attestation Root CA.
+	if trustPath, _, err = webauthn.VerifyAttestationCertificateWithRoots(attStmt.credCert, attStmt.caCerts, []*x509.Certificate{appleAppAttestationRootCACert}); err != nil {
+		err = &webauthn.VerificationError{Type: "app attest attestation", Field: "certificate", Msg: err.Error()}
+		return
+	}
+
+	// Concatenate authenticatorData and clientDataHash to form nonceToHash, and perform SHA-256 hash
+	// of nonceToHash to produce nonce.  Verify that nonce equals the value of the extension with
+	// OID 1.2.840.113635.100.8.2 in credCert.
+	rawAuthnData := authnData.Raw
+	nonceToHash := make([]byte, len(rawAuthnData)+len(clientDataHash))
+	copy(nonceToHash, rawAuthnData)
+	copy(nonceToHash[len(rawAuthnData):], clientDataHash)
+	nonce := sha256.Sum256(nonceToHash)
+	if err = applenonce.Verify(attStmt.credCert, nonce[:]); err != nil {
+		err = &webauthn.VerificationError{Type: "app attest attestation", Field: "certificate extension " + applenonce.OID.String(), Msg: err.Error()}
+		return
+	}
+
+	// Verify that credential public key equals the public key of credCert, and credential ID is the
+	// SHA-256 hash of the public key of credCert.
+	if !reflect.DeepEqual(attStmt.credCert.PublicKey, authnData.Credential.PublicKey) {
+		err = &webauthn.VerificationError{Type: "app attest attestation", Field: "certificate public key", Msg: "certificate public key does not match credential public key"}
+		return
+	}
+	pub, ok := attStmt.credCert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		err = &webauthn.VerificationError{Type: "app attest attestation", Field: "certificate public key", Msg: "certificate public key is not an ECDSA key"}
+		return
+	}
+	keyID := sha256.Sum256(elliptic.Marshal(pub.Curve, pub.X, pub.Y))
+	if !bytes.Equal(keyID[:], authnData.CredentialID) {
+		err = &webauthn.VerificationError{Type: "app attest attestation", Field: "credential ID", Msg: "credential ID is not the hash of certificate public key"}
+		return
+	}
+
+	// Verify that counter is 0.
+	if authnData.Counter != 0 {
+		err = &webauthn.VerificationError{Type: "app attest attestation", Field: "counter", Msg: "expected 0, got " + strconv.FormatUint(uint64(authnData.Counter), 10)}
+		return
+	}
+
+	// Verify that aaguid is either appattestdevelop or appattest followed by seven 0x00 bytes.
+	if !bytes.Equal(authnData.AAGUID, aaguidAppAttestProduction) && !bytes.Equal(authnData.AAGUID, aaguidAppAttestDevelopment) {
+		err = &webauthn.VerificationError{Type: "app attest attestation", Field: "aaguid", Msg: fmt.Sprintf("unexpected aaguid %02x", authnData.AAGUID)}
+		return
+	}
+
+	return webauthn.AttestationTypeBasic, trustPath, nil
+}
+
+// VerifyAttestation parses and verifies CBOR encoded App Attest attestation object, and returns the attested Key.
+func VerifyAttestation(attestationObject []byte, expected *AttestationExpectedData) (*Key, error) {
+	if expected == nil {
+		return nil, errors.New("expected data is required")
+	}
+	authnData, appAttestAttStmt, err := parseAttestationObject(attestationObject)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create clientDataHash as the SHA-256 hash of the one-time challenge, and verify attestation statement.
+	clientDataHash := sha256.Sum256(expected.Challenge)
+	_, trustPath, err := appAttestAttStmt.Verify(clientDataHash[:], authnData)
+	if err != nil {
+		return nil, err
+	}
+	certs, ok := trustPath.([]*x509.Certificate)
+	if !ok {
+		return nil, &webauthn.VerificationError{Type: "app attest attestation", Field: "certificate", Msg: "trust path is not a certificate chain"}
+	}
+
+	// Verify that rpIdHash is the SHA-256 hash of App ID.
+	appIDHash := sha256.Sum256([]byte(expected.AppID))
+	if !bytes.Equal(authnData.RPIDHash, appIDHash[:]) {
+		return nil, &webauthn.VerificationError{Type: "app attest attestation", Field: "app ID", Msg: "authenticator data's rp ID hash does not match app ID hash"}
+	}
+
+	// Verify that credential ID is the key identifier.
+	if !bytes.Equal(authnData.CredentialID, expected.KeyID) {
+		return nil, &webauthn.VerificationError{Type: "app attest attestation", Field: "key ID", Msg: "credential ID does not match key ID"}
+	}
+
+	env := EnvironmentProduction
+	if bytes.Equal(authnData.AAGUID, aaguidAppAttestDevelopment) {
+		env = EnvironmentDevelopment
+		if !expected.AllowDevelopment {
+			return nil, &webauthn.VerificationError{Type: "app attest attestation", Field: "aaguid", Msg: "key is attested in development environment"}
+		}
+	}
+
+	return &Key{
+		ID:          authnData.CredentialID,
+		Credential:  authnData.Credential,
+		Environment: env,
+		Receipt:     appAttestAttStmt.receipt,
+		TrustPath:   certs,
+	}, nil
+}
+
+// VerifyAssertion parses and verifies CBOR encoded App Attest assertion of clientData, and returns the
+// counter of the assertion.  The app server verifies the challenge embedded in clientData, and stores
+// the returned counter to verify the next assertion.
+func VerifyAssertion(assertion []byte, clientData []byte, expected *AssertionExpectedData) (counter uint32, err error) {
+	if expected == nil {
+		return 0, errors.New("expected data is required")
+	}
+	if expected.Credential == nil {
+		return 0, errors.New("credential is required")
+	}
+	type rawAssertion struct {
+		Signature         []byte `cbor:"signature"`
+		AuthenticatorData []byte `cbor:"authenticatorData"`
+	}
+	var raw rawAssertion
+	if err = cbor.Unmarshal(assertion, &raw); err != nil {
+		return 0, &webauthn.UnmarshalSyntaxError{Type: "app attest assertion", Msg: err.Error()}
+	}
+	if len(raw.Signature) == 0 {
+		return 0, &webauthn.UnmarshalMissingFieldError{Type: "app attest assertion", Field: "signature"}
+	}
+	if len(raw.AuthenticatorData) == 0 {
+		return 0, &webauthn.UnmarshalMissingFieldError{Type: "app attest assertion", Field: "authenticatorData"}
+	}
+	authnData, err := webauthn.ParseAuthenticatorData(raw.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	// Compute clientDataHash as the SHA-256 hash of clientData, and nonce as the SHA-256 hash of
+	// the concatenation of authenticatorData and clientDataHash.
+	clientDataHash := sha256.Sum256(clientData)
+	nonceToHash := make([]byte, len(raw.AuthenticatorData)+len(clientDataHash))
+	copy(nonceToHash, raw.AuthenticatorData)
+	copy(nonceToHash[len(raw.AuthenticatorData):], clientDataHash[:])
+	nonce := sha256.Sum256(nonceToHash)
+
+	// Verify that signature is valid for nonce using the public key of the key.
+	if err = expected.Credential.Verify(nonce[:], raw.Signature); err != nil {
+		return 0, &webauthn.VerificationError{Type: "app attest assertion", Field: "signature", Msg: err.Error()}
+	}
+
+	// Verify that rpIdHash is the SHA-256 hash of App ID.
+	appIDHash := sha256.Sum256([]byte(expected.AppID))
+	if !bytes.Equal(authnData.RPIDHash, appIDHash[:]) {
+		return 0, &webauthn.VerificationError{Type: "app attest assertion", Field: "app ID", Msg: "authenticator data's rp ID hash does not match app ID hash"}
+	}
+
+	// Verify that counter is greater than the counter of the previous assertion.
+	if authnData.Counter <= expected.PrevCounter {
+		return 0, &webauthn.VerificationError{Type: "app attest assertion", Field: "counter", Msg: "counter " + strconv.FormatUint(uint64(authnData.Counter), 10) + " is not greater than previous counter " + strconv.FormatUint(uint64(expected.PrevCounter), 10)}
+	}
+
+	return authnData.Counter, nil
+}
+
+func init() {
+	block, _ := pem.Decode([]byte(appleAppAttestationRootCACertPem))
+	if block == nil {
+		panic("failed to PEM decode Apple App Attestation Root CA")
+	}
+
+	var err error
+	if appleAppAttestationRootCACert, err = x509.ParseCertificate(block.Bytes); err != nil {
+		panic("failed to parse Apple App Attestation Root CA: " + err.Error())
+	}
+}
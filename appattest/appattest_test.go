@@ -0,0 +1,424 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package appattest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/internal/applenonce"
+	"github.com/kappapay/webauthn/internal/testcert"
+)
+
+const testAppID = "0352187391.com.example.app"
+
+// testAppAttestKey generates App Attest attestations and assertions for a key.
+type testAppAttestKey struct {
+	key *ecdsa.PrivateKey
+	id  []byte
+}
+
+func newTestAppAttestKey() *testAppAttestKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	id := sha256.Sum256(elliptic.Marshal(key.Curve, key.X, key.Y))
+	return &testAppAttestKey{key: key, id: id[:]}
+}
+
+func (k *testAppAttestKey) coseKey() []byte {
+	point := elliptic.Marshal(k.key.Curve, k.key.X, k.key.Y)
+	data, err := cbor.Marshal(map[int]interface{}{
+		1:  2,
+		3:  webauthn.COSEAlgES256,
+		-1: 1,
+		-2: point[1:33],
+		-3: point[33:],
+	})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// attestedAuthenticatorData returns authenticator data with attested credential data.
+func (k *testAppAttestKey) attestedAuthenticatorData(appID string, counter uint32, aaguid []byte) []byte {
+	var buf bytes.Buffer
+	appIDHash := sha256.Sum256([]byte(appID))
+	buf.Write(appIDHash[:])
+	buf.WriteByte(0x40)
+	binary.Write(&buf, binary.BigEndian, counter)
+	buf.Write(aaguid)
+	binary.Write(&buf, binary.BigEndian, uint16(len(k.id)))
+	buf.Write(k.id)
+	buf.Write(k.coseKey())
+	return buf.Bytes()
+}
+
+// attestationObject returns attestation object with credential certificate issued by ca.
+func (k *testAppAttestKey) attestationObject(authnData []byte, challenge []byte, ca *testcert.CA) []byte {
+	clientDataHash := sha256.Sum256(challenge)
+	nonce := sha256.Sum256(append(append([]byte{}, authnData...), clientDataHash[:]...))
+
+	type appleAnonymousAttestation struct {
+		Nonce []byte `asn1:"tag:1,explicit"`
+	}
+	value, err := asn1.Marshal(appleAnonymousAttestation{nonce[:]})
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		Subject:         pkix.Name{CommonName: "credential"},
+		ExtraExtensions: []pkix.Extension{{Id: applenonce.OID, Value: value}},
+	}
+	credCert := testcert.NewCertificate(template, &k.key.PublicKey, ca)
+
+	data, err := cbor.Marshal(map[string]interface{}{
+		"fmt": "apple-appattest",
+		"attStmt": map[string]interface{}{
+			"x5c":     [][]byte{credCert.Raw, ca.Cert.Raw},
+			"receipt": []byte("receipt"),
+		},
+		"authData": authnData,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// withFormat returns attestation object attObj with attestation statement format fmt.
+func withFormat(attObj []byte, fmt string) []byte {
+	var m map[string]interface{}
+	if err := cbor.Unmarshal(attObj, &m); err != nil {
+		panic(err)
+	}
+	m["fmt"] = fmt
+	data, err := cbor.Marshal(m)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// assertion returns assertion of clientData.
+func (k *testAppAttestKey) assertion(appID string, counter uint32, clientData []byte) []byte {
+	var buf bytes.Buffer
+	appIDHash := sha256.Sum256([]byte(appID))
+	buf.Write(appIDHash[:])
+	buf.WriteByte(0x00)
+	binary.Write(&buf, binary.BigEndian, counter)
+	authnData := buf.Bytes()
+
+	clientDataHash := sha256.Sum256(clientData)
+	nonce := sha256.Sum256(append(append([]byte{}, authnData...), clientDataHash[:]...))
+	digest := sha256.Sum256(nonce[:])
+	r, s, err := ecdsa.Sign(rand.Reader, k.key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	sig, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		panic(err)
+	}
+
+	data, err := cbor.Marshal(map[string]interface{}{
+		"signature":         sig,
+		"authenticatorData": authnData,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func TestAppleAppAttestationRootCACert(t *testing.T) {
+	if cn := appleAppAttestationRootCACert.Subject.CommonName; cn != "Apple App Attestation Root CA" {
+		t.Errorf("root certificate common name %q, want %q", cn, "Apple App Attestation Root CA")
+	}
+	if err := appleAppAttestationRootCACert.CheckSignatureFrom(appleAppAttestationRootCACert); err != nil {
+		t.Errorf("root certificate self signature: %q", err)
+	}
+}
+
+func TestVerifyAttestation(t *testing.T) {
+	root := testcert.NewCA("Test App Attestation Root CA", nil)
+	intermediate := testcert.NewCA("Test App Attestation CA 1", root)
+	otherRoot := testcert.NewCA("Other Root CA", nil)
+	otherIntermediate := testcert.NewCA("Other CA 1", otherRoot)
+
+	savedRootCert := appleAppAttestationRootCACert
+	appleAppAttestationRootCACert = root.Cert
+	defer func() { appleAppAttestationRootCACert = savedRootCert }()
+
+	key := newTestAppAttestKey()
+	challenge := []byte("attestation challenge")
+
+	testCases := []struct {
+		name            string
+		attObj          []byte
+		expected        *AttestationExpectedData
+		wantEnvironment Environment
+		wantErrorMsg    string
+	}{
+		{
+			name:            "production",
+			attObj:          key.attestationObject(key.attestedAuthenticatorData(testAppID, 0, aaguidAppAttestProduction), challenge, intermediate),
+			expected:        &AttestationExpectedData{AppID: testAppID, Challenge: challenge, KeyID: key.id},
+			wantEnvironment: EnvironmentProduction,
+		},
+		{
+			name:            "development",
+			attObj:          key.attestationObject(key.attestedAuthenticatorData(testAppID, 0, aaguidAppAttestDevelopment), challenge, intermediate),
+			expected:        &AttestationExpectedData{AppID: testAppID, Challenge: challenge, KeyID: key.id, AllowDevelopment: true},
+			wantEnvironment: EnvironmentDevelopment,
+		},
+		{
+			name:         "development not allowed",
+			attObj:       key.attestationObject(key.attestedAuthenticatorData(testAppID, 0, aaguidAppAttestDevelopment), challenge, intermediate),
+			expected:     &AttestationExpectedData{AppID: testAppID, Challenge: challenge, KeyID: key.id},
+			wantErrorMsg: "app_attest_attestation: failed to verify aaguid: key is attested in development environment",
+		},
+		{
+			name:         "challenge mismatch",
+			attObj:       key.attestationObject(key.attestedAuthenticatorData(testAppID, 0, aaguidAppAttestProduction), []byte("other challenge"), intermediate),
+			expected:     &AttestationExpectedData{AppID: testAppID, Challenge: challenge, KeyID: key.id},
+			wantErrorMsg: "app_attest_attestation: failed to verify certificate extension 1.2.840.113635.100.8.2: nonce does not match certificate extension",
+		},
+		{
+			name:         "app ID mismatch",
+			attObj:       key.attestationObject(key.attestedAuthenticatorData("0352187391.com.example.other", 0, aaguidAppAttestProduction), challenge, intermediate),
+			expected:     &AttestationExpectedData{AppID: testAppID, Challenge: challenge, KeyID: key.id},
+			wantErrorMsg: "app_attest_attestation: failed to verify app ID",
+		},
+		{
+			name:         "key ID mismatch",
+			attObj:       key.attestationObject(key.attestedAuthenticatorData(testAppID, 0, aaguidAppAttestProduction), challenge, intermediate),
+			expected:     &AttestationExpectedData{AppID: testAppID, Challenge: challenge, KeyID: []byte("other key")},
+			wantErrorMsg: "app_attest_attestation: failed to verify key ID",
+		},
+		{
+			name:         "nonzero counter",
+			attObj:       key.attestationObject(key.attestedAuthenticatorData(testAppID, 1, aaguidAppAttestProduction), challenge, intermediate),
+			expected:     &AttestationExpectedData{AppID: testAppID, Challenge: challenge, KeyID: key.id},
+			wantErrorMsg: "app_attest_attestation: failed to verify counter: expected 0, got 1",
+		},
+		{
+			name:         "unexpected aaguid",
+			attObj:       key.attestationObject(key.attestedAuthenticatorData(testAppID, 0, make([]byte, 16)), challenge, intermediate),
+			expected:     &AttestationExpectedData{AppID: testAppID, Challenge: challenge, KeyID: key.id},
+			wantErrorMsg: "app_attest_attestation: failed to verify aaguid: unexpected aaguid",
+		},
+		{
+			name:         "untrusted root",
+			attObj:       key.attestationObject(key.attestedAuthenticatorData(testAppID, 0, aaguidAppAttestProduction), challenge, otherIntermediate),
+			expected:     &AttestationExpectedData{AppID: testAppID, Challenge: challenge, KeyID: key.id},
+			wantErrorMsg: "app_attest_attestation: failed to verify certificate: x509: certificate signed by unknown authority",
+		},
+		{
+			name:         "other attestation statement format",
+			attObj:       withFormat(key.attestationObject(key.attestedAuthenticatorData(testAppID, 0, aaguidAppAttestProduction), challenge, intermediate), "packed"),
+			expected:     &AttestationExpectedData{AppID: testAppID, Challenge: challenge, KeyID: key.id},
+			wantErrorMsg: "expected attestation statement format \"apple-appattest\", got \"packed\"",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k, err := VerifyAttestation(tc.attObj, tc.expected)
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyAttestation() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyAttestation() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyAttestation() returns error %q", err)
+			}
+			if !bytes.Equal(k.ID, key.id) {
+				t.Errorf("key ID %02x, want %02x", k.ID, key.id)
+			}
+			if k.Environment != tc.wantEnvironment {
+				t.Errorf("environment %v, want %v", k.Environment, tc.wantEnvironment)
+			}
+			if !bytes.Equal(k.Receipt, []byte("receipt")) {
+				t.Errorf("receipt %q, want %q", k.Receipt, "receipt")
+			}
+			if len(k.TrustPath) != 3 || !k.TrustPath[2].Equal(root.Cert) {
+				t.Errorf("trust path %v, want chain from credential certificate to root", k.TrustPath)
+			}
+		})
+	}
+}
+
+func TestAppAttestFormatNotRegistered(t *testing.T) {
+	root := testcert.NewCA("Test App Attestation Root CA", nil)
+	savedRootCert := appleAppAttestationRootCACert
+	appleAppAttestationRootCACert = root.Cert
+	defer func() { appleAppAttestationRootCACert = savedRootCert }()
+
+	key := newTestAppAttestKey()
+	challenge := []byte("attestation challenge")
+	attObj := key.attestationObject(key.attestedAuthenticatorData(testAppID, 0, aaguidAppAttestProduction), challenge, root)
+
+	if _, err := VerifyAttestation(attObj, &AttestationExpectedData{AppID: testAppID, Challenge: challenge, KeyID: key.id}); err != nil {
+		t.Fatalf("VerifyAttestation() returns error %q", err)
+	}
+
+	// App Attest attestation objects must not be accepted as WebAuthn registrations.
+	credential := `{"id":"` + base64.RawURLEncoding.EncodeToString(key.id) + `","type":"public-key","response":{` +
+		`"clientDataJSON":"` + base64.RawURLEncoding.EncodeToString([]byte(`{"type":"webauthn.create","challenge":"YXR0ZXN0YXRpb24gY2hhbGxlbmdl","origin":"https://acme.com"}`)) + `",` +
+		`"attestationObject":"` + base64.RawURLEncoding.EncodeToString(attObj) + `"}}`
+	wantErrorMsg := "attestation statement format apple-appattest is not registered"
+	if _, err := webauthn.ParseAttestation(strings.NewReader(credential)); err == nil {
+		t.Errorf("ParseAttestation() returns no error, want error containing substring %q", wantErrorMsg)
+	} else if !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("ParseAttestation() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	key := newTestAppAttestKey()
+	otherKey := newTestAppAttestKey()
+	credential, _, err := webauthn.ParseCredential(key.coseKey())
+	if err != nil {
+		t.Fatalf("ParseCredential() returns error %q", err)
+	}
+	clientData := []byte(`{"challenge":"assertion challenge","level":42}`)
+
+	testCases := []struct {
+		name         string
+		assertion    []byte
+		prevCounter  uint32
+		wantCounter  uint32
+		wantErrorMsg string
+	}{
+		{
+			name:        "first assertion",
+			assertion:   key.assertion(testAppID, 1, clientData),
+			wantCounter: 1,
+		},
+		{
+			name:        "later assertion",
+			assertion:   key.assertion(testAppID, 8, clientData),
+			prevCounter: 5,
+			wantCounter: 8,
+		},
+		{
+			name:         "replayed counter",
+			assertion:    key.assertion(testAppID, 5, clientData),
+			prevCounter:  5,
+			wantErrorMsg: "app_attest_assertion: failed to verify counter: counter 5 is not greater than previous counter 5",
+		},
+		{
+			name:         "signed by other key",
+			assertion:    otherKey.assertion(testAppID, 1, clientData),
+			wantErrorMsg: "app_attest_assertion: failed to verify signature",
+		},
+		{
+			name:         "app ID mismatch",
+			assertion:    key.assertion("0352187391.com.example.other", 1, clientData),
+			wantErrorMsg: "app_attest_assertion: failed to verify app ID",
+		},
+		{
+			name:         "missing signature",
+			assertion:    []byte{0xa1, 0x71, 'a', 'u', 't', 'h', 'e', 'n', 't', 'i', 'c', 'a', 't', 'o', 'r', 'D', 'a', 't', 'a', 0x41, 0x00},
+			wantErrorMsg: "app_attest_assertion: missing signature",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			counter, err := VerifyAssertion(tc.assertion, clientData, &AssertionExpectedData{AppID: testAppID, Credential: credential, PrevCounter: tc.prevCounter})
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyAssertion() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyAssertion() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyAssertion() returns error %q", err)
+			}
+			if counter != tc.wantCounter {
+				t.Errorf("counter %d, want %d", counter, tc.wantCounter)
+			}
+		})
+	}
+}
+
+func TestVerifyMissingExpectedData(t *testing.T) {
+	key := newTestAppAttestKey()
+	clientData := []byte(`{"challenge":"assertion challenge"}`)
+
+	testCases := []struct {
+		name         string
+		verify       func() error
+		wantErrorMsg string
+	}{
+		{
+			name: "attestation without expected data",
+			verify: func() error {
+				_, err := VerifyAttestation(key.attestationObject(key.attestedAuthenticatorData(testAppID, 0, aaguidAppAttestProduction), []byte("attestation challenge"), testcert.NewCA("Test App Attestation Root CA", nil)), nil)
+				return err
+			},
+			wantErrorMsg: "expected data is required",
+		},
+		{
+			name: "assertion without expected data",
+			verify: func() error {
+				_, err := VerifyAssertion(key.assertion(testAppID, 1, clientData), clientData, nil)
+				return err
+			},
+			wantErrorMsg: "expected data is required",
+		},
+		{
+			name: "assertion without credential",
+			verify: func() error {
+				_, err := VerifyAssertion(key.assertion(testAppID, 1, clientData), clientData, &AssertionExpectedData{AppID: testAppID})
+				return err
+			},
+			wantErrorMsg: "credential is required",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.verify(); err == nil {
+				t.Errorf("verify returns no error, want error containing substring %q", tc.wantErrorMsg)
+			} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+				t.Errorf("verify returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+			}
+		})
+	}
+}
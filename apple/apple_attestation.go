@@ -0,0 +1,133 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package apple
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/internal/applenonce"
+)
+
+// Apple WebAuthn Root CA certificate is available at https://www.apple.com/certificateauthority/private/
+const appleWebAuthnRootCACertPem = `-----BEGIN CERTIFICATE-----
+MIICEjCCAZmgAwIBAgIQaB0BbHo84wIlpQGUKEdXcTAKBggqhkjOPQQDAzBLMR8w
+HQYDVQQDDBZBcHBsZSBXZWJBdXRobiBSb290IENBMRMwEQYDVQQKDApBcHBsZSBJ
+bmMuMRMwEQYDVQQIDApDYWxpZm9ybmlhMB4XDTIwMDMxODE4MjEzMloXDTQ1MDMx
+NTAwMDAwMFowSzEfMB0GA1UEAwwWQXBwbGUgV2ViQXV0aG4gUm9vdCBDQTETMBEG
+A1UECgwKQXBwbGUgSW5jLjETMBEGA1UECAwKQ2FsaWZvcm5pYTB2MBAGByqGSM49
+AgEGBSuBBAAiA2IABCJCQ2pTVhzjl4Wo6IhHtMSAzO2cv+H9DQKev3//fG59G11k
+xu9eI0/7o6V5uShBpe1u6l6mS19S1FEh6yGljnZAJ+2GNP1mi/YK2kSXIuTHjxA/
+pcoRf7XkOtO4o1qlcaNCMEAwDwYDVR0TAQH/BAUwAwEB/zAdBgNVHQ4EFgQUJtdk
+2cV4wlpn0afeaxLQG2PxxtcwDgYDVR0PAQH/BAQDAgEGMAoGCCqGSM49BAMDA2cA
+MGQCMFrZ+9DsJ1PW9hfNdBywZDsWDbWFp28it1d/5w2RPkRX3Bbn/UbDTNLx7Jr3
+jAGGiQIwHFj+dJZYUJR786osByBelJYsVZd2GbHQu209b5RCmGQ21gpSAk9QZW4B
+1bWeT0vT
+-----END CERTIFICATE-----`
+
+var appleWebAuthnRootCACert *x509.Certificate
+
+type appleAttestationStatement struct {
+	credCert *x509.Certificate   // Credential certificate.
+	caCerts  []*x509.Certificate // Credential certificate chain.
+}
+
+func parseAttestation(data []byte) (webauthn.AttestationStatement, error) {
+	type rawAttStmt struct {
+		X5C [][]byte `cbor:"x5c"`
+	}
+
+	var raw rawAttStmt
+	if err := cbor.Unmarshal(data, &raw); err != nil {
+		return nil, &webauthn.UnmarshalSyntaxError{Type: "apple attestation", Msg: err.Error()}
+	}
+
+	if len(raw.X5C) == 0 {
+		return nil, &webauthn.UnmarshalMissingFieldError{Type: "apple attestation", Field: "x5c"}
+	}
+
+	attStmt := &appleAttestationStatement{}
+	for i := 0; i < len(raw.X5C); i++ {
+		c, err := x509.ParseCertificate(raw.X5C[i])
+		if err != nil {
+			return nil, &webauthn.UnmarshalSyntaxError{Type: "apple attestation", Field: fmt.Sprintf("x5c[%d]", i), Msg: err.Error()}
+		}
+		if i == 0 {
+			attStmt.credCert = c
+		} else {
+			attStmt.caCerts = append(attStmt.caCerts, c)
+		}
+	}
+
+	return attStmt, nil
+}
+
+// Verify implements the webauthn.AttestationStatement interface.  It follows apple anonymous
+// attestation statement verification procedure defined in
+// https://w3c.github.io/webauthn/#sctn-apple-anonymous-attestation
+func (attStmt *appleAttestationStatement) Verify(clientDataHash []byte, authnData *webauthn.AuthenticatorData) (attType webauthn.AttestationType, trustPath interface{}, err error) {
+	// Concatenate authenticatorData and clientDataHash to form nonceToHash, and perform SHA-256 hash
+	// of nonceToHash to produce nonce.
+	rawAuthnData := authnData.Raw
+	nonceToHash := make([]byte, len(rawAuthnData)+len(clientDataHash))
+	copy(nonceToHash, rawAuthnData)
+	copy(nonceToHash[len(rawAuthnData):], clientDataHash)
+	nonce := sha256.Sum256(nonceToHash)
+
+	// Verify that nonce equals the value of the extension with OID 1.2.840.113635.100.8.2 in credCert.
+	if err = applenonce.Verify(attStmt.credCert, nonce[:]); err != nil {
+		err = &webauthn.VerificationError{Type: "apple attestation", Field: "certificate extension " + applenonce.OID.String(), Msg: err.Error()}
+		return
+	}
+
+	// Verify that the credential public key equals the Subject Public Key of credCert.
+	if !reflect.DeepEqual(attStmt.credCert.PublicKey, authnData.Credential.PublicKey) {
+		err = &webauthn.VerificationError{Type: "apple attestation", Field: "certificate public key", Msg: "certificate public key does not match credential public key"}
+		return
+	}
+
+	// Verify credCert by building certificate chain to Apple WebAuthn Root CA.
+	if trustPath, _, err = webauthn.VerifyAttestationCertificateWithRoots(attStmt.credCert, attStmt.caCerts, []*x509.Certificate{appleWebAuthnRootCACert}); err != nil {
+		err = &webauthn.VerificationError{Type: "apple attestation", Field: "certificate", Msg: err.Error()}
+		return
+	}
+
+	// If successful, return implementation-specific values representing attestation type Anonymization CA
+	// and attestation trust path x5c.
+	return webauthn.AttestationTypeAnonCA, trustPath, nil
+}
+
+func init() {
+	block, _ := pem.Decode([]byte(appleWebAuthnRootCACertPem))
+	if block == nil {
+		panic("failed to PEM decode Apple WebAuthn Root CA")
+	}
+
+	var err error
+	if appleWebAuthnRootCACert, err = x509.ParseCertificate(block.Bytes); err != nil {
+		panic("failed to parse Apple WebAuthn Root CA: " + err.Error())
+	}
+
+	webauthn.RegisterAttestationFormat("apple", parseAttestation)
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package apple
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/internal/applenonce"
+	"github.com/kappapay/webauthn/internal/testcert"
+)
+
+// newTestCredCert returns credential certificate issued by ca with nonce certificate extension.
+func newTestCredCert(ca *testcert.CA, credentialKey *ecdsa.PrivateKey, nonce []byte) *x509.Certificate {
+	template := &x509.Certificate{Subject: pkix.Name{CommonName: "credential"}}
+	if nonce != nil {
+		type appleAnonymousAttestation struct {
+			Nonce []byte `asn1:"tag:1,explicit"`
+		}
+		value, err := asn1.Marshal(appleAnonymousAttestation{nonce})
+		if err != nil {
+			panic(err)
+		}
+		template.ExtraExtensions = []pkix.Extension{{Id: applenonce.OID, Value: value}}
+	}
+	return testcert.NewCertificate(template, &credentialKey.PublicKey, ca)
+}
+
+func TestAppleWebAuthnRootCACert(t *testing.T) {
+	if cn := appleWebAuthnRootCACert.Subject.CommonName; cn != "Apple WebAuthn Root CA" {
+		t.Errorf("root certificate common name %q, want %q", cn, "Apple WebAuthn Root CA")
+	}
+	if err := appleWebAuthnRootCACert.CheckSignatureFrom(appleWebAuthnRootCACert); err != nil {
+		t.Errorf("root certificate self signature: %q", err)
+	}
+}
+
+func TestParseAppleAttestationError(t *testing.T) {
+	testCases := []struct {
+		name         string
+		attStmt      interface{}
+		wantErrorMsg string
+	}{
+		{"missing x5c", map[string]interface{}{}, "apple_attestation: missing x5c"},
+		{"invalid x5c", map[string]interface{}{"x5c": [][]byte{{1, 2, 3}}}, "apple_attestation: failed to unmarshal x5c[0]"},
+		{"not a map", []int{1}, "apple_attestation: failed to unmarshal"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := cbor.Marshal(tc.attStmt)
+			if err != nil {
+				t.Fatalf("failed to marshal attestation statement: %q", err)
+			}
+			if _, err = parseAttestation(data); err == nil {
+				t.Errorf("parseAttestation() returns no error, want error containing substring %q", tc.wantErrorMsg)
+			} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+				t.Errorf("parseAttestation() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+			}
+		})
+	}
+}
+
+func TestVerifyAppleAttestation(t *testing.T) {
+	root := testcert.NewCA("Test WebAuthn Root CA", nil)
+	intermediate := testcert.NewCA("Test WebAuthn CA 1", root)
+	otherRoot := testcert.NewCA("Other Root CA", nil)
+	otherIntermediate := testcert.NewCA("Other CA 1", otherRoot)
+
+	savedRootCert := appleWebAuthnRootCACert
+	appleWebAuthnRootCACert = root.Cert
+	defer func() { appleWebAuthnRootCACert = savedRootCert }()
+
+	credentialKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatureAlgorithm, err := webauthn.CoseAlgToSignatureAlgorithm(webauthn.COSEAlgES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authnData := &webauthn.AuthenticatorData{
+		Raw:        []byte("authenticator data"),
+		Credential: &webauthn.Credential{SignatureAlgorithm: signatureAlgorithm, PublicKey: &credentialKey.PublicKey},
+	}
+	clientDataHash := sha256.Sum256([]byte("client data"))
+	nonce := sha256.Sum256(append(append([]byte{}, authnData.Raw...), clientDataHash[:]...))
+
+	testCases := []struct {
+		name         string
+		x5c          []*x509.Certificate
+		wantErrorMsg string
+	}{
+		{
+			name: "apple attestation",
+			x5c:  []*x509.Certificate{newTestCredCert(intermediate, credentialKey, nonce[:]), intermediate.Cert},
+		},
+		{
+			name:         "nonce mismatch",
+			x5c:          []*x509.Certificate{newTestCredCert(intermediate, credentialKey, clientDataHash[:]), intermediate.Cert},
+			wantErrorMsg: "apple_attestation: failed to verify certificate extension 1.2.840.113635.100.8.2: nonce does not match certificate extension",
+		},
+		{
+			name:         "missing nonce extension",
+			x5c:          []*x509.Certificate{newTestCredCert(intermediate, credentialKey, nil), intermediate.Cert},
+			wantErrorMsg: "apple_attestation: failed to verify certificate extension 1.2.840.113635.100.8.2: certificate extension is missing",
+		},
+		{
+			name:         "public key mismatch",
+			x5c:          []*x509.Certificate{newTestCredCert(intermediate, otherKey, nonce[:]), intermediate.Cert},
+			wantErrorMsg: "apple_attestation: failed to verify certificate public key",
+		},
+		{
+			name:         "missing intermediate certificate",
+			x5c:          []*x509.Certificate{newTestCredCert(intermediate, credentialKey, nonce[:])},
+			wantErrorMsg: "apple_attestation: failed to verify certificate",
+		},
+		{
+			name:         "untrusted root",
+			x5c:          []*x509.Certificate{newTestCredCert(otherIntermediate, credentialKey, nonce[:]), otherIntermediate.Cert},
+			wantErrorMsg: "apple_attestation: failed to verify certificate: x509: certificate signed by unknown authority",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var x5c [][]byte
+			for _, c := range tc.x5c {
+				x5c = append(x5c, c.Raw)
+			}
+			data, err := cbor.Marshal(map[string]interface{}{"x5c": x5c})
+			if err != nil {
+				t.Fatalf("failed to marshal attestation statement: %q", err)
+			}
+			attStmt, err := parseAttestation(data)
+			if err != nil {
+				t.Fatalf("parseAttestation() returns error %q", err)
+			}
+			attType, trustPath, err := attStmt.Verify(clientDataHash[:], authnData)
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("Verify() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("Verify() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() returns error %q", err)
+			}
+			if attType != webauthn.AttestationTypeAnonCA {
+				t.Errorf("attestation type %v, want %v", attType, webauthn.AttestationTypeAnonCA)
+			}
+			chain, ok := trustPath.([]*x509.Certificate)
+			if !ok || len(chain) != 3 || !chain[0].Equal(tc.x5c[0]) || !chain[2].Equal(root.Cert) {
+				t.Errorf("trust path %v, want chain from credential certificate to root", trustPath)
+			}
+		})
+	}
+}
@@ -31,10 +31,12 @@ type PublicKeyCredentialAssertion struct {
 	AuthnData  *AuthenticatorData   // Authenticator data returned by the authenticator.
 	Signature  []byte               // Raw signature returned from the authenticator.
 	UserHandle []byte               // User handle returned from the authenticator, or null.
+
+	ClientExtensionResults AuthenticationExtensionsClientOutputs // Client extension outputs.
 }
 
 // UnmarshalJSON implements json.Unmarshaler interface.  rawId, clientDataJSON, authenticatorData,
-// signature, and userHandle are base64 URL encoded.
+// signature, and userHandle are base64 URL encoded.  clientExtensionResults is optional.
 func (credentialAssertion *PublicKeyCredentialAssertion) UnmarshalJSON(data []byte) (err error) {
 	type rawAuthenticatorAssertionResponse struct {
 		ClientDataJSON    string `json:"clientDataJSON"`    // JSON-serialized client data passed to the authenticator by the client.
@@ -47,6 +49,8 @@ func (credentialAssertion *PublicKeyCredentialAssertion) UnmarshalJSON(data []by
 		RawID    string                            `json:"rawId,omitempty"` // Raw credential ID.
 		Response rawAuthenticatorAssertionResponse `json:"response"`        // Authenticator's response to client's request to generate an authentication assertion.
 		Type     string                            `json:"type"`            // "public-key"
+
+		ClientExtensionResults AuthenticationExtensionsClientOutputs `json:"clientExtensionResults"` // Client extension outputs.
 	}
 	var raw rawPublicKeyCredential
 	if err = json.Unmarshal(data, &raw); err != nil {
@@ -129,7 +133,7 @@ func (credentialAssertion *PublicKeyCredentialAssertion) UnmarshalJSON(data []by
 		return err
 	}
 
-	if credentialAssertion.AuthnData, _, err = parseAuthenticatorData(rawAuthenticatorData); err != nil {
+	if credentialAssertion.AuthnData, err = ParseAuthenticatorData(rawAuthenticatorData); err != nil {
 		return err
 	}
 	// Verify that credential id and public key are empty.
@@ -138,6 +142,7 @@ func (credentialAssertion *PublicKeyCredentialAssertion) UnmarshalJSON(data []by
 	}
 	credentialAssertion.Signature = rawSignature
 	credentialAssertion.UserHandle = rawUserHandle
+	credentialAssertion.ClientExtensionResults = raw.ClientExtensionResults
 	return nil
 }
 
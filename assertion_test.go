@@ -245,6 +245,18 @@ var (
 		"type": "public-key"
 	}`
 
+	// Authenticator data of assertion 1 with credProtect extension followed by a trailing byte.
+	assertionTrailingAuthenticatorData = `{
+		"rawId": "AAhH7cnPRBkcukjnc2G2GM1H5dkVs9P1q2VErhD57pkzKVjBbixdsufjXhUOfiD27D0VA-fPKUVYNGE2XYcjhihtYODQv-xEarplsa7Ix6hK13FA6uyRxMgHC3PhTbx-rbq_RMUbaJ-HoGVt-c820ifdoagkFR02Van8Vr9q67Bn6zHNDT_DNrQbtpIUqqX_Rg2p5o6F7bVO3uOJG9hUNgUb",
+		"response": {
+			"clientDataJSON":    "eyJjaGFsbGVuZ2UiOiJlYVR5VU5ueVBERGRLOFNORWdURVV2ejFROGR5bGtqalRpbVlkNVg3UUFvLUY4X1oxbHNKaTNCaWxVcEZaSGtJQ05EV1k4cjlpdm5UZ1c3LVhaQzNxUSIsImNsaWVudEV4dGVuc2lvbnMiOnt9LCJoYXNoQWxnb3JpdGhtIjoiU0hBLTI1NiIsIm9yaWdpbiI6Imh0dHBzOi8vbG9jYWxob3N0Ojg0NDMiLCJ0eXBlIjoid2ViYXV0aG4uZ2V0In0",
+			"authenticatorData": "SZYN5YgOjGh0NBcPZHZgW4_krrmihjLHmVzzuoMdl2OBAAABa6FrY3JlZFByb3RlY3QBAA",
+			"signature":         "MEYCIQD6dF3B0ZoaLA0r78oyRdoMNR0bN93Zi4cF_75hFAH6pQIhALY0UIsrh03u_f4yKOwzwD6Cj3_GWLJiioTT9580s1a7",
+			"userHandle":        ""
+		},
+		"type": "public-key"
+	}`
+
 	// Test data adapted from apowers313's fido2-helpers (2019) at https://github.com/apowers313/fido2-helpers/blob/master/fido2-helpers.js
 	assertionBadType = `{
 		"id":    "AAhH7cnPRBkcukjnc2G2GM1H5dkVs9P1q2VErhD57pkzKVjBbixdsufjXhUOfiD27D0VA-fPKUVYNGE2XYcjhihtYODQv-xEarplsa7Ix6hK13FA6uyRxMgHC3PhTbx-rbq_RMUbaJ-HoGVt-c820ifdoagkFR02Van8Vr9q67Bn6zHNDT_DNrQbtpIUqqX_Rg2p5o6F7bVO3uOJG9hUNgUb",
@@ -341,6 +353,7 @@ var parseAssertionErrorTests = []parseAssertionErrorTest{
 	{"user handle is not base64 encoded", []byte(assertionInvalidUserHandle), "assertion: failed to base64 decode user handle"},
 	{"client data is not well-formed JSON", []byte(assertionBadClientDataJSON), "client_data: failed to unmarshal: invalid character"},
 	{"authenticator data is not well-formed", []byte(assertionBadAuthenticatorData), "authenticator_data: failed to unmarshal: unexpected EOF"},
+	{"trailing data after authenticator data", []byte(assertionTrailingAuthenticatorData), "authenticator_data: trailing data after authenticator data"},
 	{"bad type", []byte(assertionBadType), "assertion: expected type as \"public-key\", got \"key\""},
 }
 
@@ -18,6 +18,7 @@ package webauthn
 
 import (
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"sync"
@@ -36,6 +37,9 @@ const (
 	AttestationTypeCA
 	AttestationTypeECDAA
 	AttestationTypeNone
+	AttestationTypeAnonCA
+	AttestationTypeCompound
+	AttestationTypeUncertain // Attestation signature is valid, but no trust anchor is known to determine attestation type.
 )
 
 func (attType AttestationType) String() string {
@@ -50,6 +54,12 @@ func (attType AttestationType) String() string {
 		return "ECDAA"
 	case AttestationTypeNone:
 		return "None"
+	case AttestationTypeAnonCA:
+		return "AnonCA"
+	case AttestationTypeCompound:
+		return "Compound"
+	case AttestationTypeUncertain:
+		return "Uncertain"
 	default:
 		return "Undefined"
 	}
@@ -61,7 +71,29 @@ type AttestationStatement interface {
 	Verify(clientDataHash []byte, authnData *AuthenticatorData) (attType AttestationType, trustPath interface{}, err error)
 }
 
-func parseAttestationObject(data []byte) (authnData *AuthenticatorData, attStmt AttestationStatement, err error) {
+// AttestationDetailer is implemented by attestation statements that report format specific details,
+// such as the app that created the credential, after successful verification.
+type AttestationDetailer interface {
+	// AttestationDetails returns format specific details of the verified attestation statement.
+	AttestationDetails() interface{}
+}
+
+// CertificateChainer is implemented by attestation statements that nest attestation statements, such as
+// compound attestation statements, whose trust path isn't a certificate chain.
+type CertificateChainer interface {
+	// CertificateChains returns attestation certificate chains of nested trust paths of the verified
+	// attestation statement, so that their revocation status can be checked.
+	CertificateChains() [][]*x509.Certificate
+}
+
+// NestedAttestationFormatter is implemented by attestation statements that nest attestation statements of
+// other formats, such as compound attestation statements.
+type NestedAttestationFormatter interface {
+	// NestedAttestationFormats returns attestation statement format identifiers of nested attestation statements.
+	NestedAttestationFormats() []string
+}
+
+func parseAttestationObject(data []byte) (authnData *AuthenticatorData, format string, attStmt AttestationStatement, err error) {
 	type rawAttestationObject struct {
 		AuthnData []byte          `cbor:"authData"`
 		Fmt       string          `cbor:"fmt"`
@@ -69,26 +101,26 @@ func parseAttestationObject(data []byte) (authnData *AuthenticatorData, attStmt
 	}
 	var raw rawAttestationObject
 	if err = cbor.Unmarshal(data, &raw); err != nil {
-		return nil, nil, &UnmarshalSyntaxError{Type: "attestation object", Msg: err.Error()}
+		return nil, "", nil, &UnmarshalSyntaxError{Type: "attestation object", Msg: err.Error()}
 	}
 	if len(raw.AuthnData) == 0 {
-		return nil, nil, &UnmarshalMissingFieldError{Type: "attestation object", Field: "authenticator data"}
+		return nil, "", nil, &UnmarshalMissingFieldError{Type: "attestation object", Field: "authenticator data"}
 	}
 	if len(raw.Fmt) == 0 {
-		return nil, nil, &UnmarshalMissingFieldError{Type: "attestation object", Field: "attestation statement format"}
+		return nil, "", nil, &UnmarshalMissingFieldError{Type: "attestation object", Field: "attestation statement format"}
 	}
 
-	if authnData, _, err = parseAuthenticatorData(raw.AuthnData); err != nil {
-		return nil, nil, err
+	if authnData, err = ParseAuthenticatorData(raw.AuthnData); err != nil {
+		return nil, "", nil, err
 	}
 	// Verify that credential id and credential are not empty.
 	if len(authnData.CredentialID) == 0 || authnData.Credential == nil {
-		return nil, nil, &UnmarshalMissingFieldError{Type: "attestation object", Field: "credential data"}
+		return nil, "", nil, &UnmarshalMissingFieldError{Type: "attestation object", Field: "credential data"}
 	}
 	if attStmt, err = parseAttestationStatement(raw.Fmt, raw.AttStmt); err != nil {
-		return nil, nil, err
+		return nil, "", nil, err
 	}
-	return
+	return authnData, raw.Fmt, attStmt, nil
 }
 
 // PublicKeyCredentialAttestation represents the Web Authentication structure of PublicKeyCredential
@@ -98,11 +130,14 @@ type PublicKeyCredentialAttestation struct {
 	RawID      []byte
 	ClientData *CollectedClientData
 	AuthnData  *AuthenticatorData
+	Fmt        string // Attestation statement format identifier.
 	AttStmt    AttestationStatement
+
+	ClientExtensionResults AuthenticationExtensionsClientOutputs // Client extension outputs.
 }
 
 // UnmarshalJSON implements json.Unmarshaler interface.  rawId, clientDataJSON, and attestationObject
-// are base64 URL encoded.
+// are base64 URL encoded.  clientExtensionResults is optional.
 func (credentialAttestation *PublicKeyCredentialAttestation) UnmarshalJSON(data []byte) (err error) {
 	type rawAuthenticatorAttestationResponse struct {
 		ClientDataJSON    string `json:"clientDataJSON"`    // JSON-serialized client data passed to the authenticator by the client.
@@ -113,6 +148,8 @@ func (credentialAttestation *PublicKeyCredentialAttestation) UnmarshalJSON(data
 		RawID    string                              `json:"rawId,omitempty"` // Raw credential ID.
 		Response rawAuthenticatorAttestationResponse `json:"response"`        // Authenticator's response to client's request to create a public key credential.
 		Type     string                              `json:"type"`            // "public-key"
+
+		ClientExtensionResults AuthenticationExtensionsClientOutputs `json:"clientExtensionResults"` // Client extension outputs.
 	}
 	var raw rawPublicKeyCredential
 	if err = json.Unmarshal(data, &raw); err != nil {
@@ -177,7 +214,9 @@ func (credentialAttestation *PublicKeyCredentialAttestation) UnmarshalJSON(data
 		return err
 	}
 
-	credentialAttestation.AuthnData, credentialAttestation.AttStmt, err = parseAttestationObject(rawAttestationObject)
+	credentialAttestation.ClientExtensionResults = raw.ClientExtensionResults
+
+	credentialAttestation.AuthnData, credentialAttestation.Fmt, credentialAttestation.AttStmt, err = parseAttestationObject(rawAttestationObject)
 	return
 }
 
@@ -230,6 +269,23 @@ func UnregisterAttestationFormat(name string) {
 	formatsMu.Unlock()
 }
 
+// ParseAttestationStatement parses CBOR encoded attestation statement of given format with the registered
+// attestation statement format.  It is used by formats that nest attestation statements, such as compound.
+func ParseAttestationStatement(format string, data []byte) (AttestationStatement, error) {
+	return parseAttestationStatement(format, data)
+}
+
+// attestationFormatRegistered returns if the given attestation statement format is registered.
+func attestationFormatRegistered(format string) bool {
+	formats, _ := atomicFormats.Load().([]attestationFormat)
+	for _, f := range formats {
+		if f.name == format {
+			return true
+		}
+	}
+	return false
+}
+
 func parseAttestationStatement(format string, data []byte) (AttestationStatement, error) {
 	formats, _ := atomicFormats.Load().([]attestationFormat)
 	for _, f := range formats {
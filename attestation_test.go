@@ -503,11 +503,18 @@ func TestParseAuthenticatorDataError(t *testing.T) {
 	invalidDataBuf4.Write(credentialIDLength)
 	invalidDataBuf4.Write(credentialID[:])
 
-	// include extension
-	var extensionIncluded bytes.Buffer
-	extensionIncluded.Write(rpIDHash[:])
-	extensionIncluded.WriteByte(0x80) // flag: up = 0, uv = 0, no attestation, extensions included
-	extensionIncluded.Write(counter)
+	// include extension flag without extension data
+	var extensionMissing bytes.Buffer
+	extensionMissing.Write(rpIDHash[:])
+	extensionMissing.WriteByte(0x80) // flag: up = 0, uv = 0, no attestation, extensions included
+	extensionMissing.Write(counter)
+
+	// include extension data that isn't a map
+	var extensionNotMap bytes.Buffer
+	extensionNotMap.Write(rpIDHash[:])
+	extensionNotMap.WriteByte(0x80) // flag: up = 0, uv = 0, no attestation, extensions included
+	extensionNotMap.Write(counter)
+	extensionNotMap.Write(cborMarshal([]int{1, 2, 3}))
 
 	testCases := []struct {
 		name         string
@@ -518,7 +525,8 @@ func TestParseAuthenticatorDataError(t *testing.T) {
 		{"truncated credential data", invalidDataBuf2.Bytes(), "authenticator_data: failed to unmarshal: unexpected EOF"},
 		{"truncated credential data", invalidDataBuf3.Bytes(), "authenticator_data: failed to unmarshal: unexpected EOF"},
 		{"truncated credential data", invalidDataBuf4.Bytes(), "credential: failed to unmarshal: EOF"},
-		{"authenticator extension not supported", extensionIncluded.Bytes(), "authenticator data extension is not supported"},
+		{"missing authenticator extension", extensionMissing.Bytes(), "authenticator_data: failed to unmarshal extensions: EOF"},
+		{"authenticator extension isn't a map", extensionNotMap.Bytes(), "authenticator_data: failed to unmarshal extensions: cbor: cannot unmarshal array"},
 	}
 
 	for _, tc := range testCases {
@@ -530,6 +538,77 @@ func TestParseAuthenticatorDataError(t *testing.T) {
 	}
 }
 
+func TestParseAuthenticatorDataExtensions(t *testing.T) {
+	rpIDHash := sha256.Sum256([]byte("localhost"))
+	counter := []byte{0, 0, 0, 12}
+
+	testCases := []struct {
+		name               string
+		extensions         map[string]interface{}
+		wantExtensions     map[string]interface{}
+		wantMinPinLength   int
+		wantMinPinLengthOK bool
+		wantCredBlob       []byte
+		wantCredBlobOK     bool
+		wantCredBlobStored bool
+		wantStoredOK       bool
+	}{
+		{
+			name:               "minPinLength and credBlob in registration",
+			extensions:         map[string]interface{}{"minPinLength": 6, "credBlob": true},
+			wantExtensions:     map[string]interface{}{"minPinLength": uint64(6), "credBlob": true},
+			wantMinPinLength:   6,
+			wantMinPinLengthOK: true,
+			wantCredBlobStored: true,
+			wantStoredOK:       true,
+		},
+		{
+			name:           "credBlob in authentication",
+			extensions:     map[string]interface{}{"credBlob": []byte{1, 2, 3}},
+			wantExtensions: map[string]interface{}{"credBlob": []byte{1, 2, 3}},
+			wantCredBlob:   []byte{1, 2, 3},
+			wantCredBlobOK: true,
+		},
+		{
+			name:           "unknown extension",
+			extensions:     map[string]interface{}{"unknown": "value"},
+			wantExtensions: map[string]interface{}{"unknown": "value"},
+		},
+		{
+			name:           "minPinLength of wrong type",
+			extensions:     map[string]interface{}{"minPinLength": "6"},
+			wantExtensions: map[string]interface{}{"minPinLength": "6"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			buf.Write(rpIDHash[:])
+			buf.WriteByte(0x81) // flag: up = 1, uv = 0, no attestation, extensions included
+			buf.Write(counter)
+			buf.Write(cborMarshal(tc.extensions))
+
+			authnData, _, err := parseAuthenticatorData(buf.Bytes())
+			if err != nil {
+				t.Fatalf("parseAuthenticatorData() returns error %q", err)
+			}
+			if !reflect.DeepEqual(authnData.Extensions, tc.wantExtensions) {
+				t.Errorf("extensions %v, want %v", authnData.Extensions, tc.wantExtensions)
+			}
+			if minPinLength, ok := authnData.MinPinLength(); minPinLength != tc.wantMinPinLength || ok != tc.wantMinPinLengthOK {
+				t.Errorf("MinPinLength() returns (%d, %t), want (%d, %t)", minPinLength, ok, tc.wantMinPinLength, tc.wantMinPinLengthOK)
+			}
+			if credBlob, ok := authnData.CredBlob(); !bytes.Equal(credBlob, tc.wantCredBlob) || ok != tc.wantCredBlobOK {
+				t.Errorf("CredBlob() returns (%v, %t), want (%v, %t)", credBlob, ok, tc.wantCredBlob, tc.wantCredBlobOK)
+			}
+			if stored, ok := authnData.CredBlobStored(); stored != tc.wantCredBlobStored || ok != tc.wantStoredOK {
+				t.Errorf("CredBlobStored() returns (%t, %t), want (%t, %t)", stored, ok, tc.wantCredBlobStored, tc.wantStoredOK)
+			}
+		})
+	}
+}
+
 func TestParseAttestationObjectError(t *testing.T) {
 	coseKeyES256 := map[int]interface{}{
 		labelKty: coseKeyTypeEllipticCurve,
@@ -559,6 +638,17 @@ func TestParseAttestationObjectError(t *testing.T) {
 	authnDataBuf.Write(credentialID[:])
 	authnDataBuf.Write(credentialKeyData)
 
+	var authnDataTrailingBuf bytes.Buffer
+	authnDataTrailingBuf.Write(rpIDHash[:])
+	authnDataTrailingBuf.WriteByte(0xc4) // flag: up = 0, uv = 1, attestation = 1, extensions = 1
+	authnDataTrailingBuf.Write(counter)
+	authnDataTrailingBuf.Write(aaguid[:])
+	authnDataTrailingBuf.Write(credentialIDLength)
+	authnDataTrailingBuf.Write(credentialID[:])
+	authnDataTrailingBuf.Write(credentialKeyData)
+	authnDataTrailingBuf.Write(cborMarshal(map[string]interface{}{"credProtect": 1}))
+	authnDataTrailingBuf.WriteByte(0x00)
+
 	var authnDataNoCredentialBuf bytes.Buffer
 	authnDataNoCredentialBuf.Write(rpIDHash[:])
 	authnDataNoCredentialBuf.WriteByte(0x04) // flag: up = 0, uv = 1, attestation = 0, extensions = 0
@@ -596,6 +686,12 @@ func TestParseAttestationObjectError(t *testing.T) {
 		"attStmt":  attStmt,
 	}
 
+	trailingAuthn := map[string]interface{}{
+		"authData": authnDataTrailingBuf.Bytes(),
+		"fmt":      "mock",
+		"attStmt":  attStmt,
+	}
+
 	testCases := []struct {
 		name         string
 		data         []byte
@@ -607,10 +703,11 @@ func TestParseAttestationObjectError(t *testing.T) {
 		{"bad authn data", cborMarshal(badAuthn), "authenticator_data: failed to unmarshal: unexpected EOF"},
 		{"attestation statement format not registered", cborMarshal(notRegisteredFmt), "attestation statement format mock is not registered"},
 		{"authn data does not include credential data", cborMarshal(noCredential), "attestation_object: missing credential data"},
+		{"trailing data after authn data", cborMarshal(trailingAuthn), "authenticator_data: trailing data after authenticator data"},
 	}
 
 	for _, tc := range testCases {
-		if _, _, err := parseAttestationObject(tc.data); err == nil {
+		if _, _, _, err := parseAttestationObject(tc.data); err == nil {
 			t.Errorf("%s: parseAttestationObject() returns no error, want error containing substring %q", tc.name, tc.wantErrorMsg)
 		} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
 			t.Errorf("%s: parseAttestationObject() returns error %q, want error containing substring %q", tc.name, err, tc.wantErrorMsg)
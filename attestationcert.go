@@ -0,0 +1,334 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TrustAnchorStore provides root certificates trusted to verify attestation certificate chains.
+type TrustAnchorStore interface {
+	// TrustAnchors returns root certificates trusted for attestation certificates of given attestation
+	// statement format and AAGUID.  aaguid is nil or all zeros if the authenticator doesn't have one.
+	TrustAnchors(format string, aaguid []byte) []*x509.Certificate
+}
+
+// TrustAnchorStoreFunc is an adapter to allow the use of ordinary functions, such as FIDO Metadata
+// Service lookups, as TrustAnchorStore.
+type TrustAnchorStoreFunc func(format string, aaguid []byte) []*x509.Certificate
+
+// TrustAnchors calls f(format, aaguid).
+func (f TrustAnchorStoreFunc) TrustAnchors(format string, aaguid []byte) []*x509.Certificate {
+	return f(format, aaguid)
+}
+
+// CertificateStore is a set of trusted root certificates keyed by a string, such as a TPM manufacturer
+// ID or an attestation certificate key identifier.  It is safe for concurrent use, so it can be updated
+// while attestations are verified.
+type CertificateStore struct {
+	mu        sync.RWMutex
+	normalize func(key string) string
+	certs     map[string][]*x509.Certificate
+}
+
+// NewCertificateStore returns an empty set of root certificates.  If normalize isn't nil, keys are
+// normalized with it, so that different spellings of a key, such as upper and lower case hex digits,
+// select the same root certificates.
+func NewCertificateStore(normalize func(key string) string) *CertificateStore {
+	return &CertificateStore{normalize: normalize, certs: make(map[string][]*x509.Certificate)}
+}
+
+func (s *CertificateStore) normalizeKey(key string) string {
+	if s.normalize == nil {
+		return key
+	}
+	return s.normalize(key)
+}
+
+// Add adds root certificate trusted for given key to the set.
+func (s *CertificateStore) Add(key string, cert *x509.Certificate) {
+	key = s.normalizeKey(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.certs[key] {
+		if c.Equal(cert) {
+			return
+		}
+	}
+	s.certs[key] = append(s.certs[key], cert)
+}
+
+// AddPEM parses PEM encoded root certificates and adds them to the set for given key.
+func (s *CertificateStore) AddPEM(key string, data []byte) error {
+	certs, err := ParseCertificatesPEM(data)
+	if err != nil {
+		return err
+	}
+	for _, c := range certs {
+		s.Add(key, c)
+	}
+	return nil
+}
+
+// AddPEMFile reads PEM encoded root certificates from file, such as root certificates published by
+// authenticator vendors or of a FIDO metadata statement, and adds them to the set for given key.
+func (s *CertificateStore) AddPEMFile(key string, filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if err = s.AddPEM(key, data); err != nil {
+		return errors.New(filename + ": " + err.Error())
+	}
+	return nil
+}
+
+// Set replaces root certificates trusted for given key.
+func (s *CertificateStore) Set(key string, certs []*x509.Certificate) {
+	key = s.normalizeKey(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(certs) == 0 {
+		delete(s.certs, key)
+		return
+	}
+	s.certs[key] = append([]*x509.Certificate(nil), certs...)
+}
+
+// Certificates returns root certificates added for given key.
+func (s *CertificateStore) Certificates(key string) []*x509.Certificate {
+	key = s.normalizeKey(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*x509.Certificate(nil), s.certs[key]...)
+}
+
+// TrustAnchors is a TrustAnchorStore of root certificates trusted for an attestation statement format,
+// an AAGUID, both, or globally.  Empty format matches any format and nil AAGUID matches any AAGUID.
+// Only root certificates of the most specific match are trusted: format and AAGUID, AAGUID, format,
+// and then global root certificates.
+type TrustAnchors struct {
+	certs *CertificateStore
+}
+
+// NewTrustAnchors returns an empty set of trust anchors.
+func NewTrustAnchors() *TrustAnchors {
+	return &TrustAnchors{certs: NewCertificateStore(nil)}
+}
+
+// Add adds root certificate trusted for given attestation statement format and AAGUID to the set.
+func (s *TrustAnchors) Add(format string, aaguid []byte, cert *x509.Certificate) {
+	s.certs.Add(trustAnchorKey(format, aaguidKey(aaguid)), cert)
+}
+
+// AddPEM parses PEM encoded root certificates and adds them to the set for given attestation statement
+// format and AAGUID.
+func (s *TrustAnchors) AddPEM(format string, aaguid []byte, data []byte) error {
+	return s.certs.AddPEM(trustAnchorKey(format, aaguidKey(aaguid)), data)
+}
+
+// Set replaces root certificates trusted for given attestation statement format and AAGUID.
+func (s *TrustAnchors) Set(format string, aaguid []byte, certs []*x509.Certificate) {
+	s.certs.Set(trustAnchorKey(format, aaguidKey(aaguid)), certs)
+}
+
+// TrustAnchors implements the TrustAnchorStore interface.  It returns root certificates of the most
+// specific match for given attestation statement format and AAGUID.
+func (s *TrustAnchors) TrustAnchors(format string, aaguid []byte) []*x509.Certificate {
+	id := aaguidKey(aaguid)
+	for _, key := range []string{trustAnchorKey(format, id), trustAnchorKey("", id), trustAnchorKey(format, ""), trustAnchorKey("", "")} {
+		if certs := s.certs.Certificates(key); len(certs) > 0 {
+			return certs
+		}
+	}
+	return nil
+}
+
+// trustAnchorKey returns key of trust anchors of given attestation statement format and hex encoded AAGUID.
+func trustAnchorKey(format string, aaguid string) string {
+	return format + "/" + aaguid
+}
+
+// aaguidKey returns hex encoded AAGUID.  All zeros AAGUID, used by authenticators without AAGUID, is
+// encoded as empty string, which matches any AAGUID.
+func aaguidKey(aaguid []byte) string {
+	if len(bytes.Trim(aaguid, "\x00")) == 0 {
+		return ""
+	}
+	return hex.EncodeToString(aaguid)
+}
+
+// DefaultTrustAnchors is the set of root certificates trusted to verify attestation certificate chains of
+// formats without fixed root certificates, such as packed and fido-u2f.  Formats with fixed root
+// certificates, such as apple and android-key, and tpm, which trusts root certificates of the TPM
+// manufacturer, don't use it.  It is empty by default.
+var DefaultTrustAnchors = NewTrustAnchors()
+
+// CertificatePolicy determines how attestation certificate chains are verified.
+type CertificatePolicy struct {
+	// TrustAnchors provides root certificates trusted by VerifyAttestationCertificate in addition to root
+	// certificates of attestation statement formats.  DefaultTrustAnchors is used if it is nil.
+	TrustAnchors TrustAnchorStore
+
+	// Now returns current time, used to verify certificate validity periods.  time.Now is used if it is nil.
+	Now func() time.Time
+
+	// KeyUsages specifies extended key usages that certificate chains must allow.  Any extended key
+	// usage is accepted if it is empty.
+	KeyUsages []x509.ExtKeyUsage
+
+	// MaxPathLength is the maximum number of certificates in a chain, from attestation certificate to
+	// trusted root certificate inclusive.  Length of chains is unlimited if it is 0.  Path length
+	// constraints of CA certificates are always enforced.
+	MaxPathLength int
+
+	// SignatureAlgorithms specifies signature algorithms allowed for certificates in a chain, except for
+	// self-signature of trusted root certificate.  Any signature algorithm is allowed if it is empty.
+	SignatureAlgorithms []x509.SignatureAlgorithm
+}
+
+// DefaultCertificatePolicy is the policy used to verify attestation certificate chains.  Modify it during
+// initialization, before verifying attestations.
+var DefaultCertificatePolicy = &CertificatePolicy{}
+
+// VerifyAttestationCertificate verifies attestation certificate chain with DefaultCertificatePolicy.
+func VerifyAttestationCertificate(format string, aaguid []byte, attestnCert *x509.Certificate, caCerts []*x509.Certificate, roots []*x509.Certificate) (trustPath []*x509.Certificate, trusted bool, err error) {
+	return DefaultCertificatePolicy.VerifyAttestationCertificate(format, aaguid, attestnCert, caCerts, roots)
+}
+
+// VerifyAttestationCertificateWithRoots verifies attestation certificate chain to given roots with
+// DefaultCertificatePolicy.
+func VerifyAttestationCertificateWithRoots(attestnCert *x509.Certificate, caCerts []*x509.Certificate, roots []*x509.Certificate) (trustPath []*x509.Certificate, trusted bool, err error) {
+	return DefaultCertificatePolicy.VerifyAttestationCertificateWithRoots(attestnCert, caCerts, roots)
+}
+
+// VerifyAttestationCertificate builds certificate chain from attestation certificate through caCerts to
+// a trusted root certificate, and returns the chain as trust path.  Trusted root certificates are roots
+// of the attestation statement format and trust anchors of policy for format and aaguid.  Self-signed
+// certificates in caCerts are never trusted.  If there isn't any trusted root certificate, the chain is
+// verified to the last certificate of caCerts, or to attestation certificate itself, and it returns
+// false, so that attestation type is uncertain.
+func (policy *CertificatePolicy) VerifyAttestationCertificate(format string, aaguid []byte, attestnCert *x509.Certificate, caCerts []*x509.Certificate, roots []*x509.Certificate) (trustPath []*x509.Certificate, trusted bool, err error) {
+	store := policy.TrustAnchors
+	if store == nil {
+		store = DefaultTrustAnchors
+	}
+	return policy.VerifyAttestationCertificateWithRoots(attestnCert, caCerts, append(append([]*x509.Certificate(nil), roots...), store.TrustAnchors(format, aaguid)...))
+}
+
+// VerifyAttestationCertificateWithRoots is like VerifyAttestationCertificate, but only roots are
+// trusted, without trust anchors of policy.  It is used by attestation statement formats whose
+// attestation certificates chain to fixed root certificates, such as apple, or to root certificates of
+// a known manufacturer, such as tpm, so that trust anchors of other authenticators don't apply to them.
+func (policy *CertificatePolicy) VerifyAttestationCertificateWithRoots(attestnCert *x509.Certificate, caCerts []*x509.Certificate, roots []*x509.Certificate) (trustPath []*x509.Certificate, trusted bool, err error) {
+	verifyOptions := x509.VerifyOptions{
+		Roots:         x509.NewCertPool(),
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     policy.KeyUsages,
+	}
+	if len(verifyOptions.KeyUsages) == 0 {
+		verifyOptions.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	}
+	if policy.Now != nil {
+		verifyOptions.CurrentTime = policy.Now()
+	}
+
+	// Without trusted root certificates, the last certificate of the chain is a provisional root, so
+	// that validity periods, key usages, and policy are still verified.  Only trust status differs.
+	trusted = len(roots) > 0
+	if !trusted {
+		if len(caCerts) == 0 {
+			roots = []*x509.Certificate{attestnCert}
+		} else {
+			roots, caCerts = caCerts[len(caCerts)-1:], caCerts[:len(caCerts)-1]
+		}
+	}
+	for _, c := range roots {
+		verifyOptions.Roots.AddCert(c)
+	}
+	for _, c := range caCerts {
+		verifyOptions.Intermediates.AddCert(c)
+	}
+
+	chains, err := attestnCert.Verify(verifyOptions)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, chain := range chains {
+		if err = policy.verifyChain(chain); err == nil {
+			return chain, trusted, nil
+		}
+	}
+	return nil, false, err
+}
+
+// verifyChain verifies that chain satisfies path length and signature algorithm requirements of policy.
+func (policy *CertificatePolicy) verifyChain(chain []*x509.Certificate) error {
+	if policy.MaxPathLength > 0 && len(chain) > policy.MaxPathLength {
+		return errors.New("certificate chain of " + strconv.Itoa(len(chain)) + " certificates is longer than maximum path length " + strconv.Itoa(policy.MaxPathLength))
+	}
+	if len(policy.SignatureAlgorithms) > 0 {
+		for _, c := range chain[:len(chain)-1] {
+			allowed := false
+			for _, alg := range policy.SignatureAlgorithms {
+				if c.SignatureAlgorithm == alg {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				return errors.New("certificate \"" + c.Subject.CommonName + "\" signature algorithm " + c.SignatureAlgorithm.String() + " is not allowed")
+			}
+		}
+	}
+	return nil
+}
+
+// ParseCertificatesPEM returns certificates of PEM blocks of type "CERTIFICATE" in data.  Other PEM
+// blocks are skipped.  It returns an error if there isn't any certificate.
+func ParseCertificatesPEM(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificate")
+	}
+	return certs, nil
+}
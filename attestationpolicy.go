@@ -0,0 +1,63 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn
+
+// AttestationPolicy determines how an attestation statement is handled when its format isn't allowed by
+// the attestation conveyance preference or attestation formats of the attestation options.
+type AttestationPolicy int
+
+// Attestation policies.
+const (
+	AttestationPolicyReject AttestationPolicy = iota // Reject attestation statements of formats that weren't allowed.
+	AttestationPolicyNone                            // Treat unwanted attestation statements as "none", dropping the attestation certificates.
+)
+
+// verifyAttestationFormat verifies that attestation statement format is allowed by the attestation conveyance
+// preference and attestation formats of the attestation options, and returns true if the attestation statement
+// is unwanted and should be treated as "none" attestation statement.  "direct" attestation conveyance preference
+// requires an attestation statement other than "none", and "none" attestation conveyance preference doesn't want
+// any.  If attestation formats are provided, attestation statement format must be one of them or "none".
+func verifyAttestationFormat(format string, preference AttestationConveyancePreference, formats []string, policy AttestationPolicy) (asNone bool, err error) {
+	if format == "none" {
+		if preference == AttestationDirect {
+			return false, &VerificationError{Type: "attestation", Field: "attestation statement format", Msg: "attestation statement is required, got \"none\""}
+		}
+		return false, nil
+	}
+
+	if preference == AttestationNone {
+		if policy == AttestationPolicyNone {
+			return true, nil
+		}
+		return false, &VerificationError{Type: "attestation", Field: "attestation statement format", Msg: "attestation statement isn't wanted, got \"" + format + "\""}
+	}
+
+	if len(formats) == 0 {
+		return false, nil
+	}
+	for _, f := range formats {
+		if f == format {
+			return false, nil
+		}
+	}
+	if policy == AttestationPolicyNone && preference != AttestationDirect {
+		return true, nil
+	}
+	return false, &VerificationError{Type: "attestation", Field: "attestation statement format", Msg: "attestation statement format \"" + format + "\" isn't allowed"}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyAttestationFormat(t *testing.T) {
+	testCases := []struct {
+		name         string
+		format       string
+		preference   AttestationConveyancePreference
+		formats      []string
+		policy       AttestationPolicy
+		wantAsNone   bool
+		wantErrorMsg string
+	}{
+		{"no preference", "packed", "", nil, AttestationPolicyReject, false, ""},
+		{"none with no preference", "none", "", nil, AttestationPolicyReject, false, ""},
+		{"none with none preference", "none", AttestationNone, nil, AttestationPolicyReject, false, ""},
+		{"none with indirect preference", "none", AttestationIndirect, nil, AttestationPolicyReject, false, ""},
+		{"none with direct preference", "none", AttestationDirect, nil, AttestationPolicyReject, false, "attestation statement is required, got \"none\""},
+		{"none with direct preference with none policy", "none", AttestationDirect, nil, AttestationPolicyNone, false, "attestation statement is required, got \"none\""},
+		{"packed with none preference", "packed", AttestationNone, nil, AttestationPolicyReject, false, "attestation statement isn't wanted, got \"packed\""},
+		{"packed with none preference with none policy", "packed", AttestationNone, nil, AttestationPolicyNone, true, ""},
+		{"packed with direct preference", "packed", AttestationDirect, nil, AttestationPolicyReject, false, ""},
+		{"allowed format", "tpm", AttestationDirect, []string{"packed", "tpm"}, AttestationPolicyReject, false, ""},
+		{"none with formats", "none", AttestationIndirect, []string{"packed"}, AttestationPolicyReject, false, ""},
+		{"format not allowed", "fido-u2f", AttestationIndirect, []string{"packed", "tpm"}, AttestationPolicyReject, false, "attestation statement format \"fido-u2f\" isn't allowed"},
+		{"format not allowed with none policy", "fido-u2f", AttestationIndirect, []string{"packed", "tpm"}, AttestationPolicyNone, true, ""},
+		{"format not allowed with direct preference with none policy", "fido-u2f", AttestationDirect, []string{"packed", "tpm"}, AttestationPolicyNone, false, "attestation statement format \"fido-u2f\" isn't allowed"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			asNone, err := verifyAttestationFormat(tc.format, tc.preference, tc.formats, tc.policy)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("verifyAttestationFormat() returns error %q", err)
+			} else if tc.wantErrorMsg != "" && err == nil {
+				t.Errorf("verifyAttestationFormat() returns no error, want error containing substring %q", tc.wantErrorMsg)
+			} else if tc.wantErrorMsg != "" && !strings.Contains(err.Error(), tc.wantErrorMsg) {
+				t.Errorf("verifyAttestationFormat() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+			}
+			if asNone != tc.wantAsNone {
+				t.Errorf("verifyAttestationFormat() returns %t, want %t", asNone, tc.wantAsNone)
+			}
+		})
+	}
+}
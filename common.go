@@ -17,10 +17,13 @@ limitations under the License.
 package webauthn
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
 	"strings"
+
+	"github.com/fxamacker/cbor/v2"
 )
 
 // AuthenticatorData represents the Web Authentication structure of the same name,
@@ -35,6 +38,21 @@ type AuthenticatorData struct {
 	CredentialID []byte                 // Identifier of a public key credential source (optional).
 	Credential   *Credential            // Algorithm and public key portion of a Relying Party-specific credential key pair (optional).
 	Extensions   map[string]interface{} // Extension-defined authenticator data (optional).
+
+	rawExtensions map[string]cbor.RawMessage // CBOR encoded authenticator extension outputs, keyed by extension identifier.
+}
+
+// ParseAuthenticatorData parses authenticator data and returns AuthenticatorData,
+// as defined in http://w3c.github.io/webauthn/#sctn-authenticator-data
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	authnData, rest, err := parseAuthenticatorData(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, &UnmarshalBadDataError{Type: "authenticator data", Msg: "trailing data after authenticator data"}
+	}
+	return authnData, nil
 }
 
 func parseAuthenticatorData(data []byte) (authnData *AuthenticatorData, rest []byte, err error) {
@@ -79,7 +97,20 @@ func parseAuthenticatorData(data []byte) (authnData *AuthenticatorData, rest []b
 	}
 
 	if extensionDataIncluded {
-		return nil, nil, &UnsupportedFeatureError{Feature: "authenticator data extension"}
+		decoder := cbor.NewDecoder(bytes.NewReader(rest))
+		if err = decoder.Decode(&authnData.rawExtensions); err != nil {
+			return nil, nil, &UnmarshalSyntaxError{Type: "authenticator data", Field: "extensions", Msg: err.Error()}
+		}
+		rest = rest[decoder.NumBytesRead():]
+
+		authnData.Extensions = make(map[string]interface{}, len(authnData.rawExtensions))
+		for id, rawValue := range authnData.rawExtensions {
+			var v interface{}
+			if err = cbor.Unmarshal(rawValue, &v); err != nil {
+				return nil, nil, &UnmarshalSyntaxError{Type: "authenticator data", Field: "extension " + id, Msg: err.Error()}
+			}
+			authnData.Extensions[id] = v
+		}
 	}
 
 	return
@@ -106,10 +137,12 @@ type TokenBinding struct {
 // as defined in http://w3c.github.io/webauthn/#dictionary-client-data
 type CollectedClientData struct {
 	Raw          []byte        `json:"-"`            // Complete raw client data content.
-	Type         string        `json:"type"`         // "webauthn.create" when creating new credentials, and "webauthn.get" when getting an assertion.
+	Type         string        `json:"type"`         // "webauthn.create" when creating new credentials, "webauthn.get" when getting an assertion, and "payment.get" when getting a Secure Payment Confirmation assertion.
 	Challenge    string        `json:"challenge"`    // base64 url encoded chanllenge provided by the Relying Party.
 	Origin       string        `json:"origin"`       // Fully qualified origin of the requester.
 	TokenBinding *TokenBinding `json:"tokenBinding"` // State of the Token Binding protocol used when communicating with the Relying Party.  Its absence indicates that the client doesn't support token binding.
+
+	Payment *CollectedClientAdditionalPaymentData `json:"payment,omitempty"` // Transaction data shown to the user (Secure Payment Confirmation only).
 }
 
 func parseClientData(data []byte) (clientData *CollectedClientData, err error) {
@@ -131,6 +164,10 @@ func parseClientData(data []byte) (clientData *CollectedClientData, err error) {
 	if clientData.TokenBinding != nil && len(clientData.TokenBinding.Status) == 0 {
 		return nil, &UnmarshalMissingFieldError{Type: "client data", Field: "token binding status"}
 	}
+	// Verify payment is present in Secure Payment Confirmation client data.
+	if clientData.Type == "payment.get" && clientData.Payment == nil {
+		return nil, &UnmarshalMissingFieldError{Type: "client data", Field: "payment"}
+	}
 	return
 }
 
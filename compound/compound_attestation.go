@@ -0,0 +1,147 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package compound
+
+import (
+	"crypto/x509"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/kappapay/webauthn"
+)
+
+// Result represents attestation type and trust path of a verified attestation statement nested in
+// compound attestation statement.  Compound attestation statements are verified with attestation type
+// webauthn.AttestationTypeCompound and a []Result trust path, so callers need WeakestType to tell whether
+// any nested attestation statement is only of type Self, Uncertain, or None.
+type Result struct {
+	Format    string                   // Attestation statement format identifier, such as "packed".
+	Type      webauthn.AttestationType // Attestation type.
+	TrustPath interface{}              // Attestation trust path.
+	Details   interface{}              // Format specific details if the attestation statement implements webauthn.AttestationDetailer.
+}
+
+type compoundAttestationStatement struct {
+	fmts     []string                        // Attestation statement format identifiers of nested attestation statements.
+	attStmts []webauthn.AttestationStatement // Nested attestation statements.
+	chains   [][]*x509.Certificate           // Certificate chains of verified nested trust paths.
+}
+
+// WeakestType returns the weakest attestation type of nested attestation statements: None, then
+// Uncertain, then Self, and otherwise the first attestation type in results.
+func WeakestType(results []Result) webauthn.AttestationType {
+	rank := func(attType webauthn.AttestationType) int {
+		switch attType {
+		case webauthn.AttestationTypeNone:
+			return 0
+		case webauthn.AttestationTypeUncertain:
+			return 1
+		case webauthn.AttestationTypeSelf:
+			return 2
+		default:
+			return 3
+		}
+	}
+	var weakest webauthn.AttestationType
+	for i, r := range results {
+		if i == 0 || rank(r.Type) < rank(weakest) {
+			weakest = r.Type
+		}
+	}
+	return weakest
+}
+
+func parseAttestation(data []byte) (webauthn.AttestationStatement, error) {
+	type rawAttStmt struct {
+		Fmt     string          `cbor:"fmt"`     // Attestation statement format identifier.
+		AttStmt cbor.RawMessage `cbor:"attStmt"` // Attestation statement of the format.
+	}
+
+	var raw []rawAttStmt
+	if err := cbor.Unmarshal(data, &raw); err != nil {
+		return nil, &webauthn.UnmarshalSyntaxError{Type: "compound attestation", Msg: err.Error()}
+	}
+
+	if len(raw) < 2 {
+		return nil, &webauthn.UnmarshalBadDataError{Type: "compound attestation", Msg: fmt.Sprintf("expected at least 2 attestation statements, got %d attestation statements", len(raw))}
+	}
+
+	attStmt := &compoundAttestationStatement{}
+	for i, r := range raw {
+		if len(r.Fmt) == 0 {
+			return nil, &webauthn.UnmarshalMissingFieldError{Type: "compound attestation", Field: fmt.Sprintf("attStmt[%d].fmt", i)}
+		}
+		if r.Fmt == "compound" {
+			return nil, &webauthn.UnmarshalBadDataError{Type: "compound attestation", Msg: "compound attestation can not nest compound attestation statement"}
+		}
+		if len(r.AttStmt) == 0 {
+			return nil, &webauthn.UnmarshalMissingFieldError{Type: "compound attestation", Field: fmt.Sprintf("attStmt[%d].attStmt", i)}
+		}
+		s, err := webauthn.ParseAttestationStatement(r.Fmt, r.AttStmt)
+		if err != nil {
+			return nil, err
+		}
+		attStmt.fmts = append(attStmt.fmts, r.Fmt)
+		attStmt.attStmts = append(attStmt.attStmts, s)
+	}
+
+	return attStmt, nil
+}
+
+// Verify implements the webauthn.AttestationStatement interface.  It follows compound attestation
+// statement verification procedure defined in https://w3c.github.io/webauthn/#sctn-compound-attestation
+func (attStmt *compoundAttestationStatement) Verify(clientDataHash []byte, authnData *webauthn.AuthenticatorData) (attType webauthn.AttestationType, trustPath interface{}, err error) {
+	// For each nested attestation statement, verify it with the verification procedure of its format,
+	// using the same authenticatorData and clientDataHash.  All nested attestation statements must be valid.
+	results := make([]Result, len(attStmt.attStmts))
+	for i, s := range attStmt.attStmts {
+		results[i].Format = attStmt.fmts[i]
+		if results[i].Type, results[i].TrustPath, err = s.Verify(clientDataHash, authnData); err != nil {
+			return 0, nil, err
+		}
+		if detailer, ok := s.(webauthn.AttestationDetailer); ok {
+			results[i].Details = detailer.AttestationDetails()
+		}
+	}
+	attStmt.chains = nil
+	for _, r := range results {
+		if chain, ok := r.TrustPath.([]*x509.Certificate); ok {
+			attStmt.chains = append(attStmt.chains, chain)
+		}
+	}
+
+	// If successful, return attestation type Compound and results of nested attestation statements as
+	// attestation trust path.
+	return webauthn.AttestationTypeCompound, results, nil
+}
+
+// CertificateChains implements the webauthn.CertificateChainer interface.  It returns certificate chains
+// of nested trust paths, so that webauthn.VerifyRegistration checks their revocation status.
+func (attStmt *compoundAttestationStatement) CertificateChains() [][]*x509.Certificate {
+	return attStmt.chains
+}
+
+// NestedAttestationFormats implements the webauthn.NestedAttestationFormatter interface.
+func (attStmt *compoundAttestationStatement) NestedAttestationFormats() []string {
+	return attStmt.fmts
+}
+
+func init() {
+	webauthn.RegisterAttestationFormat("compound", parseAttestation)
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package compound
+
+import (
+	"bytes"
+	"crypto/x509"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/internal/testcert"
+)
+
+// testAttestationStatement is attestation statement of "test" format.  Its CBOR encoded attestation
+// statement is a text string selecting verification result: "basic", "chain" with testChain trust path,
+// "fail", or "invalid" which fails parsing.
+type testAttestationStatement struct {
+	result         string
+	clientDataHash []byte
+	authnData      *webauthn.AuthenticatorData
+}
+
+// testChain is trust path of "chain" test attestation statements.
+var testChain = []*x509.Certificate{testcert.NewCA("Test Attestation", nil).Cert}
+
+func parseTestAttestation(data []byte) (webauthn.AttestationStatement, error) {
+	var result string
+	if err := cbor.Unmarshal(data, &result); err != nil || result == "invalid" {
+		return nil, &webauthn.UnmarshalSyntaxError{Type: "test attestation", Msg: "invalid test attestation statement"}
+	}
+	return &testAttestationStatement{result: result}, nil
+}
+
+func (attStmt *testAttestationStatement) Verify(clientDataHash []byte, authnData *webauthn.AuthenticatorData) (webauthn.AttestationType, interface{}, error) {
+	attStmt.clientDataHash, attStmt.authnData = clientDataHash, authnData
+	switch attStmt.result {
+	case "fail":
+		return 0, nil, &webauthn.VerificationError{Type: "test attestation", Field: "signature", Msg: "invalid signature"}
+	case "chain":
+		return webauthn.AttestationTypeBasic, testChain, nil
+	}
+	return webauthn.AttestationTypeBasic, "test trust path", nil
+}
+
+func (attStmt *testAttestationStatement) AttestationDetails() interface{} {
+	return "test details"
+}
+
+func init() {
+	webauthn.RegisterAttestationFormat("test", parseTestAttestation)
+}
+
+type nestedAttStmt struct {
+	Fmt     string          `cbor:"fmt"`
+	AttStmt cbor.RawMessage `cbor:"attStmt"`
+}
+
+func newNestedAttStmt(t *testing.T, format string, attStmt interface{}) nestedAttStmt {
+	data, err := cbor.Marshal(attStmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nestedAttStmt{Fmt: format, AttStmt: data}
+}
+
+func marshalCompound(t *testing.T, attStmts ...nestedAttStmt) []byte {
+	data, err := cbor.Marshal(attStmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseCompoundAttestationError(t *testing.T) {
+	none := newNestedAttStmt(t, "none", map[string]interface{}{})
+	missingAttStmt, err := cbor.Marshal([]interface{}{none, map[string]interface{}{"fmt": "test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name         string
+		data         []byte
+		wantErrorMsg string
+	}{
+		{"not an array", []byte{0xa0}, "webauthn/compound_attestation: failed to unmarshal"},
+		{"one attestation statement", marshalCompound(t, none), "expected at least 2 attestation statements, got 1 attestation statements"},
+		{"missing fmt", marshalCompound(t, none, newNestedAttStmt(t, "", "basic")), "attStmt[1].fmt"},
+		{"missing attStmt", missingAttStmt, "attStmt[1].attStmt"},
+		{"nested compound", marshalCompound(t, none, newNestedAttStmt(t, "compound", []interface{}{})), "compound attestation can not nest compound attestation statement"},
+		{"unregistered format", marshalCompound(t, none, newNestedAttStmt(t, "unknown", "basic")), "attestation statement format unknown"},
+		{"invalid nested attestation statement", marshalCompound(t, none, newNestedAttStmt(t, "test", "invalid")), "invalid test attestation statement"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parseAttestation(tc.data); err == nil {
+				t.Errorf("parseAttestation() returns no error, want error containing substring %q", tc.wantErrorMsg)
+			} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+				t.Errorf("parseAttestation() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+			}
+		})
+	}
+}
+
+func TestVerifyCompoundAttestation(t *testing.T) {
+	data := marshalCompound(t, newNestedAttStmt(t, "none", map[string]interface{}{}), newNestedAttStmt(t, "test", "basic"))
+	attStmt, err := webauthn.ParseAttestationStatement("compound", data)
+	if err != nil {
+		t.Fatalf("ParseAttestationStatement() returns error %q", err)
+	}
+
+	clientDataHash := bytes.Repeat([]byte{0x01}, 32)
+	authnData := &webauthn.AuthenticatorData{}
+	attType, trustPath, err := attStmt.Verify(clientDataHash, authnData)
+	if err != nil {
+		t.Fatalf("Verify() returns error %q", err)
+	}
+	if attType != webauthn.AttestationTypeCompound {
+		t.Errorf("attestation type %v, want %v", attType, webauthn.AttestationTypeCompound)
+	}
+	wantTrustPath := []Result{
+		{Format: "none", Type: webauthn.AttestationTypeNone},
+		{Format: "test", Type: webauthn.AttestationTypeBasic, TrustPath: "test trust path", Details: "test details"},
+	}
+	if !reflect.DeepEqual(trustPath, wantTrustPath) {
+		t.Errorf("trust path %+v, want %+v", trustPath, wantTrustPath)
+	}
+	if got := WeakestType(wantTrustPath); got != webauthn.AttestationTypeNone {
+		t.Errorf("WeakestType() = %v, want %v", got, webauthn.AttestationTypeNone)
+	}
+	if formats := attStmt.(webauthn.NestedAttestationFormatter).NestedAttestationFormats(); !reflect.DeepEqual(formats, []string{"none", "test"}) {
+		t.Errorf("NestedAttestationFormats() = %v, want [none test]", formats)
+	}
+	nested := attStmt.(*compoundAttestationStatement).attStmts[1].(*testAttestationStatement)
+	if !bytes.Equal(nested.clientDataHash, clientDataHash) || nested.authnData != authnData {
+		t.Errorf("nested attestation statement is verified with different clientDataHash or authenticator data")
+	}
+}
+
+func TestCompoundAttestationCertificateChains(t *testing.T) {
+	data := marshalCompound(t, newNestedAttStmt(t, "test", "basic"), newNestedAttStmt(t, "test", "chain"))
+	attStmt, err := parseAttestation(data)
+	if err != nil {
+		t.Fatalf("parseAttestation() returns error %q", err)
+	}
+	if _, _, err = attStmt.Verify(bytes.Repeat([]byte{0x01}, 32), &webauthn.AuthenticatorData{}); err != nil {
+		t.Fatalf("Verify() returns error %q", err)
+	}
+	chains := attStmt.(webauthn.CertificateChainer).CertificateChains()
+	if !reflect.DeepEqual(chains, [][]*x509.Certificate{testChain}) {
+		t.Errorf("CertificateChains() = %v, want certificate chain of nested trust path", chains)
+	}
+}
+
+func TestWeakestType(t *testing.T) {
+	testCases := []struct {
+		name  string
+		types []webauthn.AttestationType
+		want  webauthn.AttestationType
+	}{
+		{"trusted", []webauthn.AttestationType{webauthn.AttestationTypeBasic, webauthn.AttestationTypeAnonCA}, webauthn.AttestationTypeBasic},
+		{"self", []webauthn.AttestationType{webauthn.AttestationTypeBasic, webauthn.AttestationTypeSelf}, webauthn.AttestationTypeSelf},
+		{"uncertain", []webauthn.AttestationType{webauthn.AttestationTypeSelf, webauthn.AttestationTypeUncertain, webauthn.AttestationTypeCA}, webauthn.AttestationTypeUncertain},
+		{"none", []webauthn.AttestationType{webauthn.AttestationTypeUncertain, webauthn.AttestationTypeNone}, webauthn.AttestationTypeNone},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			results := make([]Result, len(tc.types))
+			for i, attType := range tc.types {
+				results[i].Type = attType
+			}
+			if got := WeakestType(results); got != tc.want {
+				t.Errorf("WeakestType() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerifyCompoundAttestationError(t *testing.T) {
+	data := marshalCompound(t, newNestedAttStmt(t, "test", "basic"), newNestedAttStmt(t, "test", "fail"))
+	attStmt, err := parseAttestation(data)
+	if err != nil {
+		t.Fatalf("parseAttestation() returns error %q", err)
+	}
+	attType, trustPath, err := attStmt.Verify(bytes.Repeat([]byte{0x01}, 32), &webauthn.AuthenticatorData{})
+	if err == nil {
+		t.Fatalf("Verify() returns no error, want error")
+	} else if verificationErr, ok := err.(*webauthn.VerificationError); !ok || verificationErr.Type != "test attestation" {
+		t.Errorf("Verify() returns error %q, want error of nested attestation statement", err)
+	}
+	if attType != 0 || trustPath != nil {
+		t.Errorf("Verify() returns attestation type %v and trust path %v, want none", attType, trustPath)
+	}
+}
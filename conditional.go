@@ -0,0 +1,187 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// defaultConditionalTimeout is the default time in milliseconds that conditional mediation
+// assertion options and their challenges stay valid.
+const defaultConditionalTimeout = 10 * 60 * 1000
+
+// ChallengeStore stores challenges of conditional mediation assertion options, so each challenge
+// can be used at most once before it expires.  It must be safe for concurrent use.
+type ChallengeStore interface {
+	// Add stores challenge until it expires.
+	Add(challenge []byte, expires time.Time) error
+
+	// Consume removes challenge and returns true if challenge was stored and hasn't expired.
+	Consume(challenge []byte) (bool, error)
+}
+
+// MemoryChallengeStore is a ChallengeStore that keeps challenges in memory.  Zero value MemoryChallengeStore is
+// ready to use.
+type MemoryChallengeStore struct {
+	mu         sync.Mutex
+	challenges map[string]time.Time
+}
+
+// Add implements the ChallengeStore interface.  It also removes expired challenges.
+func (s *MemoryChallengeStore) Add(challenge []byte, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for c, exp := range s.challenges {
+		if !now.Before(exp) {
+			delete(s.challenges, c)
+		}
+	}
+	if s.challenges == nil {
+		s.challenges = make(map[string]time.Time)
+	}
+	s.challenges[string(challenge)] = expires
+	return nil
+}
+
+// Consume implements the ChallengeStore interface.
+func (s *MemoryChallengeStore) Consume(challenge []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expires, ok := s.challenges[string(challenge)]
+	if !ok {
+		return false, nil
+	}
+	delete(s.challenges, string(challenge))
+	return time.Now().Before(expires), nil
+}
+
+// CredentialLookupFunc returns the registered credential record identified by credential ID and
+// user handle returned in an assertion, or nil if the credential isn't registered.
+type CredentialLookupFunc func(credentialID []byte, userHandle []byte) (*CredentialRecord, error)
+
+// ConditionalAssertionExpectedData represents data needed to verify assertions obtained with conditional
+// mediation, when the user isn't known until the assertion is received.
+type ConditionalAssertionExpectedData struct {
+	Origin             string
+	RPID               string
+	UserVerification   UserVerificationRequirement
+	Challenges         ChallengeStore       // Challenges of conditional mediation assertion options.
+	LookupCredential   CredentialLookupFunc // Finds the credential record of the assertion.
+	TokenBinding       *TokenBinding        // Token Binding state of the TLS connection (optional).
+	TokenBindingPolicy TokenBindingPolicy   // Verification of client data token binding without TokenBinding (optional).
+}
+
+// NewConditionalAssertionOptions returns a CredentialRequestOptions for conditional mediation (autofill UI)
+// from config, and adds its challenge to challenges.  Allowed credentials are left empty so the client
+// offers discoverable credentials, and the challenge stays valid for config.ConditionalTimeout.
+func NewConditionalAssertionOptions(config *Config, challenges ChallengeStore) (*CredentialRequestOptions, error) {
+	if challenges == nil {
+		return nil, errors.New("challenge store is required")
+	}
+	options, err := NewAssertionOptions(config, &User{})
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := config.ConditionalTimeout
+	if timeout == 0 {
+		timeout = defaultConditionalTimeout
+	}
+	options.Timeout = timeout
+
+	expires := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+	if err := challenges.Add(options.Challenge, expires); err != nil {
+		return nil, err
+	}
+
+	return &CredentialRequestOptions{Mediation: MediationConditional, PublicKey: options}, nil
+}
+
+// NewConditionalAttestationOptions returns a CredentialCreationOptions for conditional create from config and
+// user, so the client can create a passkey automatically, such as right after a password login.  Attestation
+// obtained with these options must be verified with AttestationExpectedData.Mediation set to "conditional".
+func NewConditionalAttestationOptions(config *Config, user *User) (*CredentialCreationOptions, error) {
+	options, err := NewAttestationOptions(config, user)
+	if err != nil {
+		return nil, err
+	}
+	return &CredentialCreationOptions{Mediation: MediationConditional, PublicKey: options}, nil
+}
+
+// VerifyConditionalAuthentication verifies assertion obtained with conditional mediation and returns
+// AuthenticationResult or error.  The challenge is consumed from expected challenges, and the credential
+// record is found from the credential ID and user handle of the assertion.
+func VerifyConditionalAuthentication(credentialAssertion *PublicKeyCredentialAssertion, expected *ConditionalAssertionExpectedData) (*AuthenticationResult, error) {
+	if expected.Challenges == nil {
+		return nil, errors.New("challenge store is required")
+	}
+	if expected.LookupCredential == nil {
+		return nil, errors.New("credential lookup function is required")
+	}
+
+	// Verify that userHandle is present, because discoverable credentials return the user handle.
+	if len(credentialAssertion.UserHandle) == 0 {
+		return nil, &VerificationError{Type: "assertion", Field: "user handle", Msg: "user handle is required to identify the user"}
+	}
+
+	// Verify that C.challenge is a challenge of conditional mediation assertion options that hasn't
+	// expired or been used.
+	challenge, err := base64DecodeString(credentialAssertion.ClientData.Challenge)
+	if err != nil {
+		return nil, &VerificationError{Type: "assertion", Field: "client data challenge", Msg: err.Error()}
+	}
+	ok, err := expected.Challenges.Consume(challenge)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &VerificationError{Type: "assertion", Field: "client data challenge", Msg: "challenge is unknown, expired, or already used"}
+	}
+
+	// Find the credential record being used for the assertion.
+	record, err := expected.LookupCredential(credentialAssertion.RawID, credentialAssertion.UserHandle)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, &VerificationError{Type: "assertion", Field: "credential ID", Msg: "credential is not registered"}
+	}
+
+	result, err := VerifyAuthentication(credentialAssertion, &AssertionExpectedData{
+		Origin:             expected.Origin,
+		RPID:               expected.RPID,
+		Challenge:          credentialAssertion.ClientData.Challenge,
+		UserVerification:   expected.UserVerification,
+		UserID:             record.UserID,
+		UserCredentialIDs:  [][]byte{record.ID},
+		PrevCounter:        record.SignCount,
+		Credential:         record.Credential,
+		DevicePublicKeys:   record.DevicePublicKeys,
+		TokenBinding:       expected.TokenBinding,
+		TokenBindingPolicy: expected.TokenBindingPolicy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.CredentialRecord = record
+	return result, nil
+}
@@ -0,0 +1,335 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kappapay/webauthn"
+)
+
+func TestNewConditionalAssertionOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cfg         *webauthn.Config
+		wantTimeout uint64
+	}{
+		{"default conditional timeout", getTestConfig(), 600000},
+		{"conditional timeout", func() *webauthn.Config {
+			cfg := getTestConfig()
+			cfg.ConditionalTimeout = 300000
+			return cfg
+		}(), 300000},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			challenges := &webauthn.MemoryChallengeStore{}
+			options, err := webauthn.NewConditionalAssertionOptions(tc.cfg, challenges)
+			if err != nil {
+				t.Fatalf("NewConditionalAssertionOptions() returns error %q", err)
+			}
+			if options.Mediation != webauthn.MediationConditional {
+				t.Errorf("mediation %q, want %q", options.Mediation, webauthn.MediationConditional)
+			}
+			if len(options.PublicKey.AllowCredentials) != 0 {
+				t.Errorf("allowCredentials %v, want empty", options.PublicKey.AllowCredentials)
+			}
+			if options.PublicKey.Timeout != tc.wantTimeout {
+				t.Errorf("timeout %d, want %d", options.PublicKey.Timeout, tc.wantTimeout)
+			}
+			if len(options.PublicKey.Challenge) != tc.cfg.ChallengeLength {
+				t.Errorf("challenge length %d, want %d", len(options.PublicKey.Challenge), tc.cfg.ChallengeLength)
+			}
+			if ok, err := challenges.Consume(options.PublicKey.Challenge); err != nil || !ok {
+				t.Errorf("Consume() returns (%t, %v), want (true, nil)", ok, err)
+			}
+			if ok, err := challenges.Consume(options.PublicKey.Challenge); err != nil || ok {
+				t.Errorf("Consume() of used challenge returns (%t, %v), want (false, nil)", ok, err)
+			}
+		})
+	}
+}
+
+func TestMemoryChallengeStoreExpired(t *testing.T) {
+	challenges := &webauthn.MemoryChallengeStore{}
+	challenge := []byte("expired challenge")
+	if err := challenges.Add(challenge, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Add() returns error %q", err)
+	}
+	if ok, err := challenges.Consume(challenge); err != nil || ok {
+		t.Errorf("Consume() of expired challenge returns (%t, %v), want (false, nil)", ok, err)
+	}
+}
+
+func TestVerifyConditionalAuthentication(t *testing.T) {
+	authenticator := newTestAuthenticator()
+	authenticator.userHandle = []byte{1, 2, 3, 4}
+	record := &webauthn.CredentialRecord{
+		ID:         authenticator.credentialID,
+		UserID:     authenticator.userHandle,
+		Credential: authenticator.credential(),
+		SignCount:  1,
+	}
+	lookupErr := errors.New("database is unavailable")
+	noCredential := func(credentialID []byte, userHandle []byte) (*webauthn.CredentialRecord, error) {
+		return nil, nil
+	}
+
+	testCases := []struct {
+		name         string
+		userHandle   []byte
+		addChallenge bool
+		lookup       webauthn.CredentialLookupFunc
+		wantErrorMsg string
+	}{
+		{
+			name:         "discoverable credential",
+			userHandle:   authenticator.userHandle,
+			addChallenge: true,
+			lookup: func(credentialID []byte, userHandle []byte) (*webauthn.CredentialRecord, error) {
+				if bytes.Equal(credentialID, record.ID) && bytes.Equal(userHandle, record.UserID) {
+					return record, nil
+				}
+				return nil, nil
+			},
+		},
+		{
+			name:         "missing user handle",
+			userHandle:   nil,
+			addChallenge: true,
+			lookup:       noCredential,
+			wantErrorMsg: "assertion: failed to verify user handle: user handle is required to identify the user",
+		},
+		{
+			name:         "unknown challenge",
+			userHandle:   authenticator.userHandle,
+			addChallenge: false,
+			lookup:       noCredential,
+			wantErrorMsg: "assertion: failed to verify client data challenge: challenge is unknown, expired, or already used",
+		},
+		{
+			name:         "unregistered credential",
+			userHandle:   authenticator.userHandle,
+			addChallenge: true,
+			lookup:       noCredential,
+			wantErrorMsg: "assertion: failed to verify credential ID: credential is not registered",
+		},
+		{
+			name:         "lookup error",
+			userHandle:   authenticator.userHandle,
+			addChallenge: true,
+			lookup: func(credentialID []byte, userHandle []byte) (*webauthn.CredentialRecord, error) {
+				return nil, lookupErr
+			},
+			wantErrorMsg: lookupErr.Error(),
+		},
+		{
+			name:         "credential owned by other user",
+			userHandle:   []byte{5, 6, 7, 8},
+			addChallenge: true,
+			lookup: func(credentialID []byte, userHandle []byte) (*webauthn.CredentialRecord, error) {
+				return record, nil
+			},
+			wantErrorMsg: "assertion: failed to verify user handle",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			challenges := &webauthn.MemoryChallengeStore{}
+			challenge := []byte("conditional mediation challenge")
+			if tc.addChallenge {
+				if err := challenges.Add(challenge, time.Now().Add(time.Minute)); err != nil {
+					t.Fatalf("Add() returns error %q", err)
+				}
+			}
+
+			a := *authenticator
+			a.userHandle = tc.userHandle
+			clientData := []byte(`{"type":"webauthn.get","challenge":"` + base64.RawURLEncoding.EncodeToString(challenge) + `","origin":"https://acme.com"}`)
+			authnData := a.authenticatorData("acme.com", 0x05, 2, nil)
+			credentialAssertion, err := webauthn.ParseAssertion(bytes.NewReader(a.assertion(authnData, clientData, nil)))
+			if err != nil {
+				t.Fatalf("ParseAssertion() returns error %q", err)
+			}
+			expected := &webauthn.ConditionalAssertionExpectedData{
+				Origin:           "https://acme.com",
+				RPID:             "acme.com",
+				UserVerification: webauthn.UserVerificationRequired,
+				Challenges:       challenges,
+				LookupCredential: tc.lookup,
+			}
+			result, err := webauthn.VerifyConditionalAuthentication(credentialAssertion, expected)
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyConditionalAuthentication() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyConditionalAuthentication() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyConditionalAuthentication() returns error %q", err)
+			}
+			if result.CredentialRecord != record {
+				t.Errorf("credential record %v, want %v", result.CredentialRecord, record)
+			}
+
+			// Verify that the challenge can't be used again.
+			wantErrorMsg := "assertion: failed to verify client data challenge: challenge is unknown, expired, or already used"
+			if _, err = webauthn.VerifyConditionalAuthentication(credentialAssertion, expected); err == nil {
+				t.Errorf("VerifyConditionalAuthentication() with used challenge returns no error, want error containing substring %q", wantErrorMsg)
+			} else if !strings.Contains(err.Error(), wantErrorMsg) {
+				t.Errorf("VerifyConditionalAuthentication() with used challenge returns error %q, want error containing substring %q", err, wantErrorMsg)
+			}
+		})
+	}
+}
+
+func TestVerifyConditionalAuthenticationMissingExpectedData(t *testing.T) {
+	authenticator := newTestAuthenticator()
+	authenticator.userHandle = []byte{1, 2, 3, 4}
+	clientData := []byte(`{"type":"webauthn.get","challenge":"Y29uZGl0aW9uYWwgbWVkaWF0aW9uIGNoYWxsZW5nZQ","origin":"https://acme.com"}`)
+	authnData := authenticator.authenticatorData("acme.com", 0x05, 2, nil)
+	credentialAssertion, err := webauthn.ParseAssertion(bytes.NewReader(authenticator.assertion(authnData, clientData, nil)))
+	if err != nil {
+		t.Fatalf("ParseAssertion() returns error %q", err)
+	}
+	lookup := func(credentialID []byte, userHandle []byte) (*webauthn.CredentialRecord, error) {
+		return nil, nil
+	}
+
+	testCases := []struct {
+		name         string
+		challenges   webauthn.ChallengeStore
+		lookup       webauthn.CredentialLookupFunc
+		wantErrorMsg string
+	}{
+		{"missing challenge store", nil, lookup, "challenge store is required"},
+		{"missing credential lookup", &webauthn.MemoryChallengeStore{}, nil, "credential lookup function is required"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expected := &webauthn.ConditionalAssertionExpectedData{
+				Origin:           "https://acme.com",
+				RPID:             "acme.com",
+				UserVerification: webauthn.UserVerificationRequired,
+				Challenges:       tc.challenges,
+				LookupCredential: tc.lookup,
+			}
+			if _, err := webauthn.VerifyConditionalAuthentication(credentialAssertion, expected); err == nil {
+				t.Errorf("VerifyConditionalAuthentication() returns no error, want error containing substring %q", tc.wantErrorMsg)
+			} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+				t.Errorf("VerifyConditionalAuthentication() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+			}
+		})
+	}
+	if _, err := webauthn.NewConditionalAssertionOptions(getTestConfig(), nil); err == nil || err.Error() != "challenge store is required" {
+		t.Errorf("NewConditionalAssertionOptions() returns error %v, want %q", err, "challenge store is required")
+	}
+}
+
+func TestNewConditionalAttestationOptions(t *testing.T) {
+	user := &webauthn.User{ID: []byte{1, 2, 3}, Name: "Jane Doe", DisplayName: "Jane"}
+	options, err := webauthn.NewConditionalAttestationOptions(getTestConfig(), user)
+	if err != nil {
+		t.Fatalf("NewConditionalAttestationOptions() returns error %q", err)
+	}
+	if options.Mediation != webauthn.MediationConditional {
+		t.Errorf("mediation %q, want %q", options.Mediation, webauthn.MediationConditional)
+	}
+	if !bytes.Equal(options.PublicKey.User.ID, user.ID) {
+		t.Errorf("user id %v, want %v", options.PublicKey.User.ID, user.ID)
+	}
+}
+
+func TestVerifyRegistrationConditionalCreate(t *testing.T) {
+	// register mock attestation statement
+	webauthn.RegisterAttestationFormat("mock", parseMockAttestation)
+	defer webauthn.UnregisterAttestationFormat("mock")
+
+	authenticator := newTestAuthenticator()
+	challenge := "33EHav-jZ1v9qwH783aU-j0ARx6r5o-YHh-wd7C6jPbd7Wh6ytbIZosIIACehwf9"
+	clientData := []byte(`{"type":"webauthn.create","challenge":"` + challenge + `","origin":"https://acme.com"}`)
+	userID := []byte{1, 2, 3}
+
+	testCases := []struct {
+		name            string
+		flags           byte
+		mediation       webauthn.CredentialMediationRequirement
+		wantAutoCreated bool
+		wantErrorMsg    string
+	}{
+		{"conditional create without user presence", 0x00, webauthn.MediationConditional, true, ""},
+		{"conditional create with user presence", 0x01, webauthn.MediationConditional, true, ""},
+		{"registration with user presence", 0x01, "", false, ""},
+		{"registration without user presence", 0x00, "", false, "attestation: failed to verify user present: user wasn't present"},
+		{"required mediation without user presence", 0x00, webauthn.MediationRequired, false, "attestation: failed to verify user present: user wasn't present"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authnData := authenticator.attestedAuthenticatorData("acme.com", tc.flags, nil)
+			credentialAttestation, err := webauthn.ParseAttestation(bytes.NewReader(authenticator.attestation(authnData, clientData, nil)))
+			if err != nil {
+				t.Fatalf("ParseAttestation() returns error %q", err)
+			}
+			expected := &webauthn.AttestationExpectedData{
+				Origin:           "https://acme.com",
+				RPID:             "acme.com",
+				CredentialAlgs:   []int{webauthn.COSEAlgES256},
+				Challenge:        challenge,
+				UserVerification: webauthn.UserVerificationPreferred,
+				Mediation:        tc.mediation,
+				UserID:           userID,
+			}
+			result, err := webauthn.VerifyRegistration(credentialAttestation, expected)
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyRegistration() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyRegistration() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyRegistration() returns error %q", err)
+			}
+			if result.AutoCreated != tc.wantAutoCreated {
+				t.Errorf("auto created %t, want %t", result.AutoCreated, tc.wantAutoCreated)
+			}
+			record := result.CredentialRecord
+			if record.AutoCreated != tc.wantAutoCreated {
+				t.Errorf("credential record auto created %t, want %t", record.AutoCreated, tc.wantAutoCreated)
+			}
+			if !bytes.Equal(record.ID, authenticator.credentialID) {
+				t.Errorf("credential record id %v, want %v", record.ID, authenticator.credentialID)
+			}
+			if !bytes.Equal(record.UserID, userID) {
+				t.Errorf("credential record user id %v, want %v", record.UserID, userID)
+			}
+			if !bytes.Equal(record.Credential.Raw, authenticator.coseKey()) {
+				t.Errorf("credential record public key %02x, want %02x", record.Credential.Raw, authenticator.coseKey())
+			}
+		})
+	}
+}
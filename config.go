@@ -34,7 +34,15 @@ type Config struct {
 	ResidentKey             ResidentKeyRequirement
 	UserVerification        UserVerificationRequirement
 	Attestation             AttestationConveyancePreference
+	AttestationFormats      []string // Attestation statement formats preferred by the Relying Party, most preferred first (optional).
 	CredentialAlgs          []int
+	AppID                   string                                         // FIDO AppID of credentials registered with the legacy FIDO U2F JavaScript API (optional).
+	MinPinLength            int                                            // Minimum PIN length required by policy, requested with the minPinLength extension (optional).
+	GetCredBlob             bool                                           // Request the credBlob stored with the credential during authentication (optional).
+	UVM                     bool                                           // Request user verification methods used by the authenticator (optional).
+	DevicePubKey            *AuthenticationExtensionsDevicePublicKeyInputs // Request a device-bound key during registration and authentication (optional).
+	Payment                 bool                                           // Register credentials for use with Secure Payment Confirmation (optional).
+	ConditionalTimeout      uint64                                         // Time in milliseconds that conditional mediation options and challenges stay valid (optional, defaults to 10 minutes).
 }
 
 const (
@@ -82,6 +90,11 @@ func (c *Config) Valid() error {
 		c.Attestation != AttestationDirect {
 		return errors.New("attestation must be \"none\", \"indirect\", or \"direct\"")
 	}
+	for _, format := range c.AttestationFormats {
+		if !attestationFormatRegistered(format) {
+			return errors.New("attestation statement format " + format + " is not registered")
+		}
+	}
 	if len(c.CredentialAlgs) == 0 {
 		return errors.New("there must be at least one credential algorithm")
 	}
@@ -90,6 +103,28 @@ func (c *Config) Valid() error {
 			return errors.New("credential algorithm " + strconv.Itoa(alg) + " is not registered")
 		}
 	}
+	if c.ConditionalTimeout != 0 && c.ConditionalTimeout < c.Timeout {
+		return errors.New("conditional timeout must not be less than timeout")
+	}
+	if c.MinPinLength < 0 {
+		return errors.New("minimum PIN length must not be a negative number")
+	}
+	if c.AppID != "" {
+		u, err := url.Parse(c.AppID)
+		if err != nil {
+			return errors.New("app id " + c.AppID + " is not a valid URL: " + err.Error())
+		}
+		if u.Scheme != "https" || u.Host == "" {
+			return errors.New("app id " + c.AppID + " must be an https URL")
+		}
+	}
+	if c.DevicePubKey != nil &&
+		c.DevicePubKey.Attestation != "" &&
+		c.DevicePubKey.Attestation != AttestationNone &&
+		c.DevicePubKey.Attestation != AttestationIndirect &&
+		c.DevicePubKey.Attestation != AttestationDirect {
+		return errors.New("device public key attestation must be \"\", \"none\", \"indirect\", or \"direct\"")
+	}
 
 	return nil
 }
@@ -48,6 +48,20 @@ var configTests = []configTest{
 			CredentialAlgs:          []int{COSEAlgES256, COSEAlgPS256, COSEAlgRS256},
 		},
 	},
+	{
+		name: "config with app id",
+		cfg: &Config{
+			RPID:             "acme.com",
+			RPName:           "ACME Corporation",
+			Timeout:          uint64(30000),
+			ChallengeLength:  64,
+			ResidentKey:      ResidentKeyDiscouraged,
+			UserVerification: UserVerificationPreferred,
+			Attestation:      AttestationNone,
+			CredentialAlgs:   []int{COSEAlgES256},
+			AppID:            "https://acme.com/app-id.json",
+		},
+	},
 }
 
 var configErrorTests = []configErrorTest{
@@ -179,6 +193,57 @@ var configErrorTests = []configErrorTest{
 		},
 		wantErrorMsg: "credential algorithm -1 is not registered",
 	},
+	{
+		name: "invalid app id",
+		cfg: &Config{
+			RPID:                    "acme.com",
+			RPName:                  "ACME Corporation",
+			RPIcon:                  "https://acme.com/avatar.png",
+			Timeout:                 uint64(30000),
+			ChallengeLength:         64,
+			AuthenticatorAttachment: AuthenticatorPlatform,
+			ResidentKey:             ResidentKeyPreferred,
+			UserVerification:        UserVerificationPreferred,
+			Attestation:             AttestationNone,
+			CredentialAlgs:          []int{COSEAlgES256},
+			AppID:                   "http://acme.com/app-id.json",
+		},
+		wantErrorMsg: "app id http://acme.com/app-id.json must be an https URL",
+	},
+	{
+		name: "conditional timeout less than timeout",
+		cfg: &Config{
+			RPID:                    "acme.com",
+			RPName:                  "ACME Corporation",
+			RPIcon:                  "https://acme.com/avatar.png",
+			Timeout:                 uint64(30000),
+			ChallengeLength:         64,
+			AuthenticatorAttachment: AuthenticatorPlatform,
+			ResidentKey:             ResidentKeyPreferred,
+			UserVerification:        UserVerificationPreferred,
+			Attestation:             AttestationNone,
+			CredentialAlgs:          []int{COSEAlgES256},
+			ConditionalTimeout:      uint64(10000),
+		},
+		wantErrorMsg: "conditional timeout must not be less than timeout",
+	},
+	{
+		name: "attestation format not registered",
+		cfg: &Config{
+			RPID:                    "acme.com",
+			RPName:                  "ACME Corporation",
+			RPIcon:                  "https://acme.com/avatar.png",
+			Timeout:                 uint64(30000),
+			ChallengeLength:         64,
+			AuthenticatorAttachment: AuthenticatorPlatform,
+			ResidentKey:             ResidentKeyPreferred,
+			UserVerification:        UserVerificationPreferred,
+			Attestation:             AttestationDirect,
+			AttestationFormats:      []string{"none", "unknown"},
+			CredentialAlgs:          []int{COSEAlgES256},
+		},
+		wantErrorMsg: "attestation statement format unknown is not registered",
+	},
 }
 
 func TestConfig(t *testing.T) {
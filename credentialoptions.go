@@ -123,6 +123,18 @@ type AuthenticatorSelectionCriteria struct {
 	UserVerification        UserVerificationRequirement `json:"userVerification,omitempty"`        // Authentication factor capability, defaulting to "preferred".
 }
 
+// CredentialMediationRequirement represents the Credential Management enumeration of the same name,
+// as defined in https://w3c.github.io/webappsec-credential-management/#enumdef-credentialmediationrequirement
+type CredentialMediationRequirement string
+
+// CredentialMediationRequirement enumeration.
+const (
+	MediationSilent      CredentialMediationRequirement = "silent"
+	MediationOptional    CredentialMediationRequirement = "optional"
+	MediationConditional CredentialMediationRequirement = "conditional"
+	MediationRequired    CredentialMediationRequirement = "required"
+)
+
 // PublicKeyCredentialType represents the Web Authentication enumeration of the same name,
 // as defined in http://w3c.github.io/webauthn/#enum-credentialType
 type PublicKeyCredentialType string
@@ -175,25 +187,40 @@ const (
 
 // PublicKeyCredentialCreationOptions represents the Web Authentication structure of the same name,
 // as defined in http://w3c.github.io/webauthn/#dictionary-makecredentialoptions
-// Extensions are not supported.
 type PublicKeyCredentialCreationOptions struct {
-	RP                     PublicKeyCredentialRpEntity     `json:"rp"`                               // Relying Party data responsible for the request.
-	User                   PublicKeyCredentialUserEntity   `json:"user"`                             // User data for which the Relying Party is requesting attestation.
-	Challenge              bufferString                    `json:"challenge"`                        // Challenge for generating new credential's attestation object.
-	PubKeyCredParams       []PublicKeyCredentialParameters `json:"pubKeyCredParams"`                 // Desired properties of the credential to be created.  The sequence is ordered from most preferred to least preferred.
-	Timeout                uint64                          `json:"timeout,omitempty"`                // Time in milliseconds for client to wait for the call to complete.  Client can override this value.
-	ExcludeCredentials     []PublicKeyCredentialDescriptor `json:"excludeCredentials,omitempty"`     // Used by Relying Parties to limit the creation of multiple credentials for the same account on a single authenticator.
-	AuthenticatorSelection AuthenticatorSelectionCriteria  `json:"authenticatorSelection,omitempty"` // Used by Relying Parties to select appropriate authenticators.
-	Attestation            AttestationConveyancePreference `json:"attestation,omitempty"`            // Used by Relying Parties to specify preference for attestation conveyance.
+	RP                     PublicKeyCredentialRpEntity           `json:"rp"`                               // Relying Party data responsible for the request.
+	User                   PublicKeyCredentialUserEntity         `json:"user"`                             // User data for which the Relying Party is requesting attestation.
+	Challenge              bufferString                          `json:"challenge"`                        // Challenge for generating new credential's attestation object.
+	PubKeyCredParams       []PublicKeyCredentialParameters       `json:"pubKeyCredParams"`                 // Desired properties of the credential to be created.  The sequence is ordered from most preferred to least preferred.
+	Timeout                uint64                                `json:"timeout,omitempty"`                // Time in milliseconds for client to wait for the call to complete.  Client can override this value.
+	ExcludeCredentials     []PublicKeyCredentialDescriptor       `json:"excludeCredentials,omitempty"`     // Used by Relying Parties to limit the creation of multiple credentials for the same account on a single authenticator.
+	AuthenticatorSelection AuthenticatorSelectionCriteria        `json:"authenticatorSelection,omitempty"` // Used by Relying Parties to select appropriate authenticators.
+	Attestation            AttestationConveyancePreference       `json:"attestation,omitempty"`            // Used by Relying Parties to specify preference for attestation conveyance.
+	AttestationFormats     []string                              `json:"attestationFormats,omitempty"`     // Attestation statement format identifiers preferred by Relying Parties.  The sequence is ordered from most preferred to least preferred.
+	Extensions             *AuthenticationExtensionsClientInputs `json:"extensions,omitempty"`             // Additional parameters requesting additional processing by the client and authenticator.
 }
 
 // PublicKeyCredentialRequestOptions represents the Web Authentication structure of the same name,
 // as defined in http://w3c.github.io/webauthn/#dictionary-assertion-options
-// Extensions are not supported.
 type PublicKeyCredentialRequestOptions struct {
-	Challenge        bufferString                    `json:"challenge"`                  // Challenge that the selected authenticator signs, along with other data, when producing an authentication assertion.
-	Timeout          uint64                          `json:"timeout,omitempty"`          // Time in milliseconds for client to wait for the call to complete.  Client can override this value.
-	RPID             string                          `json:"rpId,omitempty"`             // Relying Party identifier.
-	AllowCredentials []PublicKeyCredentialDescriptor `json:"allowCredentials,omitempty"` // A list of public key credentials acceptable to the caller.  The sequence is ordered from most preferred to least preferred.
-	UserVerification UserVerificationRequirement     `json:"userVerification,omitempty"` // Relying Party's requirements for user verification.
+	Challenge        bufferString                          `json:"challenge"`                  // Challenge that the selected authenticator signs, along with other data, when producing an authentication assertion.
+	Timeout          uint64                                `json:"timeout,omitempty"`          // Time in milliseconds for client to wait for the call to complete.  Client can override this value.
+	RPID             string                                `json:"rpId,omitempty"`             // Relying Party identifier.
+	AllowCredentials []PublicKeyCredentialDescriptor       `json:"allowCredentials,omitempty"` // A list of public key credentials acceptable to the caller.  The sequence is ordered from most preferred to least preferred.
+	UserVerification UserVerificationRequirement           `json:"userVerification,omitempty"` // Relying Party's requirements for user verification.
+	Extensions       *AuthenticationExtensionsClientInputs `json:"extensions,omitempty"`       // Additional parameters requesting additional processing by the client and authenticator.
+}
+
+// CredentialCreationOptions represents the Credential Management structure of the same name,
+// as defined in https://w3c.github.io/webappsec-credential-management/#dictdef-credentialcreationoptions
+type CredentialCreationOptions struct {
+	Mediation CredentialMediationRequirement      `json:"mediation,omitempty"` // Mediation requirement of the request, such as "conditional" for conditional create.
+	PublicKey *PublicKeyCredentialCreationOptions `json:"publicKey"`           // Options for creating a public key credential.
+}
+
+// CredentialRequestOptions represents the Credential Management structure of the same name,
+// as defined in https://w3c.github.io/webappsec-credential-management/#dictdef-credentialrequestoptions
+type CredentialRequestOptions struct {
+	Mediation CredentialMediationRequirement     `json:"mediation,omitempty"` // Mediation requirement of the request, such as "conditional" for autofill UI.
+	PublicKey *PublicKeyCredentialRequestOptions `json:"publicKey"`           // Options for requesting a public key credential.
 }
@@ -53,7 +53,8 @@ func TestPublicKeyCredentialCreationOptionsJSONMarshal(t *testing.T) {
 			RequireResidentKey:      true,
 			UserVerification:        UserVerificationRequired,
 		},
-		Attestation: AttestationDirect,
+		Attestation:        AttestationDirect,
+		AttestationFormats: []string{"packed", "tpm"},
 	}
 	b, err := json.Marshal(options)
 	if err != nil {
@@ -0,0 +1,165 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const extensionDevicePubKey = "devicePubKey"
+
+// AuthenticationExtensionsDevicePublicKeyInputs represents the Web Authentication structure of the same name,
+// as defined in https://w3c.github.io/webauthn/#sctn-device-publickey-extension
+type AuthenticationExtensionsDevicePublicKeyInputs struct {
+	Attestation        AttestationConveyancePreference `json:"attestation,omitempty"`        // Preference for attestation of the device public key.
+	AttestationFormats []string                        `json:"attestationFormats,omitempty"` // Preferred attestation statement formats, from most preferred to least preferred.
+}
+
+// AuthenticationExtensionsDevicePublicKeyOutputs represents the Web Authentication structure of the same name,
+// as defined in https://w3c.github.io/webauthn/#sctn-device-publickey-extension
+type AuthenticationExtensionsDevicePublicKeyOutputs struct {
+	AuthenticatorOutput bufferString `json:"authenticatorOutput"` // CBOR encoded devicePubKey authenticator extension output.
+	Signature           bufferString `json:"signature"`           // Signature over authenticator data and client data hash by the device private key.
+}
+
+// DevicePublicKey represents a device-bound key returned by the devicePubKey extension (also known as
+// supplemental public keys), as defined in https://w3c.github.io/webauthn/#sctn-device-publickey-extension
+type DevicePublicKey struct {
+	AAGUID          []byte               // AAGUID of the authenticator holding the device key.
+	Credential      *Credential          // Algorithm and public key of the device key.
+	Scope           uint                 // Scope of the device key (0 for entire authenticator).
+	Nonce           []byte               // Nonce chosen by the authenticator for device key attestation.
+	Fmt             string               // Attestation statement format of the device key.
+	AttStmt         AttestationStatement // Attestation statement of the device key.
+	AttestationType AttestationType      // Verified attestation type of the device key.
+	TrustPath       interface{}          // Verified attestation trust path of the device key.
+}
+
+// Equal returns if device public key has the same AAGUID and public key as the given device public key.
+// Device public keys without public key are never equal.
+func (dpk *DevicePublicKey) Equal(other *DevicePublicKey) bool {
+	if dpk == nil || other == nil || dpk.Credential == nil || other.Credential == nil {
+		return false
+	}
+	return bytes.Equal(dpk.AAGUID, other.AAGUID) && bytes.Equal(dpk.Credential.Raw, other.Credential.Raw)
+}
+
+// NewDevicePublicKey returns a DevicePublicKey from AAGUID and COSE_Key encoded public key of a
+// previously seen device key, so it can be compared with device keys in later assertions.
+func NewDevicePublicKey(aaguid []byte, coseKeyData []byte) (*DevicePublicKey, error) {
+	c, rest, err := ParseCredential(coseKeyData)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, &UnmarshalBadDataError{Type: "device public key", Msg: "trailing data after device public key"}
+	}
+	return &DevicePublicKey{AAGUID: aaguid, Credential: c}, nil
+}
+
+func parseDevicePublicKey(data []byte) (*DevicePublicKey, error) {
+	type rawAttObjForDevicePublicKey struct {
+		AAGUID  []byte          `cbor:"aaguid"`
+		DPK     []byte          `cbor:"dpk"`
+		Scope   uint            `cbor:"scope"`
+		Nonce   []byte          `cbor:"nonce"`
+		Fmt     string          `cbor:"fmt"`
+		AttStmt cbor.RawMessage `cbor:"attStmt"`
+	}
+	var raw rawAttObjForDevicePublicKey
+	if err := cbor.Unmarshal(data, &raw); err != nil {
+		return nil, &UnmarshalSyntaxError{Type: "device public key", Msg: err.Error()}
+	}
+	if len(raw.AAGUID) != 16 {
+		return nil, &UnmarshalBadDataError{Type: "device public key", Msg: "aaguid must be 16 bytes"}
+	}
+	if len(raw.DPK) == 0 {
+		return nil, &UnmarshalMissingFieldError{Type: "device public key", Field: "dpk"}
+	}
+	if len(raw.Fmt) == 0 {
+		return nil, &UnmarshalMissingFieldError{Type: "device public key", Field: "fmt"}
+	}
+	dpk, err := NewDevicePublicKey(raw.AAGUID, raw.DPK)
+	if err != nil {
+		return nil, err
+	}
+	dpk.Scope = raw.Scope
+	dpk.Nonce = raw.Nonce
+	dpk.Fmt = raw.Fmt
+	if dpk.AttStmt, err = parseAttestationStatement(raw.Fmt, raw.AttStmt); err != nil {
+		return nil, err
+	}
+	return dpk, nil
+}
+
+// verifyDevicePublicKey verifies devicePubKey extension outputs and returns the device public key,
+// or nil if the extension output is absent.
+func verifyDevicePublicKey(typ string, authnData *AuthenticatorData, clientData *CollectedClientData, clientOutputs *AuthenticationExtensionsDevicePublicKeyOutputs) (*DevicePublicKey, error) {
+	authenticatorOutput, ok := authnData.rawExtensions[extensionDevicePubKey]
+	if !ok && clientOutputs == nil {
+		return nil, nil
+	}
+	if !ok {
+		return nil, &VerificationError{Type: typ, Field: "devicePubKey extension", Msg: "authenticator extension output is missing"}
+	}
+	if clientOutputs == nil {
+		return nil, &VerificationError{Type: typ, Field: "devicePubKey extension", Msg: "client extension output is missing"}
+	}
+
+	// Verify that authenticatorOutput of client extension output is the authenticator extension output
+	// in authenticator data.
+	if !bytes.Equal(clientOutputs.AuthenticatorOutput, authenticatorOutput) {
+		return nil, &VerificationError{Type: typ, Field: "devicePubKey extension", Msg: "client extension output does not match authenticator extension output"}
+	}
+
+	dpk, err := parseDevicePublicKey(authenticatorOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify that signature is a valid signature over the concatenation of authenticator data and
+	// client data hash using the device public key.
+	clientDataHash := sha256.Sum256(clientData.Raw)
+	signed := make([]byte, len(authnData.Raw)+len(clientDataHash))
+	copy(signed, authnData.Raw)
+	copy(signed[len(authnData.Raw):], clientDataHash[:])
+	if err = dpk.Credential.Verify(signed, clientOutputs.Signature); err != nil {
+		return nil, &VerificationError{Type: typ, Field: "devicePubKey signature", Msg: err.Error()}
+	}
+
+	// Verify the device public key attestation statement using its attestation statement format
+	// verification procedure, with the concatenation of aaguid, dpk, and nonce as authenticator data.
+	var attAuthnDataBuf bytes.Buffer
+	attAuthnDataBuf.Write(dpk.AAGUID)
+	attAuthnDataBuf.Write(dpk.Credential.Raw)
+	attAuthnDataBuf.Write(dpk.Nonce)
+	attAuthnData := &AuthenticatorData{
+		Raw:        attAuthnDataBuf.Bytes(),
+		RPIDHash:   authnData.RPIDHash,
+		AAGUID:     dpk.AAGUID,
+		Credential: dpk.Credential,
+	}
+	if dpk.AttestationType, dpk.TrustPath, err = dpk.AttStmt.Verify(clientDataHash[:], attAuthnData); err != nil {
+		return nil, err
+	}
+	return dpk, nil
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/kappapay/webauthn"
+)
+
+var devicePubKeyAAGUID = []byte{0xad, 0xce, 0x00, 0x02, 0x35, 0xbc, 0xc6, 0x0a, 0x64, 0x8b, 0x0b, 0x25, 0xf1, 0xf0, 0x55, 0x03}
+
+// devicePubKeyTest generates devicePubKey extension outputs for a device key.
+type devicePubKeyTest struct {
+	device *testAuthenticator
+	aaguid []byte
+}
+
+// attObj returns CBOR encoded devicePubKey authenticator extension output with none attestation.
+func (d *devicePubKeyTest) attObj() []byte {
+	return cborMarshal(map[string]interface{}{
+		"aaguid":  d.aaguid,
+		"dpk":     d.device.coseKey(),
+		"scope":   0,
+		"nonce":   []byte{},
+		"fmt":     "none",
+		"attStmt": map[string]interface{}{},
+	})
+}
+
+// extensions returns CBOR encoded authenticator extension outputs containing attObj.
+func (d *devicePubKeyTest) extensions(attObj []byte) []byte {
+	return cborMarshal(map[string]interface{}{"devicePubKey": cbor.RawMessage(attObj)})
+}
+
+// clientExtensionResults returns devicePubKey client extension outputs with authenticatorOutput and
+// a device signature over authnData and clientData.
+func (d *devicePubKeyTest) clientExtensionResults(authenticatorOutput []byte, authnData []byte, clientData []byte) map[string]interface{} {
+	return map[string]interface{}{
+		"devicePubKey": map[string]string{
+			"authenticatorOutput": base64.RawURLEncoding.EncodeToString(authenticatorOutput),
+			"signature":           base64.RawURLEncoding.EncodeToString(d.device.sign(authnData, clientData)),
+		},
+	}
+}
+
+func TestVerifyRegistrationDevicePubKey(t *testing.T) {
+	// register mock attestation statement
+	webauthn.RegisterAttestationFormat("mock", parseMockAttestation)
+	defer webauthn.UnregisterAttestationFormat("mock")
+
+	authenticator := newTestAuthenticator()
+	dpk := &devicePubKeyTest{device: newTestAuthenticator(), aaguid: devicePubKeyAAGUID}
+	otherDevice := &devicePubKeyTest{device: newTestAuthenticator(), aaguid: devicePubKeyAAGUID}
+	challenge := "33EHav-jZ1v9qwH783aU-j0ARx6r5o-YHh-wd7C6jPbd7Wh6ytbIZosIIACehwf9"
+	clientData := []byte(`{"type":"webauthn.create","challenge":"` + challenge + `","origin":"https://acme.com"}`)
+	attObj := dpk.attObj()
+
+	testCases := []struct {
+		name         string
+		extensions   []byte
+		clientOutput func(authnData []byte) map[string]interface{}
+		wantErrorMsg string
+	}{
+		{
+			"device public key",
+			dpk.extensions(attObj),
+			func(authnData []byte) map[string]interface{} {
+				return dpk.clientExtensionResults(attObj, authnData, clientData)
+			},
+			"",
+		},
+		{
+			"device signature by other device",
+			dpk.extensions(attObj),
+			func(authnData []byte) map[string]interface{} {
+				return otherDevice.clientExtensionResults(attObj, authnData, clientData)
+			},
+			"attestation: failed to verify devicePubKey signature",
+		},
+		{
+			"client extension output doesn't match authenticator extension output",
+			dpk.extensions(attObj),
+			func(authnData []byte) map[string]interface{} {
+				return dpk.clientExtensionResults(otherDevice.attObj(), authnData, clientData)
+			},
+			"attestation: failed to verify devicePubKey extension: client extension output does not match authenticator extension output",
+		},
+		{
+			"missing client extension output",
+			dpk.extensions(attObj),
+			func(authnData []byte) map[string]interface{} { return nil },
+			"attestation: failed to verify devicePubKey extension: client extension output is missing",
+		},
+		{
+			"missing authenticator extension output",
+			nil,
+			func(authnData []byte) map[string]interface{} {
+				return dpk.clientExtensionResults(attObj, authnData, clientData)
+			},
+			"attestation: failed to verify devicePubKey extension: authenticator extension output is missing",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authnData := authenticator.attestedAuthenticatorData("acme.com", 0x01, tc.extensions)
+			var clientExtensionResults interface{}
+			if output := tc.clientOutput(authnData); output != nil {
+				clientExtensionResults = output
+			}
+			credentialAttestation, err := webauthn.ParseAttestation(bytes.NewReader(authenticator.attestation(authnData, clientData, clientExtensionResults)))
+			if err != nil {
+				t.Fatalf("ParseAttestation() returns error %q", err)
+			}
+			expected := &webauthn.AttestationExpectedData{
+				Origin:           "https://acme.com",
+				RPID:             "acme.com",
+				CredentialAlgs:   []int{webauthn.COSEAlgES256},
+				Challenge:        challenge,
+				UserVerification: webauthn.UserVerificationPreferred,
+			}
+			result, err := webauthn.VerifyRegistration(credentialAttestation, expected)
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyRegistration() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyRegistration() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyRegistration() returns error %q", err)
+			}
+			if result.AttestationType != webauthn.AttestationTypeBasic {
+				t.Errorf("attestation type %v, want %v", result.AttestationType, webauthn.AttestationTypeBasic)
+			}
+			if result.DevicePublicKey == nil {
+				t.Fatalf("device public key is nil")
+			}
+			if !bytes.Equal(result.DevicePublicKey.AAGUID, devicePubKeyAAGUID) {
+				t.Errorf("device public key aaguid %02x, want %02x", result.DevicePublicKey.AAGUID, devicePubKeyAAGUID)
+			}
+			if !bytes.Equal(result.DevicePublicKey.Credential.Raw, dpk.device.coseKey()) {
+				t.Errorf("device public key %02x, want %02x", result.DevicePublicKey.Credential.Raw, dpk.device.coseKey())
+			}
+			if result.DevicePublicKey.AttestationType != webauthn.AttestationTypeNone {
+				t.Errorf("device public key attestation type %v, want %v", result.DevicePublicKey.AttestationType, webauthn.AttestationTypeNone)
+			}
+		})
+	}
+}
+
+func TestVerifyAuthenticationDevicePubKey(t *testing.T) {
+	authenticator := newTestAuthenticator()
+	dpk := &devicePubKeyTest{device: newTestAuthenticator(), aaguid: devicePubKeyAAGUID}
+	challenge := "eaTyUNnyPDDdK8SNEgTEUvz1Q8dylkjjTimYd5X7QAo"
+	clientData := []byte(`{"type":"webauthn.get","challenge":"` + challenge + `","origin":"https://acme.com"}`)
+
+	knownDevicePublicKey, err := webauthn.NewDevicePublicKey(devicePubKeyAAGUID, dpk.device.coseKey())
+	if err != nil {
+		t.Fatalf("NewDevicePublicKey() returns error %q", err)
+	}
+	otherDevicePublicKey, err := webauthn.NewDevicePublicKey(devicePubKeyAAGUID, newTestAuthenticator().coseKey())
+	if err != nil {
+		t.Fatalf("NewDevicePublicKey() returns error %q", err)
+	}
+
+	testCases := []struct {
+		name                   string
+		devicePublicKeys       []*webauthn.DevicePublicKey
+		wantNewDevicePublicKey bool
+	}{
+		{"no device public keys seen", nil, true},
+		{"device public key seen", []*webauthn.DevicePublicKey{otherDevicePublicKey, knownDevicePublicKey}, false},
+		{"device public key not seen", []*webauthn.DevicePublicKey{otherDevicePublicKey}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attObj := dpk.attObj()
+			authnData := authenticator.authenticatorData("acme.com", 0x01, 1, dpk.extensions(attObj))
+			clientExtensionResults := dpk.clientExtensionResults(attObj, authnData, clientData)
+			credentialAssertion, err := webauthn.ParseAssertion(bytes.NewReader(authenticator.assertion(authnData, clientData, clientExtensionResults)))
+			if err != nil {
+				t.Fatalf("ParseAssertion() returns error %q", err)
+			}
+			expected := &webauthn.AssertionExpectedData{
+				Origin:           "https://acme.com",
+				RPID:             "acme.com",
+				Challenge:        challenge,
+				UserVerification: webauthn.UserVerificationPreferred,
+				Credential:       authenticator.credential(),
+				DevicePublicKeys: tc.devicePublicKeys,
+			}
+			result, err := webauthn.VerifyAuthentication(credentialAssertion, expected)
+			if err != nil {
+				t.Fatalf("VerifyAuthentication() returns error %q", err)
+			}
+			if result.DevicePublicKey == nil {
+				t.Fatalf("device public key is nil")
+			}
+			if !result.DevicePublicKey.Equal(knownDevicePublicKey) {
+				t.Errorf("device public key %02x, want %02x", result.DevicePublicKey.Credential.Raw, knownDevicePublicKey.Credential.Raw)
+			}
+			if result.NewDevicePublicKey != tc.wantNewDevicePublicKey {
+				t.Errorf("new device public key %t, want %t", result.NewDevicePublicKey, tc.wantNewDevicePublicKey)
+			}
+		})
+	}
+}
+
+func TestDevicePublicKeyEqual(t *testing.T) {
+	device := newTestAuthenticator()
+	dpk, err := webauthn.NewDevicePublicKey(devicePubKeyAAGUID, device.coseKey())
+	if err != nil {
+		t.Fatalf("NewDevicePublicKey() returns error %q", err)
+	}
+	sameDPK, err := webauthn.NewDevicePublicKey(devicePubKeyAAGUID, device.coseKey())
+	if err != nil {
+		t.Fatalf("NewDevicePublicKey() returns error %q", err)
+	}
+	otherDPK, err := webauthn.NewDevicePublicKey(devicePubKeyAAGUID, newTestAuthenticator().coseKey())
+	if err != nil {
+		t.Fatalf("NewDevicePublicKey() returns error %q", err)
+	}
+
+	testCases := []struct {
+		name  string
+		dpk   *webauthn.DevicePublicKey
+		other *webauthn.DevicePublicKey
+		want  bool
+	}{
+		{"same device key", dpk, sameDPK, true},
+		{"other device key", dpk, otherDPK, false},
+		{"other AAGUID", dpk, &webauthn.DevicePublicKey{AAGUID: make([]byte, 16), Credential: dpk.Credential}, false},
+		{"nil device key", dpk, nil, false},
+		{"nil receiver", nil, dpk, false},
+		{"device key without public key", dpk, &webauthn.DevicePublicKey{AAGUID: devicePubKeyAAGUID}, false},
+		{"receiver without public key", &webauthn.DevicePublicKey{AAGUID: devicePubKeyAAGUID}, dpk, false},
+		{"both without public key", &webauthn.DevicePublicKey{AAGUID: devicePubKeyAAGUID}, &webauthn.DevicePublicKey{AAGUID: devicePubKeyAAGUID}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.dpk.Equal(tc.other); got != tc.want {
+				t.Errorf("Equal() returns %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
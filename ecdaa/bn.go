@@ -0,0 +1,123 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package ecdaa
+
+import (
+	"crypto/sha256"
+	"math/big"
+)
+
+// Parameters of TPM_ECC_BN_P256, the Barreto-Naehrig curve E: y² = x³ + 3 over Fp of 256-bit prime
+// order n, with BN parameter u = -0x6882F5C030B0A801, so that p = 36u⁴ + 36u³ + 24u² + 6u + 1 and
+// n = 36u⁴ + 36u³ + 18u² + 6u + 1.  G2 is the order n subgroup of the sextic twist
+// E': y² = x³ + 3ξ over Fp2, with ξ = 1 + i.
+var (
+	p      = bigFromHex("FFFFFFFFFFFCF0CD46E5F25EEE71A49F0CDC65FB12980A82D3292DDBAED33013")
+	order  = bigFromHex("FFFFFFFFFFFCF0CD46E5F25EEE71A49E0CDC65FB1299921AF62D536CD10B500D")
+	u      = new(big.Int).Neg(bigFromHex("6882F5C030B0A801"))
+	curveB = big.NewInt(3)
+	xi     = fp2{a: big.NewInt(1), b: big.NewInt(1)}
+)
+
+var (
+	twistB       fp2      // Coefficient 3ξ of twist E'.
+	xiInv        fp2      // 1/ξ.
+	frobW        [6]fp2   // ξ^(k(p-1)/6), so that (w^k)^p = w^k·frobW[k].
+	twistFrobX   fp2      // ξ^(-(p-1)/3), x coordinate factor of Frobenius endomorphism on E'.
+	twistFrobY   fp2      // ξ^(-(p-1)/2), y coordinate factor of Frobenius endomorphism on E'.
+	ateLoopCount *big.Int // 6u + 2, Miller loop length of optimal ate pairing.
+	finalExpHard *big.Int // (p⁴ - p² + 1)/n, hard part of final exponentiation.
+
+	// finalExpHardDigits are base p digits of finalExpHard, least significant first.
+	finalExpHardDigits [4]*big.Int
+
+	// g1 is the generator P1 = (1, 2) of G1.
+	g1 = &g1Point{x: big.NewInt(1), y: big.NewInt(2)}
+
+	// g2 is the generator P2 of G2.
+	g2 = &twistPoint{
+		x: fp2{
+			a: bigFromHex("FE0C3350B4C96C2028560F577C28913ACE1C539A12BF843CD22616B689C09EFB"),
+			b: bigFromHex("4EA66057738AC054DB5AE1C637D813B924DD78E287D03589D269ED34A37E6A2B"),
+		},
+		y: fp2{
+			a: bigFromHex("8FDFB9183ABA4D19D06EE4E9DC23664D1D1141858536B239EA1F7959EFF70814"),
+			b: bigFromHex("FAAB1C432C742E3D03F74C15C4F2F1FF818FA77A907D71CEF316ACCA64262B78"),
+		},
+	}
+)
+
+func init() {
+	twistB = xi.mulScalar(curveB)
+	xiInv = xi.inv()
+
+	pMinus1 := new(big.Int).Sub(p, big.NewInt(1))
+	e := new(big.Int).Div(pMinus1, big.NewInt(6))
+	frobW[0] = fp2One()
+	frobW[1] = xi.exp(e)
+	for k := 2; k < len(frobW); k++ {
+		frobW[k] = frobW[k-1].mul(frobW[1])
+	}
+	twistFrobX = frobW[2].inv()
+	twistFrobY = frobW[3].inv()
+
+	ateLoopCount = new(big.Int).Mul(u, big.NewInt(6))
+	ateLoopCount.Add(ateLoopCount, big.NewInt(2))
+
+	p2 := new(big.Int).Mul(p, p)
+	finalExpHard = new(big.Int).Mul(p2, p2)
+	finalExpHard.Sub(finalExpHard, p2)
+	finalExpHard.Add(finalExpHard, big.NewInt(1))
+	finalExpHard.Div(finalExpHard, order)
+	e = new(big.Int).Set(finalExpHard)
+	for i := range finalExpHardDigits {
+		finalExpHardDigits[i] = new(big.Int)
+		e.DivMod(e, p, finalExpHardDigits[i])
+	}
+}
+
+func bigFromHex(s string) *big.Int {
+	k, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("ecdaa: invalid hex number " + s)
+	}
+	return k
+}
+
+// bigNumberToBytes returns big-endian encoding of k in bigNumberSize bytes, as BigNumberToB in
+// FIDO ECDAA algorithm.
+func bigNumberToBytes(k *big.Int) []byte {
+	b := k.Bytes()
+	if len(b) >= bigNumberSize {
+		return b
+	}
+	padded := make([]byte, bigNumberSize)
+	copy(padded[bigNumberSize-len(b):], b)
+	return padded
+}
+
+// hashToZn returns SHA-256 digest of concatenation of data modulo n, as H in FIDO ECDAA algorithm.
+func hashToZn(data ...[]byte) *big.Int {
+	h := sha256.New()
+	for _, d := range data {
+		h.Write(d)
+	}
+	k := new(big.Int).SetBytes(h.Sum(nil))
+	return k.Mod(k, order)
+}
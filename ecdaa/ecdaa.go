@@ -0,0 +1,171 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+/*
+Package ecdaa verifies FIDO ECDAA (Elliptic Curve based Direct Anonymous Attestation) signatures over
+the TPM_ECC_BN_P256 curve with SHA-256, as specified in
+https://fidoalliance.org/specs/fido-v2.0-id-20180227/fido-ecdaa-algorithm-v2.0-id-20180227.html.
+
+ECDAA-Issuer public keys are looked up by ecdaaKeyId of packed and tpm attestation statements through
+DefaultResolver, which needs to be populated, for example, with ECDAA trust anchors of FIDO metadata
+statements.  Pairing arithmetic is implemented in pure Go.
+*/
+package ecdaa
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"math/big"
+	"strconv"
+
+	"github.com/kappapay/webauthn"
+)
+
+// Sizes of encoded big numbers, G1 points, G2 points, and signatures.
+const (
+	bigNumberSize = 32
+	g1PointSize   = 1 + 2*bigNumberSize
+	g2PointSize   = 1 + 4*bigNumberSize
+	signatureSize = 3*bigNumberSize + 4*g1PointSize
+)
+
+// SignatureAlgorithm returns signature algorithm of ECDAA attestation statements with given COSE
+// algorithm identifier.  Only webauthn.COSEAlgED256 is supported.
+func SignatureAlgorithm(coseAlg int) (webauthn.SignatureAlgorithm, error) {
+	if coseAlg != webauthn.COSEAlgED256 {
+		return webauthn.SignatureAlgorithm{}, &webauthn.UnsupportedFeatureError{Feature: "ECDAA COSE algorithm " + strconv.Itoa(coseAlg)}
+	}
+	return webauthn.SignatureAlgorithm{Hash: crypto.SHA256, COSEAlgorithm: coseAlg}, nil
+}
+
+// IssuerPublicKey is an ECDAA-Issuer public key (X, Y, c, sx, sy), where X and Y are in G2, and
+// (c, sx, sy) is the issuer's proof of knowledge of the secret key.
+type IssuerPublicKey struct {
+	x, y      *twistPoint
+	c, sx, sy *big.Int
+}
+
+// ParseIssuerPublicKey returns ECDAA-Issuer public key of given components, encoded as in ECDAA trust
+// anchors of FIDO metadata statements: X and Y with ECPoint2ToB, and c, sx, and sy with BigNumberToB.
+// It verifies that X and Y are in G2 and that c = H(Ux | Uy | P2 | X | Y), where Ux = sx·P2 - c·X and
+// Uy = sy·P2 - c·Y.
+func ParseIssuerPublicKey(x, y, c, sx, sy []byte) (*IssuerPublicKey, error) {
+	ipk := &IssuerPublicKey{}
+	var err error
+	if ipk.x, err = unmarshalG2(x); err != nil {
+		return nil, errors.New("ecdaa: failed to parse ECDAA-Issuer public key X: " + err.Error())
+	}
+	if ipk.y, err = unmarshalG2(y); err != nil {
+		return nil, errors.New("ecdaa: failed to parse ECDAA-Issuer public key Y: " + err.Error())
+	}
+	for _, f := range []struct {
+		name string
+		data []byte
+		k    **big.Int
+	}{{"c", c, &ipk.c}, {"sx", sx, &ipk.sx}, {"sy", sy, &ipk.sy}} {
+		if *f.k, err = parseBigNumber(f.data); err != nil {
+			return nil, errors.New("ecdaa: failed to parse ECDAA-Issuer public key " + f.name + ": " + err.Error())
+		}
+	}
+
+	negC := new(big.Int).Sub(order, ipk.c)
+	ux := g2.mul(ipk.sx).add(ipk.x.mul(negC))
+	uy := g2.mul(ipk.sy).add(ipk.y.mul(negC))
+	if hashToZn(ux.marshal(), uy.marshal(), g2.marshal(), ipk.x.marshal(), ipk.y.marshal()).Cmp(ipk.c) != 0 {
+		return nil, errors.New("ecdaa: ECDAA-Issuer public key proof is invalid")
+	}
+	return ipk, nil
+}
+
+// KeyID returns ecdaaKeyId of ipk, which is BigNumberToB encoding of its c component.
+func (ipk *IssuerPublicKey) KeyID() []byte {
+	return bigNumberToBytes(ipk.c)
+}
+
+// Equal returns if ipk and other are the same ECDAA-Issuer public key.
+func (ipk *IssuerPublicKey) Equal(other *IssuerPublicKey) bool {
+	return ipk.x.equal(other.x) && ipk.y.equal(other.y) && bytes.Equal(ipk.KeyID(), other.KeyID())
+}
+
+// signature is an ECDAA signature (c, s, R, S, T, W, n), encoded as c | s | R | S | T | W | n.
+type signature struct {
+	c, s        *big.Int
+	r, s1, t, w *g1Point
+	n           []byte
+}
+
+func parseSignature(data []byte) (*signature, error) {
+	if len(data) != signatureSize {
+		return nil, errors.New("ecdaa: invalid signature length")
+	}
+	sig := &signature{}
+	var err error
+	if sig.c, err = parseBigNumber(data[:bigNumberSize]); err != nil {
+		return nil, errors.New("ecdaa: failed to parse signature c: " + err.Error())
+	}
+	data = data[bigNumberSize:]
+	if sig.s, err = parseBigNumber(data[:bigNumberSize]); err != nil {
+		return nil, errors.New("ecdaa: failed to parse signature s: " + err.Error())
+	}
+	data = data[bigNumberSize:]
+	for _, f := range []struct {
+		name string
+		p    **g1Point
+	}{{"R", &sig.r}, {"S", &sig.s1}, {"T", &sig.t}, {"W", &sig.w}} {
+		if *f.p, err = unmarshalG1(data[:g1PointSize]); err != nil {
+			return nil, errors.New("ecdaa: failed to parse signature " + f.name + ": " + err.Error())
+		}
+		data = data[g1PointSize:]
+	}
+	sig.n = data
+	return sig, nil
+}
+
+// Verify performs ECDAA-Verify of sig over message with ipk.  It verifies that e(R, Y) = e(S, P2),
+// e(T, P2) = e(R + W, X), and c = H(n | H(U | S | W | message)), where U = s·S - c·W.
+func (ipk *IssuerPublicKey) Verify(sig, message []byte) error {
+	s, err := parseSignature(sig)
+	if err != nil {
+		return err
+	}
+	if !pairingCheck([]*g1Point{s.r, s.s1.neg()}, []*twistPoint{ipk.y, g2}) {
+		return errors.New("ecdaa: signature is not signed with credential of ECDAA-Issuer: e(R, Y) != e(S, P2)")
+	}
+	if !pairingCheck([]*g1Point{s.t, s.r.add(s.w).neg()}, []*twistPoint{g2, ipk.x}) {
+		return errors.New("ecdaa: signature is not signed with credential of ECDAA-Issuer: e(T, P2) != e(R + W, X)")
+	}
+	U := s.s1.mul(s.s).add(s.w.mul(new(big.Int).Sub(order, s.c)))
+	c2 := hashToZn(U.marshal(), s.s1.marshal(), s.w.marshal(), message)
+	if hashToZn(s.n, bigNumberToBytes(c2)).Cmp(s.c) != 0 {
+		return errors.New("ecdaa: signature does not match message")
+	}
+	return nil
+}
+
+// parseBigNumber returns big number of BigNumberToB encoding, which must be less than n.
+func parseBigNumber(data []byte) (*big.Int, error) {
+	if len(data) != bigNumberSize {
+		return nil, errors.New("invalid big number length")
+	}
+	k := new(big.Int).SetBytes(data)
+	if k.Cmp(order) >= 0 {
+		return nil, errors.New("big number is not less than group order")
+	}
+	return k, nil
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package ecdaa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// testIssuer is an ECDAA-Issuer with secret key (x, y).
+type testIssuer struct {
+	x, y                  *big.Int
+	ipkX, ipkY, c, sx, sy []byte
+}
+
+// testCredential is an ECDAA credential (A, B, C, D) of authenticator secret key sk.
+type testCredential struct {
+	sk         *big.Int
+	a, b, c, d *g1Point
+}
+
+func randomZn(t *testing.T) *big.Int {
+	k, err := rand.Int(rand.Reader, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	x, y := randomZn(t), randomZn(t)
+	X, Y := g2.mul(x), g2.mul(y)
+	rx, ry := randomZn(t), randomZn(t)
+	c := hashToZn(g2.mul(rx).marshal(), g2.mul(ry).marshal(), g2.marshal(), X.marshal(), Y.marshal())
+	sx := new(big.Int).Mul(c, x)
+	sx.Add(sx, rx).Mod(sx, order)
+	sy := new(big.Int).Mul(c, y)
+	sy.Add(sy, ry).Mod(sy, order)
+	return &testIssuer{
+		x:    x,
+		y:    y,
+		ipkX: X.marshal(),
+		ipkY: Y.marshal(),
+		c:    bigNumberToBytes(c),
+		sx:   bigNumberToBytes(sx),
+		sy:   bigNumberToBytes(sy),
+	}
+}
+
+func (is *testIssuer) publicKey(t *testing.T) *IssuerPublicKey {
+	ipk, err := ParseIssuerPublicKey(is.ipkX, is.ipkY, is.c, is.sx, is.sy)
+	if err != nil {
+		t.Fatalf("ParseIssuerPublicKey() returns error %q", err)
+	}
+	return ipk
+}
+
+// newCredential issues credential A = l·P1, B = y·A, C = x·(A + D), D = l·y·Q for authenticator
+// public key Q = sk·P1.
+func (is *testIssuer) newCredential(t *testing.T) *testCredential {
+	sk, l := randomZn(t), randomZn(t)
+	A := g1.mul(l)
+	ly := new(big.Int).Mul(l, is.y)
+	D := g1.mul(sk).mul(ly.Mod(ly, order))
+	return &testCredential{sk: sk, a: A, b: A.mul(is.y), c: A.add(D).mul(is.x), d: D}
+}
+
+func (cred *testCredential) sign(t *testing.T, message []byte) []byte {
+	l := randomZn(t)
+	R, S, T, W := cred.a.mul(l), cred.b.mul(l), cred.c.mul(l), cred.d.mul(l)
+	r := randomZn(t)
+	c2 := hashToZn(S.mul(r).marshal(), S.marshal(), W.marshal(), message)
+	n := bigNumberToBytes(randomZn(t))
+	c := hashToZn(n, bigNumberToBytes(c2))
+	s := new(big.Int).Mul(c, cred.sk)
+	s.Add(s, r).Mod(s, order)
+
+	var sig []byte
+	sig = append(sig, bigNumberToBytes(c)...)
+	sig = append(sig, bigNumberToBytes(s)...)
+	for _, P := range []*g1Point{R, S, T, W} {
+		sig = append(sig, P.marshal()...)
+	}
+	return append(sig, n...)
+}
+
+func TestParseIssuerPublicKey(t *testing.T) {
+	is := newTestIssuer(t)
+	ipk := is.publicKey(t)
+	if !bytes.Equal(ipk.KeyID(), is.c) {
+		t.Errorf("KeyID() = %x, want %x", ipk.KeyID(), is.c)
+	}
+	if !ipk.Equal(is.publicKey(t)) {
+		t.Errorf("Equal() returns false for the same ECDAA-Issuer public key")
+	}
+	if ipk.Equal(newTestIssuer(t).publicKey(t)) {
+		t.Errorf("Equal() returns true for different ECDAA-Issuer public keys")
+	}
+
+	other := newTestIssuer(t)
+	notInG2 := append([]byte(nil), is.ipkX...)
+	notInG2[len(notInG2)-1] ^= 0x01
+	testCases := []struct {
+		name            string
+		x, y, c, sx, sy []byte
+		wantErrorMsg    string
+	}{
+		{"invalid X encoding", is.ipkX[1:], is.ipkY, is.c, is.sx, is.sy, "failed to parse ECDAA-Issuer public key X: invalid G2 point encoding"},
+		{"X not on curve", notInG2, is.ipkY, is.c, is.sx, is.sy, "failed to parse ECDAA-Issuer public key X: G2 point is not on curve"},
+		{"invalid Y encoding", is.ipkX, is.ipkY[:64], is.c, is.sx, is.sy, "failed to parse ECDAA-Issuer public key Y: invalid G2 point encoding"},
+		{"invalid c length", is.ipkX, is.ipkY, is.c[1:], is.sx, is.sy, "failed to parse ECDAA-Issuer public key c: invalid big number length"},
+		{"sx not less than n", is.ipkX, is.ipkY, is.c, bigNumberToBytes(order), is.sy, "failed to parse ECDAA-Issuer public key sx: big number is not less than group order"},
+		{"wrong Y", is.ipkX, other.ipkY, is.c, is.sx, is.sy, "ECDAA-Issuer public key proof is invalid"},
+		{"wrong sy", is.ipkX, is.ipkY, is.c, is.sx, other.sy, "ECDAA-Issuer public key proof is invalid"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseIssuerPublicKey(tc.x, tc.y, tc.c, tc.sx, tc.sy)
+			if err == nil {
+				t.Errorf("ParseIssuerPublicKey() returns no error, want error containing substring %q", tc.wantErrorMsg)
+			} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+				t.Errorf("ParseIssuerPublicKey() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	is := newTestIssuer(t)
+	ipk := is.publicKey(t)
+	cred := is.newCredential(t)
+	message := []byte("authenticatorData and clientDataHash")
+	sig := cred.sign(t, message)
+
+	corrupt := func(offset int) []byte {
+		b := append([]byte(nil), sig...)
+		b[offset] ^= 0x01
+		return b
+	}
+	// Signature of credential issued with another ECDAA-Issuer, and signature with W not of sk.
+	otherIssuerSig := newTestIssuer(t).newCredential(t).sign(t, message)
+	forged := *cred
+	forged.sk = randomZn(t)
+
+	testCases := []struct {
+		name         string
+		sig          []byte
+		message      []byte
+		wantErrorMsg string
+	}{
+		{"valid", sig, message, ""},
+		{"invalid length", sig[:len(sig)-1], message, "invalid signature length"},
+		{"c not less than n", append(bigNumberToBytes(order), sig[bigNumberSize:]...), message, "failed to parse signature c"},
+		{"R not on curve", corrupt(2*bigNumberSize + g1PointSize - 1), message, "failed to parse signature R: G1 point is not on curve"},
+		{"W invalid encoding", corrupt(2*bigNumberSize + 3*g1PointSize), message, "failed to parse signature W: invalid G1 point encoding"},
+		{"credential of other issuer", otherIssuerSig, message, "e(R, Y) != e(S, P2)"},
+		{"wrong secret key", forged.sign(t, message), message, "signature does not match message"},
+		{"wrong s", corrupt(2*bigNumberSize - 1), message, "signature does not match message"},
+		{"wrong n", corrupt(len(sig) - 1), message, "signature does not match message"},
+		{"wrong message", sig, []byte("other message"), "signature does not match message"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ipk.Verify(tc.sig, tc.message)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("Verify() returns error %q", err)
+			} else if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("Verify() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("Verify() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+			}
+		})
+	}
+}
+
+// Known-answer vector generated by testdata/known_answer.py, a Python implementation of ECDAA-Issuer
+// setup and ECDAA-Sign written from the FIDO ECDAA algorithm specification without code of this package.
+// Secret scalars x, y, rx, ry, sk, l, l', r, and n are SHA-256 digests of their names modulo the group
+// order, and message is SHA-256("authenticatorData") | SHA-256("clientDataJSON").
+var (
+	knownAnswerX = "0424eebef44501c21f4b45c73196b391f1d962ed4c806733e2e0d365fbb40d2bde6a335fbe21821d5484ff3910e07a02" +
+		"b4de2dea2332c6272f0f9352280525cba10b28769e6220dd5cf474c463d04dcbd474ca6fa08aff3594b6bbb50665484c" +
+		"b97f70b0c38a1aead3c4d7047a95d330a29d9033ba34cd17a3be007720e4fdfe29"
+	knownAnswerY = "041bdf559f09829f4febaef17be93411f9d2c86cecec9dad6b67515e42a7f55a758886cd372977dbcbf704dfe8ab427f" +
+		"f4b6e954cdb16137aeddd9abfbc72e9c84c81fa36032eda109886ea6ed739389de955ffd59c50aa4fdd685bb71f03fd1" +
+		"946029e21c9d5262938591bc6b8dc61f11dbf6628070b2f6038ccd58f4b17fee90"
+	knownAnswerC       = "9e3cc108b81f876911183c34225c4255ebca65dbadd0932efa64398e216bd39a"
+	knownAnswerSx      = "b97e7da79260326d2f4f9b1d6421370dd3e503a9ad884f9a9e70fe4f2ae4bfa0"
+	knownAnswerSy      = "6deaab652b321f96c90a066b4d224a250cdfd1fb1199cf149e2713871ea673d6"
+	knownAnswerMessage = "4a91aa6a04e215bf5045741adf7a6afeda4a66c05430622493bbea120836ce9a6d85b2394787b6e05d680ad872cf6434" +
+		"fb63fcf67a3f0588757fc841f2984881"
+	knownAnswerSignature = "3c87e6ea613c7ccf48795a2f136e3dfee5d8e57d327add3ea42d661e4033b62df917cefa42788b7cc08cd1ab6b70167c" +
+		"f3239edfdd1a306709e4bff95469803604ee3ef05dc62c1e9841e5bd04edfb0cdb2858b9ad31545350d734813c0788d4" +
+		"3d0f534163f5244620bf73702ee367d95ec040e786f9d3605572d79cd387bd664e0458883f955a535516a56f80eff196" +
+		"0199a2a964a1584fbc2cdc3840803ce68412b8bcba11a8a0e3bf75757264434f60db97e497f17c8f6abaab7bb7f02fd0" +
+		"0b3204e5d77768fec4fb3a06a24f269dd6f629f866b7fd5d07d4928b20f3a2a7f080df0ea37dca1d56a0129567c9f247" +
+		"3960b7238c9e58324b91a44a5115ce4ce269ce046ad39d1d60be80373d1a0f44137aee09de5543fb48ecc2dc8a8299bd" +
+		"4729a49529bc3b0ed85e53fd73fff938b36f633865c56f5ecc8c5b822f26a71226abaa1e1b16b1df538ba12dc3f97edb" +
+		"b85caa7050d46c148134290feba80f8236c83db9"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestVerifyKnownAnswer(t *testing.T) {
+	c := mustDecodeHex(t, knownAnswerC)
+	ipk, err := ParseIssuerPublicKey(mustDecodeHex(t, knownAnswerX), mustDecodeHex(t, knownAnswerY), c, mustDecodeHex(t, knownAnswerSx), mustDecodeHex(t, knownAnswerSy))
+	if err != nil {
+		t.Fatalf("ParseIssuerPublicKey() returns error %q", err)
+	}
+	if !bytes.Equal(ipk.KeyID(), c) {
+		t.Errorf("KeyID() = %x, want %x", ipk.KeyID(), c)
+	}
+	message := mustDecodeHex(t, knownAnswerMessage)
+	sig := mustDecodeHex(t, knownAnswerSignature)
+	if err := ipk.Verify(sig, message); err != nil {
+		t.Errorf("Verify() returns error %q", err)
+	}
+	wantErrorMsg := "signature does not match message"
+	if err := ipk.Verify(sig, message[1:]); err == nil {
+		t.Errorf("Verify() returns no error, want error containing substring %q", wantErrorMsg)
+	} else if !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("Verify() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	}
+}
+
+func TestIssuerKeys(t *testing.T) {
+	ipk := newTestIssuer(t).publicKey(t)
+	keys := NewIssuerKeys()
+	if _, err := keys.ResolveIssuerKey(ipk.KeyID()); err == nil {
+		t.Errorf("ResolveIssuerKey() returns no error for unknown key")
+	}
+	keys.Add(ipk)
+	if got, err := keys.ResolveIssuerKey(ipk.KeyID()); err != nil {
+		t.Errorf("ResolveIssuerKey() returns error %q", err)
+	} else if got != ipk {
+		t.Errorf("ResolveIssuerKey() returns a different key")
+	}
+	keys.Remove(ipk.KeyID())
+	if _, err := keys.ResolveIssuerKey(ipk.KeyID()); err == nil {
+		t.Errorf("ResolveIssuerKey() returns no error after key is removed")
+	}
+}
+
+func TestResolveIssuerKey(t *testing.T) {
+	defer func(r IssuerKeyResolver) { DefaultResolver = r }(DefaultResolver)
+
+	ipk := newTestIssuer(t).publicKey(t)
+	DefaultResolver = IssuerKeyResolverFunc(func(keyID []byte) (*IssuerPublicKey, error) {
+		if bytes.Equal(keyID, ipk.KeyID()) {
+			return ipk, nil
+		}
+		return nil, nil
+	})
+	if got, err := ResolveIssuerKey(ipk.KeyID()); err != nil || got != ipk {
+		t.Errorf("ResolveIssuerKey() = %v, %v, want ECDAA-Issuer public key", got, err)
+	}
+	wantErrorMsg := "ecdaa: ECDAA-Issuer public key 0102 is not trusted"
+	if _, err := ResolveIssuerKey([]byte{1, 2}); err == nil || err.Error() != wantErrorMsg {
+		t.Errorf("ResolveIssuerKey() returns error %v, want %q", err, wantErrorMsg)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package ecdaa
+
+import "math/big"
+
+// fp12 is an element c[0] + c[1]·w + ... + c[5]·w⁵ of Fp12 = Fp2[w]/(w⁶ - ξ).
+type fp12 [6]fp2
+
+func fp12One() fp12 {
+	var r fp12
+	r[0] = fp2One()
+	for k := 1; k < len(r); k++ {
+		r[k] = fp2Zero()
+	}
+	return r
+}
+
+func (x fp12) mul(y fp12) fp12 {
+	var t [11]fp2
+	for k := range t {
+		t[k] = fp2Zero()
+	}
+	for i := range x {
+		if x[i].isZero() {
+			continue
+		}
+		for j := range y {
+			if y[j].isZero() {
+				continue
+			}
+			t[i+j] = t[i+j].add(x[i].mul(y[j]))
+		}
+	}
+	var r fp12
+	for k := range r {
+		r[k] = t[k]
+		if k+6 < len(t) {
+			r[k] = r[k].add(t[k+6].mul(xi))
+		}
+	}
+	return r
+}
+
+// square returns x², computing each cross product x[i]·x[j] once.
+func (x fp12) square() fp12 {
+	var t [11]fp2
+	for k := range t {
+		t[k] = fp2Zero()
+	}
+	for i := range x {
+		t[2*i] = t[2*i].add(x[i].square())
+		for j := i + 1; j < len(x); j++ {
+			xij := x[i].mul(x[j])
+			t[i+j] = t[i+j].add(xij).add(xij)
+		}
+	}
+	var r fp12
+	for k := range r {
+		r[k] = t[k]
+		if k+6 < len(t) {
+			r[k] = r[k].add(t[k+6].mul(xi))
+		}
+	}
+	return r
+}
+
+// frobenius returns x^p.  Since (w^k)^p = w^k·ξ^(k(p-1)/6), each coefficient is conjugated and
+// multiplied by a constant.
+func (x fp12) frobenius() fp12 {
+	var r fp12
+	for k := range x {
+		r[k] = x[k].conj().mul(frobW[k])
+	}
+	return r
+}
+
+// frobeniusN returns x^(p^n).
+func (x fp12) frobeniusN(n int) fp12 {
+	for i := 0; i < n; i++ {
+		x = x.frobenius()
+	}
+	return x
+}
+
+// conjugate returns x^(p^6), which is the inverse of x if x is in the cyclotomic subgroup.
+func (x fp12) conjugate() fp12 {
+	return x.frobeniusN(6)
+}
+
+// inv returns 1/x.  x·x^(p^6) is in Fp6 = Fp2[w²], and the norm of an Fp6 element g over Fp2 is
+// g·g^(p²)·g^(p⁴), so 1/x = x^(p^6)·g^(p²)·g^(p⁴)/(g·g^(p²)·g^(p⁴)) with g = x·x^(p^6).
+func (x fp12) inv() fp12 {
+	xc := x.conjugate()
+	g := x.mul(xc)
+	g2 := g.frobeniusN(2)
+	g4 := g.frobeniusN(4)
+	t := xc.mul(g2).mul(g4)
+	norm := g.mul(g2).mul(g4)[0].inv()
+	for k := range t {
+		t[k] = t[k].mul(norm)
+	}
+	return t
+}
+
+func (x fp12) exp(e *big.Int) fp12 {
+	r := fp12One()
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		r = r.square()
+		if e.Bit(i) == 1 {
+			r = r.mul(x)
+		}
+	}
+	return r
+}
+
+func (x fp12) equal(y fp12) bool {
+	for k := range x {
+		if !x[k].equal(y[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (x fp12) isOne() bool {
+	return x.equal(fp12One())
+}
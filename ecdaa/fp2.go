@@ -0,0 +1,98 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package ecdaa
+
+import "math/big"
+
+// fp2 is an element a + b·i of Fp2 = Fp[i]/(i² + 1).
+type fp2 struct {
+	a, b *big.Int
+}
+
+func newFp2(a, b *big.Int) fp2 {
+	return fp2{a: new(big.Int).Mod(a, p), b: new(big.Int).Mod(b, p)}
+}
+
+func fp2Zero() fp2 {
+	return fp2{a: new(big.Int), b: new(big.Int)}
+}
+
+func fp2One() fp2 {
+	return fp2{a: big.NewInt(1), b: new(big.Int)}
+}
+
+func (x fp2) add(y fp2) fp2 {
+	return newFp2(new(big.Int).Add(x.a, y.a), new(big.Int).Add(x.b, y.b))
+}
+
+func (x fp2) sub(y fp2) fp2 {
+	return newFp2(new(big.Int).Sub(x.a, y.a), new(big.Int).Sub(x.b, y.b))
+}
+
+func (x fp2) neg() fp2 {
+	return newFp2(new(big.Int).Neg(x.a), new(big.Int).Neg(x.b))
+}
+
+// conj returns a - b·i, which is also x^p.
+func (x fp2) conj() fp2 {
+	return newFp2(x.a, new(big.Int).Neg(x.b))
+}
+
+func (x fp2) mul(y fp2) fp2 {
+	ac := new(big.Int).Mul(x.a, y.a)
+	bd := new(big.Int).Mul(x.b, y.b)
+	ad := new(big.Int).Mul(x.a, y.b)
+	bc := new(big.Int).Mul(x.b, y.a)
+	return newFp2(ac.Sub(ac, bd), ad.Add(ad, bc))
+}
+
+func (x fp2) mulScalar(k *big.Int) fp2 {
+	return newFp2(new(big.Int).Mul(x.a, k), new(big.Int).Mul(x.b, k))
+}
+
+func (x fp2) square() fp2 {
+	return x.mul(x)
+}
+
+// inv returns 1/x, or zero if x is zero.
+func (x fp2) inv() fp2 {
+	t := new(big.Int).Mul(x.a, x.a)
+	t.Add(t, new(big.Int).Mul(x.b, x.b))
+	t.ModInverse(t, p)
+	return newFp2(new(big.Int).Mul(x.a, t), new(big.Int).Neg(new(big.Int).Mul(x.b, t)))
+}
+
+func (x fp2) exp(e *big.Int) fp2 {
+	r := fp2One()
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		r = r.square()
+		if e.Bit(i) == 1 {
+			r = r.mul(x)
+		}
+	}
+	return r
+}
+
+func (x fp2) isZero() bool {
+	return x.a.Sign() == 0 && x.b.Sign() == 0
+}
+
+func (x fp2) equal(y fp2) bool {
+	return x.a.Cmp(y.a) == 0 && x.b.Cmp(y.b) == 0
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package ecdaa
+
+import (
+	"errors"
+	"math/big"
+)
+
+// g1Point is a point of E in affine coordinates.  G1 is the group of all points of E, since the
+// order of E is prime.
+type g1Point struct {
+	x, y *big.Int
+	inf  bool // Point at infinity.
+}
+
+func (P *g1Point) isOnCurve() bool {
+	if P.inf {
+		return true
+	}
+	y2 := new(big.Int).Mul(P.y, P.y)
+	y2.Mod(y2, p)
+	x3 := new(big.Int).Mul(P.x, P.x)
+	x3.Mul(x3, P.x)
+	x3.Add(x3, curveB)
+	x3.Mod(x3, p)
+	return y2.Cmp(x3) == 0
+}
+
+func (P *g1Point) equal(Q *g1Point) bool {
+	if P.inf || Q.inf {
+		return P.inf == Q.inf
+	}
+	return P.x.Cmp(Q.x) == 0 && P.y.Cmp(Q.y) == 0
+}
+
+func (P *g1Point) neg() *g1Point {
+	if P.inf {
+		return P
+	}
+	y := new(big.Int).Neg(P.y)
+	return &g1Point{x: P.x, y: y.Mod(y, p)}
+}
+
+func (P *g1Point) add(Q *g1Point) *g1Point {
+	if P.inf {
+		return Q
+	}
+	if Q.inf {
+		return P
+	}
+	var lambda *big.Int
+	if P.x.Cmp(Q.x) == 0 {
+		if P.y.Cmp(Q.y) != 0 || P.y.Sign() == 0 {
+			return &g1Point{inf: true}
+		}
+		// λ = 3x²/2y
+		lambda = new(big.Int).Mul(P.x, P.x)
+		lambda.Mul(lambda, big.NewInt(3))
+		lambda.Mul(lambda, new(big.Int).ModInverse(new(big.Int).Lsh(P.y, 1), p))
+	} else {
+		// λ = (y2 - y1)/(x2 - x1)
+		dx := new(big.Int).Sub(Q.x, P.x)
+		dx.Mod(dx, p)
+		lambda = new(big.Int).Sub(Q.y, P.y)
+		lambda.Mul(lambda, dx.ModInverse(dx, p))
+	}
+	lambda.Mod(lambda, p)
+	x := new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, P.x)
+	x.Sub(x, Q.x)
+	x.Mod(x, p)
+	y := new(big.Int).Sub(P.x, x)
+	y.Mul(y, lambda)
+	y.Sub(y, P.y)
+	y.Mod(y, p)
+	return &g1Point{x: x, y: y}
+}
+
+func (P *g1Point) mul(k *big.Int) *g1Point {
+	R := &g1Point{inf: true}
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		R = R.add(R)
+		if k.Bit(i) == 1 {
+			R = R.add(P)
+		}
+	}
+	return R
+}
+
+// marshal returns uncompressed encoding 0x04 | x | y of P, as ECPointToB in FIDO ECDAA algorithm.
+func (P *g1Point) marshal() []byte {
+	if P.inf {
+		return []byte{0x00}
+	}
+	b := make([]byte, 0, g1PointSize)
+	b = append(b, 0x04)
+	b = append(b, bigNumberToBytes(P.x)...)
+	return append(b, bigNumberToBytes(P.y)...)
+}
+
+// unmarshalG1 returns G1 point of uncompressed encoding 0x04 | x | y.  Point at infinity is rejected.
+func unmarshalG1(data []byte) (*g1Point, error) {
+	if len(data) != g1PointSize || data[0] != 0x04 {
+		return nil, errors.New("invalid G1 point encoding")
+	}
+	P := &g1Point{
+		x: new(big.Int).SetBytes(data[1 : 1+bigNumberSize]),
+		y: new(big.Int).SetBytes(data[1+bigNumberSize:]),
+	}
+	if P.x.Cmp(p) >= 0 || P.y.Cmp(p) >= 0 || !P.isOnCurve() {
+		return nil, errors.New("G1 point is not on curve")
+	}
+	return P, nil
+}
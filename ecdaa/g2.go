@@ -0,0 +1,147 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package ecdaa
+
+import (
+	"errors"
+	"math/big"
+)
+
+// twistPoint is a point of twist E' in affine coordinates.
+type twistPoint struct {
+	x, y fp2
+	inf  bool // Point at infinity.
+}
+
+func (P *twistPoint) isOnCurve() bool {
+	if P.inf {
+		return true
+	}
+	return P.y.square().equal(P.x.square().mul(P.x).add(twistB))
+}
+
+// inG2 returns if P is in the order n subgroup G2 of E'.
+func (P *twistPoint) inG2() bool {
+	return P.isOnCurve() && P.mul(order).inf
+}
+
+func (P *twistPoint) equal(Q *twistPoint) bool {
+	if P.inf || Q.inf {
+		return P.inf == Q.inf
+	}
+	return P.x.equal(Q.x) && P.y.equal(Q.y)
+}
+
+func (P *twistPoint) neg() *twistPoint {
+	if P.inf {
+		return P
+	}
+	return &twistPoint{x: P.x, y: P.y.neg()}
+}
+
+// slope returns slope of the line through P and Q, or false if the line is vertical.
+func (P *twistPoint) slope(Q *twistPoint) (fp2, bool) {
+	if P.x.equal(Q.x) {
+		if !P.y.equal(Q.y) || P.y.isZero() {
+			return fp2{}, false
+		}
+		// λ = 3x²/2y
+		return P.x.square().mulScalar(big.NewInt(3)).mul(P.y.add(P.y).inv()), true
+	}
+	// λ = (y2 - y1)/(x2 - x1)
+	return Q.y.sub(P.y).mul(Q.x.sub(P.x).inv()), true
+}
+
+// addWithSlope returns P + Q, where lambda is slope of the line through P and Q.
+func (P *twistPoint) addWithSlope(Q *twistPoint, lambda fp2) *twistPoint {
+	x := lambda.square().sub(P.x).sub(Q.x)
+	y := lambda.mul(P.x.sub(x)).sub(P.y)
+	return &twistPoint{x: x, y: y}
+}
+
+func (P *twistPoint) add(Q *twistPoint) *twistPoint {
+	if P.inf {
+		return Q
+	}
+	if Q.inf {
+		return P
+	}
+	lambda, ok := P.slope(Q)
+	if !ok {
+		return &twistPoint{inf: true}
+	}
+	return P.addWithSlope(Q, lambda)
+}
+
+func (P *twistPoint) mul(k *big.Int) *twistPoint {
+	R := &twistPoint{inf: true}
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		R = R.add(R)
+		if k.Bit(i) == 1 {
+			R = R.add(P)
+		}
+	}
+	return R
+}
+
+// frobenius returns the image of P under the p-power Frobenius endomorphism of E(Fp12), mapped back
+// to E'.  It equals p·P for P in G2.
+func (P *twistPoint) frobenius() *twistPoint {
+	if P.inf {
+		return P
+	}
+	return &twistPoint{x: P.x.conj().mul(twistFrobX), y: P.y.conj().mul(twistFrobY)}
+}
+
+// marshal returns uncompressed encoding 0x04 | x.a | x.b | y.a | y.b of P, where x = x.a + x.b·i and
+// y = y.a + y.b·i, as ECPoint2ToB in FIDO ECDAA algorithm.
+func (P *twistPoint) marshal() []byte {
+	if P.inf {
+		return []byte{0x00}
+	}
+	b := make([]byte, 0, g2PointSize)
+	b = append(b, 0x04)
+	for _, k := range []*big.Int{P.x.a, P.x.b, P.y.a, P.y.b} {
+		b = append(b, bigNumberToBytes(k)...)
+	}
+	return b
+}
+
+// unmarshalG2 returns G2 point of uncompressed encoding 0x04 | x.a | x.b | y.a | y.b.  Point at
+// infinity and points outside of G2 are rejected.
+func unmarshalG2(data []byte) (*twistPoint, error) {
+	if len(data) != g2PointSize || data[0] != 0x04 {
+		return nil, errors.New("invalid G2 point encoding")
+	}
+	var k [4]*big.Int
+	for i := range k {
+		k[i] = new(big.Int).SetBytes(data[1+i*bigNumberSize : 1+(i+1)*bigNumberSize])
+		if k[i].Cmp(p) >= 0 {
+			return nil, errors.New("G2 point is not on curve")
+		}
+	}
+	P := &twistPoint{x: fp2{a: k[0], b: k[1]}, y: fp2{a: k[2], b: k[3]}}
+	if !P.isOnCurve() {
+		return nil, errors.New("G2 point is not on curve")
+	}
+	if !P.mul(order).inf {
+		return nil, errors.New("G2 point is not in subgroup of order n")
+	}
+	return P, nil
+}
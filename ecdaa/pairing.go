@@ -0,0 +1,124 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package ecdaa
+
+import "math/big"
+
+// Optimal ate pairing e: G1 x G2 -> GT.  Points of G2 are mapped to E(Fp12) by the untwisting
+// isomorphism ψ(x, y) = (x·w⁻², y·w⁻³), so the Miller loop runs on E' and only line functions are
+// evaluated in Fp12.
+
+// lineStep returns T + Q, and the line through ψ(T) and ψ(Q) evaluated at P.  Vertical lines are
+// returned as 1, because they are eliminated by final exponentiation.
+func lineStep(T, Q *twistPoint, P *g1Point) (*twistPoint, fp12) {
+	lambda, ok := T.slope(Q)
+	if !ok {
+		return &twistPoint{inf: true}, fp12One()
+	}
+	// With slope λ·w⁻¹ of the line on E(Fp12), the line function is
+	// yP - y·w⁻³ - λ·w⁻¹·(xP - x·w⁻²) = yP + (λ·x - y)/ξ·w³ - λ·xP/ξ·w⁵.
+	var l fp12
+	for k := range l {
+		l[k] = fp2Zero()
+	}
+	l[0] = fp2{a: P.y, b: new(big.Int)}
+	l[3] = lambda.mul(T.x).sub(T.y).mul(xiInv)
+	l[5] = lambda.mulScalar(P.x).mul(xiInv).neg()
+	return T.addWithSlope(Q, lambda), l
+}
+
+// millerLoop returns the Miller function of optimal ate pairing of P and Q before final exponentiation.
+func millerLoop(P *g1Point, Q *twistPoint) fp12 {
+	f := fp12One()
+	if P.inf || Q.inf {
+		return f
+	}
+
+	var l fp12
+	T := Q
+	s := new(big.Int).Abs(ateLoopCount)
+	for i := s.BitLen() - 2; i >= 0; i-- {
+		T, l = lineStep(T, T, P)
+		f = f.square().mul(l)
+		if s.Bit(i) == 1 {
+			T, l = lineStep(T, Q, P)
+			f = f.mul(l)
+		}
+	}
+	if ateLoopCount.Sign() < 0 {
+		f = f.conjugate()
+		T = T.neg()
+	}
+
+	Q1 := Q.frobenius()
+	Q2 := Q1.frobenius().neg()
+	T, l = lineStep(T, Q1, P)
+	f = f.mul(l)
+	_, l = lineStep(T, Q2, P)
+	return f.mul(l)
+}
+
+// finalExponentiation returns f^((p¹² - 1)/n).
+func finalExponentiation(f fp12) fp12 {
+	f = f.conjugate().mul(f.inv())
+	f = f.frobeniusN(2).mul(f)
+
+	// Hard part: with (p⁴ - p² + 1)/n = λ0 + λ1·p + λ2·p² + λ3·p³, compute the product of
+	// (f^(p^i))^λi with simultaneous exponentiation over precomputed products of f^(p^i).
+	var table [16]fp12
+	table[0] = fp12One()
+	fi := f
+	for i := 0; i < len(finalExpHardDigits); i++ {
+		for j := 1 << uint(i); j < 2<<uint(i); j++ {
+			table[j] = table[j-(1<<uint(i))].mul(fi)
+		}
+		fi = fi.frobenius()
+	}
+	bitLen := 0
+	for _, d := range finalExpHardDigits {
+		if d.BitLen() > bitLen {
+			bitLen = d.BitLen()
+		}
+	}
+	r := fp12One()
+	for b := bitLen - 1; b >= 0; b-- {
+		r = r.square()
+		j := 0
+		for i, d := range finalExpHardDigits {
+			j |= int(d.Bit(b)) << uint(i)
+		}
+		if j != 0 {
+			r = r.mul(table[j])
+		}
+	}
+	return r
+}
+
+func pairing(P *g1Point, Q *twistPoint) fp12 {
+	return finalExponentiation(millerLoop(P, Q))
+}
+
+// pairingCheck returns if the product of e(ps[i], qs[i]) is 1.
+func pairingCheck(ps []*g1Point, qs []*twistPoint) bool {
+	f := fp12One()
+	for i := range ps {
+		f = f.mul(millerLoop(ps[i], qs[i]))
+	}
+	return finalExponentiation(f).isOne()
+}
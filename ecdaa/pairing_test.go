@@ -0,0 +1,97 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package ecdaa
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestCurveParameters(t *testing.T) {
+	u2 := new(big.Int).Mul(u, u)
+	u3 := new(big.Int).Mul(u2, u)
+	u4 := new(big.Int).Mul(u3, u)
+	poly := func(c2 int64) *big.Int {
+		k := new(big.Int).Mul(u4, big.NewInt(36))
+		k.Add(k, new(big.Int).Mul(u3, big.NewInt(36)))
+		k.Add(k, new(big.Int).Mul(u2, big.NewInt(c2)))
+		k.Add(k, new(big.Int).Mul(u, big.NewInt(6)))
+		return k.Add(k, big.NewInt(1))
+	}
+	if poly(24).Cmp(p) != 0 {
+		t.Errorf("p = %x, want 36u⁴ + 36u³ + 24u² + 6u + 1", p)
+	}
+	if poly(18).Cmp(order) != 0 {
+		t.Errorf("n = %x, want 36u⁴ + 36u³ + 18u² + 6u + 1", order)
+	}
+	if !g1.isOnCurve() || !g1.mul(order).inf {
+		t.Errorf("P1 is not a point of order n")
+	}
+	if !g2.inG2() {
+		t.Errorf("P2 is not in G2")
+	}
+	if !g2.frobenius().equal(g2.mul(p)) {
+		t.Errorf("Frobenius endomorphism of P2 != p·P2")
+	}
+}
+
+func TestFp12Inv(t *testing.T) {
+	var x fp12
+	for k := range x {
+		x[k] = newFp2(big.NewInt(int64(k+1)), big.NewInt(int64(7*k+3)))
+	}
+	if !x.mul(x.inv()).isOne() {
+		t.Errorf("x·x⁻¹ != 1")
+	}
+	if !x.frobeniusN(12).equal(x) {
+		t.Errorf("x^(p¹²) != x")
+	}
+}
+
+func TestPairing(t *testing.T) {
+	e := pairing(g1, g2)
+	if e.isOne() {
+		t.Fatalf("e(P1, P2) = 1")
+	}
+	if !e.exp(order).isOne() {
+		t.Errorf("e(P1, P2)^n != 1")
+	}
+
+	a := big.NewInt(0x1234567)
+	b := bigFromHex("ABCDEF0123456789ABCDEF0123456789")
+	ab := new(big.Int).Mul(a, b)
+	if got, want := pairing(g1.mul(a), g2.mul(b)), e.exp(ab); !got.equal(want) {
+		t.Errorf("e(a·P1, b·P2) != e(P1, P2)^ab")
+	}
+	if !pairingCheck([]*g1Point{g1.mul(ab), g1.neg()}, []*twistPoint{g2, g2.mul(ab)}) {
+		t.Errorf("e(ab·P1, P2)·e(-P1, ab·P2) != 1")
+	}
+	if pairingCheck([]*g1Point{g1.mul(a), g1.neg()}, []*twistPoint{g2, g2.mul(b)}) {
+		t.Errorf("e(a·P1, P2)·e(-P1, b·P2) = 1")
+	}
+}
+
+func TestFinalExponentiation(t *testing.T) {
+	f := millerLoop(g1, g2)
+	easy := f.conjugate().mul(f.inv())
+	easy = easy.frobeniusN(2).mul(easy)
+	if got, want := finalExponentiation(f), easy.exp(finalExpHard); !got.equal(want) {
+		t.Errorf("finalExponentiation() != f^((p¹² - 1)/n)")
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package ecdaa
+
+import (
+	"encoding/hex"
+	"errors"
+	"sync"
+)
+
+// IssuerKeyResolver looks up ECDAA-Issuer public key identified by ecdaaKeyId of attestation
+// statements.  ResolveIssuerKey returns an error if the key isn't trusted.
+type IssuerKeyResolver interface {
+	ResolveIssuerKey(keyID []byte) (*IssuerPublicKey, error)
+}
+
+// IssuerKeyResolverFunc is an adapter to allow the use of ordinary functions as IssuerKeyResolver,
+// for example, to look up ECDAA trust anchors in FIDO metadata service.
+type IssuerKeyResolverFunc func(keyID []byte) (*IssuerPublicKey, error)
+
+// ResolveIssuerKey calls f(keyID).
+func (f IssuerKeyResolverFunc) ResolveIssuerKey(keyID []byte) (*IssuerPublicKey, error) {
+	return f(keyID)
+}
+
+// IssuerKeys is a set of trusted ECDAA-Issuer public keys keyed by ecdaaKeyId.  Keys may be added and
+// removed by one goroutine while others resolve them.
+type IssuerKeys struct {
+	mu   sync.RWMutex
+	keys map[string]*IssuerPublicKey
+}
+
+// NewIssuerKeys returns an empty set of trusted ECDAA-Issuer public keys.
+func NewIssuerKeys() *IssuerKeys {
+	return &IssuerKeys{keys: make(map[string]*IssuerPublicKey)}
+}
+
+// Add adds ECDAA-Issuer public key to the set.
+func (k *IssuerKeys) Add(ipk *IssuerPublicKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[string(ipk.KeyID())] = ipk
+}
+
+// Remove removes ECDAA-Issuer public key identified by keyID from the set.
+func (k *IssuerKeys) Remove(keyID []byte) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.keys, string(keyID))
+}
+
+// ResolveIssuerKey implements the IssuerKeyResolver interface.
+func (k *IssuerKeys) ResolveIssuerKey(keyID []byte) (*IssuerPublicKey, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	ipk, ok := k.keys[string(keyID)]
+	if !ok {
+		return nil, errors.New("ecdaa: ECDAA-Issuer public key " + hex.EncodeToString(keyID) + " is not trusted")
+	}
+	return ipk, nil
+}
+
+// DefaultResolver resolves ECDAA-Issuer public keys of packed and tpm attestation statements.  It is
+// an empty IssuerKeys by default, trusted ECDAA-Issuer public keys need to be added, or it needs to
+// be replaced, before ECDAA attestation statements can be verified.
+var DefaultResolver IssuerKeyResolver = NewIssuerKeys()
+
+// ResolveIssuerKey looks up ECDAA-Issuer public key identified by keyID with DefaultResolver.
+func ResolveIssuerKey(keyID []byte) (*IssuerPublicKey, error) {
+	ipk, err := DefaultResolver.ResolveIssuerKey(keyID)
+	if err == nil && ipk == nil {
+		err = errors.New("ecdaa: ECDAA-Issuer public key " + hex.EncodeToString(keyID) + " is not trusted")
+	}
+	return ipk, err
+}
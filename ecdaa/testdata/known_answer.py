@@ -0,0 +1,162 @@
+# Copyright 2019-present Faye Amacker.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+# Modified by Kappa
+
+# Generates the known-answer vector of TestVerifyKnownAnswer in ecdaa_test.go.
+#
+# ECDAA-Issuer setup and ECDAA-Sign are implemented directly from the FIDO ECDAA Algorithm specification
+# (https://fidoalliance.org/specs/fido-v2.0-id-20180227/fido-ecdaa-algorithm-v2.0-id-20180227.html)
+# for curve TPM_ECC_BN_P256, with affine point arithmetic and no code shared with the Go package.  Secret scalars are SHA-256 digests of their names modulo the group order, so the
+# output is deterministic.
+#
+# Usage: python3 known_answer.py  (Python 3.8 or later, no third party packages)
+
+import hashlib
+
+# TPM_ECC_BN_P256 field prime and group order.
+p = 0xFFFFFFFFFFFCF0CD46E5F25EEE71A49F0CDC65FB12980A82D3292DDBAED33013
+n = 0xFFFFFFFFFFFCF0CD46E5F25EEE71A49E0CDC65FB1299921AF62D536CD10B500D
+
+
+# G1 is the curve y^2 = x^3 + 3 over Fp.  None is the point at infinity.
+def g1add(P, Q):
+    if P is None:
+        return Q
+    if Q is None:
+        return P
+    (x1, y1), (x2, y2) = P, Q
+    if x1 == x2:
+        if (y1 + y2) % p == 0:
+            return None
+        l = 3 * x1 * x1 * pow(2 * y1, -1, p) % p
+    else:
+        l = (y2 - y1) * pow(x2 - x1, -1, p) % p
+    x3 = (l * l - x1 - x2) % p
+    return (x3, (l * (x1 - x3) - y1) % p)
+
+
+def g1mul(P, k):
+    R = None
+    for b in bin(k)[2:]:
+        R = g1add(R, R)
+        if b == '1':
+            R = g1add(R, P)
+    return R
+
+
+# Fp2 = Fp[i]/(i^2 + 1), elements are (real, imaginary).
+def f2add(a, b):
+    return ((a[0] + b[0]) % p, (a[1] + b[1]) % p)
+
+
+def f2sub(a, b):
+    return ((a[0] - b[0]) % p, (a[1] - b[1]) % p)
+
+
+def f2mul(a, b):
+    return ((a[0] * b[0] - a[1] * b[1]) % p, (a[0] * b[1] + a[1] * b[0]) % p)
+
+
+def f2inv(a):
+    d = pow(a[0] * a[0] + a[1] * a[1], -1, p)
+    return (a[0] * d % p, -a[1] * d % p)
+
+
+# G2 is the twist y^2 = x^3 + 3(1 + i) over Fp2.
+def g2add(P, Q):
+    if P is None:
+        return Q
+    if Q is None:
+        return P
+    (x1, y1), (x2, y2) = P, Q
+    if x1 == x2:
+        if f2add(y1, y2) == (0, 0):
+            return None
+        l = f2mul(f2mul((3, 0), f2mul(x1, x1)), f2inv(f2add(y1, y1)))
+    else:
+        l = f2mul(f2sub(y2, y1), f2inv(f2sub(x2, x1)))
+    x3 = f2sub(f2sub(f2mul(l, l), x1), x2)
+    return (x3, f2sub(f2mul(l, f2sub(x1, x3)), y1))
+
+
+def g2mul(P, k):
+    R = None
+    for b in bin(k)[2:]:
+        R = g2add(R, R)
+        if b == '1':
+            R = g2add(R, P)
+    return R
+
+
+# Generators of G1 and G2.
+P1 = (1, 2)
+P2 = ((0xFE0C3350B4C96C2028560F577C28913ACE1C539A12BF843CD22616B689C09EFB,
+       0x4EA66057738AC054DB5AE1C637D813B924DD78E287D03589D269ED34A37E6A2B),
+      (0x8FDFB9183ABA4D19D06EE4E9DC23664D1D1141858536B239EA1F7959EFF70814,
+       0xFAAB1C432C742E3D03F74C15C4F2F1FF818FA77A907D71CEF316ACCA64262B78))
+assert f2mul(P2[1], P2[1]) == f2add(f2mul(P2[0], f2mul(P2[0], P2[0])), (3, 3))
+assert g2mul(P2, n) is None
+
+
+# Big-endian encodings of scalars, and uncompressed encodings of G1 and G2 points.
+def B(k):
+    return k.to_bytes(32, 'big')
+
+
+def E1(P):
+    return b'\x04' + B(P[0]) + B(P[1])
+
+
+def E2(P):
+    return b'\x04' + B(P[0][0]) + B(P[0][1]) + B(P[1][0]) + B(P[1][1])
+
+
+# H hashes to Zn.
+def H(*parts):
+    return int.from_bytes(hashlib.sha256(b''.join(parts)).digest(), 'big') % n
+
+
+def scalar(name):
+    return int.from_bytes(hashlib.sha256(name.encode()).digest(), 'big') % n
+
+
+# ECDAA-Issuer setup: public key X, Y and proof c, sx, sy.
+x, y, rx, ry = scalar("x"), scalar("y"), scalar("rx"), scalar("ry")
+X, Y = g2mul(P2, x), g2mul(P2, y)
+c = H(E2(g2mul(P2, rx)), E2(g2mul(P2, ry)), E2(P2), E2(X), E2(Y))
+sx, sy = (rx + c * x) % n, (ry + c * y) % n
+
+# ECDAA-Join: credential A, B, C, D of authenticator secret key sk.
+sk, l = scalar("sk"), scalar("l")
+A = g1mul(P1, l)
+Bc = g1mul(A, y)
+Q = g1mul(P1, sk)
+D = g1mul(Q, l * y % n)
+C = g1mul(g1add(A, D), x)
+
+# ECDAA-Sign of message with randomized credential R, S, T, W.
+msg = hashlib.sha256(b"authenticatorData").digest() + hashlib.sha256(b"clientDataJSON").digest()
+l2 = scalar("l'")
+R, S, T, W = g1mul(A, l2), g1mul(Bc, l2), g1mul(C, l2), g1mul(D, l2)
+r = scalar("r")
+U = g1mul(S, r)
+c2 = H(E1(U), E1(S), E1(W), msg)
+nn = B(scalar("n"))
+cs = H(nn, B(c2))
+s = (r + cs * sk) % n
+sig = B(cs) + B(s) + E1(R) + E1(S) + E1(T) + E1(W) + nn
+
+for k, v in [("X", E2(X)), ("Y", E2(Y)), ("c", B(c)), ("sx", B(sx)), ("sy", B(sy)), ("message", msg), ("signature", sig)]:
+    print(k, v.hex())
@@ -0,0 +1,97 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn
+
+import "github.com/fxamacker/cbor/v2"
+
+// AuthenticationExtensionsClientInputs represents the Web Authentication structure of the same name,
+// as defined in http://w3c.github.io/webauthn/#dictdef-authenticationextensionsclientinputs
+type AuthenticationExtensionsClientInputs struct {
+	AppID        string       `json:"appid,omitempty"`        // FIDO AppID of credentials registered with the legacy FIDO U2F JavaScript API (authentication only).
+	AppIDExclude string       `json:"appidExclude,omitempty"` // FIDO AppID of legacy FIDO U2F credentials to exclude (registration only).
+	MinPinLength bool         `json:"minPinLength,omitempty"` // Request the authenticator's current minimum PIN length (registration only).
+	CredBlob     bufferString `json:"credBlob,omitempty"`     // Opaque data to store with the new credential on the authenticator (registration only).
+	GetCredBlob  bool         `json:"getCredBlob,omitempty"`  // Request the credBlob stored with the credential (authentication only).
+	UVM          bool         `json:"uvm,omitempty"`          // Request user verification methods used by the authenticator.
+
+	DevicePubKey *AuthenticationExtensionsDevicePublicKeyInputs `json:"devicePubKey,omitempty"` // Request a device-bound key in addition to the credential key.
+	Payment      *AuthenticationExtensionsPaymentInputs         `json:"payment,omitempty"`      // Register a Secure Payment Confirmation credential (registration only).
+}
+
+func (extensions *AuthenticationExtensionsClientInputs) isEmpty() bool {
+	return extensions.AppID == "" &&
+		extensions.AppIDExclude == "" &&
+		!extensions.MinPinLength &&
+		len(extensions.CredBlob) == 0 &&
+		!extensions.GetCredBlob &&
+		!extensions.UVM &&
+		extensions.DevicePubKey == nil &&
+		extensions.Payment == nil
+}
+
+// AuthenticationExtensionsClientOutputs represents the Web Authentication structure of the same name,
+// as defined in http://w3c.github.io/webauthn/#dictdef-authenticationextensionsclientoutputs
+type AuthenticationExtensionsClientOutputs struct {
+	AppID        bool         `json:"appid,omitempty"`        // Client used the FIDO AppID instead of the RP ID to compute rpIdHash.
+	AppIDExclude bool         `json:"appidExclude,omitempty"` // Client processed the appidExclude extension.
+	CredBlob     bool         `json:"credBlob,omitempty"`     // Authenticator stored the credBlob with the new credential.
+	GetCredBlob  bufferString `json:"getCredBlob,omitempty"`  // The credBlob stored with the credential.
+
+	DevicePubKey *AuthenticationExtensionsDevicePublicKeyOutputs `json:"devicePubKey,omitempty"` // Device-bound key and its signature.
+}
+
+// Authenticator extension identifiers.
+const (
+	extensionMinPinLength = "minPinLength"
+	extensionCredBlob     = "credBlob"
+)
+
+// authenticatorExtension decodes the authenticator extension output of given extension identifier
+// into v, and returns false if the output is absent or can't be decoded into v.
+func (authnData *AuthenticatorData) authenticatorExtension(id string, v interface{}) bool {
+	rawValue, ok := authnData.rawExtensions[id]
+	if !ok {
+		return false
+	}
+	return cbor.Unmarshal(rawValue, v) == nil
+}
+
+// MinPinLength returns the current minimum PIN length of the authenticator, reported by the
+// minPinLength authenticator extension output in attestation authenticator data.
+func (authnData *AuthenticatorData) MinPinLength() (minPinLength int, ok bool) {
+	var n uint8
+	if !authnData.authenticatorExtension(extensionMinPinLength, &n) {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// CredBlobStored returns if the authenticator stored the credBlob with the new credential,
+// reported by the credBlob authenticator extension output in attestation authenticator data.
+func (authnData *AuthenticatorData) CredBlobStored() (stored bool, ok bool) {
+	ok = authnData.authenticatorExtension(extensionCredBlob, &stored)
+	return
+}
+
+// CredBlob returns the credBlob stored with the credential, reported by the credBlob
+// authenticator extension output in assertion authenticator data.
+func (authnData *AuthenticatorData) CredBlob() (credBlob []byte, ok bool) {
+	ok = authnData.authenticatorExtension(extensionCredBlob, &credBlob)
+	return
+}
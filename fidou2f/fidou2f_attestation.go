@@ -102,13 +102,21 @@ func (attStmt *fidou2fAttestationStatement) Verify(clientDataHash []byte, authnD
 	}
 
 	// Optionally, inspect x5c and consult externally provided knowledge to determine whether
-	// attStmt conveys a Basic or AttCA attestation.
+	// attStmt conveys a Basic or AttCA attestation.  Trusted U2F vendor root certificates are looked
+	// up by attestation certificate key identifier.  If there isn't any, attestation type is uncertain.
+	var trusted bool
+	if trustPath, trusted, err = webauthn.VerifyAttestationCertificate("fido-u2f", authnData.AAGUID, attStmt.attestnCert, nil, DefaultRoots.Certificates(KeyIdentifier(attStmt.attestnCert))); err != nil {
+		err = &webauthn.VerificationError{Type: "fido u2f attestation", Field: "certificate", Msg: err.Error()}
+		return
+	}
+	if !trusted {
+		return webauthn.AttestationTypeUncertain, trustPath, nil
+	}
 
 	// If successful, return implementation-specific values representing attestation type Basic,
 	// AttCA or uncertainty, and attesation trust path x5c.
-	return webauthn.AttestationTypeBasic, []*x509.Certificate{attStmt.attestnCert}, nil
+	return webauthn.AttestationTypeBasic, trustPath, nil
 }
-
 func init() {
 	webauthn.RegisterAttestationFormat("fido-u2f", parseAttestation)
 }
@@ -24,6 +24,7 @@ import (
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/kappapay/webauthn"
 )
@@ -147,8 +148,8 @@ var parseTests = []parseTest{
 }
 
 var verifyTests = []verifyTest{
-	{"attestation 1", []byte(attestation1), webauthn.AttestationTypeBasic, []*x509.Certificate{parseCertificate(attestation1CredCert)}},
-	{"attestation 2", []byte(attestation2), webauthn.AttestationTypeBasic, []*x509.Certificate{parseCertificate(attestation2CredCert)}},
+	{"attestation 1", []byte(attestation1), webauthn.AttestationTypeUncertain, []*x509.Certificate{parseCertificate(attestation1CredCert)}},
+	{"attestation 2", []byte(attestation2), webauthn.AttestationTypeUncertain, []*x509.Certificate{parseCertificate(attestation2CredCert)}},
 }
 
 func parseCertificate(data []byte) *x509.Certificate {
@@ -191,6 +192,10 @@ func TestParseFIDOU2FAttestation(t *testing.T) {
 }
 
 func TestVerifyFIDOU2FAttestation(t *testing.T) {
+	// Attestation certificates of test data are verified while they were valid.
+	defer func(now func() time.Time) { webauthn.DefaultCertificatePolicy.Now = now }(webauthn.DefaultCertificatePolicy.Now)
+	webauthn.DefaultCertificatePolicy.Now = func() time.Time { return time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC) }
+
 	for _, tc := range verifyTests {
 		t.Run(tc.name, func(t *testing.T) {
 			var credentialAttestation webauthn.PublicKeyCredentialAttestation
@@ -0,0 +1,61 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package fidou2f
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"strings"
+
+	"github.com/kappapay/webauthn"
+)
+
+// Roots is a set of trusted U2F vendor root certificates keyed by attestation certificate key
+// identifier, which is how FIDO metadata statements identify U2F authenticators without AAGUID.
+type Roots = webauthn.CertificateStore
+
+// NewRoots returns an empty set of trusted root certificates, with key identifiers normalized to lower
+// case hex digits.
+func NewRoots() *Roots {
+	return webauthn.NewCertificateStore(strings.ToLower)
+}
+
+// DefaultRoots is the set of root certificates trusted to verify attestation certificates of fido-u2f
+// attestation statements, in addition to webauthn.DefaultTrustAnchors.  It is empty by default, fido-u2f
+// attestation statements are verified with attestation type uncertain until U2F vendor root certificates
+// are added.
+var DefaultRoots = NewRoots()
+
+// KeyIdentifier returns attestation certificate key identifier of c as lower case hex string, as used in
+// attestationCertificateKeyIdentifiers of FIDO metadata statements.  It is SHA-1 hash of subjectPublicKey
+// (method 1 of RFC 5280 section 4.2.1.2), regardless of the subject key identifier extension, which an
+// authenticator vendor may have computed with another method.
+func KeyIdentifier(c *x509.Certificate) string {
+	var spki struct {
+		Algorithm        asn1.RawValue
+		SubjectPublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(c.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return ""
+	}
+	sum := sha1.Sum(spki.SubjectPublicKey.RightAlign())
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package fidou2f
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/internal/testcert"
+)
+
+const attestation1KeyID = "a72096772326b1b282b286c3e7d64089bd7aaad9"
+
+// newTestRootCertificate returns self-signed root certificate.
+func newTestRootCertificate(name string, subjectKeyID []byte) *x509.Certificate {
+	return testcert.New(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		SubjectKeyId:          subjectKeyID,
+	}, nil).Cert
+}
+
+func TestRoots(t *testing.T) {
+	root1 := newTestRootCertificate("Test Root CA 1", nil)
+	root2 := newTestRootCertificate("Test Root CA 2", nil)
+
+	roots := NewRoots()
+	if certs := roots.Certificates(attestation1KeyID); len(certs) != 0 {
+		t.Errorf("roots %v, want none", certs)
+	}
+
+	roots.Add(attestation1KeyID, root1)
+	roots.Add(strings.ToUpper(attestation1KeyID), root1)
+	if certs := roots.Certificates(attestation1KeyID); len(certs) != 1 || !certs[0].Equal(root1) {
+		t.Errorf("roots %v, want root1", certs)
+	}
+	if certs := roots.Certificates("0102"); len(certs) != 0 {
+		t.Errorf("roots of other key identifier %v, want none", certs)
+	}
+
+	dir, err := ioutil.TempDir("", "fidou2f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "root.pem")
+	if err := ioutil.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root2.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := roots.AddPEMFile("0102", filename); err != nil {
+		t.Fatalf("AddPEMFile() returns error %q", err)
+	}
+	if certs := roots.Certificates("0102"); len(certs) != 1 || !certs[0].Equal(root2) {
+		t.Errorf("roots %v, want root2", certs)
+	}
+	if err := roots.AddPEMFile("0102", filepath.Join(dir, "missing.pem")); err == nil {
+		t.Errorf("AddPEMFile() of missing file returns no error")
+	}
+
+	wantErrorMsg := "no PEM encoded certificate"
+	if err := roots.AddPEM("0102", []byte("not pem")); err == nil {
+		t.Errorf("AddPEM() returns no error, want error containing substring %q", wantErrorMsg)
+	} else if !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("AddPEM() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	}
+
+	roots.Set(attestation1KeyID, nil)
+	if certs := roots.Certificates(attestation1KeyID); len(certs) != 0 {
+		t.Errorf("roots %v, want none", certs)
+	}
+}
+
+func TestKeyIdentifier(t *testing.T) {
+	// Attestation certificate without subject key identifier extension.
+	if keyID := KeyIdentifier(parseCertificate(attestation1CredCert)); keyID != attestation1KeyID {
+		t.Errorf("KeyIdentifier() = %s, want %s", keyID, attestation1KeyID)
+	}
+	// Certificate with subject key identifier extension not computed with method 1 of RFC 5280.
+	c := newTestRootCertificate("Test Root CA", []byte{0x0a, 0x0b, 0x0c})
+	var spki struct {
+		Algorithm        pkix.AlgorithmIdentifier
+		SubjectPublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(c.RawSubjectPublicKeyInfo, &spki); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum(spki.SubjectPublicKey.Bytes)
+	if keyID, want := KeyIdentifier(c), hex.EncodeToString(sum[:]); keyID != want {
+		t.Errorf("KeyIdentifier() = %s, want %s", keyID, want)
+	}
+}
+
+func TestVerifyFIDOU2FAttestationRoots(t *testing.T) {
+	savedRoots := DefaultRoots
+	defer func() { DefaultRoots = savedRoots }()
+
+	attestnCert := parseCertificate(attestation1CredCert)
+	testCases := []struct {
+		name          string
+		keyID         string
+		root          *x509.Certificate
+		wantAttType   webauthn.AttestationType
+		wantTrustPath interface{}
+		wantErrorMsg  string
+	}{
+		{
+			name:          "attestation certificate is trusted",
+			keyID:         attestation1KeyID,
+			root:          attestnCert,
+			wantAttType:   webauthn.AttestationTypeBasic,
+			wantTrustPath: []*x509.Certificate{attestnCert},
+		},
+		{
+			name:          "roots of other authenticator",
+			keyID:         "0102",
+			root:          attestnCert,
+			wantAttType:   webauthn.AttestationTypeUncertain,
+			wantTrustPath: []*x509.Certificate{attestnCert},
+		},
+		{
+			name:         "attestation certificate is not issued by trusted root",
+			keyID:        attestation1KeyID,
+			root:         newTestRootCertificate("Test Root CA", nil),
+			wantErrorMsg: "webauthn/fido_u2f_attestation: failed to verify certificate: x509: certificate signed by unknown authority",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			DefaultRoots = NewRoots()
+			DefaultRoots.Add(tc.keyID, tc.root)
+
+			var credentialAttestation webauthn.PublicKeyCredentialAttestation
+			if err := json.Unmarshal([]byte(attestation1), &credentialAttestation); err != nil {
+				t.Fatalf("failed to unmarshal attestation %s: %q", attestation1, err)
+			}
+			attType, trustPath, err := credentialAttestation.VerifyAttestationStatement()
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyAttestationStatement() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyAttestationStatement() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyAttestationStatement() returns error %q", err)
+			}
+			if attType != tc.wantAttType {
+				t.Errorf("attestation type %v, want %v", attType, tc.wantAttType)
+			}
+			if !reflect.DeepEqual(trustPath, tc.wantTrustPath) {
+				t.Errorf("trust path %v, want %v", trustPath, tc.wantTrustPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+// Package appidentity matches Android app identities reported by android-key and android-safetynet
+// attestations against allowed package names and digests.
+package appidentity
+
+import "bytes"
+
+// AllowsName returns true if name is in allowed names, or if allowed names is empty.
+func AllowsName(allowed []string, name string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, v := range allowed {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsDigest returns true if digest is in allowed digests, or if allowed digests is empty.
+func AllowsDigest(allowed [][]byte, digest []byte) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, v := range allowed {
+		if bytes.Equal(v, digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchDigest returns the first of digests that is allowed.  If allowed digests is empty, any digest
+// is allowed, and nil is returned without digests.  ok is false if none of digests is allowed.
+func MatchDigest(allowed [][]byte, digests [][]byte) (digest []byte, ok bool) {
+	for _, d := range digests {
+		if AllowsDigest(allowed, d) {
+			return d, true
+		}
+	}
+	return nil, len(allowed) == 0
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+// Package applenonce verifies the nonce certificate extension of Apple anonymous attestation and
+// App Attest credential certificates.
+package applenonce
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+)
+
+// OID is the object identifier of the nonce certificate extension.
+var OID = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 8, 2}
+
+// Verify verifies that certificate c has the nonce certificate extension with the given nonce.
+func Verify(c *x509.Certificate, nonce []byte) error {
+	type appleAnonymousAttestation struct {
+		Nonce []byte `asn1:"tag:1,explicit"`
+	}
+	for _, ext := range c.Extensions {
+		if ext.Id.Equal(OID) {
+			var v appleAnonymousAttestation
+			if rest, err := asn1.Unmarshal(ext.Value, &v); err != nil {
+				return errors.New("failed to unmarshal certificate extension: " + err.Error())
+			} else if len(rest) != 0 {
+				return errors.New("trailing data after certificate extension")
+			}
+			if !bytes.Equal(v.Nonce, nonce) {
+				return errors.New("nonce does not match certificate extension")
+			}
+			return nil
+		}
+	}
+	return errors.New("certificate extension is missing")
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+// Package testcert generates certificates and keys for tests of attestation certificate chains.
+package testcert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"sync/atomic"
+	"time"
+)
+
+// Default validity period of generated certificates.
+var (
+	NotBefore = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	NotAfter  = time.Date(2040, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// serialNumber is the last serial number given to a certificate without one.  It starts high above
+// serial numbers chosen by tests, so generated serial numbers don't collide with them.
+var serialNumber int64 = 1 << 32
+
+// CA is a certificate and its private key.  Despite its name, it is also used for leaf certificates.
+type CA struct {
+	Key  *ecdsa.PrivateKey
+	Cert *x509.Certificate
+}
+
+// NewKey returns a new P-256 ECDSA private key.
+func NewKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// NewCertificate returns certificate of template for public key pub issued by parent.  Serial number
+// and validity period are filled in if template doesn't have them.
+func NewCertificate(template *x509.Certificate, pub crypto.PublicKey, parent *CA) *x509.Certificate {
+	if template.SerialNumber == nil {
+		template.SerialNumber = big.NewInt(atomic.AddInt64(&serialNumber, 1))
+	}
+	if template.NotBefore.IsZero() {
+		template.NotBefore = NotBefore
+	}
+	if template.NotAfter.IsZero() {
+		template.NotAfter = NotAfter
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent.Cert, pub, parent.Key)
+	if err != nil {
+		panic(err)
+	}
+	c, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// New returns certificate of template with a new key, issued by parent, or self-signed if parent is nil.
+func New(template *x509.Certificate, parent *CA) *CA {
+	key := NewKey()
+	if parent == nil {
+		parent = &CA{Key: key, Cert: template}
+	}
+	return &CA{Key: key, Cert: NewCertificate(template, &key.PublicKey, parent)}
+}
+
+// NewCA returns CA named name issued by parent, or self-signed root CA if parent is nil.
+func NewCA(name string, parent *CA) *CA {
+	return New(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, parent)
+}
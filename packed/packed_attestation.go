@@ -27,6 +27,7 @@ import (
 
 	"github.com/fxamacker/cbor/v2"
 	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/ecdaa"
 )
 
 var oidPackedCertificateExt = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
@@ -65,7 +66,12 @@ func parseAttestation(data []byte) (webauthn.AttestationStatement, error) {
 
 	attStmt := &packedAttestationStatement{sig: raw.Sig}
 
-	if attStmt.SignatureAlgorithm, err = webauthn.CoseAlgToSignatureAlgorithm(raw.Alg); err != nil {
+	if len(raw.ECDAAKeyID) > 0 {
+		attStmt.ecdaaKeyID = raw.ECDAAKeyID
+		if attStmt.SignatureAlgorithm, err = ecdaa.SignatureAlgorithm(raw.Alg); err != nil {
+			return nil, err
+		}
+	} else if attStmt.SignatureAlgorithm, err = webauthn.CoseAlgToSignatureAlgorithm(raw.Alg); err != nil {
 		return nil, err
 	}
 
@@ -81,11 +87,6 @@ func parseAttestation(data []byte) (webauthn.AttestationStatement, error) {
 		}
 	}
 
-	if len(raw.ECDAAKeyID) > 0 {
-		attStmt.ecdaaKeyID = raw.ECDAAKeyID
-		return nil, &webauthn.UnsupportedFeatureError{Feature: "Elliptic Curve based Direct Anonymous Attestation (ECDAA)"}
-	}
-
 	return attStmt, nil
 }
 
@@ -106,13 +107,14 @@ func (attStmt *packedAttestationStatement) Verify(clientDataHash []byte, authnDa
 			return
 		}
 
-		// Verify leaf certificate by building certificate chain.
-		if trustPath, err = verifyAttestationCert(attStmt.attestnCert, attStmt.caCerts); err != nil {
+		// Verify leaf certificate by building certificate chain to a trusted root.
+		var trusted bool
+		if trustPath, trusted, err = webauthn.VerifyAttestationCertificate("packed", authnData.AAGUID, attStmt.attestnCert, attStmt.caCerts, nil); err != nil {
 			err = &webauthn.VerificationError{Type: "packed attestation", Field: "certificate", Msg: err.Error()}
 			return
 		}
 
-		// todo: check for revocation
+		// Revocation status of trust path is checked by webauthn.VerifyRegistration with AttestationExpectedData.Revocation.
 
 		// Verify that attestnCert meets requirements.
 		if err = verifyPackedAttestationStatementCert(attStmt.attestnCert); err != nil {
@@ -128,13 +130,32 @@ func (attStmt *packedAttestationStatement) Verify(clientDataHash []byte, authnDa
 		}
 
 		// Optionally, inspect x5c and consult externally provided knowledge to determine whether
-		// attStmt conveys a Basic or AttCA attestation.
+		// attStmt conveys a Basic or AttCA attestation.  Without a trusted root, attestation type is uncertain.
+		if !trusted {
+			return webauthn.AttestationTypeUncertain, trustPath, nil
+		}
 
 		// If successful, return implementation-specific values representing attestation type Basic,
 		// AttCA or uncertainty, and attestation trust path x5c.
 		return webauthn.AttestationTypeBasic, trustPath, nil
 	} else if len(attStmt.ecdaaKeyID) > 0 {
-		return webauthn.AttestationTypeECDAA, attStmt.ecdaaKeyID, &webauthn.UnsupportedFeatureError{Feature: "Elliptic Curve based Direct Anonymous Attestation (ECDAA)"}
+		// Look up ECDAA-Issuer public key identified by ecdaaKeyId.
+		var ipk *ecdaa.IssuerPublicKey
+		if ipk, err = ecdaa.ResolveIssuerKey(attStmt.ecdaaKeyID); err != nil {
+			err = &webauthn.VerificationError{Type: "packed attestation", Field: "ecdaaKeyId", Msg: err.Error()}
+			return
+		}
+
+		// Perform ECDAA-Verify on sig to verify that it is a valid signature over the concatenation of
+		// authenticatorData and clientDataHash using ECDAA-Issuer public key identified by ecdaaKeyId.
+		if err = ipk.Verify(attStmt.sig, signed); err != nil {
+			err = &webauthn.VerificationError{Type: "packed attestation", Field: "signature", Msg: err.Error()}
+			return
+		}
+
+		// If successful, return implementation-specific values representing attestation type ECDAA and
+		// attestation trust path ECDAA-Issuer public key.
+		return webauthn.AttestationTypeECDAA, ipk, nil
 	} else {
 		// Validate that alg matches the algorithm of credentialPublicKey in authenticatorData.
 		if attStmt.Algorithm != authnData.Credential.Algorithm {
@@ -155,33 +176,6 @@ func (attStmt *packedAttestationStatement) Verify(clientDataHash []byte, authnDa
 	}
 }
 
-func verifyAttestationCert(attestnCert *x509.Certificate, caCerts []*x509.Certificate) (trustPath []*x509.Certificate, err error) {
-	var verifyOptions x509.VerifyOptions
-
-	if len(caCerts) > 0 {
-		lastCert := caCerts[len(caCerts)-1]
-		if bytes.Equal(lastCert.RawIssuer, lastCert.RawSubject) && lastCert.IsCA {
-			caCerts = caCerts[:len(caCerts)-1]
-
-			verifyOptions.Roots = x509.NewCertPool()
-			verifyOptions.Roots.AddCert(lastCert)
-		}
-		if len(caCerts) > 0 {
-			verifyOptions.Intermediates = x509.NewCertPool()
-			for _, c := range caCerts {
-				verifyOptions.Intermediates.AddCert(c)
-			}
-		}
-	}
-
-	var chains [][]*x509.Certificate
-	chains, err = attestnCert.Verify(verifyOptions)
-	if err != nil {
-		return nil, err
-	}
-	return chains[0], nil
-}
-
 func verifyPackedAttestationStatementCert(c *x509.Certificate) error {
 	// Version MUST be set to 3 (which is indicated by an ASN.1 INTEGER with value 2).
 	if c.Version != 3 {
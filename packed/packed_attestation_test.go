@@ -26,7 +26,10 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/fxamacker/cbor/v2"
 	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/ecdaa"
+	"github.com/kappapay/webauthn/internal/testcert"
 )
 
 var (
@@ -239,7 +242,7 @@ var parseTests = []parseTest{
 }
 
 var verifyTests = []verifyTest{
-	{"basic attestation 1", []byte(basicAttestation1), webauthn.AttestationTypeBasic, []*x509.Certificate{parseCertificate(basicAttestation1CredCert), parseCertificate(basicAttestation1CACert0), parseCertificate(basicAttestation1CACert1)}},
+	{"basic attestation 1 without trusted root", []byte(basicAttestation1), webauthn.AttestationTypeUncertain, []*x509.Certificate{parseCertificate(basicAttestation1CredCert), parseCertificate(basicAttestation1CACert0), parseCertificate(basicAttestation1CACert1)}},
 	{"self attestation 1", []byte(selfAttestation1), webauthn.AttestationTypeSelf, nil},
 }
 
@@ -323,6 +326,9 @@ func TestVerifyPackedAttestation(t *testing.T) {
 }
 
 func TestVerifyPackedAttestationError(t *testing.T) {
+	webauthn.DefaultTrustAnchors.Set("packed", nil, []*x509.Certificate{parseCertificate(basicAttestation1CACert1)})
+	defer webauthn.DefaultTrustAnchors.Set("packed", nil, nil)
+
 	for _, tc := range verifyErrorTests {
 		t.Run(tc.name, func(t *testing.T) {
 			var credentialAttestation webauthn.PublicKeyCredentialAttestation
@@ -337,3 +343,211 @@ func TestVerifyPackedAttestationError(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyPackedAttestationTrustAnchors(t *testing.T) {
+	feitianRoot := parseCertificate(basicAttestation1CACert1)
+	feitianAAGUID := []byte("B82ED73C8FB4E5A2")
+	otherAAGUID := []byte("0123456789ABCDEF")
+	otherRoot := testcert.NewCA("Other Root CA", nil).Cert
+	wantTrustPath := []*x509.Certificate{parseCertificate(basicAttestation1CredCert), parseCertificate(basicAttestation1CACert0), feitianRoot}
+	x5c := []*x509.Certificate{parseCertificate(basicAttestation1CredCert), parseCertificate(basicAttestation1CACert0), feitianRoot}
+
+	testCases := []struct {
+		name          string
+		format        string
+		aaguid        []byte
+		root          *x509.Certificate
+		wantAttType   webauthn.AttestationType
+		wantTrustPath []*x509.Certificate
+		wantErrorMsg  string
+	}{
+		{"root for format", "packed", nil, feitianRoot, webauthn.AttestationTypeBasic, wantTrustPath, ""},
+		{"root for AAGUID", "", feitianAAGUID, feitianRoot, webauthn.AttestationTypeBasic, wantTrustPath, ""},
+		{"root for format and AAGUID", "packed", feitianAAGUID, feitianRoot, webauthn.AttestationTypeBasic, wantTrustPath, ""},
+		{"global root", "", nil, feitianRoot, webauthn.AttestationTypeBasic, wantTrustPath, ""},
+		{"root for other format", "tpm", nil, feitianRoot, webauthn.AttestationTypeUncertain, x5c, ""},
+		{"root for other AAGUID", "packed", otherAAGUID, feitianRoot, webauthn.AttestationTypeUncertain, x5c, ""},
+		{"self-signed root in x5c isn't trusted", "packed", nil, otherRoot, 0, nil, "certificate signed by unknown authority"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			anchors := webauthn.NewTrustAnchors()
+			anchors.Add(tc.format, tc.aaguid, tc.root)
+			savedAnchors := webauthn.DefaultCertificatePolicy.TrustAnchors
+			webauthn.DefaultCertificatePolicy.TrustAnchors = anchors
+			defer func() { webauthn.DefaultCertificatePolicy.TrustAnchors = savedAnchors }()
+
+			var credentialAttestation webauthn.PublicKeyCredentialAttestation
+			if err := json.Unmarshal([]byte(basicAttestation1), &credentialAttestation); err != nil {
+				t.Fatalf("failed to unmarshal attestation %s: %q", basicAttestation1, err)
+			}
+			attType, trustPath, err := credentialAttestation.VerifyAttestationStatement()
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyAttestationStatement() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyAttestationStatement() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyAttestationStatement() returns error %q", err)
+			}
+			if attType != tc.wantAttType {
+				t.Errorf("attestation type %v, want %v", attType, tc.wantAttType)
+			}
+			if !reflect.DeepEqual(trustPath, tc.wantTrustPath) {
+				t.Errorf("trust path %v, want %v", trustPath, tc.wantTrustPath)
+			}
+		})
+	}
+}
+
+// ECDAA-Issuer public key and ECDAA signature over 37 bytes of 0x01 authenticator data and 32 bytes of 0x02
+// client data hash, generated with a test ECDAA-Issuer.
+var (
+	ecdaaIssuerX = []byte{
+		0x04, 0xf0, 0x4d, 0x3e, 0x05, 0x6b, 0x9c, 0xfe, 0xc2, 0x9e, 0x1c, 0x55, 0xd1, 0x87, 0x04, 0x35,
+		0xd6, 0x63, 0x76, 0xa5, 0xea, 0x82, 0xef, 0x73, 0xd2, 0xe4, 0xad, 0x00, 0xf2, 0xa7, 0xc2, 0xa1,
+		0x64, 0x95, 0x5b, 0xf2, 0xa0, 0x04, 0x06, 0xa1, 0x8a, 0xfa, 0x33, 0x28, 0xea, 0x0b, 0x75, 0xc4,
+		0xd4, 0x0c, 0x06, 0x87, 0x6f, 0x3f, 0xdc, 0x4f, 0x51, 0x27, 0x21, 0x50, 0x4b, 0x19, 0xcc, 0xb6,
+		0x79, 0xa9, 0x17, 0x7a, 0x78, 0xb7, 0xc4, 0xf4, 0x4a, 0x98, 0x43, 0xac, 0x4c, 0x22, 0x6f, 0x9b,
+		0x31, 0xdf, 0x85, 0xfe, 0xc8, 0x22, 0x8f, 0x66, 0x03, 0x5d, 0x4e, 0x95, 0x5b, 0x42, 0xf4, 0xc7,
+		0x89, 0x01, 0x52, 0xe3, 0x75, 0xd9, 0x19, 0x45, 0xa4, 0x9a, 0x47, 0xbb, 0xcc, 0x72, 0x4b, 0xc7,
+		0xba, 0xde, 0x1e, 0x08, 0x04, 0xb5, 0xaa, 0x1e, 0xaf, 0x25, 0xde, 0x91, 0x19, 0x54, 0xe6, 0x60,
+		0xf7,
+	}
+	ecdaaIssuerY = []byte{
+		0x04, 0x35, 0x7c, 0x37, 0xbb, 0x52, 0xea, 0xd4, 0xc1, 0x90, 0xfb, 0x58, 0x25, 0x29, 0xa2, 0xf9,
+		0x97, 0xf4, 0x21, 0xa3, 0x82, 0x06, 0x57, 0x23, 0xd9, 0xd2, 0xaa, 0x4f, 0x15, 0xef, 0xc1, 0xc3,
+		0x1f, 0x24, 0x28, 0x64, 0x30, 0x9d, 0xe9, 0xd9, 0x76, 0xd1, 0x47, 0xfa, 0xb5, 0x61, 0xd5, 0x37,
+		0x16, 0xee, 0x62, 0x7f, 0xdc, 0x58, 0x85, 0xb8, 0xcb, 0x83, 0xe5, 0xdc, 0xd5, 0x6e, 0x1a, 0xd2,
+		0x1c, 0xbf, 0xff, 0xad, 0x0b, 0x79, 0x4b, 0xbd, 0x51, 0x00, 0x76, 0xf8, 0xde, 0xef, 0xd6, 0x69,
+		0xaa, 0xab, 0xef, 0x83, 0xac, 0x4f, 0xad, 0x32, 0x7a, 0xe6, 0x71, 0xb2, 0xda, 0x06, 0xb3, 0xc3,
+		0x3d, 0x02, 0x40, 0x84, 0x75, 0xe9, 0xfd, 0x57, 0x77, 0xfc, 0xc7, 0x55, 0xe7, 0xef, 0x75, 0x1c,
+		0x1a, 0x3e, 0x49, 0x1f, 0x25, 0x42, 0x56, 0xc0, 0x51, 0xb5, 0xee, 0x7e, 0xcd, 0x34, 0x69, 0x2b,
+		0x15,
+	}
+	ecdaaIssuerC = []byte{
+		0xdd, 0x4f, 0xce, 0x53, 0xb8, 0x19, 0xb0, 0xa7, 0x15, 0x24, 0x68, 0x1f, 0x0f, 0xfa, 0x85, 0x99,
+		0x05, 0x64, 0x50, 0xe4, 0x86, 0xc2, 0xb4, 0x65, 0xda, 0x02, 0x04, 0xac, 0x87, 0x87, 0xed, 0xcb,
+	}
+	ecdaaIssuerSx = []byte{
+		0xfe, 0x7c, 0xee, 0x7a, 0xf4, 0x71, 0x7b, 0x5a, 0xb2, 0x66, 0xec, 0x9f, 0xdb, 0x3f, 0xa1, 0x1a,
+		0x8e, 0x28, 0x43, 0x72, 0xc7, 0x55, 0xbc, 0xf4, 0xb9, 0x4f, 0x4f, 0x19, 0x4f, 0x6e, 0xbd, 0x7e,
+	}
+	ecdaaIssuerSy = []byte{
+		0x50, 0xab, 0xe9, 0xc7, 0x55, 0xa8, 0x57, 0x19, 0xab, 0xad, 0xf7, 0x27, 0x6e, 0x16, 0xa9, 0xa1,
+		0x2f, 0x4a, 0x5a, 0x0d, 0xdd, 0x00, 0xa1, 0x4d, 0x75, 0x49, 0x1e, 0xa2, 0x21, 0x0f, 0x04, 0x4e,
+	}
+	ecdaaSig = []byte{
+		0xd5, 0xb6, 0xd3, 0x81, 0xf4, 0x2d, 0x04, 0x48, 0x9c, 0x53, 0xf9, 0x6e, 0x1b, 0x94, 0xc8, 0xfb,
+		0xc5, 0xd3, 0x91, 0x6a, 0x82, 0x47, 0x4b, 0x8d, 0x75, 0x91, 0x94, 0x63, 0x38, 0xae, 0xd6, 0xb4,
+		0x42, 0xf4, 0x55, 0x06, 0x1b, 0xbe, 0xfd, 0x49, 0xa7, 0x49, 0x48, 0x6a, 0xf3, 0x1f, 0x8d, 0x48,
+		0x9d, 0x78, 0xe9, 0xdc, 0xa6, 0xde, 0x00, 0x1b, 0x2b, 0x22, 0xad, 0x86, 0x80, 0xcd, 0xeb, 0xf5,
+		0x04, 0x68, 0x43, 0xc0, 0x66, 0xcc, 0xbb, 0x24, 0xc6, 0x78, 0x54, 0x2e, 0x1d, 0xf2, 0xf2, 0x32,
+		0xe4, 0x86, 0xaa, 0x1d, 0x7c, 0x1e, 0x05, 0x18, 0xc6, 0xc1, 0x2d, 0xa1, 0x38, 0x9b, 0x5c, 0xa5,
+		0xef, 0x79, 0xba, 0xfe, 0x9d, 0x11, 0xa4, 0xcb, 0xe0, 0x4d, 0x2b, 0x6e, 0xba, 0xb3, 0x8a, 0xea,
+		0xc9, 0xdf, 0x62, 0xb0, 0xfd, 0xc5, 0xe2, 0x58, 0x96, 0x5a, 0x90, 0xe8, 0x83, 0x9c, 0x56, 0x0f,
+		0xf9, 0x04, 0xd9, 0x7f, 0x68, 0x29, 0x5d, 0xe8, 0x11, 0xd2, 0xcb, 0xc7, 0x36, 0xd5, 0xa9, 0x5a,
+		0xcb, 0x2c, 0x01, 0x31, 0x83, 0xef, 0xa8, 0x81, 0x53, 0x7e, 0x99, 0x37, 0xd1, 0xb6, 0x37, 0x1d,
+		0xb3, 0x71, 0x4a, 0xe2, 0x14, 0xe6, 0x9d, 0x34, 0x90, 0xef, 0x5d, 0xa2, 0x75, 0xdd, 0xc5, 0x9c,
+		0xa5, 0x69, 0x5f, 0xe4, 0x2f, 0x0a, 0xe0, 0x80, 0x00, 0x0f, 0xda, 0xfd, 0x8b, 0x13, 0xfc, 0x32,
+		0x41, 0x42, 0x04, 0xd0, 0xcf, 0x1f, 0x77, 0x76, 0xb1, 0xab, 0xb6, 0xe9, 0x8c, 0x39, 0x51, 0x8d,
+		0xcf, 0x7e, 0xbc, 0x1f, 0x2b, 0x5b, 0x6a, 0xfa, 0xab, 0x0f, 0xfd, 0x88, 0x73, 0x7c, 0x99, 0x2b,
+		0x87, 0xa1, 0x7f, 0x7c, 0x7e, 0xbb, 0x1b, 0xec, 0x37, 0xca, 0x9b, 0x06, 0xe0, 0xf8, 0x53, 0x6c,
+		0x50, 0x25, 0x52, 0x5c, 0x62, 0xf3, 0x73, 0xb2, 0xcc, 0x0b, 0x4a, 0x89, 0x7b, 0xa5, 0xd0, 0x04,
+		0xc7, 0x7b, 0xab, 0x04, 0xba, 0xe8, 0x29, 0xf2, 0x3f, 0xab, 0x4c, 0x28, 0xde, 0x7a, 0x8f, 0x1e,
+		0xf2, 0x7b, 0xda, 0x0b, 0xf7, 0x5d, 0xff, 0x42, 0xe8, 0x31, 0xd4, 0x9d, 0x46, 0x92, 0x63, 0xd9,
+		0x9c, 0xc2, 0x2d, 0xf3, 0x39, 0xb1, 0xd4, 0xfc, 0x50, 0xf1, 0x3c, 0x43, 0x8e, 0x0b, 0x54, 0xa4,
+		0x90, 0x8b, 0x32, 0xae, 0x01, 0x08, 0xe0, 0x78, 0xc7, 0x54, 0x26, 0x7f, 0x2a, 0x65, 0xba, 0x85,
+		0xe8, 0x80, 0xd5, 0x0c, 0x33, 0xf1, 0xc3, 0xdb, 0xae, 0xd7, 0xfc, 0x71, 0x79, 0x93, 0x1f, 0x52,
+		0x07, 0xce, 0xb7, 0x75, 0xdc, 0x77, 0x4e, 0x5e, 0x99, 0xd1, 0x7b, 0x22, 0x1b, 0xb3, 0x20, 0xc6,
+		0x19, 0x7b, 0xa2, 0x4c,
+	}
+)
+
+func TestParsePackedECDAAAttestation(t *testing.T) {
+	testCases := []struct {
+		name         string
+		alg          int
+		wantErrorMsg string
+	}{
+		{"ED256", webauthn.COSEAlgED256, ""},
+		{"ES256", webauthn.COSEAlgES256, "ECDAA COSE algorithm -7 is not supported"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := cbor.Marshal(map[string]interface{}{"alg": tc.alg, "sig": ecdaaSig, "ecdaaKeyId": ecdaaIssuerC})
+			if err != nil {
+				t.Fatal(err)
+			}
+			attStmt, err := parseAttestation(data)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("parseAttestation() returns error %q", err)
+			} else if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("parseAttestation() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("parseAttestation() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if keyID := attStmt.(*packedAttestationStatement).ecdaaKeyID; !bytes.Equal(keyID, ecdaaIssuerC) {
+				t.Errorf("attestation ecdaaKeyID %x, want %x", keyID, ecdaaIssuerC)
+			}
+		})
+	}
+}
+
+func TestVerifyPackedECDAAAttestation(t *testing.T) {
+	ipk, err := ecdaa.ParseIssuerPublicKey(ecdaaIssuerX, ecdaaIssuerY, ecdaaIssuerC, ecdaaIssuerSx, ecdaaIssuerSy)
+	if err != nil {
+		t.Fatalf("ParseIssuerPublicKey() returns error %q", err)
+	}
+	savedResolver := ecdaa.DefaultResolver
+	defer func() { ecdaa.DefaultResolver = savedResolver }()
+	keys := ecdaa.NewIssuerKeys()
+	keys.Add(ipk)
+	ecdaa.DefaultResolver = keys
+
+	alg, err := ecdaa.SignatureAlgorithm(webauthn.COSEAlgED256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authnData := &webauthn.AuthenticatorData{Raw: bytes.Repeat([]byte{0x01}, 37)}
+	clientDataHash := bytes.Repeat([]byte{0x02}, 32)
+
+	testCases := []struct {
+		name           string
+		keyID          []byte
+		clientDataHash []byte
+		wantErrorMsg   string
+	}{
+		{"valid", ipk.KeyID(), clientDataHash, ""},
+		{"untrusted ECDAA-Issuer public key", []byte{0x01, 0x02}, clientDataHash, "ecdaaKeyId: ecdaa: ECDAA-Issuer public key 0102 is not trusted"},
+		{"wrong client data hash", ipk.KeyID(), bytes.Repeat([]byte{0x03}, 32), "signature: ecdaa: signature does not match message"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attStmt := &packedAttestationStatement{SignatureAlgorithm: alg, sig: ecdaaSig, ecdaaKeyID: tc.keyID}
+			attType, trustPath, err := attStmt.Verify(tc.clientDataHash, authnData)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("Verify() returns error %q", err)
+			} else if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("Verify() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("Verify() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if attType != webauthn.AttestationTypeECDAA {
+				t.Errorf("attestation type %v, want %v", attType, webauthn.AttestationTypeECDAA)
+			}
+			if trustPath != ipk {
+				t.Errorf("trust path %v, want ECDAA-Issuer public key", trustPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn
+
+// AuthenticationExtensionsPaymentInputs represents the Secure Payment Confirmation structure of the same name,
+// as defined in https://w3c.github.io/secure-payment-confirmation/#sctn-payment-extension-registration
+type AuthenticationExtensionsPaymentInputs struct {
+	IsPayment bool `json:"isPayment,omitempty"` // Register the credential for use with Secure Payment Confirmation.
+}
+
+// PaymentCurrencyAmount represents the Payment Request API structure of the same name,
+// as defined in https://w3c.github.io/payment-request/#dom-paymentcurrencyamount
+type PaymentCurrencyAmount struct {
+	Currency string `json:"currency"` // ISO 4217 currency code.
+	Value    string `json:"value"`    // Monetary value as a decimal string.
+}
+
+// PaymentCredentialInstrument represents the Secure Payment Confirmation structure of the same name,
+// as defined in https://w3c.github.io/secure-payment-confirmation/#dictdef-paymentcredentialinstrument
+type PaymentCredentialInstrument struct {
+	DisplayName     string `json:"displayName"`               // Name of the payment instrument shown to the user.
+	Icon            string `json:"icon"`                      // URL of the payment instrument icon shown to the user.
+	IconMustBeShown bool   `json:"iconMustBeShown,omitempty"` // Icon must be successfully fetched and shown.
+}
+
+// CollectedClientAdditionalPaymentData represents the Secure Payment Confirmation structure of the same name,
+// as defined in https://w3c.github.io/secure-payment-confirmation/#dictdef-collectedclientadditionalpaymentdata
+type CollectedClientAdditionalPaymentData struct {
+	RPID        string                      `json:"rpId"`                  // RP ID of the payment credential.
+	TopOrigin   string                      `json:"topOrigin"`             // Origin of the top-level context that initiated the payment.
+	PayeeName   string                      `json:"payeeName,omitempty"`   // Name of the payee shown to the user.
+	PayeeOrigin string                      `json:"payeeOrigin,omitempty"` // Origin of the payee shown to the user.
+	Total       PaymentCurrencyAmount       `json:"total"`                 // Transaction amount shown to the user.
+	Instrument  PaymentCredentialInstrument `json:"instrument"`            // Payment instrument shown to the user.
+}
+
+// PaymentExpectedData represents the transaction data needed to verify Secure Payment Confirmation assertions.
+type PaymentExpectedData struct {
+	TopOrigin   string
+	PayeeName   string
+	PayeeOrigin string
+	Total       PaymentCurrencyAmount
+	Instrument  PaymentCredentialInstrument
+}
+
+// verifyPayment verifies payment member of client data against expected transaction and RP ID,
+// as defined in https://w3c.github.io/secure-payment-confirmation/#sctn-verifying-assertion
+func verifyPayment(clientPayment *CollectedClientAdditionalPaymentData, rpID string, expected *PaymentExpectedData) error {
+	if clientPayment == nil {
+		return &VerificationError{Type: "assertion", Field: "client data payment", Msg: "payment is missing"}
+	}
+	if clientPayment.RPID != rpID {
+		return &VerificationError{Type: "assertion", Field: "client data payment rp ID", Msg: "expected \"" + rpID + "\", got \"" + clientPayment.RPID + "\""}
+	}
+	if clientPayment.TopOrigin != expected.TopOrigin {
+		return &VerificationError{Type: "assertion", Field: "client data payment top origin", Msg: "expected \"" + expected.TopOrigin + "\", got \"" + clientPayment.TopOrigin + "\""}
+	}
+	if clientPayment.PayeeName != expected.PayeeName {
+		return &VerificationError{Type: "assertion", Field: "client data payment payee name", Msg: "expected \"" + expected.PayeeName + "\", got \"" + clientPayment.PayeeName + "\""}
+	}
+	if clientPayment.PayeeOrigin != expected.PayeeOrigin {
+		return &VerificationError{Type: "assertion", Field: "client data payment payee origin", Msg: "expected \"" + expected.PayeeOrigin + "\", got \"" + clientPayment.PayeeOrigin + "\""}
+	}
+	if clientPayment.Total != expected.Total {
+		return &VerificationError{Type: "assertion", Field: "client data payment total", Msg: "expected " + expected.Total.Value + " " + expected.Total.Currency + ", got " + clientPayment.Total.Value + " " + clientPayment.Total.Currency}
+	}
+	if clientPayment.Instrument != expected.Instrument {
+		return &VerificationError{Type: "assertion", Field: "client data payment instrument", Msg: "payment instrument does not match expected payment instrument"}
+	}
+	return nil
+}
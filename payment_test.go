@@ -0,0 +1,119 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kappapay/webauthn"
+)
+
+func TestVerifyAuthenticationPayment(t *testing.T) {
+	authenticator := newTestAuthenticator()
+	challenge := "eaTyUNnyPDDdK8SNEgTEUvz1Q8dylkjjTimYd5X7QAo"
+	expectedPayment := &webauthn.PaymentExpectedData{
+		TopOrigin:   "https://merchant.com",
+		PayeeOrigin: "https://merchant.com",
+		Total:       webauthn.PaymentCurrencyAmount{Currency: "USD", Value: "12.34"},
+		Instrument:  webauthn.PaymentCredentialInstrument{DisplayName: "Fancy Card ****1234", Icon: "https://acme.com/card.png"},
+	}
+	payment := map[string]interface{}{
+		"rpId":        "acme.com",
+		"topOrigin":   "https://merchant.com",
+		"payeeOrigin": "https://merchant.com",
+		"total":       map[string]string{"currency": "USD", "value": "12.34"},
+		"instrument":  map[string]string{"displayName": "Fancy Card ****1234", "icon": "https://acme.com/card.png"},
+	}
+	withPayment := func(key string, value interface{}) map[string]interface{} {
+		p := make(map[string]interface{})
+		for k, v := range payment {
+			p[k] = v
+		}
+		p[key] = value
+		return p
+	}
+
+	testCases := []struct {
+		name            string
+		clientDataType  string
+		payment         map[string]interface{}
+		expectedPayment *webauthn.PaymentExpectedData
+		wantErrorMsg    string
+	}{
+		{"payment", "payment.get", payment, expectedPayment, ""},
+		{"payment client data without expected payment", "payment.get", payment, nil, "assertion: failed to verify client data type: expected \"webauthn.get\", got \"payment.get\""},
+		{"webauthn client data with expected payment", "webauthn.get", nil, expectedPayment, "assertion: failed to verify client data type: expected \"payment.get\", got \"webauthn.get\""},
+		{"wrong rp id", "payment.get", withPayment("rpId", "bank.com"), expectedPayment, "assertion: failed to verify client data payment rp ID"},
+		{"wrong top origin", "payment.get", withPayment("topOrigin", "https://evil.com"), expectedPayment, "assertion: failed to verify client data payment top origin"},
+		{"wrong payee name", "payment.get", withPayment("payeeName", "Evil Corp"), expectedPayment, "assertion: failed to verify client data payment payee name"},
+		{"wrong payee origin", "payment.get", withPayment("payeeOrigin", "https://evil.com"), expectedPayment, "assertion: failed to verify client data payment payee origin"},
+		{"wrong total value", "payment.get", withPayment("total", map[string]string{"currency": "USD", "value": "99.99"}), expectedPayment, "assertion: failed to verify client data payment total: expected 12.34 USD, got 99.99 USD"},
+		{"wrong total currency", "payment.get", withPayment("total", map[string]string{"currency": "EUR", "value": "12.34"}), expectedPayment, "assertion: failed to verify client data payment total: expected 12.34 USD, got 12.34 EUR"},
+		{"wrong instrument", "payment.get", withPayment("instrument", map[string]string{"displayName": "Other Card", "icon": "https://acme.com/card.png"}), expectedPayment, "assertion: failed to verify client data payment instrument"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rawClientData := map[string]interface{}{"type": tc.clientDataType, "challenge": challenge, "origin": "https://merchant.com"}
+			if tc.payment != nil {
+				rawClientData["payment"] = tc.payment
+			}
+			clientData, err := json.Marshal(rawClientData)
+			if err != nil {
+				t.Fatalf("failed to marshal client data: %q", err)
+			}
+			authnData := authenticator.authenticatorData("acme.com", 0x05, 1, nil)
+			credentialAssertion, err := webauthn.ParseAssertion(bytes.NewReader(authenticator.assertion(authnData, clientData, nil)))
+			if err != nil {
+				t.Fatalf("ParseAssertion() returns error %q", err)
+			}
+			expected := &webauthn.AssertionExpectedData{
+				Origin:           "https://merchant.com",
+				RPID:             "acme.com",
+				Challenge:        challenge,
+				UserVerification: webauthn.UserVerificationRequired,
+				Credential:       authenticator.credential(),
+				Payment:          tc.expectedPayment,
+			}
+			_, err = webauthn.VerifyAuthentication(credentialAssertion, expected)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("VerifyAuthentication() returns error %q", err)
+			} else if tc.wantErrorMsg != "" && err == nil {
+				t.Errorf("VerifyAuthentication() returns no error, want error containing substring %q", tc.wantErrorMsg)
+			} else if tc.wantErrorMsg != "" && !strings.Contains(err.Error(), tc.wantErrorMsg) {
+				t.Errorf("VerifyAuthentication() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+			}
+		})
+	}
+}
+
+func TestParseAssertionPaymentMissing(t *testing.T) {
+	authenticator := newTestAuthenticator()
+	clientData := []byte(`{"type":"payment.get","challenge":"eaTyUNnyPDDdK8SNEgTEUvz1Q8dylkjjTimYd5X7QAo","origin":"https://merchant.com"}`)
+	authnData := authenticator.authenticatorData("acme.com", 0x05, 1, nil)
+	wantErrorMsg := "client_data: missing payment"
+	_, err := webauthn.ParseAssertion(bytes.NewReader(authenticator.assertion(authnData, clientData, nil)))
+	if err == nil {
+		t.Errorf("ParseAssertion() returns no error, want error containing substring %q", wantErrorMsg)
+	} else if !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("ParseAssertion() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	}
+}
@@ -0,0 +1,600 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RevocationStatus is revocation status of an attestation certificate.
+type RevocationStatus string
+
+// Revocation statuses.
+const (
+	RevocationStatusGood      RevocationStatus = "good"      // Certificate isn't revoked.
+	RevocationStatusRevoked   RevocationStatus = "revoked"   // Certificate is revoked.
+	RevocationStatusUnknown   RevocationStatus = "unknown"   // Revocation status couldn't be determined from CRL distribution points and OCSP responders.
+	RevocationStatusUnchecked RevocationStatus = "unchecked" // Certificate doesn't have HTTP CRL distribution point or OCSP responder.
+)
+
+// RevocationResult is revocation status of a certificate in attestation trust path.
+type RevocationResult struct {
+	Certificate *x509.Certificate
+	Status      RevocationStatus
+	Source      string    // URL of CRL distribution point or OCSP responder that determined status, if any.
+	RevokedAt   time.Time // Revocation time, if revoked.
+	Reason      int       // CRL reason code, such as 1 for keyCompromise, if revoked.
+	Err         error     // Reason revocation status couldn't be determined, if unknown.
+}
+
+// RevocationMode determines how certificates are handled when their revocation status can't be determined.
+type RevocationMode int
+
+// Revocation modes.
+const (
+	RevocationSoftFail RevocationMode = iota // Accept certificates of unknown revocation status.
+	RevocationHardFail                       // Reject certificates of unknown revocation status.
+)
+
+// RevocationFetcher fetches CRLs and OCSP responses.
+type RevocationFetcher interface {
+	// FetchCRL returns DER encoded CRL from CRL distribution point url.
+	FetchCRL(url string) ([]byte, error)
+
+	// FetchOCSP sends DER encoded OCSP request to OCSP responder url, and returns DER encoded OCSP response.
+	FetchOCSP(url string, request []byte) ([]byte, error)
+}
+
+// maxRevocationResponseSize is the maximum size of CRL and OCSP response fetched by HTTPRevocationFetcher.
+const maxRevocationResponseSize = 10 << 20
+
+// defaultRevocationClient is the HTTP client of HTTPRevocationFetcher without Client.  CRL and OCSP
+// responder URLs come from attestation certificates, so a slow endpoint must not block registration.
+var defaultRevocationClient = &http.Client{Timeout: 10 * time.Second}
+
+// HTTPRevocationFetcher is a RevocationFetcher that fetches CRLs and OCSP responses with HTTP.
+type HTTPRevocationFetcher struct {
+	Client *http.Client // HTTP client, an HTTP client with a 10 second timeout is used if it is nil.
+}
+
+// FetchCRL implements the RevocationFetcher interface.
+func (f *HTTPRevocationFetcher) FetchCRL(url string) ([]byte, error) {
+	resp, err := f.client().Get(url)
+	if err != nil {
+		return nil, err
+	}
+	return readRevocationResponse(resp)
+}
+
+// FetchOCSP implements the RevocationFetcher interface.
+func (f *HTTPRevocationFetcher) FetchOCSP(url string, request []byte) ([]byte, error) {
+	resp, err := f.client().Post(url, "application/ocsp-request", bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+	return readRevocationResponse(resp)
+}
+
+func (f *HTTPRevocationFetcher) client() *http.Client {
+	if f.Client != nil {
+		return f.Client
+	}
+	return defaultRevocationClient
+}
+
+func readRevocationResponse(resp *http.Response) ([]byte, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("HTTP status " + resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxRevocationResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxRevocationResponseSize {
+		return nil, errors.New("response is larger than " + strconv.Itoa(maxRevocationResponseSize) + " bytes")
+	}
+	return data, nil
+}
+
+// defaultRevocationCacheDuration is how long CRLs and OCSP responses without next update time are cached.
+const defaultRevocationCacheDuration = time.Hour
+
+type cachedCRL struct {
+	crl     *pkix.CertificateList
+	expires time.Time
+}
+
+type cachedOCSPResponse struct {
+	result  RevocationResult
+	expires time.Time
+}
+
+// RevocationChecker checks revocation status of attestation certificates with CRLs from CRL distribution
+// points and OCSP responders from Authority Information Access extension.  CRLs and OCSP responses are
+// cached until their next update time.  It is safe for concurrent use.
+type RevocationChecker struct {
+	fetcher RevocationFetcher
+	mode    RevocationMode
+	now     func() time.Time
+
+	mu   sync.Mutex
+	crls map[string]*cachedCRL
+	ocsp map[string]*cachedOCSPResponse
+}
+
+// NewRevocationChecker returns RevocationChecker using fetcher to get CRLs and OCSP responses.
+func NewRevocationChecker(fetcher RevocationFetcher, mode RevocationMode) *RevocationChecker {
+	return &RevocationChecker{
+		fetcher: fetcher,
+		mode:    mode,
+		now:     time.Now,
+		crls:    make(map[string]*cachedCRL),
+		ocsp:    make(map[string]*cachedOCSPResponse),
+	}
+}
+
+// Check checks revocation status of every certificate in trust path except the last one, which is issuer
+// of the certificate before it.  It returns revocation results, or an error if any certificate is revoked,
+// or if revocation status of any certificate is unknown in RevocationHardFail mode.
+func (c *RevocationChecker) Check(trustPath []*x509.Certificate) ([]*RevocationResult, error) {
+	var results []*RevocationResult
+	for i := 0; i+1 < len(trustPath); i++ {
+		result := c.checkCertificate(trustPath[i], trustPath[i+1])
+		switch {
+		case result.Status == RevocationStatusRevoked:
+			return nil, errors.New("certificate \"" + result.Certificate.Subject.CommonName + "\" is revoked by " + result.Source)
+		case result.Status == RevocationStatusUnknown && c.mode == RevocationHardFail:
+			return nil, errors.New("certificate \"" + result.Certificate.Subject.CommonName + "\" revocation status is unknown: " + result.Err.Error())
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// checkCertificate returns revocation status of cert from its OCSP responders, and then from its CRL
+// distribution points, until one of them determines it.
+func (c *RevocationChecker) checkCertificate(cert *x509.Certificate, issuer *x509.Certificate) *RevocationResult {
+	var errs []string
+	for _, url := range cert.OCSPServer {
+		if !isHTTPURL(url) {
+			continue
+		}
+		result, err := c.checkOCSP(url, cert, issuer)
+		if err != nil {
+			errs = append(errs, url+": "+err.Error())
+			continue
+		}
+		return result
+	}
+	for _, url := range cert.CRLDistributionPoints {
+		if !isHTTPURL(url) {
+			continue
+		}
+		result, err := c.checkCRL(url, cert, issuer)
+		if err != nil {
+			errs = append(errs, url+": "+err.Error())
+			continue
+		}
+		return result
+	}
+	if len(errs) > 0 {
+		return &RevocationResult{Certificate: cert, Status: RevocationStatusUnknown, Err: errors.New(strings.Join(errs, "; "))}
+	}
+	return &RevocationResult{Certificate: cert, Status: RevocationStatusUnchecked}
+}
+
+func isHTTPURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+// crl returns cached CRL of url, or fetches it if it is missing or expired.
+func (c *RevocationChecker) crl(url string) (*pkix.CertificateList, error) {
+	now := c.now()
+
+	c.mu.Lock()
+	cached := c.crls[url]
+	c.mu.Unlock()
+	if cached != nil && now.Before(cached.expires) {
+		return cached.crl, nil
+	}
+
+	data, err := c.fetcher.FetchCRL(url)
+	if err != nil {
+		return nil, errors.New("failed to fetch CRL: " + err.Error())
+	}
+	crl, err := x509.ParseCRL(data)
+	if err != nil {
+		return nil, errors.New("failed to parse CRL: " + err.Error())
+	}
+	if crl.HasExpired(now) {
+		return nil, errors.New("CRL next update " + crl.TBSCertList.NextUpdate.UTC().Format(time.RFC3339) + " has passed")
+	}
+	expires := crl.TBSCertList.NextUpdate
+	if expires.IsZero() {
+		expires = now.Add(defaultRevocationCacheDuration)
+	}
+
+	c.mu.Lock()
+	c.crls[url] = &cachedCRL{crl: crl, expires: expires}
+	c.mu.Unlock()
+	return crl, nil
+}
+
+func (c *RevocationChecker) checkCRL(url string, cert *x509.Certificate, issuer *x509.Certificate) (*RevocationResult, error) {
+	crl, err := c.crl(url)
+	if err != nil {
+		return nil, err
+	}
+	if err = issuer.CheckCRLSignature(crl); err != nil {
+		return nil, errors.New("failed to verify CRL signature: " + err.Error())
+	}
+	result := &RevocationResult{Certificate: cert, Status: RevocationStatusGood, Source: url}
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			result.Status = RevocationStatusRevoked
+			result.RevokedAt = revoked.RevocationTime
+			for _, ext := range revoked.Extensions {
+				if ext.Id.Equal(oidExtensionReasonCode) {
+					var reason asn1.Enumerated
+					if _, err := asn1.Unmarshal(ext.Value, &reason); err == nil {
+						result.Reason = int(reason)
+					}
+				}
+			}
+			break
+		}
+	}
+	return result, nil
+}
+
+var (
+	oidExtensionReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+	oidOCSPBasicResponse   = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	oidSHA1                = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256              = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+)
+
+// ocspSignatureAlgorithms maps signature algorithm OIDs of OCSP responses to x509.SignatureAlgorithm.
+var ocspSignatureAlgorithms = []struct {
+	oid asn1.ObjectIdentifier
+	alg x509.SignatureAlgorithm
+}{
+	{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}, x509.SHA1WithRSA},
+	{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}, x509.SHA256WithRSA},
+	{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}, x509.SHA384WithRSA},
+	{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}, x509.SHA512WithRSA},
+	{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}, x509.ECDSAWithSHA1},
+	{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}, x509.ECDSAWithSHA256},
+	{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}, x509.ECDSAWithSHA384},
+	{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}, x509.ECDSAWithSHA512},
+}
+
+// OCSP structures, as defined in https://tools.ietf.org/html/rfc6960#section-4
+type ocspCertID struct {
+	HashAlgorithm  pkix.AlgorithmIdentifier
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+}
+
+type ocspRequestEntry struct {
+	CertID ocspCertID
+}
+
+type ocspTBSRequest struct {
+	Version     int `asn1:"explicit,tag:0,default:0,optional"`
+	RequestList []ocspRequestEntry
+}
+
+type ocspRequest struct {
+	TBSRequest ocspTBSRequest
+}
+
+type ocspResponseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type ocspResponse struct {
+	Status        asn1.Enumerated
+	ResponseBytes ocspResponseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspBasicResponse struct {
+	TBSResponseData    asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseData struct {
+	Version            int `asn1:"explicit,tag:0,default:0,optional"`
+	ResponderID        asn1.RawValue
+	ProducedAt         time.Time `asn1:"generalized"`
+	Responses          []ocspSingleResponse
+	ResponseExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspRevokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspSingleResponse struct {
+	CertID           ocspCertID
+	CertStatus       asn1.RawValue    // CHOICE of good [0] NULL, revoked [1] RevokedInfo, or unknown [2] NULL.
+	ThisUpdate       time.Time        `asn1:"generalized"`
+	NextUpdate       time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	SingleExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+// newOCSPCertID returns OCSP certificate ID of cert issued by issuer, hashed with given hash algorithm.
+func newOCSPCertID(cert *x509.Certificate, issuer *x509.Certificate, hashAlg asn1.ObjectIdentifier) (ocspCertID, error) {
+	var hash crypto.Hash
+	switch {
+	case hashAlg.Equal(oidSHA1):
+		hash = crypto.SHA1
+	case hashAlg.Equal(oidSHA256):
+		hash = crypto.SHA256
+	default:
+		return ocspCertID{}, errors.New("OCSP certificate ID hash algorithm " + hashAlg.String() + " is not supported")
+	}
+	var spki struct {
+		Algorithm        pkix.AlgorithmIdentifier
+		SubjectPublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return ocspCertID{}, errors.New("failed to unmarshal issuer public key: " + err.Error())
+	}
+	nameHash := hash.New()
+	nameHash.Write(issuer.RawSubject)
+	keyHash := hash.New()
+	keyHash.Write(spki.SubjectPublicKey.RightAlign())
+	return ocspCertID{
+		HashAlgorithm:  pkix.AlgorithmIdentifier{Algorithm: hashAlg, Parameters: asn1.NullRawValue},
+		IssuerNameHash: nameHash.Sum(nil),
+		IssuerKeyHash:  keyHash.Sum(nil),
+		SerialNumber:   cert.SerialNumber,
+	}, nil
+}
+
+// status returns certificate status of single response, and its revocation info if it is revoked.
+// Certificate status is parsed explicitly by its tag, so that a malformed or unexpected status is an
+// error instead of being taken for good.
+func (single *ocspSingleResponse) status() (RevocationStatus, *ocspRevokedInfo, error) {
+	certStatus := single.CertStatus
+	if certStatus.Class == asn1.ClassContextSpecific {
+		switch {
+		case certStatus.Tag == 0 && !certStatus.IsCompound && len(certStatus.Bytes) == 0:
+			return RevocationStatusGood, nil, nil
+		case certStatus.Tag == 1 && certStatus.IsCompound:
+			var info ocspRevokedInfo
+			if rest, err := asn1.UnmarshalWithParams(certStatus.FullBytes, &info, "tag:1"); err != nil {
+				return "", nil, errors.New("failed to unmarshal OCSP revoked info: " + err.Error())
+			} else if len(rest) != 0 {
+				return "", nil, errors.New("trailing data after OCSP revoked info")
+			}
+			return RevocationStatusRevoked, &info, nil
+		case certStatus.Tag == 2 && !certStatus.IsCompound && len(certStatus.Bytes) == 0:
+			return RevocationStatusUnknown, nil, nil
+		}
+	}
+	return "", nil, errors.New("OCSP certificate status with class " + strconv.Itoa(certStatus.Class) + " and tag " + strconv.Itoa(certStatus.Tag) + " is invalid")
+}
+
+// isOCSPResponder returns if cert is the OCSP responder identified by responderID, which is a CHOICE of
+// byName [1] Name, or byKey [2] SHA-1 hash of responder public key.
+func isOCSPResponder(responderID asn1.RawValue, cert *x509.Certificate) bool {
+	if responderID.Class != asn1.ClassContextSpecific || !responderID.IsCompound {
+		return false
+	}
+	switch responderID.Tag {
+	case 1:
+		return bytes.Equal(responderID.Bytes, cert.RawSubject)
+	case 2:
+		var keyHash []byte
+		if rest, err := asn1.Unmarshal(responderID.Bytes, &keyHash); err != nil || len(rest) != 0 {
+			return false
+		}
+		var spki struct {
+			Algorithm        pkix.AlgorithmIdentifier
+			SubjectPublicKey asn1.BitString
+		}
+		if _, err := asn1.Unmarshal(cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+			return false
+		}
+		sum := sha1.Sum(spki.SubjectPublicKey.RightAlign())
+		return bytes.Equal(keyHash, sum[:])
+	}
+	return false
+}
+
+func (id ocspCertID) equal(other ocspCertID) bool {
+	return id.HashAlgorithm.Algorithm.Equal(other.HashAlgorithm.Algorithm) &&
+		bytes.Equal(id.IssuerNameHash, other.IssuerNameHash) &&
+		bytes.Equal(id.IssuerKeyHash, other.IssuerKeyHash) &&
+		id.SerialNumber.Cmp(other.SerialNumber) == 0
+}
+
+func (c *RevocationChecker) checkOCSP(url string, cert *x509.Certificate, issuer *x509.Certificate) (*RevocationResult, error) {
+	certID, err := newOCSPCertID(cert, issuer, oidSHA1)
+	if err != nil {
+		return nil, err
+	}
+	key := url + " " + hex.EncodeToString(certID.IssuerKeyHash) + " " + cert.SerialNumber.Text(16)
+	now := c.now()
+
+	c.mu.Lock()
+	cached := c.ocsp[key]
+	c.mu.Unlock()
+	if cached != nil && now.Before(cached.expires) {
+		result := cached.result
+		result.Certificate = cert
+		return &result, nil
+	}
+
+	request, err := asn1.Marshal(ocspRequest{TBSRequest: ocspTBSRequest{RequestList: []ocspRequestEntry{{CertID: certID}}}})
+	if err != nil {
+		return nil, errors.New("failed to marshal OCSP request: " + err.Error())
+	}
+	data, err := c.fetcher.FetchOCSP(url, request)
+	if err != nil {
+		return nil, errors.New("failed to fetch OCSP response: " + err.Error())
+	}
+	single, err := parseOCSPResponse(data, certID, issuer, now)
+	if err != nil {
+		return nil, err
+	}
+
+	status, revoked, err := single.status()
+	if err != nil {
+		return nil, err
+	}
+	result := &RevocationResult{Certificate: cert, Status: status, Source: url}
+	switch status {
+	case RevocationStatusUnknown:
+		return nil, errors.New("OCSP responder doesn't know certificate")
+	case RevocationStatusRevoked:
+		result.RevokedAt = revoked.RevocationTime
+		result.Reason = int(revoked.Reason)
+	}
+	expires := single.NextUpdate
+	if expires.IsZero() {
+		expires = now.Add(defaultRevocationCacheDuration)
+	}
+
+	c.mu.Lock()
+	c.ocsp[key] = &cachedOCSPResponse{result: *result, expires: expires}
+	c.mu.Unlock()
+	return result, nil
+}
+
+// parseOCSPResponse parses DER encoded OCSP response, verifies that it is signed by issuer or by an OCSP
+// responder certificate issued by issuer, and returns the single response for certID.
+func parseOCSPResponse(data []byte, certID ocspCertID, issuer *x509.Certificate, now time.Time) (*ocspSingleResponse, error) {
+	var resp ocspResponse
+	if rest, err := asn1.Unmarshal(data, &resp); err != nil {
+		return nil, errors.New("failed to unmarshal OCSP response: " + err.Error())
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after OCSP response")
+	}
+	if resp.Status != 0 {
+		return nil, errors.New("OCSP response status is " + strconv.Itoa(int(resp.Status)))
+	}
+	if !resp.ResponseBytes.ResponseType.Equal(oidOCSPBasicResponse) {
+		return nil, errors.New("OCSP response type " + resp.ResponseBytes.ResponseType.String() + " is not supported")
+	}
+
+	var basic ocspBasicResponse
+	if rest, err := asn1.Unmarshal(resp.ResponseBytes.Response, &basic); err != nil {
+		return nil, errors.New("failed to unmarshal basic OCSP response: " + err.Error())
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after basic OCSP response")
+	}
+	var responseData ocspResponseData
+	if rest, err := asn1.Unmarshal(basic.TBSResponseData.FullBytes, &responseData); err != nil {
+		return nil, errors.New("failed to unmarshal OCSP response data: " + err.Error())
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after OCSP response data")
+	}
+
+	// Verify that OCSP response is signed by the OCSP responder identified by responder ID, which is
+	// issuer, or an OCSP responder certificate issued by issuer and included in the response.
+	var signer *x509.Certificate
+	if isOCSPResponder(responseData.ResponderID, issuer) {
+		signer = issuer
+	}
+	for i := 0; signer == nil && i < len(basic.Certificates); i++ {
+		responder, err := x509.ParseCertificate(basic.Certificates[i].FullBytes)
+		if err != nil {
+			return nil, errors.New("failed to parse OCSP responder certificate: " + err.Error())
+		}
+		if !isOCSPResponder(responseData.ResponderID, responder) {
+			continue
+		}
+		if err = responder.CheckSignatureFrom(issuer); err != nil {
+			return nil, errors.New("OCSP responder certificate isn't issued by issuer: " + err.Error())
+		}
+		ocspSigning := false
+		for _, usage := range responder.ExtKeyUsage {
+			if usage == x509.ExtKeyUsageOCSPSigning {
+				ocspSigning = true
+				break
+			}
+		}
+		if !ocspSigning {
+			return nil, errors.New("OCSP responder certificate doesn't have OCSP signing extended key usage")
+		}
+		signer = responder
+	}
+	if signer == nil {
+		return nil, errors.New("OCSP responder ID doesn't identify issuer or OCSP responder certificate")
+	}
+	sigAlg := x509.UnknownSignatureAlgorithm
+	for _, alg := range ocspSignatureAlgorithms {
+		if alg.oid.Equal(basic.SignatureAlgorithm.Algorithm) {
+			sigAlg = alg.alg
+			break
+		}
+	}
+	if sigAlg == x509.UnknownSignatureAlgorithm {
+		return nil, errors.New("OCSP response signature algorithm " + basic.SignatureAlgorithm.Algorithm.String() + " is not supported")
+	}
+	if err := signer.CheckSignature(sigAlg, basic.TBSResponseData.FullBytes, basic.Signature.RightAlign()); err != nil {
+		return nil, errors.New("failed to verify OCSP response signature: " + err.Error())
+	}
+
+	for i := range responseData.Responses {
+		single := &responseData.Responses[i]
+		id := certID
+		if !single.CertID.HashAlgorithm.Algorithm.Equal(certID.HashAlgorithm.Algorithm) {
+			var err error
+			if id, err = newOCSPCertID(&x509.Certificate{SerialNumber: certID.SerialNumber}, issuer, single.CertID.HashAlgorithm.Algorithm); err != nil {
+				continue
+			}
+		}
+		if !single.CertID.equal(id) {
+			continue
+		}
+		if now.Before(single.ThisUpdate) {
+			return nil, errors.New("OCSP response this update " + single.ThisUpdate.UTC().Format(time.RFC3339) + " is in the future")
+		}
+		if !single.NextUpdate.IsZero() && now.After(single.NextUpdate) {
+			return nil, errors.New("OCSP response next update " + single.NextUpdate.UTC().Format(time.RFC3339) + " has passed")
+		}
+		return single, nil
+	}
+	return nil, errors.New("OCSP response doesn't have certificate status")
+}
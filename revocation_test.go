@@ -0,0 +1,577 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kappapay/webauthn/internal/testcert"
+)
+
+var (
+	testRevocationNow        = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	testRevocationThisUpdate = testRevocationNow.Add(-24 * time.Hour)
+	testRevocationNextUpdate = testRevocationNow.Add(7 * 24 * time.Hour)
+)
+
+// newTestRevocableCertificate returns a certificate issued by parent with given CRL distribution point and OCSP responder.
+func newTestRevocableCertificate(commonName string, serialNumber int64, parent *testcert.CA, crlURL string, ocspURL string, extKeyUsage []x509.ExtKeyUsage) *testcert.CA {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serialNumber),
+		Subject:      pkix.Name{CommonName: commonName},
+		ExtKeyUsage:  extKeyUsage,
+	}
+	if crlURL != "" {
+		template.CRLDistributionPoints = []string{crlURL}
+	}
+	if ocspURL != "" {
+		template.OCSPServer = []string{ocspURL}
+	}
+	return testcert.New(template, parent)
+}
+
+// newTestCRL returns DER encoded CRL signed by issuer, revoking certificates with given serial numbers for key compromise.
+func newTestCRL(issuer *testcert.CA, nextUpdate time.Time, revokedSerialNumbers ...int64) []byte {
+	reason, err := asn1.Marshal(asn1.Enumerated(1))
+	if err != nil {
+		panic(err)
+	}
+	var revoked []pkix.RevokedCertificate
+	for _, serialNumber := range revokedSerialNumbers {
+		revoked = append(revoked, pkix.RevokedCertificate{
+			SerialNumber:   big.NewInt(serialNumber),
+			RevocationTime: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+			Extensions:     []pkix.Extension{{Id: oidExtensionReasonCode, Value: reason}},
+		})
+	}
+	crl, err := issuer.Cert.CreateCRL(rand.Reader, issuer.Key, revoked, testRevocationThisUpdate, nextUpdate)
+	if err != nil {
+		panic(err)
+	}
+	return crl
+}
+
+// newTestOCSPResponse returns DER encoded OCSP response for cert issued by issuer, signed by signer.
+// The certificate is revoked for key compromise if revokedAt isn't zero.
+func newTestOCSPResponse(issuer *testcert.CA, signer *testcert.CA, cert *x509.Certificate, revokedAt time.Time, nextUpdate time.Time) []byte {
+	certStatus := ocspStatusGood
+	if !revokedAt.IsZero() {
+		certStatus = ocspStatusRevoked(revokedAt)
+	}
+	return newTestOCSPResponseWith(issuer, signer, cert, certStatus, ocspResponderByKey(signer.Cert), nextUpdate)
+}
+
+// newTestOCSPResponseWith returns DER encoded OCSP response for cert issued by issuer with given
+// certificate status and responder ID, signed by signer.
+func newTestOCSPResponseWith(issuer *testcert.CA, signer *testcert.CA, cert *x509.Certificate, certStatus asn1.RawValue, responderID asn1.RawValue, nextUpdate time.Time) []byte {
+	certID, err := newOCSPCertID(cert, issuer.Cert, oidSHA1)
+	if err != nil {
+		panic(err)
+	}
+	tbsResponseData, err := asn1.Marshal(ocspResponseData{
+		ResponderID: responderID,
+		ProducedAt:  testRevocationThisUpdate,
+		Responses:   []ocspSingleResponse{{CertID: certID, CertStatus: certStatus, ThisUpdate: testRevocationThisUpdate, NextUpdate: nextUpdate}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	digest := sha256.Sum256(tbsResponseData)
+	signature, err := signer.Key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		panic(err)
+	}
+	basic := ocspBasicResponse{
+		TBSResponseData:    asn1.RawValue{FullBytes: tbsResponseData},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	}
+	if signer != issuer {
+		basic.Certificates = []asn1.RawValue{{FullBytes: signer.Cert.Raw}}
+	}
+	basicData, err := asn1.Marshal(basic)
+	if err != nil {
+		panic(err)
+	}
+	resp, err := asn1.Marshal(ocspResponse{ResponseBytes: ocspResponseBytes{ResponseType: oidOCSPBasicResponse, Response: basicData}})
+	if err != nil {
+		panic(err)
+	}
+	return resp
+}
+
+// OCSP certificate statuses good and unknown.
+var (
+	ocspStatusGood    = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0}
+	ocspStatusUnknown = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2}
+)
+
+// ocspStatusRevoked returns OCSP certificate status revoked at revokedAt for key compromise.
+func ocspStatusRevoked(revokedAt time.Time) asn1.RawValue {
+	data, err := asn1.MarshalWithParams(ocspRevokedInfo{RevocationTime: revokedAt, Reason: 1}, "tag:1")
+	if err != nil {
+		panic(err)
+	}
+	return asn1.RawValue{FullBytes: data}
+}
+
+// ocspResponderByKey returns OCSP responder ID of cert by SHA-1 hash of its public key.
+func ocspResponderByKey(cert *x509.Certificate) asn1.RawValue {
+	var spki struct {
+		Algorithm        pkix.AlgorithmIdentifier
+		SubjectPublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+		panic(err)
+	}
+	keyHash := sha1.Sum(spki.SubjectPublicKey.RightAlign())
+	data, err := asn1.Marshal(keyHash[:])
+	if err != nil {
+		panic(err)
+	}
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true, Bytes: data}
+}
+
+// ocspResponderByName returns OCSP responder ID of cert by its subject.
+func ocspResponderByName(cert *x509.Certificate) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: cert.RawSubject}
+}
+
+type testRevocationFetcher struct {
+	crls         map[string][]byte
+	ocsp         map[string][]byte
+	crlFetches   int
+	ocspFetches  int
+	ocspRequests [][]byte
+}
+
+func (f *testRevocationFetcher) FetchCRL(url string) ([]byte, error) {
+	f.crlFetches++
+	if data, ok := f.crls[url]; ok {
+		return data, nil
+	}
+	return nil, errors.New("HTTP status 404 Not Found")
+}
+
+func (f *testRevocationFetcher) FetchOCSP(url string, request []byte) ([]byte, error) {
+	f.ocspFetches++
+	f.ocspRequests = append(f.ocspRequests, request)
+	if data, ok := f.ocsp[url]; ok {
+		return data, nil
+	}
+	return nil, errors.New("HTTP status 404 Not Found")
+}
+
+func TestRevocationCheckerCheck(t *testing.T) {
+	const crlURL = "http://crl.example.com/ca.crl"
+	const ocspURL = "http://ocsp.example.com"
+
+	ca := newTestCertificate("Revocation Test CA", nil, true, nil)
+	otherCA := newTestCertificate("Other Test CA", nil, true, nil)
+	crlLeaf := newTestRevocableCertificate("CRL Attestation", 1, ca, crlURL, "", nil)
+	ocspLeaf := newTestRevocableCertificate("OCSP Attestation", 2, ca, "", ocspURL, nil)
+	bothLeaf := newTestRevocableCertificate("Attestation", 3, ca, crlURL, ocspURL, nil)
+	ldapLeaf := newTestRevocableCertificate("LDAP Attestation", 4, ca, "ldap://crl.example.com/ca.crl", "", nil)
+	responder := newTestRevocableCertificate("OCSP Responder", 5, ca, "", "", []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning})
+	nonResponder := newTestRevocableCertificate("Not OCSP Responder", 6, ca, "", "", []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth})
+	revokedAt := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		cert         *x509.Certificate
+		crls         map[string][]byte
+		ocsp         map[string][]byte
+		mode         RevocationMode
+		wantStatus   RevocationStatus
+		wantSource   string
+		wantErrorMsg string
+	}{
+		{"no revocation information", newTestCertificate("Attestation", ca, false, nil).Cert, nil, nil, RevocationHardFail, RevocationStatusUnchecked, "", ""},
+		{"non-HTTP CRL distribution point", ldapLeaf.Cert, nil, nil, RevocationHardFail, RevocationStatusUnchecked, "", ""},
+		{"CRL good", crlLeaf.Cert, map[string][]byte{crlURL: newTestCRL(ca, testRevocationNextUpdate, 4)}, nil, RevocationHardFail, RevocationStatusGood, crlURL, ""},
+		{"CRL revoked", crlLeaf.Cert, map[string][]byte{crlURL: newTestCRL(ca, testRevocationNextUpdate, 1)}, nil, RevocationSoftFail, "", "", "certificate \"CRL Attestation\" is revoked by " + crlURL},
+		{"CRL signed by other CA in soft-fail mode", crlLeaf.Cert, map[string][]byte{crlURL: newTestCRL(otherCA, testRevocationNextUpdate, 4)}, nil, RevocationSoftFail, RevocationStatusUnknown, "", ""},
+		{"CRL signed by other CA in hard-fail mode", crlLeaf.Cert, map[string][]byte{crlURL: newTestCRL(otherCA, testRevocationNextUpdate, 4)}, nil, RevocationHardFail, "", "", "certificate \"CRL Attestation\" revocation status is unknown: " + crlURL + ": failed to verify CRL signature"},
+		{"stale CRL", crlLeaf.Cert, map[string][]byte{crlURL: newTestCRL(ca, testRevocationNow.Add(-time.Hour), 4)}, nil, RevocationHardFail, "", "", "CRL next update 2029-12-31T23:00:00Z has passed"},
+		{"CRL unavailable in soft-fail mode", crlLeaf.Cert, nil, nil, RevocationSoftFail, RevocationStatusUnknown, "", ""},
+		{"CRL unavailable in hard-fail mode", crlLeaf.Cert, nil, nil, RevocationHardFail, "", "", "failed to fetch CRL: HTTP status 404 Not Found"},
+		{"OCSP good", ocspLeaf.Cert, nil, map[string][]byte{ocspURL: newTestOCSPResponse(ca, ca, ocspLeaf.Cert, time.Time{}, testRevocationNextUpdate)}, RevocationHardFail, RevocationStatusGood, ocspURL, ""},
+		{"OCSP revoked", ocspLeaf.Cert, nil, map[string][]byte{ocspURL: newTestOCSPResponse(ca, ca, ocspLeaf.Cert, revokedAt, testRevocationNextUpdate)}, RevocationSoftFail, "", "", "certificate \"OCSP Attestation\" is revoked by " + ocspURL},
+		{"OCSP good from delegated responder", ocspLeaf.Cert, nil, map[string][]byte{ocspURL: newTestOCSPResponse(ca, responder, ocspLeaf.Cert, time.Time{}, testRevocationNextUpdate)}, RevocationHardFail, RevocationStatusGood, ocspURL, ""},
+		{"OCSP responder without OCSP signing", ocspLeaf.Cert, nil, map[string][]byte{ocspURL: newTestOCSPResponse(ca, nonResponder, ocspLeaf.Cert, time.Time{}, testRevocationNextUpdate)}, RevocationHardFail, "", "", "OCSP responder certificate doesn't have OCSP signing extended key usage"},
+		{"OCSP signed by other CA", ocspLeaf.Cert, nil, map[string][]byte{ocspURL: newTestOCSPResponse(ca, otherCA, ocspLeaf.Cert, time.Time{}, testRevocationNextUpdate)}, RevocationHardFail, "", "", "OCSP responder certificate isn't issued by issuer"},
+		{"OCSP response for other certificate", ocspLeaf.Cert, nil, map[string][]byte{ocspURL: newTestOCSPResponse(ca, ca, crlLeaf.Cert, time.Time{}, testRevocationNextUpdate)}, RevocationHardFail, "", "", "OCSP response doesn't have certificate status"},
+		{"stale OCSP response", ocspLeaf.Cert, nil, map[string][]byte{ocspURL: newTestOCSPResponse(ca, ca, ocspLeaf.Cert, time.Time{}, testRevocationNow.Add(-time.Hour))}, RevocationHardFail, "", "", "OCSP response next update 2029-12-31T23:00:00Z has passed"},
+		{"OCSP preferred to CRL", bothLeaf.Cert, map[string][]byte{crlURL: newTestCRL(ca, testRevocationNextUpdate, 3)}, map[string][]byte{ocspURL: newTestOCSPResponse(ca, ca, bothLeaf.Cert, time.Time{}, testRevocationNextUpdate)}, RevocationHardFail, RevocationStatusGood, ocspURL, ""},
+		{"CRL after OCSP failure", bothLeaf.Cert, map[string][]byte{crlURL: newTestCRL(ca, testRevocationNextUpdate)}, nil, RevocationHardFail, RevocationStatusGood, crlURL, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := NewRevocationChecker(&testRevocationFetcher{crls: tc.crls, ocsp: tc.ocsp}, tc.mode)
+			checker.now = func() time.Time { return testRevocationNow }
+
+			results, err := checker.Check([]*x509.Certificate{tc.cert, ca.Cert})
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("Check() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("Check() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check() returns error %q", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Check() returns %d results, want 1", len(results))
+			}
+			if !results[0].Certificate.Equal(tc.cert) {
+				t.Errorf("result certificate %q, want %q", results[0].Certificate.Subject.CommonName, tc.cert.Subject.CommonName)
+			}
+			if results[0].Status != tc.wantStatus {
+				t.Errorf("result status %q, want %q", results[0].Status, tc.wantStatus)
+			}
+			if results[0].Source != tc.wantSource {
+				t.Errorf("result source %q, want %q", results[0].Source, tc.wantSource)
+			}
+			if (results[0].Status == RevocationStatusUnknown) != (results[0].Err != nil) {
+				t.Errorf("result status %q with error %v", results[0].Status, results[0].Err)
+			}
+		})
+	}
+}
+
+func TestRevocationCheckerOCSPResponses(t *testing.T) {
+	responses := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if data, ok := responses[r.URL.Path]; ok && r.Method == http.MethodPost {
+			w.Write(data)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	ca := testcert.NewCA("Test Attestation CA", nil)
+	otherCA := testcert.NewCA("Other CA", nil)
+	responder := newTestRevocableCertificate("OCSP Responder", 100, ca, "", "", []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning})
+	revokedAt := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		response     func(cert *x509.Certificate) []byte
+		wantStatus   RevocationStatus
+		wantErrorMsg string
+	}{
+		{
+			name: "good by responder key",
+			response: func(cert *x509.Certificate) []byte {
+				return newTestOCSPResponseWith(ca, ca, cert, ocspStatusGood, ocspResponderByKey(ca.Cert), testRevocationNextUpdate)
+			},
+			wantStatus: RevocationStatusGood,
+		},
+		{
+			name: "good by responder name",
+			response: func(cert *x509.Certificate) []byte {
+				return newTestOCSPResponseWith(ca, ca, cert, ocspStatusGood, ocspResponderByName(ca.Cert), testRevocationNextUpdate)
+			},
+			wantStatus: RevocationStatusGood,
+		},
+		{
+			name: "good from delegated responder by name",
+			response: func(cert *x509.Certificate) []byte {
+				return newTestOCSPResponseWith(ca, responder, cert, ocspStatusGood, ocspResponderByName(responder.Cert), testRevocationNextUpdate)
+			},
+			wantStatus: RevocationStatusGood,
+		},
+		{
+			name: "revoked",
+			response: func(cert *x509.Certificate) []byte {
+				return newTestOCSPResponseWith(ca, ca, cert, ocspStatusRevoked(revokedAt), ocspResponderByKey(ca.Cert), testRevocationNextUpdate)
+			},
+			wantErrorMsg: "is revoked by " + server.URL,
+		},
+		{
+			name:         "malformed response",
+			response:     func(cert *x509.Certificate) []byte { return []byte("not an OCSP response") },
+			wantErrorMsg: "failed to unmarshal OCSP response",
+		},
+		{
+			name: "unknown status",
+			response: func(cert *x509.Certificate) []byte {
+				return newTestOCSPResponseWith(ca, ca, cert, ocspStatusUnknown, ocspResponderByKey(ca.Cert), testRevocationNextUpdate)
+			},
+			wantErrorMsg: "OCSP responder doesn't know certificate",
+		},
+		{
+			name: "invalid status tag",
+			response: func(cert *x509.Certificate) []byte {
+				return newTestOCSPResponseWith(ca, ca, cert, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 3}, ocspResponderByKey(ca.Cert), testRevocationNextUpdate)
+			},
+			wantErrorMsg: "OCSP certificate status with class 2 and tag 3 is invalid",
+		},
+		{
+			name: "good status with content",
+			response: func(cert *x509.Certificate) []byte {
+				return newTestOCSPResponseWith(ca, ca, cert, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: []byte{0x01}}, ocspResponderByKey(ca.Cert), testRevocationNextUpdate)
+			},
+			wantErrorMsg: "OCSP certificate status with class 2 and tag 0 is invalid",
+		},
+		{
+			name: "revoked status without revocation time",
+			response: func(cert *x509.Certificate) []byte {
+				return newTestOCSPResponseWith(ca, ca, cert, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true}, ocspResponderByKey(ca.Cert), testRevocationNextUpdate)
+			},
+			wantErrorMsg: "failed to unmarshal OCSP revoked info",
+		},
+		{
+			name: "wrong responder key",
+			response: func(cert *x509.Certificate) []byte {
+				return newTestOCSPResponseWith(ca, ca, cert, ocspStatusGood, ocspResponderByKey(otherCA.Cert), testRevocationNextUpdate)
+			},
+			wantErrorMsg: "OCSP responder ID doesn't identify issuer or OCSP responder certificate",
+		},
+		{
+			name: "wrong responder name",
+			response: func(cert *x509.Certificate) []byte {
+				return newTestOCSPResponseWith(ca, ca, cert, ocspStatusGood, ocspResponderByName(otherCA.Cert), testRevocationNextUpdate)
+			},
+			wantErrorMsg: "OCSP responder ID doesn't identify issuer or OCSP responder certificate",
+		},
+		{
+			name: "responder ID of issuer signed by delegated responder",
+			response: func(cert *x509.Certificate) []byte {
+				return newTestOCSPResponseWith(ca, responder, cert, ocspStatusGood, ocspResponderByKey(ca.Cert), testRevocationNextUpdate)
+			},
+			wantErrorMsg: "failed to verify OCSP response signature",
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := "/ocsp/" + strconv.Itoa(i)
+			leaf := newTestRevocableCertificate("OCSP Attestation", int64(i+1), ca, "", server.URL+path, nil)
+			responses[path] = tc.response(leaf.Cert)
+
+			checker := NewRevocationChecker(&HTTPRevocationFetcher{Client: server.Client()}, RevocationHardFail)
+			checker.now = func() time.Time { return testRevocationNow }
+			results, err := checker.Check([]*x509.Certificate{leaf.Cert, ca.Cert})
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("Check() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("Check() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check() returns error %q", err)
+			}
+			if len(results) != 1 || results[0].Status != tc.wantStatus {
+				t.Errorf("Check() returns %+v, want status %q", results, tc.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRevocationCheckerRevokedReason(t *testing.T) {
+	const crlURL = "http://crl.example.com/ca.crl"
+
+	ca := newTestCertificate("Revocation Test CA", nil, true, nil)
+	intermediate := newTestRevocableCertificate("Revocation Test Intermediate CA", 1, ca, crlURL, "", nil)
+	checker := NewRevocationChecker(&testRevocationFetcher{crls: map[string][]byte{crlURL: newTestCRL(ca, testRevocationNextUpdate, 1)}}, RevocationSoftFail)
+	checker.now = func() time.Time { return testRevocationNow }
+
+	result := checker.checkCertificate(intermediate.Cert, ca.Cert)
+	if result.Status != RevocationStatusRevoked {
+		t.Fatalf("result status %q, want %q", result.Status, RevocationStatusRevoked)
+	}
+	if want := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC); !result.RevokedAt.Equal(want) {
+		t.Errorf("result revocation time %v, want %v", result.RevokedAt, want)
+	}
+	if result.Reason != 1 {
+		t.Errorf("result reason %d, want 1 (keyCompromise)", result.Reason)
+	}
+}
+
+func TestRevocationCheckerCache(t *testing.T) {
+	const crlURL = "http://crl.example.com/ca.crl"
+	const ocspURL = "http://ocsp.example.com"
+
+	ca := newTestCertificate("Revocation Test CA", nil, true, nil)
+	crlLeaf := newTestRevocableCertificate("CRL Attestation", 1, ca, crlURL, "", nil)
+	otherCRLLeaf := newTestRevocableCertificate("Other CRL Attestation", 2, ca, crlURL, "", nil)
+	ocspLeaf := newTestRevocableCertificate("OCSP Attestation", 3, ca, "", ocspURL, nil)
+
+	fetcher := &testRevocationFetcher{
+		crls: map[string][]byte{crlURL: newTestCRL(ca, testRevocationNextUpdate)},
+		ocsp: map[string][]byte{ocspURL: newTestOCSPResponse(ca, ca, ocspLeaf.Cert, time.Time{}, testRevocationNextUpdate)},
+	}
+	now := testRevocationNow
+	checker := NewRevocationChecker(fetcher, RevocationHardFail)
+	checker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		for _, cert := range []*x509.Certificate{crlLeaf.Cert, otherCRLLeaf.Cert, ocspLeaf.Cert} {
+			if _, err := checker.Check([]*x509.Certificate{cert, ca.Cert}); err != nil {
+				t.Fatalf("Check() returns error %q", err)
+			}
+		}
+	}
+	if fetcher.crlFetches != 1 || fetcher.ocspFetches != 1 {
+		t.Errorf("fetched %d CRLs and %d OCSP responses, want 1 and 1", fetcher.crlFetches, fetcher.ocspFetches)
+	}
+
+	// CRL and OCSP response are fetched again after their next update time.
+	now = testRevocationNextUpdate.Add(time.Minute)
+	fetcher.crls[crlURL] = newTestCRL(ca, now.Add(time.Hour), 1)
+	if _, err := checker.Check([]*x509.Certificate{crlLeaf.Cert, ca.Cert}); err == nil || !strings.Contains(err.Error(), "is revoked") {
+		t.Errorf("Check() returns error %v, want revoked certificate error", err)
+	}
+	if _, err := checker.Check([]*x509.Certificate{ocspLeaf.Cert, ca.Cert}); err == nil || !strings.Contains(err.Error(), "OCSP response next update") {
+		t.Errorf("Check() returns error %v, want stale OCSP response error", err)
+	}
+	if fetcher.crlFetches != 2 || fetcher.ocspFetches != 2 {
+		t.Errorf("fetched %d CRLs and %d OCSP responses, want 2 and 2", fetcher.crlFetches, fetcher.ocspFetches)
+	}
+}
+
+func TestOCSPRequest(t *testing.T) {
+	const ocspURL = "http://ocsp.example.com"
+
+	ca := newTestCertificate("Revocation Test CA", nil, true, nil)
+	leaf := newTestRevocableCertificate("OCSP Attestation", 42, ca, "", ocspURL, nil)
+	fetcher := &testRevocationFetcher{ocsp: map[string][]byte{ocspURL: newTestOCSPResponse(ca, ca, leaf.Cert, time.Time{}, testRevocationNextUpdate)}}
+	checker := NewRevocationChecker(fetcher, RevocationHardFail)
+	checker.now = func() time.Time { return testRevocationNow }
+
+	if _, err := checker.Check([]*x509.Certificate{leaf.Cert, ca.Cert}); err != nil {
+		t.Fatalf("Check() returns error %q", err)
+	}
+	if len(fetcher.ocspRequests) != 1 {
+		t.Fatalf("sent %d OCSP requests, want 1", len(fetcher.ocspRequests))
+	}
+	var request ocspRequest
+	if _, err := asn1.Unmarshal(fetcher.ocspRequests[0], &request); err != nil {
+		t.Fatalf("failed to unmarshal OCSP request: %q", err)
+	}
+	if len(request.TBSRequest.RequestList) != 1 {
+		t.Fatalf("OCSP request has %d certificates, want 1", len(request.TBSRequest.RequestList))
+	}
+	certID := request.TBSRequest.RequestList[0].CertID
+	nameHash := sha1.Sum(ca.Cert.RawSubject)
+	if !certID.HashAlgorithm.Algorithm.Equal(oidSHA1) || string(certID.IssuerNameHash) != string(nameHash[:]) || certID.SerialNumber.Int64() != 42 {
+		t.Errorf("OCSP request certificate ID %+v, want SHA-1 certificate ID of serial number 42", certID)
+	}
+}
+
+func TestHTTPRevocationFetcher(t *testing.T) {
+	ca := newTestCertificate("Revocation Test CA", nil, true, nil)
+	crl := newTestCRL(ca, testRevocationNextUpdate)
+	ocspResponse := []byte("ocsp response")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/ca.crl" && r.Method == http.MethodGet:
+			w.Write(crl)
+		case r.URL.Path == "/ocsp" && r.Method == http.MethodPost && r.Header.Get("Content-Type") == "application/ocsp-request":
+			request, _ := ioutil.ReadAll(r.Body)
+			if string(request) != "ocsp request" {
+				http.Error(w, "malformed request", http.StatusBadRequest)
+				return
+			}
+			w.Write(ocspResponse)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := &HTTPRevocationFetcher{Client: server.Client()}
+	if data, err := fetcher.FetchCRL(server.URL + "/ca.crl"); err != nil {
+		t.Errorf("FetchCRL() returns error %q", err)
+	} else if string(data) != string(crl) {
+		t.Errorf("FetchCRL() returns %d bytes, want CRL of %d bytes", len(data), len(crl))
+	}
+	if data, err := fetcher.FetchOCSP(server.URL+"/ocsp", []byte("ocsp request")); err != nil {
+		t.Errorf("FetchOCSP() returns error %q", err)
+	} else if string(data) != string(ocspResponse) {
+		t.Errorf("FetchOCSP() returns %q, want %q", data, ocspResponse)
+	}
+	if _, err := fetcher.FetchCRL(server.URL + "/missing.crl"); err == nil || !strings.Contains(err.Error(), "HTTP status 404 Not Found") {
+		t.Errorf("FetchCRL() returns error %v, want error containing substring %q", err, "HTTP status 404 Not Found")
+	}
+
+	// Revocation status is checked with CRL served by HTTP.
+	leaf := newTestRevocableCertificate("CRL Attestation", 1, ca, server.URL+"/ca.crl", "", nil)
+	checker := NewRevocationChecker(fetcher, RevocationHardFail)
+	checker.now = func() time.Time { return testRevocationNow }
+	results, err := checker.Check([]*x509.Certificate{leaf.Cert, ca.Cert})
+	if err != nil {
+		t.Fatalf("Check() returns error %q", err)
+	}
+	if len(results) != 1 || results[0].Status != RevocationStatusGood {
+		t.Errorf("Check() returns %v, want good revocation status", results)
+	}
+}
+
+func TestHTTPRevocationFetcherTimeout(t *testing.T) {
+	if defaultRevocationClient.Timeout <= 0 {
+		t.Errorf("default HTTP client timeout %v, want bounded timeout", defaultRevocationClient.Timeout)
+	}
+
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	savedClient := defaultRevocationClient
+	defaultRevocationClient = &http.Client{Timeout: 50 * time.Millisecond}
+	defer func() { defaultRevocationClient = savedClient }()
+
+	// Fetcher without client doesn't wait for slow endpoints indefinitely.
+	fetcher := &HTTPRevocationFetcher{}
+	wantErrorMsg := "Client.Timeout exceeded"
+	if _, err := fetcher.FetchCRL(server.URL + "/ca.crl"); err == nil {
+		t.Errorf("FetchCRL() returns no error, want error containing substring %q", wantErrorMsg)
+	} else if !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("FetchCRL() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	}
+	if _, err := fetcher.FetchOCSP(server.URL+"/ocsp", []byte("ocsp request")); err == nil {
+		t.Errorf("FetchOCSP() returns no error, want error containing substring %q", wantErrorMsg)
+	} else if !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("FetchOCSP() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	}
+}
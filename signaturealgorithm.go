@@ -36,6 +36,7 @@ const (
 	COSEAlgRS256 = -257   // RSASSA-PKCS1-v1_5 with SHA-256
 	COSEAlgRS384 = -258   // RSASSA-PKCS1-v1_5 with SHA-384
 	COSEAlgRS512 = -259   // RSASSA-PKCS1-v1_5 with SHA-512
+	COSEAlgED256 = -260   // ECDAA with TPM_ECC_BN_P256 and SHA-256
 )
 
 // SignatureAlgorithm represents signature algorithm, and its corresponding public key algorithm,
@@ -0,0 +1,69 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn
+
+// TokenBindingPolicy determines how client data token binding is verified when the Token Binding state
+// of the TLS connection isn't provided.
+type TokenBindingPolicy int
+
+// Token binding policies.
+const (
+	TokenBindingPolicyLenient TokenBindingPolicy = iota // Don't verify client data token binding without expected Token Binding state.
+	TokenBindingPolicyStrict                            // Reject "present" client data token binding without expected Token Binding state.
+)
+
+// verifyTokenBinding verifies that client data token binding matches the Token Binding state of the TLS connection
+// over which attestation or assertion was obtained, as defined in http://w3c.github.io/webauthn/#sctn-registering-a-new-credential
+// and http://w3c.github.io/webauthn/#sctn-verifying-assertion.  Token binding status "supported" and an absent
+// token binding are treated the same, because the TLS layer only knows whether Token Binding was used.
+func verifyTokenBinding(typ string, clientTokenBinding *TokenBinding, expected *TokenBinding, policy TokenBindingPolicy) error {
+	clientPresent := clientTokenBinding != nil && clientTokenBinding.Status == TokenBindingPresent
+
+	if expected == nil {
+		if clientPresent && policy == TokenBindingPolicyStrict {
+			return &VerificationError{Type: typ, Field: "client data token binding", Msg: "token binding is present, but Token Binding state of the connection is unknown"}
+		}
+		return nil
+	}
+
+	if expected.Status != TokenBindingPresent {
+		if clientPresent {
+			return &VerificationError{Type: typ, Field: "client data token binding", Msg: "token binding is present, but Token Binding wasn't used on the connection"}
+		}
+		return nil
+	}
+
+	// Verify that token binding status is present and C.tokenBinding.id matches the base64url encoding of
+	// the Token Binding ID for the connection.
+	if !clientPresent {
+		return &VerificationError{Type: typ, Field: "client data token binding", Msg: "token binding isn't present, but Token Binding was used on the connection"}
+	}
+	clientID, err := base64DecodeString(clientTokenBinding.ID)
+	if err != nil {
+		return &VerificationError{Type: typ, Field: "client data token binding", Msg: "token binding ID isn't base64url encoded: " + err.Error()}
+	}
+	expectedID, err := base64DecodeString(expected.ID)
+	if err != nil {
+		return &VerificationError{Type: typ, Field: "client data token binding", Msg: "expected token binding ID isn't base64url encoded: " + err.Error()}
+	}
+	if len(expectedID) == 0 || string(clientID) != string(expectedID) {
+		return &VerificationError{Type: typ, Field: "client data token binding", Msg: "token binding ID does not match Token Binding ID of the connection"}
+	}
+	return nil
+}
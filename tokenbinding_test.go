@@ -0,0 +1,64 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyTokenBinding(t *testing.T) {
+	present := &TokenBinding{Status: TokenBindingPresent, ID: "dGJpZA"}
+	supported := &TokenBinding{Status: TokenBindingSupported}
+
+	testCases := []struct {
+		name               string
+		clientTokenBinding *TokenBinding
+		expected           *TokenBinding
+		policy             TokenBindingPolicy
+		wantErrorMsg       string
+	}{
+		{"no token binding", nil, nil, TokenBindingPolicyLenient, ""},
+		{"present without expected state", present, nil, TokenBindingPolicyLenient, ""},
+		{"present without expected state with strict policy", present, nil, TokenBindingPolicyStrict, "token binding is present, but Token Binding state of the connection is unknown"},
+		{"supported without expected state with strict policy", supported, nil, TokenBindingPolicyStrict, ""},
+		{"present", present, &TokenBinding{Status: TokenBindingPresent, ID: "dGJpZA"}, TokenBindingPolicyLenient, ""},
+		{"present with padded expected id", present, &TokenBinding{Status: TokenBindingPresent, ID: "dGJpZA=="}, TokenBindingPolicyLenient, ""},
+		{"present with different id", present, &TokenBinding{Status: TokenBindingPresent, ID: "b3RoZXI"}, TokenBindingPolicyLenient, "token binding ID does not match Token Binding ID of the connection"},
+		{"present with empty expected id", present, &TokenBinding{Status: TokenBindingPresent}, TokenBindingPolicyLenient, "token binding ID does not match Token Binding ID of the connection"},
+		{"present when not used on connection", present, &TokenBinding{Status: TokenBindingSupported}, TokenBindingPolicyLenient, "token binding is present, but Token Binding wasn't used on the connection"},
+		{"supported when not used on connection", supported, &TokenBinding{Status: TokenBindingSupported}, TokenBindingPolicyLenient, ""},
+		{"absent when not used on connection", nil, &TokenBinding{}, TokenBindingPolicyLenient, ""},
+		{"supported when used on connection", supported, &TokenBinding{Status: TokenBindingPresent, ID: "dGJpZA"}, TokenBindingPolicyLenient, "token binding isn't present, but Token Binding was used on the connection"},
+		{"absent when used on connection", nil, &TokenBinding{Status: TokenBindingPresent, ID: "dGJpZA"}, TokenBindingPolicyLenient, "token binding isn't present, but Token Binding was used on the connection"},
+		{"present with invalid id", &TokenBinding{Status: TokenBindingPresent, ID: "!!"}, &TokenBinding{Status: TokenBindingPresent, ID: "dGJpZA"}, TokenBindingPolicyLenient, "token binding ID isn't base64url encoded"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifyTokenBinding("assertion", tc.clientTokenBinding, tc.expected, tc.policy)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("verifyTokenBinding() returns error %q", err)
+			} else if tc.wantErrorMsg != "" && err == nil {
+				t.Errorf("verifyTokenBinding() returns no error, want error containing substring %q", tc.wantErrorMsg)
+			} else if tc.wantErrorMsg != "" && !strings.Contains(err.Error(), tc.wantErrorMsg) {
+				t.Errorf("verifyTokenBinding() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+			}
+		})
+	}
+}
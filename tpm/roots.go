@@ -0,0 +1,54 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+
+Modified by Kappa
+*/
+
+package tpm
+
+import (
+	"strings"
+
+	"github.com/kappapay/webauthn"
+)
+
+// Roots is a set of trusted TPM vendor root certificates keyed by TPM manufacturer ID defined in the
+// TCG Vendor ID Registry, such as "id:4E544300".  AIK certificates are only verified with root
+// certificates of the manufacturer in their subject alternative name, so a root certificate trusted for
+// several manufacturers, such as Microsoft TPM root certificate authority that issues AIK certificates
+// for Windows, needs to be added for each of them.
+type Roots = webauthn.CertificateStore
+
+// NewRoots returns an empty set of trusted root certificates, with manufacturer IDs normalized to
+// upper case hex digits.
+func NewRoots() *Roots {
+	return webauthn.NewCertificateStore(normalizeManufacturerID)
+}
+
+// DefaultRoots is the set of root certificates trusted to verify AIK certificates of tpm attestation
+// statements.  Trust anchors of webauthn.CertificatePolicy don't apply to tpm attestation statements.  It
+// is empty by default, tpm attestation statements are verified with attestation type uncertain until TPM
+// vendor and Microsoft TPM root certificates are added.
+var DefaultRoots = NewRoots()
+
+// normalizeManufacturerID returns manufacturer ID with upper case hex digits, because TPM vendors
+// encode the same manufacturer ID in different cases.
+func normalizeManufacturerID(manufacturerID string) string {
+	if strings.HasPrefix(manufacturerID, "id:") {
+		return "id:" + strings.ToUpper(manufacturerID[3:])
+	}
+	return manufacturerID
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+
+Modified by Kappa
+*/
+
+package tpm
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/internal/testcert"
+)
+
+// newTestAIKCertificate returns AIK certificate issued by parent.
+func newTestAIKCertificate(parent *testcert.CA) *x509.Certificate {
+	template := &x509.Certificate{UnknownExtKeyUsage: []asn1.ObjectIdentifier{oidTcgKpAikCertificate}}
+	return testcert.NewCertificate(template, &testcert.NewKey().PublicKey, parent)
+}
+
+func TestRoots(t *testing.T) {
+	root1 := testcert.NewCA("Test Root CA 1", nil)
+	root2 := testcert.NewCA("Test Root CA 2", nil)
+
+	roots := NewRoots()
+	if certs := roots.Certificates("id:4E544300"); len(certs) != 0 {
+		t.Errorf("roots of empty roots %v, want none", certs)
+	}
+
+	roots.Add("id:4E544300", root1.Cert)
+	roots.Add("id:4e544300", root1.Cert)
+	if certs := roots.Certificates("id:4E544300"); len(certs) != 1 || !certs[0].Equal(root1.Cert) {
+		t.Errorf("roots %v, want root1", certs)
+	}
+	if certs := roots.Certificates("id:49465800"); len(certs) != 0 {
+		t.Errorf("roots of other manufacturer %v, want none", certs)
+	}
+
+	dir, err := ioutil.TempDir("", "tpm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "root.pem")
+	if err := ioutil.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root2.Cert.Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := roots.AddPEMFile("id:49465800", filename); err != nil {
+		t.Fatalf("AddPEMFile() returns error %q", err)
+	}
+	if certs := roots.Certificates("id:49465800"); len(certs) != 1 || !certs[0].Equal(root2.Cert) {
+		t.Errorf("roots %v, want root2", certs)
+	}
+	if err := roots.AddPEMFile("id:49465800", filepath.Join(dir, "missing.pem")); err == nil {
+		t.Errorf("AddPEMFile() of missing file returns no error")
+	}
+
+	wantErrorMsg := "no PEM encoded certificate"
+	if err := roots.AddPEM("id:49465800", []byte("not pem")); err == nil {
+		t.Errorf("AddPEM() returns no error, want error containing substring %q", wantErrorMsg)
+	} else if !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("AddPEM() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	}
+
+	roots.Set("id:4E544300", nil)
+	if certs := roots.Certificates("id:4E544300"); len(certs) != 0 {
+		t.Errorf("roots %v, want none", certs)
+	}
+}
+
+func TestVerifyAttestationCertManufacturerRoots(t *testing.T) {
+	nuvotonRoot := testcert.NewCA("Test Nuvoton Root CA", nil)
+	nuvotonIntermediate := testcert.NewCA("Test Nuvoton Intermediate CA", nuvotonRoot)
+	infineonRoot := testcert.NewCA("Test Infineon Root CA", nil)
+	infineonIntermediate := testcert.NewCA("Test Infineon Intermediate CA", infineonRoot)
+	microsoftRoot := testcert.NewCA("Test Microsoft Root CA", nil)
+	microsoftIntermediate := testcert.NewCA("Test Microsoft Intermediate CA", microsoftRoot)
+	attackerRoot := testcert.NewCA("Attacker Root CA", nil)
+
+	nuvotonAIK := newTestAIKCertificate(nuvotonIntermediate)
+	infineonAIK := newTestAIKCertificate(infineonIntermediate)
+	microsoftAIK := newTestAIKCertificate(microsoftIntermediate)
+	attackerAIK := newTestAIKCertificate(attackerRoot)
+
+	savedRoots := DefaultRoots
+	DefaultRoots = NewRoots()
+	defer func() { DefaultRoots = savedRoots }()
+	DefaultRoots.Add("id:4E544300", nuvotonRoot.Cert)
+	DefaultRoots.Add("id:49465800", infineonRoot.Cert)
+
+	testCases := []struct {
+		name         string
+		aikCert      *x509.Certificate
+		caCerts      []*x509.Certificate
+		manufacturer string
+		addMicrosoft bool
+		wantTrusted  bool
+		wantErrorMsg string
+	}{
+		{"manufacturer root", nuvotonAIK, []*x509.Certificate{nuvotonIntermediate.Cert}, "id:4E544300", false, true, ""},
+		{"manufacturer root in x5c", nuvotonAIK, []*x509.Certificate{nuvotonIntermediate.Cert, nuvotonRoot.Cert}, "id:4E544300", false, true, ""},
+		{"other manufacturer root", infineonAIK, []*x509.Certificate{infineonIntermediate.Cert}, "id:4E544300", false, false, "certificate signed by unknown authority"},
+		{"self-signed root in x5c", attackerAIK, []*x509.Certificate{attackerRoot.Cert}, "id:4E544300", false, false, "certificate signed by unknown authority"},
+		{"no manufacturer root", nuvotonAIK, []*x509.Certificate{nuvotonIntermediate.Cert}, "id:494E5443", false, false, ""},
+		{"microsoft root", microsoftAIK, []*x509.Certificate{microsoftIntermediate.Cert}, "id:494E5443", true, true, ""},
+		{"microsoft root of other manufacturer", microsoftAIK, []*x509.Certificate{microsoftIntermediate.Cert}, "id:494E5443", false, false, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.addMicrosoft {
+				DefaultRoots.Add(tc.manufacturer, microsoftRoot.Cert)
+				defer DefaultRoots.Set(tc.manufacturer, nil)
+			}
+			trustPath, trusted, err := webauthn.VerifyAttestationCertificateWithRoots(tc.aikCert, tc.caCerts, DefaultRoots.Certificates(tc.manufacturer))
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyAttestationCertificateWithRoots() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyAttestationCertificateWithRoots() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyAttestationCertificateWithRoots() returns error %q", err)
+			}
+			if trusted != tc.wantTrusted {
+				t.Errorf("VerifyAttestationCertificateWithRoots() returns trusted %t, want %t", trusted, tc.wantTrusted)
+			}
+			if tc.wantTrusted && len(trustPath) != 3 {
+				t.Errorf("trust path %v, want chain to trusted root", trustPath)
+			}
+			if !tc.wantTrusted && len(trustPath) != 1+len(tc.caCerts) {
+				t.Errorf("trust path %v, want x5c", trustPath)
+			}
+		})
+	}
+}
+
+func TestVerifyTPMAttestationWithoutTrustedRoot(t *testing.T) {
+	savedRoots := DefaultRoots
+	DefaultRoots = NewRoots()
+	defer func() { DefaultRoots = savedRoots }()
+
+	// Trust anchors of other formats and global trust anchors don't apply to AIK certificates.
+	webauthn.DefaultTrustAnchors.Add("tpm", nil, parseCertificate(attestation1CACert0))
+	webauthn.DefaultTrustAnchors.Add("", nil, parseCertificate(attestation1CACert0))
+	defer webauthn.DefaultTrustAnchors.Set("tpm", nil, nil)
+	defer webauthn.DefaultTrustAnchors.Set("", nil, nil)
+
+	var credentialAttestation webauthn.PublicKeyCredentialAttestation
+	if err := json.Unmarshal([]byte(attestation1), &credentialAttestation); err != nil {
+		t.Fatalf("failed to unmarshal attestation %s: %q", attestation1, err)
+	}
+	attType, trustPath, err := credentialAttestation.VerifyAttestationStatement()
+	if err != nil {
+		t.Fatalf("VerifyAttestationStatement() returns error %q", err)
+	}
+	if attType != webauthn.AttestationTypeUncertain {
+		t.Errorf("attestation type %v, want %v", attType, webauthn.AttestationTypeUncertain)
+	}
+	if certs, ok := trustPath.([]*x509.Certificate); !ok || len(certs) != 2 {
+		t.Errorf("trust path %v, want x5c", trustPath)
+	}
+}
+
+func TestVerifyTPMAttestationDetails(t *testing.T) {
+	savedRoots := DefaultRoots
+	DefaultRoots = NewRoots()
+	defer func() { DefaultRoots = savedRoots }()
+
+	// Trust the intermediate certificate of attestation 1 because Microsoft TPM root certificate isn't included in test data.
+	DefaultRoots.Add("id:4E544300", parseCertificate(attestation1CACert0))
+
+	var credentialAttestation webauthn.PublicKeyCredentialAttestation
+	if err := json.Unmarshal([]byte(attestation1), &credentialAttestation); err != nil {
+		t.Fatalf("failed to unmarshal attestation %s: %q", attestation1, err)
+	}
+	attType, _, err := credentialAttestation.VerifyAttestationStatement()
+	if err != nil {
+		t.Fatalf("VerifyAttestationStatement() returns error %q", err)
+	}
+	if attType != webauthn.AttestationTypeCA {
+		t.Errorf("attestation type %v, want %v", attType, webauthn.AttestationTypeCA)
+	}
+	wantDetails := &AttestationDetails{
+		Manufacturer:     "id:4E544300",
+		ManufacturerName: "Nuvoton Technology",
+		Model:            "NPCT6xx",
+		Version:          "id:13",
+		ClockInfo: ClockInfo{
+			Clock:        0x00000001b15a48c7,
+			ResetCount:   0x6840f9e3,
+			RestartCount: 0xd8f39f05,
+			Safe:         true,
+		},
+		FirmwareVersion:         0xa9e0c4a53fbbc413,
+		QualifiedSignerHashType: "TPM_ALG_SHA256",
+		QualifiedSigner: []byte{
+			0xbc, 0x59, 0xf4, 0xdf, 0xd9, 0xa6, 0xa4, 0x2d, 0xc3, 0xb8, 0x66, 0xaf, 0xf2, 0xdf, 0x0d, 0x19,
+			0x82, 0x6b, 0xbf, 0x01, 0x4b, 0x67, 0xab, 0x0a, 0xd6, 0xeb, 0xb1, 0x76, 0x30, 0x6b, 0x80, 0x07,
+		},
+	}
+	details := credentialAttestation.AttStmt.(webauthn.AttestationDetailer).AttestationDetails()
+	if !reflect.DeepEqual(details, wantDetails) {
+		t.Errorf("attestation details %+v, want %+v", details, wantDetails)
+	}
+}
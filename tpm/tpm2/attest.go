@@ -0,0 +1,233 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package tpm2
+
+import (
+	"fmt"
+)
+
+// ClockInfo represents TPMS_CLOCK_INFO, as specified in TPM 2.0 Part 2 section 10.11.1.
+// ResetCount and RestartCount are obfuscated by the TPM if the signing key isn't in the endorsement or platform hierarchy.
+type ClockInfo struct {
+	Clock        uint64 // Time in milliseconds during which the TPM has been powered.
+	ResetCount   uint32 // Number of occurrences of TPM Reset since the last TPM2_Clear().
+	RestartCount uint32 // Number of times that TPM2_Shutdown() or _TPM_Hash_Start have occurred since the last TPM Reset or TPM2_Clear().
+	Safe         bool   // No value of Clock greater than the current value of Clock has been previously reported by the TPM.
+}
+
+// FirmwareVersion represents TPM-vendor-specific firmware version in TPMS_ATTEST structure.  It is composed of
+// TPM_PT_FIRMWARE_VERSION_1 and TPM_PT_FIRMWARE_VERSION_2 properties, and is obfuscated by the TPM if the signing
+// key isn't in the endorsement or platform hierarchy.
+type FirmwareVersion uint64
+
+// String returns firmware version in the "major.minor.build.revision" form used by most TPM vendors, such as "7.62.3126.0".
+func (v FirmwareVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d.%d", uint16(v>>48), uint16(v>>32), uint16(v>>16), uint16(v))
+}
+
+// CertifyInfo represents TPMS_CERTIFY_INFO, as specified in TPM 2.0 Part 2 section 10.12.3.
+type CertifyInfo struct {
+	Name          Name // Name of the certified object.
+	QualifiedName Name // Qualified name of the certified object.
+}
+
+// PCRSelection represents TPMS_PCR_SELECTION, as specified in TPM 2.0 Part 2 section 10.6.2.
+type PCRSelection struct {
+	Hash   Algorithm // Hashing algorithm of the PCR bank.
+	Select []byte    // Bit map of selected PCRs, PCR n is selected if bit n%8 of Select[n/8] is set.
+}
+
+// PCRs returns indexes of selected PCRs in ascending order.
+func (s PCRSelection) PCRs() []int {
+	var pcrs []int
+	for i, b := range s.Select {
+		for bit := 0; bit < 8; bit++ {
+			if b&(1<<uint(bit)) != 0 {
+				pcrs = append(pcrs, i*8+bit)
+			}
+		}
+	}
+	return pcrs
+}
+
+// QuoteInfo represents TPMS_QUOTE_INFO, as specified in TPM 2.0 Part 2 section 10.12.4.
+type QuoteInfo struct {
+	PCRSelect []PCRSelection // PCRs included in PCRDigest.
+	PCRDigest []byte         // Digest of the selected PCRs using the hash of the signing key.
+}
+
+// Attest represents TPMS_ATTEST, as specified in TPM 2.0 Part 2 section 10.12.8.
+// Only TagAttestCertify and TagAttestQuote types are supported.
+type Attest struct {
+	Magic           uint32          // The indication that this structure was created by a TPM, always GeneratedValue.
+	Type            StructureTag    // Type of the attestation structure.
+	QualifiedSigner Name            // Qualified name of the signing key.
+	ExtraData       []byte          // External information supplied by caller.
+	ClockInfo       ClockInfo       // Clock, resetCount, restartCount, and Safe.
+	FirmwareVersion FirmwareVersion // TPM-vendor-specific value identifying the version number of the firmware.
+	Certify         *CertifyInfo    // Attested data, only for TagAttestCertify type.
+	Quote           *QuoteInfo      // Attested data, only for TagAttestQuote type.
+}
+
+// DecodeAttest decodes TPMS_ATTEST structure.
+func DecodeAttest(data []byte) (*Attest, error) {
+	d := &decoder{structure: "TPMS_ATTEST", data: data}
+
+	a := &Attest{}
+	var err error
+	if a.Magic, err = d.uint32("magic"); err != nil {
+		return nil, err
+	}
+	typ, err := d.uint16("type")
+	if err != nil {
+		return nil, err
+	}
+	a.Type = StructureTag(typ)
+	if a.QualifiedSigner, err = d.name("qualifiedSigner"); err != nil {
+		return nil, err
+	}
+	if a.ExtraData, err = d.sized("extraData"); err != nil {
+		return nil, err
+	}
+	if a.ClockInfo.Clock, err = d.uint64("clockInfo.clock"); err != nil {
+		return nil, err
+	}
+	if a.ClockInfo.ResetCount, err = d.uint32("clockInfo.resetCount"); err != nil {
+		return nil, err
+	}
+	if a.ClockInfo.RestartCount, err = d.uint32("clockInfo.restartCount"); err != nil {
+		return nil, err
+	}
+	safe, err := d.uint8("clockInfo.safe")
+	if err != nil {
+		return nil, err
+	}
+	switch safe {
+	case 0:
+		a.ClockInfo.Safe = false
+	case 1:
+		a.ClockInfo.Safe = true
+	default:
+		return nil, &UnmarshalError{Structure: "TPMS_ATTEST", Field: "clockInfo.safe", Msg: fmt.Sprintf("expected TPMI_YES_NO value, got %d", safe)}
+	}
+	firmwareVersion, err := d.uint64("firmwareVersion")
+	if err != nil {
+		return nil, err
+	}
+	a.FirmwareVersion = FirmwareVersion(firmwareVersion)
+
+	switch a.Type {
+	case TagAttestCertify:
+		certify := &CertifyInfo{}
+		if certify.Name, err = d.name("attested.name"); err != nil {
+			return nil, err
+		}
+		if certify.QualifiedName, err = d.name("attested.qualifiedName"); err != nil {
+			return nil, err
+		}
+		a.Certify = certify
+	case TagAttestQuote:
+		quote := &QuoteInfo{}
+		count, err := d.uint32("attested.pcrSelect.count")
+		if err != nil {
+			return nil, err
+		}
+		for i := uint32(0); i < count; i++ {
+			field := fmt.Sprintf("attested.pcrSelect[%d]", i)
+			var s PCRSelection
+			if s.Hash, err = d.algorithm(field + ".hash"); err != nil {
+				return nil, err
+			}
+			size, err := d.uint8(field + ".sizeofSelect")
+			if err != nil {
+				return nil, err
+			}
+			if s.Select, err = d.bytes(field+".pcrSelect", int(size)); err != nil {
+				return nil, err
+			}
+			quote.PCRSelect = append(quote.PCRSelect, s)
+		}
+		if quote.PCRDigest, err = d.sized("attested.pcrDigest"); err != nil {
+			return nil, err
+		}
+		a.Quote = quote
+	default:
+		return nil, &UnsupportedError{Structure: "TPMS_ATTEST", Feature: "type " + a.Type.String()}
+	}
+
+	if err = d.done(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Marshal encodes a as TPMS_ATTEST structure.
+func (a *Attest) Marshal() ([]byte, error) {
+	e := &encoder{structure: "TPMS_ATTEST"}
+	e.uint32(a.Magic)
+	e.uint16(uint16(a.Type))
+	if err := e.name("qualifiedSigner", a.QualifiedSigner); err != nil {
+		return nil, err
+	}
+	if err := e.sized("extraData", a.ExtraData); err != nil {
+		return nil, err
+	}
+	e.uint64(a.ClockInfo.Clock)
+	e.uint32(a.ClockInfo.ResetCount)
+	e.uint32(a.ClockInfo.RestartCount)
+	if a.ClockInfo.Safe {
+		e.uint8(1)
+	} else {
+		e.uint8(0)
+	}
+	e.uint64(uint64(a.FirmwareVersion))
+
+	switch a.Type {
+	case TagAttestCertify:
+		if a.Certify == nil {
+			return nil, &MarshalError{Structure: "TPMS_ATTEST", Field: "attested", Msg: "missing certify info"}
+		}
+		if err := e.name("attested.name", a.Certify.Name); err != nil {
+			return nil, err
+		}
+		if err := e.name("attested.qualifiedName", a.Certify.QualifiedName); err != nil {
+			return nil, err
+		}
+	case TagAttestQuote:
+		if a.Quote == nil {
+			return nil, &MarshalError{Structure: "TPMS_ATTEST", Field: "attested", Msg: "missing quote info"}
+		}
+		e.uint32(uint32(len(a.Quote.PCRSelect)))
+		for i, s := range a.Quote.PCRSelect {
+			if len(s.Select) > 0xff {
+				return nil, &MarshalError{Structure: "TPMS_ATTEST", Field: fmt.Sprintf("attested.pcrSelect[%d].pcrSelect", i), Msg: fmt.Sprintf("size %d exceeds 255 bytes", len(s.Select))}
+			}
+			e.algorithm(s.Hash)
+			e.uint8(uint8(len(s.Select)))
+			e.buf.Write(s.Select)
+		}
+		if err := e.sized("attested.pcrDigest", a.Quote.PCRDigest); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &UnsupportedError{Structure: "TPMS_ATTEST", Feature: "type " + a.Type.String()}
+	}
+
+	return e.buf.Bytes(), nil
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package tpm2
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeAttest(t *testing.T) {
+	attest, err := DecodeAttest(testCertInfo)
+	if err != nil {
+		t.Fatalf("DecodeAttest() returns error %q", err)
+	}
+	if attest.Magic != GeneratedValue {
+		t.Errorf("magic %#x, want %#x", attest.Magic, GeneratedValue)
+	}
+	if attest.Type != TagAttestCertify {
+		t.Errorf("type %s, want %s", attest.Type, TagAttestCertify)
+	}
+	wantQualifiedSigner := Name{HashAlg: AlgSHA256, Digest: mustDecodeHex("bc59f4dfd9a6a42dc3b866aff2df0d19826bbf014b67ab0ad6ebb176306b8007")}
+	if !attest.QualifiedSigner.Equal(wantQualifiedSigner) {
+		t.Errorf("qualifiedSigner %+v, want %+v", attest.QualifiedSigner, wantQualifiedSigner)
+	}
+	if len(attest.ExtraData) != 20 {
+		t.Errorf("extraData length %d, want 20", len(attest.ExtraData))
+	}
+	wantClockInfo := ClockInfo{Clock: 0x00000001b15a48c7, ResetCount: 0x6840f9e3, RestartCount: 0xd8f39f05, Safe: true}
+	if attest.ClockInfo != wantClockInfo {
+		t.Errorf("clockInfo %+v, want %+v", attest.ClockInfo, wantClockInfo)
+	}
+	if attest.FirmwareVersion != 0xa9e0c4a53fbbc413 {
+		t.Errorf("firmwareVersion %#x, want %#x", uint64(attest.FirmwareVersion), uint64(0xa9e0c4a53fbbc413))
+	}
+	if attest.Certify == nil {
+		t.Fatalf("certify info is nil")
+	}
+	if attest.Certify.Name.HashAlg != AlgSHA256 || len(attest.Certify.Name.Digest) != 32 {
+		t.Errorf("certify name %+v, want SHA-256 name", attest.Certify.Name)
+	}
+	if attest.Certify.QualifiedName.HashAlg != AlgSHA256 || len(attest.Certify.QualifiedName.Digest) != 32 {
+		t.Errorf("certify qualified name %+v, want SHA-256 name", attest.Certify.QualifiedName)
+	}
+	if attest.Quote != nil {
+		t.Errorf("quote info %+v, want nil", attest.Quote)
+	}
+
+	data, err := attest.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() returns error %q", err)
+	}
+	if !bytes.Equal(data, testCertInfo) {
+		t.Errorf("Marshal() returns %x, want %x", data, testCertInfo)
+	}
+}
+
+func TestAttestQuote(t *testing.T) {
+	attest := &Attest{
+		Magic:           GeneratedValue,
+		Type:            TagAttestQuote,
+		QualifiedSigner: Name{HashAlg: AlgSHA256, Digest: bytes.Repeat([]byte{0x01}, 32)},
+		ExtraData:       []byte("nonce"),
+		ClockInfo:       ClockInfo{Clock: 1000, ResetCount: 1, RestartCount: 2, Safe: true},
+		FirmwareVersion: 0x0007003E0C360000,
+		Quote: &QuoteInfo{
+			PCRSelect: []PCRSelection{
+				{Hash: AlgSHA1, Select: []byte{0x00, 0x00, 0x00}},
+				{Hash: AlgSHA256, Select: []byte{0x81, 0x00, 0x01}},
+			},
+			PCRDigest: bytes.Repeat([]byte{0x02}, 32),
+		},
+	}
+	data, err := attest.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() returns error %q", err)
+	}
+	decoded, err := DecodeAttest(data)
+	if err != nil {
+		t.Fatalf("DecodeAttest() returns error %q", err)
+	}
+	if !reflect.DeepEqual(decoded, attest) {
+		t.Errorf("DecodeAttest() returns %+v, want %+v", decoded, attest)
+	}
+	if pcrs := decoded.Quote.PCRSelect[0].PCRs(); len(pcrs) != 0 {
+		t.Errorf("PCRs() returns %v, want none", pcrs)
+	}
+	if pcrs, want := decoded.Quote.PCRSelect[1].PCRs(), []int{0, 7, 16}; !reflect.DeepEqual(pcrs, want) {
+		t.Errorf("PCRs() returns %v, want %v", pcrs, want)
+	}
+}
+
+func TestDecodeAttestError(t *testing.T) {
+	// clockInfo.safe is located after magic, type, qualifiedSigner, extraData, and the first 16 bytes of clockInfo.
+	safeOffset := 4 + 2 + 2 + 34 + 2 + 20 + 16
+	badSafe := append([]byte(nil), testCertInfo...)
+	badSafe[safeOffset] = 2
+
+	unsupportedType := append([]byte(nil), testCertInfo...)
+	unsupportedType[4], unsupportedType[5] = 0x80, 0x19 // TPM_ST_ATTEST_TIME
+
+	testCases := []struct {
+		name         string
+		data         []byte
+		wantErrorMsg string
+	}{
+		{"empty", nil, "tpm2: failed to unmarshal TPMS_ATTEST magic: unexpected EOF"},
+		{"truncated extraData", testCertInfo[:50], "tpm2: failed to unmarshal TPMS_ATTEST extraData: unexpected EOF"},
+		{"truncated attested", testCertInfo[:len(testCertInfo)-1], "tpm2: failed to unmarshal TPMS_ATTEST attested.qualifiedName: unexpected EOF"},
+		{"bad clockInfo.safe", badSafe, "tpm2: failed to unmarshal TPMS_ATTEST clockInfo.safe: expected TPMI_YES_NO value, got 2"},
+		{"unsupported type", unsupportedType, "tpm2: TPMS_ATTEST type TPM_ST_ATTEST_TIME is not supported"},
+		{"trailing data", append(append([]byte(nil), testCertInfo...), 0x00), "tpm2: failed to unmarshal TPMS_ATTEST: trailing data (1 bytes)"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := DecodeAttest(tc.data); err == nil {
+				t.Errorf("DecodeAttest() returns no error, want error containing substring %q", tc.wantErrorMsg)
+			} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+				t.Errorf("DecodeAttest() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+			}
+		})
+	}
+}
+
+func TestFirmwareVersionString(t *testing.T) {
+	testCases := []struct {
+		version FirmwareVersion
+		want    string
+	}{
+		{0x0007003E0C360000, "7.62.3126.0"},
+		{0x0001000200030004, "1.2.3.4"},
+		{0, "0.0.0.0"},
+	}
+	for _, tc := range testCases {
+		if s := tc.version.String(); s != tc.want {
+			t.Errorf("FirmwareVersion(%#x).String() returns %q, want %q", uint64(tc.version), s, tc.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package tpm2
+
+import (
+	"bytes"
+)
+
+// Name represents TPM2B_NAME of an object, which is the digest of its TPMT_PUBLIC structure prefixed with
+// the name algorithm, as specified in TPM 2.0 Part 1 section 16 and Part 2 section 10.5.3.  The zero value
+// represents empty TPM2B_NAME.
+type Name struct {
+	HashAlg Algorithm // Hashing algorithm used to compute the name.
+	Digest  []byte    // Digest of the object's public area.
+}
+
+// ComputeName returns name of object with given public area, which is the encoded TPMT_PUBLIC structure,
+// using hashing algorithm nameAlg.
+func ComputeName(nameAlg Algorithm, publicArea []byte) (Name, error) {
+	hash, ok := nameAlg.Hash()
+	if !ok || !hash.Available() {
+		return Name{}, &UnsupportedError{Structure: "TPM2B_NAME", Feature: "name algorithm " + nameAlg.String()}
+	}
+	h := hash.New()
+	h.Write(publicArea)
+	return Name{HashAlg: nameAlg, Digest: h.Sum(nil)}, nil
+}
+
+// Equal returns true if n and other are the same name.
+func (n Name) Equal(other Name) bool {
+	return n.HashAlg == other.HashAlg && bytes.Equal(n.Digest, other.Digest)
+}
+
+// DecodeName decodes TPM2B_NAME structure.
+func DecodeName(data []byte) (Name, error) {
+	d := &decoder{structure: "TPM2B_NAME", data: data}
+	n, err := d.name("")
+	if err != nil {
+		return Name{}, err
+	}
+	return n, d.done()
+}
+
+// Marshal encodes n as TPM2B_NAME structure.
+func (n Name) Marshal() ([]byte, error) {
+	e := &encoder{structure: "TPM2B_NAME"}
+	if err := e.name("", n); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+func (d *decoder) name(field string) (Name, error) {
+	data, err := d.sized(field)
+	if err != nil {
+		return Name{}, err
+	}
+	if len(data) == 0 {
+		return Name{}, nil
+	}
+	if len(data) < 2 {
+		return Name{}, d.eof(field)
+	}
+	return Name{HashAlg: Algorithm(uint16(data[0])<<8 | uint16(data[1])), Digest: data[2:]}, nil
+}
+
+func (e *encoder) name(field string, n Name) error {
+	if n.HashAlg == AlgError && len(n.Digest) == 0 {
+		e.uint16(0)
+		return nil
+	}
+	data := make([]byte, 2+len(n.Digest))
+	data[0], data[1] = byte(n.HashAlg>>8), byte(n.HashAlg)
+	copy(data[2:], n.Digest)
+	return e.sized(field, data)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package tpm2
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestComputeName(t *testing.T) {
+	attest, err := DecodeAttest(testCertInfo)
+	if err != nil {
+		t.Fatalf("DecodeAttest() returns error %q", err)
+	}
+	name, err := ComputeName(AlgSHA256, testPubArea)
+	if err != nil {
+		t.Fatalf("ComputeName() returns error %q", err)
+	}
+	if !name.Equal(attest.Certify.Name) {
+		t.Errorf("ComputeName() returns %x, want %x", name.Digest, attest.Certify.Name.Digest)
+	}
+
+	public, err := DecodePublic(testPubArea)
+	if err != nil {
+		t.Fatalf("DecodePublic() returns error %q", err)
+	}
+	if name, err = public.Name(); err != nil {
+		t.Errorf("Name() returns error %q", err)
+	} else if !name.Equal(attest.Certify.Name) {
+		t.Errorf("Name() returns %x, want %x", name.Digest, attest.Certify.Name.Digest)
+	}
+
+	wantErrorMsg := "name algorithm TPM_ALG_SM3_256 is not supported"
+	if _, err = ComputeName(AlgSM3256, testPubArea); err == nil {
+		t.Errorf("ComputeName() returns no error, want error containing substring %q", wantErrorMsg)
+	} else if !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("ComputeName() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	}
+}
+
+func TestName(t *testing.T) {
+	testCases := []struct {
+		name         string
+		data         []byte
+		wantName     Name
+		wantErrorMsg string
+	}{
+		{"empty name", []byte{0x00, 0x00}, Name{}, ""},
+		{"SHA-256 name", []byte{0x00, 0x04, 0x00, 0x0B, 0x01, 0x02}, Name{HashAlg: AlgSHA256, Digest: []byte{0x01, 0x02}}, ""},
+		{"truncated name", []byte{0x00, 0x04, 0x00, 0x0B}, Name{}, "tpm2: failed to unmarshal TPM2B_NAME: unexpected EOF"},
+		{"name without hash algorithm", []byte{0x00, 0x01, 0x00}, Name{}, "unexpected EOF"},
+		{"trailing data", []byte{0x00, 0x00, 0x00}, Name{}, "tpm2: failed to unmarshal TPM2B_NAME: trailing data"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, err := DecodeName(tc.data)
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("DecodeName() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("DecodeName() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeName() returns error %q", err)
+			}
+			if !name.Equal(tc.wantName) {
+				t.Errorf("DecodeName() returns %+v, want %+v", name, tc.wantName)
+			}
+			data, err := name.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal() returns error %q", err)
+			}
+			if !bytes.Equal(data, tc.data) {
+				t.Errorf("Marshal() returns %x, want %x", data, tc.data)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package tpm2
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"math/big"
+)
+
+// ObjectAttributes represents TPMA_OBJECT, as specified in TPM 2.0 Part 2 section 8.3.
+type ObjectAttributes uint32
+
+// TPMA_OBJECT flags.
+const (
+	FlagFixedTPM             ObjectAttributes = 1 << 1  // The hierarchy of the object may not change.
+	FlagSTClear              ObjectAttributes = 1 << 2  // Saved contexts of this object may not be loaded after Startup(CLEAR).
+	FlagFixedParent          ObjectAttributes = 1 << 4  // The parent of the object may not change.
+	FlagSensitiveDataOrigin  ObjectAttributes = 1 << 5  // The TPM generated all of the sensitive data other than the authValue.
+	FlagUserWithAuth         ObjectAttributes = 1 << 6  // Approval of USER role actions may be given with an HMAC session or password.
+	FlagAdminWithPolicy      ObjectAttributes = 1 << 7  // Approval of ADMIN role actions may only be given with a policy session.
+	FlagNoDA                 ObjectAttributes = 1 << 10 // The object is not subject to dictionary attack protections.
+	FlagEncryptedDuplication ObjectAttributes = 1 << 11 // The object may only be duplicated with an encrypted inner wrapper.
+	FlagRestricted           ObjectAttributes = 1 << 16 // Key usage is restricted to manipulate structures of known format.
+	FlagDecrypt              ObjectAttributes = 1 << 17 // The private portion of the key may be used to decrypt.
+	FlagSignOrEncrypt        ObjectAttributes = 1 << 18 // The private portion of the key may be used to sign.
+)
+
+// Has returns true if all of given flags are set.
+func (attrs ObjectAttributes) Has(flags ObjectAttributes) bool {
+	return attrs&flags == flags
+}
+
+// SymDefObject represents TPMT_SYM_DEF_OBJECT, as specified in TPM 2.0 Part 2 section 11.1.7.
+// KeyBits and Mode are only present if Algorithm isn't AlgNull.
+type SymDefObject struct {
+	Algorithm Algorithm // Symmetric algorithm, such as AlgAES, or AlgNull.
+	KeyBits   uint16    // Key size in bits.
+	Mode      Algorithm // Block cipher mode, such as AlgCFB.
+}
+
+// Scheme represents TPMT_RSA_SCHEME, TPMT_ECC_SCHEME, and TPMT_KDF_SCHEME, as specified in TPM 2.0 Part 2
+// section 11.2.4.2, 11.2.5.6, and 11.2.3.3.  HashAlg is only present if Scheme isn't AlgNull or AlgRSAES,
+// and Count is only present if Scheme is AlgECDAA.
+type Scheme struct {
+	Scheme  Algorithm // Scheme, such as AlgRSASSA, AlgECDSA, or AlgNull.
+	HashAlg Algorithm // Hashing algorithm used by the scheme.
+	Count   uint16    // Count value of ECDAA scheme.
+}
+
+// RSAParms represents TPMS_RSA_PARMS, as specified in TPM 2.0 Part 2 section 12.2.3.5.
+type RSAParms struct {
+	Symmetric SymDefObject // Symmetric algorithm of restricted decryption key, AlgNull otherwise.
+	Scheme    Scheme       // Signing or encryption scheme, or AlgNull if the key can be used with any scheme.
+	KeyBits   uint16       // Number of bits in the public modulus.
+	Exponent  uint32       // Public exponent, or 0 for the default exponent 65537.
+}
+
+// PublicExponent returns the public exponent of RSA key.
+func (p *RSAParms) PublicExponent() uint32 {
+	if p.Exponent == 0 {
+		return 65537
+	}
+	return p.Exponent
+}
+
+// ECCParms represents TPMS_ECC_PARMS, as specified in TPM 2.0 Part 2 section 12.2.3.6.
+type ECCParms struct {
+	Symmetric SymDefObject // Symmetric algorithm of restricted decryption key, AlgNull otherwise.
+	Scheme    Scheme       // Signing or key exchange scheme, or AlgNull if the key can be used with any scheme.
+	CurveID   ECCCurve     // ECC curve.
+	KDF       Scheme       // Key derivation scheme, or AlgNull.
+}
+
+// ECCPoint represents TPMS_ECC_POINT, as specified in TPM 2.0 Part 2 section 11.2.5.2.
+type ECCPoint struct {
+	X, Y []byte
+}
+
+// Public represents TPMT_PUBLIC, as specified in TPM 2.0 Part 2 section 12.2.4.
+// Only AlgRSA and AlgECC types are supported.
+type Public struct {
+	Type             Algorithm        // Algorithm associated with the object, AlgRSA or AlgECC.
+	NameAlg          Algorithm        // Hashing algorithm used to compute the name of the object.
+	ObjectAttributes ObjectAttributes // Attributes that, along with type, determine the manipulations of the object.
+	AuthPolicy       []byte           // Optional policy for using the object.
+	RSAParameters    *RSAParms        // RSA key parameters, only for AlgRSA type.
+	ECCParameters    *ECCParms        // ECC key parameters, only for AlgECC type.
+	RSAModulus       []byte           // RSA public modulus, only for AlgRSA type.
+	ECCPoint         *ECCPoint        // ECC public point, only for AlgECC type.
+}
+
+// DecodePublic decodes TPMT_PUBLIC structure.
+func DecodePublic(data []byte) (*Public, error) {
+	d := &decoder{structure: "TPMT_PUBLIC", data: data}
+
+	p := &Public{}
+	var err error
+	if p.Type, err = d.algorithm("type"); err != nil {
+		return nil, err
+	}
+	if p.NameAlg, err = d.algorithm("nameAlg"); err != nil {
+		return nil, err
+	}
+	attrs, err := d.uint32("objectAttributes")
+	if err != nil {
+		return nil, err
+	}
+	p.ObjectAttributes = ObjectAttributes(attrs)
+	if p.AuthPolicy, err = d.sized("authPolicy"); err != nil {
+		return nil, err
+	}
+
+	switch p.Type {
+	case AlgRSA:
+		params := &RSAParms{}
+		if params.Symmetric, err = d.symDefObject("parameters.symmetric"); err != nil {
+			return nil, err
+		}
+		if params.Scheme, err = d.scheme("parameters.scheme"); err != nil {
+			return nil, err
+		}
+		if params.KeyBits, err = d.uint16("parameters.keyBits"); err != nil {
+			return nil, err
+		}
+		if params.Exponent, err = d.uint32("parameters.exponent"); err != nil {
+			return nil, err
+		}
+		p.RSAParameters = params
+		if p.RSAModulus, err = d.sized("unique.rsa"); err != nil {
+			return nil, err
+		}
+	case AlgECC:
+		params := &ECCParms{}
+		if params.Symmetric, err = d.symDefObject("parameters.symmetric"); err != nil {
+			return nil, err
+		}
+		if params.Scheme, err = d.scheme("parameters.scheme"); err != nil {
+			return nil, err
+		}
+		curveID, err := d.uint16("parameters.curveID")
+		if err != nil {
+			return nil, err
+		}
+		params.CurveID = ECCCurve(curveID)
+		if params.KDF, err = d.scheme("parameters.kdf"); err != nil {
+			return nil, err
+		}
+		p.ECCParameters = params
+		point := &ECCPoint{}
+		if point.X, err = d.sized("unique.ecc.x"); err != nil {
+			return nil, err
+		}
+		if point.Y, err = d.sized("unique.ecc.y"); err != nil {
+			return nil, err
+		}
+		p.ECCPoint = point
+	default:
+		return nil, &UnsupportedError{Structure: "TPMT_PUBLIC", Feature: "type " + p.Type.String()}
+	}
+
+	if err = d.done(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Marshal encodes p as TPMT_PUBLIC structure.
+func (p *Public) Marshal() ([]byte, error) {
+	e := &encoder{structure: "TPMT_PUBLIC"}
+	e.algorithm(p.Type)
+	e.algorithm(p.NameAlg)
+	e.uint32(uint32(p.ObjectAttributes))
+	if err := e.sized("authPolicy", p.AuthPolicy); err != nil {
+		return nil, err
+	}
+
+	switch p.Type {
+	case AlgRSA:
+		if p.RSAParameters == nil {
+			return nil, &MarshalError{Structure: "TPMT_PUBLIC", Field: "parameters", Msg: "missing RSA parameters"}
+		}
+		e.symDefObject(p.RSAParameters.Symmetric)
+		e.scheme(p.RSAParameters.Scheme)
+		e.uint16(p.RSAParameters.KeyBits)
+		e.uint32(p.RSAParameters.Exponent)
+		if err := e.sized("unique.rsa", p.RSAModulus); err != nil {
+			return nil, err
+		}
+	case AlgECC:
+		if p.ECCParameters == nil {
+			return nil, &MarshalError{Structure: "TPMT_PUBLIC", Field: "parameters", Msg: "missing ECC parameters"}
+		}
+		if p.ECCPoint == nil {
+			return nil, &MarshalError{Structure: "TPMT_PUBLIC", Field: "unique", Msg: "missing ECC point"}
+		}
+		e.symDefObject(p.ECCParameters.Symmetric)
+		e.scheme(p.ECCParameters.Scheme)
+		e.uint16(uint16(p.ECCParameters.CurveID))
+		e.scheme(p.ECCParameters.KDF)
+		if err := e.sized("unique.ecc.x", p.ECCPoint.X); err != nil {
+			return nil, err
+		}
+		if err := e.sized("unique.ecc.y", p.ECCPoint.Y); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &UnsupportedError{Structure: "TPMT_PUBLIC", Feature: "type " + p.Type.String()}
+	}
+
+	return e.buf.Bytes(), nil
+}
+
+// Name returns the name of the object, computed from its encoded public area using NameAlg.
+func (p *Public) Name() (Name, error) {
+	data, err := p.Marshal()
+	if err != nil {
+		return Name{}, err
+	}
+	return ComputeName(p.NameAlg, data)
+}
+
+// Key returns the public key of the object as *rsa.PublicKey or *ecdsa.PublicKey.
+func (p *Public) Key() (crypto.PublicKey, error) {
+	switch p.Type {
+	case AlgRSA:
+		if p.RSAParameters == nil || len(p.RSAModulus) == 0 {
+			return nil, errors.New("tpm2: TPMT_PUBLIC doesn't have RSA public key")
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(p.RSAModulus),
+			E: int(p.RSAParameters.PublicExponent()),
+		}, nil
+	case AlgECC:
+		if p.ECCParameters == nil || p.ECCPoint == nil {
+			return nil, errors.New("tpm2: TPMT_PUBLIC doesn't have ECC public key")
+		}
+		curve, ok := p.ECCParameters.CurveID.Curve()
+		if !ok {
+			return nil, &UnsupportedError{Structure: "TPMT_PUBLIC", Feature: "curve " + p.ECCParameters.CurveID.String()}
+		}
+		x, y := new(big.Int).SetBytes(p.ECCPoint.X), new(big.Int).SetBytes(p.ECCPoint.Y)
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("tpm2: TPMT_PUBLIC ECC point is not on curve " + p.ECCParameters.CurveID.String())
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, &UnsupportedError{Structure: "TPMT_PUBLIC", Feature: "type " + p.Type.String()}
+	}
+}
+
+func (d *decoder) symDefObject(field string) (s SymDefObject, err error) {
+	if s.Algorithm, err = d.algorithm(field + ".algorithm"); err != nil || s.Algorithm == AlgNull {
+		return
+	}
+	if s.KeyBits, err = d.uint16(field + ".keyBits"); err != nil {
+		return
+	}
+	s.Mode, err = d.algorithm(field + ".mode")
+	return
+}
+
+func (e *encoder) symDefObject(s SymDefObject) {
+	e.algorithm(s.Algorithm)
+	if s.Algorithm == AlgNull {
+		return
+	}
+	e.uint16(s.KeyBits)
+	e.algorithm(s.Mode)
+}
+
+func (d *decoder) scheme(field string) (s Scheme, err error) {
+	if s.Scheme, err = d.algorithm(field + ".scheme"); err != nil || s.Scheme == AlgNull || s.Scheme == AlgRSAES {
+		return
+	}
+	if s.HashAlg, err = d.algorithm(field + ".details.hashAlg"); err != nil {
+		return
+	}
+	if s.Scheme == AlgECDAA {
+		s.Count, err = d.uint16(field + ".details.count")
+	}
+	return
+}
+
+func (e *encoder) scheme(s Scheme) {
+	e.algorithm(s.Scheme)
+	if s.Scheme == AlgNull || s.Scheme == AlgRSAES {
+		return
+	}
+	e.algorithm(s.HashAlg)
+	if s.Scheme == AlgECDAA {
+		e.uint16(s.Count)
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package tpm2
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodePublic(t *testing.T) {
+	public, err := DecodePublic(testPubArea)
+	if err != nil {
+		t.Fatalf("DecodePublic() returns error %q", err)
+	}
+	if public.Type != AlgRSA {
+		t.Errorf("type %s, want %s", public.Type, AlgRSA)
+	}
+	if public.NameAlg != AlgSHA256 {
+		t.Errorf("nameAlg %s, want %s", public.NameAlg, AlgSHA256)
+	}
+	wantAttrs := FlagFixedTPM | FlagFixedParent | FlagSensitiveDataOrigin | FlagUserWithAuth | FlagNoDA | FlagDecrypt | FlagSignOrEncrypt
+	if public.ObjectAttributes != wantAttrs {
+		t.Errorf("objectAttributes %#x, want %#x", uint32(public.ObjectAttributes), uint32(wantAttrs))
+	}
+	if len(public.AuthPolicy) != 32 {
+		t.Errorf("authPolicy length %d, want 32", len(public.AuthPolicy))
+	}
+	wantParams := &RSAParms{
+		Symmetric: SymDefObject{Algorithm: AlgNull},
+		Scheme:    Scheme{Scheme: AlgNull},
+		KeyBits:   2048,
+	}
+	if !reflect.DeepEqual(public.RSAParameters, wantParams) {
+		t.Errorf("RSA parameters %+v, want %+v", public.RSAParameters, wantParams)
+	}
+	if public.RSAParameters.PublicExponent() != 65537 {
+		t.Errorf("public exponent %d, want 65537", public.RSAParameters.PublicExponent())
+	}
+	if len(public.RSAModulus) != 256 {
+		t.Errorf("RSA modulus length %d, want 256", len(public.RSAModulus))
+	}
+	if public.ECCParameters != nil || public.ECCPoint != nil {
+		t.Errorf("RSA public area has ECC parameters %+v and point %+v", public.ECCParameters, public.ECCPoint)
+	}
+
+	key, err := public.Key()
+	if err != nil {
+		t.Fatalf("Key() returns error %q", err)
+	}
+	if rsaKey, ok := key.(*rsa.PublicKey); !ok || !bytes.Equal(rsaKey.N.Bytes(), public.RSAModulus) || rsaKey.E != 65537 {
+		t.Errorf("Key() returns %v, want RSA public key", key)
+	}
+
+	data, err := public.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() returns error %q", err)
+	}
+	if !bytes.Equal(data, testPubArea) {
+		t.Errorf("Marshal() returns %x, want %x", data, testPubArea)
+	}
+}
+
+func TestPublicMarshal(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name   string
+		public *Public
+	}{
+		{
+			name: "ECC signing key",
+			public: &Public{
+				Type:             AlgECC,
+				NameAlg:          AlgSHA256,
+				ObjectAttributes: FlagFixedTPM | FlagFixedParent | FlagSensitiveDataOrigin | FlagUserWithAuth | FlagSignOrEncrypt,
+				AuthPolicy:       []byte{},
+				ECCParameters: &ECCParms{
+					Symmetric: SymDefObject{Algorithm: AlgNull},
+					Scheme:    Scheme{Scheme: AlgECDSA, HashAlg: AlgSHA256},
+					CurveID:   CurveNISTP256,
+					KDF:       Scheme{Scheme: AlgNull},
+				},
+				ECCPoint: &ECCPoint{X: ecdsaKey.X.Bytes(), Y: ecdsaKey.Y.Bytes()},
+			},
+		},
+		{
+			name: "ECC ECDAA key",
+			public: &Public{
+				Type:             AlgECC,
+				NameAlg:          AlgSHA256,
+				ObjectAttributes: FlagFixedTPM | FlagFixedParent | FlagSensitiveDataOrigin | FlagSignOrEncrypt,
+				AuthPolicy:       []byte{},
+				ECCParameters: &ECCParms{
+					Symmetric: SymDefObject{Algorithm: AlgNull},
+					Scheme:    Scheme{Scheme: AlgECDAA, HashAlg: AlgSHA256, Count: 1},
+					CurveID:   CurveBNP256,
+					KDF:       Scheme{Scheme: AlgKDF2, HashAlg: AlgSHA256},
+				},
+				ECCPoint: &ECCPoint{X: []byte{0x01}, Y: []byte{0x02}},
+			},
+		},
+		{
+			name: "RSA restricted decryption key",
+			public: &Public{
+				Type:             AlgRSA,
+				NameAlg:          AlgSHA384,
+				ObjectAttributes: FlagFixedTPM | FlagFixedParent | FlagSensitiveDataOrigin | FlagRestricted | FlagDecrypt,
+				AuthPolicy:       []byte{0x01, 0x02, 0x03},
+				RSAParameters: &RSAParms{
+					Symmetric: SymDefObject{Algorithm: AlgAES, KeyBits: 128, Mode: AlgCFB},
+					Scheme:    Scheme{Scheme: AlgNull},
+					KeyBits:   2048,
+					Exponent:  3,
+				},
+				RSAModulus: []byte{0x01, 0x02, 0x03},
+			},
+		},
+		{
+			name: "RSA encryption key",
+			public: &Public{
+				Type:             AlgRSA,
+				NameAlg:          AlgSHA256,
+				ObjectAttributes: FlagFixedTPM | FlagFixedParent | FlagSensitiveDataOrigin | FlagDecrypt,
+				AuthPolicy:       []byte{},
+				RSAParameters: &RSAParms{
+					Symmetric: SymDefObject{Algorithm: AlgNull},
+					Scheme:    Scheme{Scheme: AlgRSAES},
+					KeyBits:   2048,
+				},
+				RSAModulus: []byte{0x01, 0x02, 0x03},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.public.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal() returns error %q", err)
+			}
+			public, err := DecodePublic(data)
+			if err != nil {
+				t.Fatalf("DecodePublic() returns error %q", err)
+			}
+			if !reflect.DeepEqual(public, tc.public) {
+				t.Errorf("DecodePublic() returns %+v, want %+v", public, tc.public)
+			}
+		})
+	}
+
+	// Verify ECC public key.
+	data, _ := testCases[0].public.Marshal()
+	public, _ := DecodePublic(data)
+	key, err := public.Key()
+	if err != nil {
+		t.Fatalf("Key() returns error %q", err)
+	}
+	if ecdsaPublicKey, ok := key.(*ecdsa.PublicKey); !ok || ecdsaPublicKey.X.Cmp(ecdsaKey.X) != 0 || ecdsaPublicKey.Y.Cmp(ecdsaKey.Y) != 0 {
+		t.Errorf("Key() returns %v, want %v", key, &ecdsaKey.PublicKey)
+	}
+
+	// ECC point isn't on curve.
+	public.ECCPoint.X = []byte{0x01}
+	wantErrorMsg := "ECC point is not on curve TPM_ECC_NIST_P256"
+	if _, err = public.Key(); err == nil {
+		t.Errorf("Key() returns no error, want error containing substring %q", wantErrorMsg)
+	} else if !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("Key() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	}
+}
+
+func TestDecodePublicError(t *testing.T) {
+	testCases := []struct {
+		name         string
+		data         []byte
+		wantErrorMsg string
+	}{
+		{"empty", nil, "tpm2: failed to unmarshal TPMT_PUBLIC type: unexpected EOF"},
+		{"truncated parameters", testPubArea[:50], "tpm2: failed to unmarshal TPMT_PUBLIC parameters.exponent: unexpected EOF"},
+		{"truncated unique", testPubArea[:len(testPubArea)-1], "tpm2: failed to unmarshal TPMT_PUBLIC unique.rsa: unexpected EOF"},
+		{"trailing data", append(append([]byte(nil), testPubArea...), 0x00), "tpm2: failed to unmarshal TPMT_PUBLIC: trailing data (1 bytes)"},
+		{"keyed hash object", []byte{0x00, 0x08, 0x00, 0x0B, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, "tpm2: TPMT_PUBLIC type TPM_ALG_KEYEDHASH is not supported"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := DecodePublic(tc.data); err == nil {
+				t.Errorf("DecodePublic() returns no error, want error containing substring %q", tc.wantErrorMsg)
+			} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+				t.Errorf("DecodePublic() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+			}
+		})
+	}
+}
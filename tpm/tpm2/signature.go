@@ -0,0 +1,133 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package tpm2
+
+import (
+	"encoding/asn1"
+	"math/big"
+)
+
+// ECCSignature represents TPMS_SIGNATURE_ECC signature values, as specified in TPM 2.0 Part 2 section 11.3.2.
+type ECCSignature struct {
+	R, S []byte
+}
+
+// Signature represents TPMT_SIGNATURE, as specified in TPM 2.0 Part 2 section 11.3.4.
+// Only RSA (AlgRSASSA and AlgRSAPSS) and ECC (AlgECDSA, AlgECDAA, AlgSM2, and AlgECSchnorr)
+// signatures are supported.  Hash, RSA, and ECC are not present if SigAlg is AlgNull.
+type Signature struct {
+	SigAlg Algorithm     // Signature algorithm.
+	Hash   Algorithm     // Hashing algorithm used to digest the signed message.
+	RSA    []byte        // RSA signature, only for RSA signature algorithms.
+	ECC    *ECCSignature // ECC signature, only for ECC signature algorithms.
+}
+
+func isRSASignatureAlgorithm(alg Algorithm) bool {
+	return alg == AlgRSASSA || alg == AlgRSAPSS
+}
+
+func isECCSignatureAlgorithm(alg Algorithm) bool {
+	return alg == AlgECDSA || alg == AlgECDAA || alg == AlgSM2 || alg == AlgECSchnorr
+}
+
+// DecodeSignature decodes TPMT_SIGNATURE structure.
+func DecodeSignature(data []byte) (*Signature, error) {
+	d := &decoder{structure: "TPMT_SIGNATURE", data: data}
+
+	s := &Signature{}
+	var err error
+	if s.SigAlg, err = d.algorithm("sigAlg"); err != nil {
+		return nil, err
+	}
+	switch {
+	case s.SigAlg == AlgNull:
+	case isRSASignatureAlgorithm(s.SigAlg):
+		if s.Hash, err = d.algorithm("signature.hash"); err != nil {
+			return nil, err
+		}
+		if s.RSA, err = d.sized("signature.sig"); err != nil {
+			return nil, err
+		}
+	case isECCSignatureAlgorithm(s.SigAlg):
+		if s.Hash, err = d.algorithm("signature.hash"); err != nil {
+			return nil, err
+		}
+		ecc := &ECCSignature{}
+		if ecc.R, err = d.sized("signature.signatureR"); err != nil {
+			return nil, err
+		}
+		if ecc.S, err = d.sized("signature.signatureS"); err != nil {
+			return nil, err
+		}
+		s.ECC = ecc
+	default:
+		return nil, &UnsupportedError{Structure: "TPMT_SIGNATURE", Feature: "sigAlg " + s.SigAlg.String()}
+	}
+
+	if err = d.done(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Marshal encodes s as TPMT_SIGNATURE structure.
+func (s *Signature) Marshal() ([]byte, error) {
+	e := &encoder{structure: "TPMT_SIGNATURE"}
+	e.algorithm(s.SigAlg)
+	switch {
+	case s.SigAlg == AlgNull:
+	case isRSASignatureAlgorithm(s.SigAlg):
+		e.algorithm(s.Hash)
+		if err := e.sized("signature.sig", s.RSA); err != nil {
+			return nil, err
+		}
+	case isECCSignatureAlgorithm(s.SigAlg):
+		if s.ECC == nil {
+			return nil, &MarshalError{Structure: "TPMT_SIGNATURE", Field: "signature", Msg: "missing ECC signature"}
+		}
+		e.algorithm(s.Hash)
+		if err := e.sized("signature.signatureR", s.ECC.R); err != nil {
+			return nil, err
+		}
+		if err := e.sized("signature.signatureS", s.ECC.S); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &UnsupportedError{Structure: "TPMT_SIGNATURE", Feature: "sigAlg " + s.SigAlg.String()}
+	}
+	return e.buf.Bytes(), nil
+}
+
+// X509Signature returns signature in the form used by x509.Certificate.CheckSignature, which is the RSA
+// signature for AlgRSASSA and AlgRSAPSS, and ASN.1 DER encoded r and s for AlgECDSA.
+func (s *Signature) X509Signature() ([]byte, error) {
+	switch s.SigAlg {
+	case AlgRSASSA, AlgRSAPSS:
+		return s.RSA, nil
+	case AlgECDSA:
+		if s.ECC == nil {
+			return nil, &MarshalError{Structure: "TPMT_SIGNATURE", Field: "signature", Msg: "missing ECC signature"}
+		}
+		return asn1.Marshal(struct {
+			R, S *big.Int
+		}{new(big.Int).SetBytes(s.ECC.R), new(big.Int).SetBytes(s.ECC.S)})
+	default:
+		return nil, &UnsupportedError{Structure: "TPMT_SIGNATURE", Feature: "X.509 signature of sigAlg " + s.SigAlg.String()}
+	}
+}
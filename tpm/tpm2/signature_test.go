@@ -0,0 +1,144 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package tpm2
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kappapay/webauthn/internal/testcert"
+)
+
+func TestDecodeSignature(t *testing.T) {
+	testCases := []struct {
+		name         string
+		data         []byte
+		wantSig      *Signature
+		wantErrorMsg string
+	}{
+		{
+			name:    "RSASSA signature",
+			data:    []byte{0x00, 0x14, 0x00, 0x0B, 0x00, 0x03, 0x01, 0x02, 0x03},
+			wantSig: &Signature{SigAlg: AlgRSASSA, Hash: AlgSHA256, RSA: []byte{0x01, 0x02, 0x03}},
+		},
+		{
+			name:    "RSAPSS signature",
+			data:    []byte{0x00, 0x16, 0x00, 0x0C, 0x00, 0x01, 0x01},
+			wantSig: &Signature{SigAlg: AlgRSAPSS, Hash: AlgSHA384, RSA: []byte{0x01}},
+		},
+		{
+			name:    "ECDSA signature",
+			data:    []byte{0x00, 0x18, 0x00, 0x0B, 0x00, 0x01, 0x01, 0x00, 0x02, 0x00, 0x80},
+			wantSig: &Signature{SigAlg: AlgECDSA, Hash: AlgSHA256, ECC: &ECCSignature{R: []byte{0x01}, S: []byte{0x00, 0x80}}},
+		},
+		{
+			name:    "null signature",
+			data:    []byte{0x00, 0x10},
+			wantSig: &Signature{SigAlg: AlgNull},
+		},
+		{
+			name:         "raw signature",
+			data:         []byte{0x71, 0x5D, 0x62, 0xCD, 0x61, 0x94, 0x58, 0x8B},
+			wantErrorMsg: "tpm2: TPMT_SIGNATURE sigAlg TPM_ALG_0x715D is not supported",
+		},
+		{
+			name:         "truncated signature",
+			data:         []byte{0x00, 0x14, 0x00, 0x0B, 0x00, 0x03, 0x01, 0x02},
+			wantErrorMsg: "tpm2: failed to unmarshal TPMT_SIGNATURE signature.sig: unexpected EOF",
+		},
+		{
+			name:         "trailing data",
+			data:         []byte{0x00, 0x14, 0x00, 0x0B, 0x00, 0x01, 0x01, 0x02},
+			wantErrorMsg: "tpm2: failed to unmarshal TPMT_SIGNATURE: trailing data (1 bytes)",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sig, err := DecodeSignature(tc.data)
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("DecodeSignature() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("DecodeSignature() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeSignature() returns error %q", err)
+			}
+			if !reflect.DeepEqual(sig, tc.wantSig) {
+				t.Errorf("DecodeSignature() returns %+v, want %+v", sig, tc.wantSig)
+			}
+			data, err := sig.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal() returns error %q", err)
+			}
+			if !bytes.Equal(data, tc.data) {
+				t.Errorf("Marshal() returns %x, want %x", data, tc.data)
+			}
+		})
+	}
+}
+
+func TestSignatureX509Signature(t *testing.T) {
+	aik := testcert.New(&x509.Certificate{Subject: pkix.Name{CommonName: "Test AIK"}}, nil)
+	key, cert := aik.Key, aik.Cert
+
+	message := []byte("TPMS_ATTEST")
+	digest := sha256.Sum256(message)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := &Signature{SigAlg: AlgECDSA, Hash: AlgSHA256, ECC: &ECCSignature{R: r.Bytes(), S: s.Bytes()}}
+	data, err := sig.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() returns error %q", err)
+	}
+	if sig, err = DecodeSignature(data); err != nil {
+		t.Fatalf("DecodeSignature() returns error %q", err)
+	}
+	x509Sig, err := sig.X509Signature()
+	if err != nil {
+		t.Fatalf("X509Signature() returns error %q", err)
+	}
+	if err = cert.CheckSignature(x509.ECDSAWithSHA256, message, x509Sig); err != nil {
+		t.Errorf("CheckSignature() returns error %q", err)
+	}
+
+	rsaSig := &Signature{SigAlg: AlgRSASSA, Hash: AlgSHA256, RSA: []byte{0x01, 0x02}}
+	if x509Sig, err = rsaSig.X509Signature(); err != nil || !bytes.Equal(x509Sig, rsaSig.RSA) {
+		t.Errorf("X509Signature() returns (%x, %v), want (%x, nil)", x509Sig, err, rsaSig.RSA)
+	}
+
+	wantErrorMsg := "X.509 signature of sigAlg TPM_ALG_SM2 is not supported"
+	sm2Sig := &Signature{SigAlg: AlgSM2, Hash: AlgSM3256, ECC: &ECCSignature{R: []byte{0x01}, S: []byte{0x02}}}
+	if _, err = sm2Sig.X509Signature(); err == nil {
+		t.Errorf("X509Signature() returns no error, want error containing substring %q", wantErrorMsg)
+	} else if !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("X509Signature() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	}
+}
@@ -0,0 +1,426 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+/*
+Package tpm2 parses and serializes TPM 2.0 structures used in TPM attestation, as specified in
+https://trustedcomputinggroup.org/wp-content/uploads/TPM-Rev-2.0-Part-2-Structures-01.38.pdf.
+
+It supports TPMS_ATTEST (certify and quote), TPMT_PUBLIC (RSA and ECC keys), TPMT_SIGNATURE, and
+TPM2B_NAME, and computes names of objects from their public area.  It is used by the tpm attestation
+format, and can be used to verify TPM quotes outside of WebAuthn.
+*/
+package tpm2
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/elliptic"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	// Register hash functions used as TPM name algorithms.
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// GeneratedValue is the value of TPMS_ATTEST magic field, which indicates that the structure was created by a TPM.
+const GeneratedValue uint32 = 0xff544347
+
+// Algorithm represents TPM_ALG_ID, as specified in TPM 2.0 Part 2 section 6.3.
+type Algorithm uint16
+
+// TPM algorithm identifiers.
+const (
+	AlgError        Algorithm = 0x0000
+	AlgRSA          Algorithm = 0x0001
+	AlgSHA1         Algorithm = 0x0004
+	AlgHMAC         Algorithm = 0x0005
+	AlgAES          Algorithm = 0x0006
+	AlgMGF1         Algorithm = 0x0007
+	AlgKeyedHash    Algorithm = 0x0008
+	AlgXOR          Algorithm = 0x000A
+	AlgSHA256       Algorithm = 0x000B
+	AlgSHA384       Algorithm = 0x000C
+	AlgSHA512       Algorithm = 0x000D
+	AlgNull         Algorithm = 0x0010
+	AlgSM3256       Algorithm = 0x0012
+	AlgSM4          Algorithm = 0x0013
+	AlgRSASSA       Algorithm = 0x0014
+	AlgRSAES        Algorithm = 0x0015
+	AlgRSAPSS       Algorithm = 0x0016
+	AlgOAEP         Algorithm = 0x0017
+	AlgECDSA        Algorithm = 0x0018
+	AlgECDH         Algorithm = 0x0019
+	AlgECDAA        Algorithm = 0x001A
+	AlgSM2          Algorithm = 0x001B
+	AlgECSchnorr    Algorithm = 0x001C
+	AlgECMQV        Algorithm = 0x001D
+	AlgKDF1SP80056A Algorithm = 0x0020
+	AlgKDF2         Algorithm = 0x0021
+	AlgKDF1SP800108 Algorithm = 0x0022
+	AlgECC          Algorithm = 0x0023
+	AlgSymCipher    Algorithm = 0x0025
+	AlgCamellia     Algorithm = 0x0026
+	AlgCTR          Algorithm = 0x0040
+	AlgOFB          Algorithm = 0x0041
+	AlgCBC          Algorithm = 0x0042
+	AlgCFB          Algorithm = 0x0043
+	AlgECB          Algorithm = 0x0044
+)
+
+var algorithmNames = map[Algorithm]string{
+	AlgError:        "TPM_ALG_ERROR",
+	AlgRSA:          "TPM_ALG_RSA",
+	AlgSHA1:         "TPM_ALG_SHA1",
+	AlgHMAC:         "TPM_ALG_HMAC",
+	AlgAES:          "TPM_ALG_AES",
+	AlgMGF1:         "TPM_ALG_MGF1",
+	AlgKeyedHash:    "TPM_ALG_KEYEDHASH",
+	AlgXOR:          "TPM_ALG_XOR",
+	AlgSHA256:       "TPM_ALG_SHA256",
+	AlgSHA384:       "TPM_ALG_SHA384",
+	AlgSHA512:       "TPM_ALG_SHA512",
+	AlgNull:         "TPM_ALG_NULL",
+	AlgSM3256:       "TPM_ALG_SM3_256",
+	AlgSM4:          "TPM_ALG_SM4",
+	AlgRSASSA:       "TPM_ALG_RSASSA",
+	AlgRSAES:        "TPM_ALG_RSAES",
+	AlgRSAPSS:       "TPM_ALG_RSAPSS",
+	AlgOAEP:         "TPM_ALG_OAEP",
+	AlgECDSA:        "TPM_ALG_ECDSA",
+	AlgECDH:         "TPM_ALG_ECDH",
+	AlgECDAA:        "TPM_ALG_ECDAA",
+	AlgSM2:          "TPM_ALG_SM2",
+	AlgECSchnorr:    "TPM_ALG_ECSCHNORR",
+	AlgECMQV:        "TPM_ALG_ECMQV",
+	AlgKDF1SP80056A: "TPM_ALG_KDF1_SP800_56A",
+	AlgKDF2:         "TPM_ALG_KDF2",
+	AlgKDF1SP800108: "TPM_ALG_KDF1_SP800_108",
+	AlgECC:          "TPM_ALG_ECC",
+	AlgSymCipher:    "TPM_ALG_SYMCIPHER",
+	AlgCamellia:     "TPM_ALG_CAMELLIA",
+	AlgCTR:          "TPM_ALG_CTR",
+	AlgOFB:          "TPM_ALG_OFB",
+	AlgCBC:          "TPM_ALG_CBC",
+	AlgCFB:          "TPM_ALG_CFB",
+	AlgECB:          "TPM_ALG_ECB",
+}
+
+// String returns TPM algorithm name, such as "TPM_ALG_SHA256".
+func (alg Algorithm) String() string {
+	if name, ok := algorithmNames[alg]; ok {
+		return name
+	}
+	return fmt.Sprintf("TPM_ALG_0x%04X", uint16(alg))
+}
+
+// Hash returns hash function of TPM hash algorithm.  It returns false if alg isn't a supported hash algorithm.
+func (alg Algorithm) Hash() (crypto.Hash, bool) {
+	switch alg {
+	case AlgSHA1:
+		return crypto.SHA1, true
+	case AlgSHA256:
+		return crypto.SHA256, true
+	case AlgSHA384:
+		return crypto.SHA384, true
+	case AlgSHA512:
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+// HashAlgorithm returns TPM hash algorithm of hash function.  It returns false if hash function doesn't have
+// a supported TPM hash algorithm.
+func HashAlgorithm(h crypto.Hash) (Algorithm, bool) {
+	switch h {
+	case crypto.SHA1:
+		return AlgSHA1, true
+	case crypto.SHA256:
+		return AlgSHA256, true
+	case crypto.SHA384:
+		return AlgSHA384, true
+	case crypto.SHA512:
+		return AlgSHA512, true
+	default:
+		return AlgNull, false
+	}
+}
+
+// ECCCurve represents TPM_ECC_CURVE, as specified in TPM 2.0 Part 2 section 6.4.
+type ECCCurve uint16
+
+// TPM ECC curve identifiers.
+const (
+	CurveNone     ECCCurve = 0x0000
+	CurveNISTP192 ECCCurve = 0x0001
+	CurveNISTP224 ECCCurve = 0x0002
+	CurveNISTP256 ECCCurve = 0x0003
+	CurveNISTP384 ECCCurve = 0x0004
+	CurveNISTP521 ECCCurve = 0x0005
+	CurveBNP256   ECCCurve = 0x0010
+	CurveBNP638   ECCCurve = 0x0011
+	CurveSM2P256  ECCCurve = 0x0020
+)
+
+var eccCurveNames = map[ECCCurve]string{
+	CurveNone:     "TPM_ECC_NONE",
+	CurveNISTP192: "TPM_ECC_NIST_P192",
+	CurveNISTP224: "TPM_ECC_NIST_P224",
+	CurveNISTP256: "TPM_ECC_NIST_P256",
+	CurveNISTP384: "TPM_ECC_NIST_P384",
+	CurveNISTP521: "TPM_ECC_NIST_P521",
+	CurveBNP256:   "TPM_ECC_BN_P256",
+	CurveBNP638:   "TPM_ECC_BN_P638",
+	CurveSM2P256:  "TPM_ECC_SM2_P256",
+}
+
+// String returns TPM ECC curve name, such as "TPM_ECC_NIST_P256".
+func (curve ECCCurve) String() string {
+	if name, ok := eccCurveNames[curve]; ok {
+		return name
+	}
+	return fmt.Sprintf("TPM_ECC_0x%04X", uint16(curve))
+}
+
+// Curve returns elliptic curve of TPM ECC curve.  It returns false if curve isn't supported by crypto/elliptic.
+func (curve ECCCurve) Curve() (elliptic.Curve, bool) {
+	switch curve {
+	case CurveNISTP224:
+		return elliptic.P224(), true
+	case CurveNISTP256:
+		return elliptic.P256(), true
+	case CurveNISTP384:
+		return elliptic.P384(), true
+	case CurveNISTP521:
+		return elliptic.P521(), true
+	default:
+		return nil, false
+	}
+}
+
+// StructureTag represents TPM_ST, as specified in TPM 2.0 Part 2 section 6.9.
+type StructureTag uint16
+
+// TPM structure tags.
+const (
+	TagRspCommand         StructureTag = 0x00C4
+	TagNull               StructureTag = 0x8000
+	TagNoSessions         StructureTag = 0x8001
+	TagSessions           StructureTag = 0x8002
+	TagAttestNV           StructureTag = 0x8014
+	TagAttestCommandAudit StructureTag = 0x8015
+	TagAttestSessionAudit StructureTag = 0x8016
+	TagAttestCertify      StructureTag = 0x8017
+	TagAttestQuote        StructureTag = 0x8018
+	TagAttestTime         StructureTag = 0x8019
+	TagAttestCreation     StructureTag = 0x801A
+	TagCreation           StructureTag = 0x8021
+	TagVerified           StructureTag = 0x8022
+	TagAuthSecret         StructureTag = 0x8023
+	TagHashCheck          StructureTag = 0x8024
+	TagAuthSigned         StructureTag = 0x8025
+	TagFUManifest         StructureTag = 0x8029
+)
+
+var structureTagNames = map[StructureTag]string{
+	TagRspCommand:         "TPM_ST_RSP_COMMAND",
+	TagNull:               "TPM_ST_NULL",
+	TagNoSessions:         "TPM_ST_NO_SESSIONS",
+	TagSessions:           "TPM_ST_SESSIONS",
+	TagAttestNV:           "TPM_ST_ATTEST_NV",
+	TagAttestCommandAudit: "TPM_ST_ATTEST_COMMAND_AUDIT",
+	TagAttestSessionAudit: "TPM_ST_ATTEST_SESSION_AUDIT",
+	TagAttestCertify:      "TPM_ST_ATTEST_CERTIFY",
+	TagAttestQuote:        "TPM_ST_ATTEST_QUOTE",
+	TagAttestTime:         "TPM_ST_ATTEST_TIME",
+	TagAttestCreation:     "TPM_ST_ATTEST_CREATION",
+	TagCreation:           "TPM_ST_CREATION",
+	TagVerified:           "TPM_ST_VERIFIED",
+	TagAuthSecret:         "TPM_ST_AUTH_SECRET",
+	TagHashCheck:          "TPM_ST_HASHCHECK",
+	TagAuthSigned:         "TPM_ST_AUTH_SIGNED",
+	TagFUManifest:         "TPM_ST_FU_MANIFEST",
+}
+
+// String returns TPM structure tag name, such as "TPM_ST_ATTEST_CERTIFY".
+func (tag StructureTag) String() string {
+	if name, ok := structureTagNames[tag]; ok {
+		return name
+	}
+	return fmt.Sprintf("TPM_ST_0x%04X", uint16(tag))
+}
+
+// UnmarshalError results when TPM structure can't be decoded.
+type UnmarshalError struct {
+	Structure string // TPM structure, such as "TPMS_ATTEST".
+	Field     string // Field of TPM structure, or empty if error isn't specific to a field.
+	Msg       string
+}
+
+func (e *UnmarshalError) Error() string {
+	if e.Field == "" {
+		return "tpm2: failed to unmarshal " + e.Structure + ": " + e.Msg
+	}
+	return "tpm2: failed to unmarshal " + e.Structure + " " + e.Field + ": " + e.Msg
+}
+
+// MarshalError results when TPM structure can't be encoded.
+type MarshalError struct {
+	Structure string // TPM structure, such as "TPMS_ATTEST".
+	Field     string // Field of TPM structure, or empty if error isn't specific to a field.
+	Msg       string
+}
+
+func (e *MarshalError) Error() string {
+	if e.Field == "" {
+		return "tpm2: failed to marshal " + e.Structure + ": " + e.Msg
+	}
+	return "tpm2: failed to marshal " + e.Structure + " " + e.Field + ": " + e.Msg
+}
+
+// UnsupportedError results when TPM structure contains a valid value not supported by this package,
+// such as a TPMT_PUBLIC of TPM_ALG_KEYEDHASH type.
+type UnsupportedError struct {
+	Structure string // TPM structure, such as "TPMT_PUBLIC".
+	Feature   string // Unsupported feature, such as "type TPM_ALG_KEYEDHASH".
+}
+
+func (e *UnsupportedError) Error() string {
+	return "tpm2: " + e.Structure + " " + e.Feature + " is not supported"
+}
+
+// decoder decodes big-endian TPM structure fields.
+type decoder struct {
+	structure string
+	data      []byte
+}
+
+func (d *decoder) eof(field string) error {
+	return &UnmarshalError{Structure: d.structure, Field: field, Msg: "unexpected EOF"}
+}
+
+func (d *decoder) uint8(field string) (uint8, error) {
+	if len(d.data) < 1 {
+		return 0, d.eof(field)
+	}
+	v := d.data[0]
+	d.data = d.data[1:]
+	return v, nil
+}
+
+func (d *decoder) uint16(field string) (uint16, error) {
+	if len(d.data) < 2 {
+		return 0, d.eof(field)
+	}
+	v := binary.BigEndian.Uint16(d.data)
+	d.data = d.data[2:]
+	return v, nil
+}
+
+func (d *decoder) uint32(field string) (uint32, error) {
+	if len(d.data) < 4 {
+		return 0, d.eof(field)
+	}
+	v := binary.BigEndian.Uint32(d.data)
+	d.data = d.data[4:]
+	return v, nil
+}
+
+func (d *decoder) uint64(field string) (uint64, error) {
+	if len(d.data) < 8 {
+		return 0, d.eof(field)
+	}
+	v := binary.BigEndian.Uint64(d.data)
+	d.data = d.data[8:]
+	return v, nil
+}
+
+func (d *decoder) algorithm(field string) (Algorithm, error) {
+	v, err := d.uint16(field)
+	return Algorithm(v), err
+}
+
+func (d *decoder) bytes(field string, n int) ([]byte, error) {
+	if len(d.data) < n {
+		return nil, d.eof(field)
+	}
+	v := d.data[:n:n]
+	d.data = d.data[n:]
+	return v, nil
+}
+
+// sized decodes a TPM2B structure, which is a 2-byte size followed by size bytes of data.
+func (d *decoder) sized(field string) ([]byte, error) {
+	n, err := d.uint16(field)
+	if err != nil {
+		return nil, err
+	}
+	return d.bytes(field, int(n))
+}
+
+func (d *decoder) done() error {
+	if len(d.data) != 0 {
+		return &UnmarshalError{Structure: d.structure, Msg: "trailing data (" + strconv.Itoa(len(d.data)) + " bytes)"}
+	}
+	return nil
+}
+
+// encoder encodes big-endian TPM structure fields.
+type encoder struct {
+	structure string
+	buf       bytes.Buffer
+}
+
+func (e *encoder) uint8(v uint8) {
+	e.buf.WriteByte(v)
+}
+
+func (e *encoder) uint16(v uint16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) uint64(v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	e.buf.Write(b[:])
+}
+
+func (e *encoder) algorithm(alg Algorithm) {
+	e.uint16(uint16(alg))
+}
+
+// sized encodes a TPM2B structure, which is a 2-byte size followed by size bytes of data.
+func (e *encoder) sized(field string, v []byte) error {
+	if len(v) > 0xffff {
+		return &MarshalError{Structure: e.structure, Field: field, Msg: "size " + strconv.Itoa(len(v)) + " exceeds 65535 bytes"}
+	}
+	e.uint16(uint16(len(v)))
+	e.buf.Write(v)
+	return nil
+}
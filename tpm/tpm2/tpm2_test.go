@@ -0,0 +1,114 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package tpm2
+
+import (
+	"crypto"
+	"encoding/hex"
+	"testing"
+)
+
+var (
+	// certInfo and pubArea of TPM attestation from apowers313's fido2-helpers (2019) at https://github.com/apowers313/fido2-helpers/blob/master/fido2-helpers.js
+	testCertInfo = mustDecodeHex("ff54434780170022000bbc59f4dfd9a6a42dc3b866aff2df0d19826bbf014b67ab0ad6ebb176306b80070014ac9f3f0569c662fb091491f1eee318c6f0c3df9b00000001b15a48c76840f9e3d8f39f0501a9e0c4a53fbbc4130022000b7121aebfa6b9afd07032f42f0925e0ec67408dd599a57bfa0f80c7f15601084f0022000b015234790fc00198cdbeb85410c2b6ab8c31bb02053a71c80c5d1096385fe3b4")
+	testPubArea  = mustDecodeHex("0001000b0006047200209dffcbf36c383ae699fb9868dc6dcb89d7153884be2803922c124158bfad22ae001000100800000000000100c5da6f4d9357bde202f5c558cd0a3156d254f2e0ad9ab57931f9826b747de1ac4f29d6070874dce57910e19844499d8e42470339b170d022b501ab88e9c2f4ed302e4719c70debe8842403ed9bdfc22730a61a1b70f616c5f1b700cacf7846137dc4b2d469a8e15aab4fad8657084022d28f44d9075323126b7007c981939fdf724caf4fbe475040431a4ea064430bcb2cfad7d05bdb9f64b5b0e0952ecf8679273d6c6dfa81601f14503316a13d0782c31a3e6bdded3d7bc46bc1fa9bef0dff83b7deaf146b582c4644821a3c62edbaa6be422bf04e43edaf5fd3783086153d7361a203061a6298ab26e1337ca1c9ed06741a5905477988e720304eae189d7f")
+)
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestAlgorithm(t *testing.T) {
+	testCases := []struct {
+		alg      Algorithm
+		wantName string
+		wantHash crypto.Hash
+	}{
+		{AlgRSA, "TPM_ALG_RSA", 0},
+		{AlgSHA1, "TPM_ALG_SHA1", crypto.SHA1},
+		{AlgSHA256, "TPM_ALG_SHA256", crypto.SHA256},
+		{AlgSHA384, "TPM_ALG_SHA384", crypto.SHA384},
+		{AlgSHA512, "TPM_ALG_SHA512", crypto.SHA512},
+		{AlgSM3256, "TPM_ALG_SM3_256", 0},
+		{AlgNull, "TPM_ALG_NULL", 0},
+		{Algorithm(0x1234), "TPM_ALG_0x1234", 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.wantName, func(t *testing.T) {
+			if name := tc.alg.String(); name != tc.wantName {
+				t.Errorf("String() returns %q, want %q", name, tc.wantName)
+			}
+			hash, ok := tc.alg.Hash()
+			if hash != tc.wantHash || ok != (tc.wantHash != 0) {
+				t.Errorf("Hash() returns (%v, %t), want %v", hash, ok, tc.wantHash)
+			}
+			if tc.wantHash != 0 {
+				if alg, ok := HashAlgorithm(tc.wantHash); !ok || alg != tc.alg {
+					t.Errorf("HashAlgorithm(%v) returns (%s, %t), want %s", tc.wantHash, alg, ok, tc.alg)
+				}
+			}
+		})
+	}
+	if alg, ok := HashAlgorithm(crypto.MD5); ok {
+		t.Errorf("HashAlgorithm(MD5) returns (%s, true), want false", alg)
+	}
+}
+
+func TestECCCurve(t *testing.T) {
+	testCases := []struct {
+		curve     ECCCurve
+		wantName  string
+		wantCurve string
+	}{
+		{CurveNISTP224, "TPM_ECC_NIST_P224", "P-224"},
+		{CurveNISTP256, "TPM_ECC_NIST_P256", "P-256"},
+		{CurveNISTP384, "TPM_ECC_NIST_P384", "P-384"},
+		{CurveNISTP521, "TPM_ECC_NIST_P521", "P-521"},
+		{CurveBNP256, "TPM_ECC_BN_P256", ""},
+		{ECCCurve(0x0099), "TPM_ECC_0x0099", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.wantName, func(t *testing.T) {
+			if name := tc.curve.String(); name != tc.wantName {
+				t.Errorf("String() returns %q, want %q", name, tc.wantName)
+			}
+			curve, ok := tc.curve.Curve()
+			if tc.wantCurve == "" {
+				if ok {
+					t.Errorf("Curve() returns (%s, true), want false", curve.Params().Name)
+				}
+			} else if !ok || curve.Params().Name != tc.wantCurve {
+				t.Errorf("Curve() returns (%v, %t), want %s", curve, ok, tc.wantCurve)
+			}
+		})
+	}
+}
+
+func TestStructureTag(t *testing.T) {
+	if name := TagAttestCertify.String(); name != "TPM_ST_ATTEST_CERTIFY" {
+		t.Errorf("String() returns %q, want %q", name, "TPM_ST_ATTEST_CERTIFY")
+	}
+	if name := StructureTag(0x1234).String(); name != "TPM_ST_0x1234" {
+		t.Errorf("String() returns %q, want %q", name, "TPM_ST_0x1234")
+	}
+}
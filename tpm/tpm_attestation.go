@@ -21,19 +21,19 @@ package tpm
 
 import (
 	"bytes"
-	"crypto"
 	"crypto/ecdsa"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
-	"encoding/binary"
 	"errors"
 	"fmt"
-	"io"
+	"math/big"
 
 	"github.com/fxamacker/cbor/v2"
 	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/ecdaa"
+	"github.com/kappapay/webauthn/tpm/tpm2"
 )
 
 var (
@@ -45,57 +45,11 @@ var (
 	oidTPMCertificateExt       = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
 )
 
-// tpmsAttest represents TPM structure TPMS_ATTEST, as specified in https://trustedcomputinggroup.org/wp-content/uploads/TPM-Rev-2.0-Part-2-Structures-01.38.pdf section 10.12.8.
-type tpmsAttest struct {
-	magic                   uint32 // The indication that this structure was created by a TPM (always TPM_GENERATED_VALUE).
-	typ                     string // Type of the attestation structure.
-	qualifiedSignerHashType string // Hashing algorithm for qualified signer.
-	qualifiedSigner         []byte // Digest of the qualified name of the signing key.
-	extraData               []byte // External information supplied by caller.
-	clockInfo               []byte // Clock, resetCount, restartCount, and Safe.
-	firmwareVersion         []byte // TPM-vendor-specific value identifying the version number of the firmware.
-	nameHashType            string // Hashing algorithm for name.
-	name                    []byte // Digest of the name of the certified object.
-	qualifiedNameHashType   string // Hashing algorithm for qualified name.
-	qualifiedName           []byte // Digest of the qualified name of the certified object.
-}
-
-// tpmaObject represents TPM structure TPMA_OBJECT, as specified in https://trustedcomputinggroup.org/wp-content/uploads/TPM-Rev-2.0-Part-2-Structures-01.38.pdf section 8.3.1.
-type tpmaObject struct {
-	fixedTPM             bool
-	stClear              bool
-	fixedParent          bool
-	sensitiveDataOrigin  bool
-	userWithAuth         bool
-	adminWithPolicy      bool
-	noDA                 bool
-	encryptedDuplication bool
-	restricted           bool
-	decrypt              bool
-	signOrEncrypt        bool
-}
+// ClockInfo represents TPM structure TPMS_CLOCK_INFO in certInfo.
+type ClockInfo = tpm2.ClockInfo
 
-// tpmuPublicParms represents the union of TPM structures TPMS_RSA_PARMS and TPMS_ECC_PARMS, as specified in https://trustedcomputinggroup.org/wp-content/uploads/TPM-Rev-2.0-Part-2-Structures-01.38.pdf section 12.2.3.5 and 12.2.3.6.
-type tpmuPublicParms struct {
-	symmetric string // TPMS_RSA_PARMS and TPMS_ECC_PARMS: symmetric algorithm used for decryption.  If the key is not a restricted decryption key, this field shall be set to TPM_ALG_NULL (0x0010).
-	scheme    string // TPMS_RSA_PARMS and TPMS_ECC_PARMS: algorithm scheme, such as TPM_ALG_RSASSA (0x0014), TPM_ALG_RSAPSS (0x0016) for RSA and TPM_ALG_ECDSA (0x0018) for ECC.
-	keyBits   uint16 // TPMS_RSA_PARMS: number of bits in the public modulus.
-	exponent  uint32 // TPMS_RSA_PARMS: the public exponent.  When zero, indicates that the exponent is default of 2^16+1(65537)
-	curveID   string // TPMS_ECC_PARMS: curve ID.
-	kdf       string // TPMS_ECC_PARMS: optional key derivation scheme.  MUST be NULL.
-}
-
-// tpmtPublic represents TPM structure TPMT_PUBLIC, as specified in https://trustedcomputinggroup.org/wp-content/uploads/TPM-Rev-2.0-Part-2-Structures-01.38.pdf section 12.2.4.
-// Only support "TPM_ALG_RSA" and "TPM_ALG_ECC" algorithm.
-type tpmtPublic struct {
-	typ              string          // Algorithm associated with this object.
-	nameAlg          string          // Algorithm used for computing the name of the object.
-	objectAttributes tpmaObject      // Attributes that, along with type, determine the manipulations of this object.
-	authPolicy       []byte          // Optional policy for using this key.
-	parameters       tpmuPublicParms // Algorithm or strcuture details.
-	rsaN             []byte          // RSA n coefficient, only for typ "TPM_ALG_RSA", stored in TPMT_PUBLIC structure unique field.
-	eccX, eccY       []byte          // ECC x and y coordinates, only for typ "TPM_ALG_ECC", stored in TPMT_PUBLIC structure unique field.
-}
+// FirmwareVersion represents TPM-vendor-specific firmware version in certInfo.
+type FirmwareVersion = tpm2.FirmwareVersion
 
 type tpmAttestationStatement struct {
 	ver                         string              // The version of TPM specification to which the signature conforms.
@@ -104,10 +58,25 @@ type tpmAttestationStatement struct {
 	caCerts                     []*x509.Certificate // AIK certificate chain.
 	ecdaaKeyID                  []byte              // The identifier of the ECDAA-Issuer public key.
 	rawSig                      []byte              // Complete raw sig content.
+	sig                         *tpm2.Signature     // Decoded sig, or nil if sig is raw signature bytes instead of TPMT_SIGNATURE structure.
 	rawCerInfo                  []byte              // Complete raw certInfo content.
 	rawPubArea                  []byte              // Complete raw pubArea content.
-	certInfo                    *tpmsAttest         // The TPMS_ATTEST structure over which signature was computed, as specified in https://trustedcomputinggroup.org/wp-content/uploads/TPM-Rev-2.0-Part-2-Structures-01.38.pdf section 10.12.8.
-	pubArea                     *tpmtPublic         // The TPMT_PUBLIC structure used by the TPM to represent the credential public key, as specified in https://trustedcomputinggroup.org/wp-content/uploads/TPM-Rev-2.0-Part-2-Structures-01.38.pdf section 12.2.4.
+	certInfo                    *tpm2.Attest        // The TPMS_ATTEST structure over which signature was computed, as specified in https://trustedcomputinggroup.org/wp-content/uploads/TPM-Rev-2.0-Part-2-Structures-01.38.pdf section 10.12.8.
+	pubArea                     *tpm2.Public        // The TPMT_PUBLIC structure used by the TPM to represent the credential public key, as specified in https://trustedcomputinggroup.org/wp-content/uploads/TPM-Rev-2.0-Part-2-Structures-01.38.pdf section 12.2.4.
+	details                     *AttestationDetails // TPM specific details of verified attestation statement.
+}
+
+// AttestationDetails represents tpm specific details of a verified attestation statement.  Relying parties can use
+// them as risk signals, for example, to flag TPMs with known-vulnerable firmware at registration time.
+type AttestationDetails struct {
+	Manufacturer            string          // TPM manufacturer ID in the TCG Vendor ID Registry, such as "id:4E544300", in AIK certificate SAN extension.
+	ManufacturerName        string          // TPM manufacturer name, such as "Nuvoton Technology".
+	Model                   string          // TPM part number in AIK certificate SAN extension.
+	Version                 string          // TPM firmware version in AIK certificate SAN extension.
+	ClockInfo               ClockInfo       // Clock information in certInfo.
+	FirmwareVersion         FirmwareVersion // TPM firmware version in certInfo.
+	QualifiedSignerHashType string          // Hashing algorithm of qualified signer in certInfo, such as "TPM_ALG_SHA256".
+	QualifiedSigner         []byte          // Digest of the qualified name of the AIK in certInfo.
 }
 
 func parseAttestation(data []byte) (webauthn.AttestationStatement, error) {
@@ -138,7 +107,12 @@ func parseAttestation(data []byte) (webauthn.AttestationStatement, error) {
 		rawPubArea: raw.PubArea,
 	}
 
-	if attStmt.SignatureAlgorithm, err = webauthn.CoseAlgToSignatureAlgorithm(raw.Alg); err != nil {
+	if len(raw.ECDAAKeyID) > 0 {
+		attStmt.ecdaaKeyID = raw.ECDAAKeyID
+		if attStmt.SignatureAlgorithm, err = ecdaa.SignatureAlgorithm(raw.Alg); err != nil {
+			return nil, err
+		}
+	} else if attStmt.SignatureAlgorithm, err = webauthn.CoseAlgToSignatureAlgorithm(raw.Alg); err != nil {
 		return nil, err
 	}
 
@@ -154,188 +128,55 @@ func parseAttestation(data []byte) (webauthn.AttestationStatement, error) {
 		}
 	}
 
-	if len(raw.ECDAAKeyID) > 0 {
-		attStmt.ecdaaKeyID = raw.ECDAAKeyID
-		return nil, &webauthn.UnsupportedFeatureError{Feature: "Elliptic Curve based Direct Anonymous Attestation (ECDAA)"}
+	// Some authenticators send raw signature bytes instead of TPMT_SIGNATURE structure, so sig is decoded
+	// unless it is shaped like a raw signature of the AIK.  ECDAA signatures are always raw FIDO ECDAA signatures.
+	if len(raw.ECDAAKeyID) == 0 && !isRawSignature(raw.Sig, attStmt.aikCert) {
+		if attStmt.sig, err = tpm2.DecodeSignature(raw.Sig); err != nil {
+			return nil, tpm2Error("sig", err)
+		}
 	}
 
-	if attStmt.certInfo, err = parseTPMCertInfo(raw.CertInfo); err != nil {
-		return nil, err
+	if attStmt.certInfo, err = tpm2.DecodeAttest(raw.CertInfo); err != nil {
+		return nil, tpm2Error("certInfo", err)
 	}
 
-	if attStmt.pubArea, err = parseTPMPubArea(raw.PubArea); err != nil {
-		return nil, err
+	if attStmt.pubArea, err = tpm2.DecodePublic(raw.PubArea); err != nil {
+		return nil, tpm2Error("pubArea", err)
 	}
 
 	return attStmt, nil
 }
 
-func parseTPMCertInfo(data []byte) (certInfo *tpmsAttest, err error) {
-	if len(data) < 6 {
-		return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "certInfo", Msg: "unexpected EOF"}
-	}
-
-	certInfo = &tpmsAttest{}
-
-	certInfo.magic, data = binary.BigEndian.Uint32(data[:4]), data[4:]
-
-	certInfo.typ, data = tpmStructureTags[int(binary.BigEndian.Uint16(data[:2]))], data[2:]
-
-	if certInfo.qualifiedSignerHashType, certInfo.qualifiedSigner, data, err = getTPM2bName(data); err != nil {
-		if err == io.ErrUnexpectedEOF {
-			return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "certInfo", Msg: "unexpected EOF"}
-		}
-		return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "certInfo.qualifiedSigner", Msg: err.Error()}
-	}
-
-	if certInfo.extraData, data, err = getTPM2bData(data); err != nil {
-		if err == io.ErrUnexpectedEOF {
-			return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "certInfo", Msg: "unexpected EOF"}
-		}
-		return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "certInfo.extraData", Msg: err.Error()}
-	}
-
-	if len(data) < 17 {
-		return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "certInfo", Msg: "unexpected EOF"}
-	}
-	certInfo.clockInfo, data = data[:17], data[17:]
-
-	if len(data) < 8 {
-		return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "certInfo", Msg: "unexpected EOF"}
-	}
-	certInfo.firmwareVersion, data = data[:8], data[8:]
-
-	if certInfo.nameHashType, certInfo.name, data, err = getTPM2bName(data); err != nil {
-		if err == io.ErrUnexpectedEOF {
-			return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "certInfo", Msg: "unexpected EOF"}
-		}
-		return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "certInfo.name", Msg: err.Error()}
-	}
-
-	if certInfo.qualifiedNameHashType, certInfo.qualifiedName, data, err = getTPM2bName(data); err != nil {
-		if err == io.ErrUnexpectedEOF {
-			return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "certInfo", Msg: "unexpected EOF"}
-		}
-		return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "certInfo.qualifiedName", Msg: err.Error()}
-	}
-
-	if len(data) != 0 {
-		return nil, &webauthn.UnmarshalBadDataError{Type: "TPM attestation", Msg: "trailing data after certInfo"}
-	}
-
-	return certInfo, nil
+// isRawSignature returns true if sig is a raw signature by the public key of aikCert instead of a
+// TPMT_SIGNATURE structure: PKCS #1 signature as long as the RSA modulus, or ASN.1 encoded ECDSA signature.
+func isRawSignature(sig []byte, aikCert *x509.Certificate) bool {
+	if aikCert == nil {
+		return false
+	}
+	switch pub := aikCert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return len(sig) == pub.Size()
+	case *ecdsa.PublicKey:
+		var ecdsaSig struct{ R, S *big.Int }
+		rest, err := asn1.Unmarshal(sig, &ecdsaSig)
+		return err == nil && len(rest) == 0
+	}
+	return false
 }
 
-func parseTPMPubArea(data []byte) (pubArea *tpmtPublic, err error) {
-	if len(data) < 8 {
-		return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "pubArea", Msg: "unexpected EOF"}
-	}
-
-	pubArea = &tpmtPublic{}
-
-	pubArea.typ, data = tpmAlgorithms[int(binary.BigEndian.Uint16(data[:2]))], data[2:]
-
-	pubArea.nameAlg, data = tpmAlgorithms[int(binary.BigEndian.Uint16(data[:2]))], data[2:]
-
-	objectAttributesInt, data := binary.BigEndian.Uint32(data[:4]), data[4:]
-	pubArea.objectAttributes.fixedTPM = (objectAttributesInt & (1 << 1)) != 0
-	pubArea.objectAttributes.stClear = (objectAttributesInt & (1 << 2)) != 0
-	pubArea.objectAttributes.fixedParent = (objectAttributesInt & (1 << 4)) != 0
-	pubArea.objectAttributes.sensitiveDataOrigin = (objectAttributesInt & (1 << 5)) != 0
-	pubArea.objectAttributes.userWithAuth = (objectAttributesInt & (1 << 6)) != 0
-	pubArea.objectAttributes.adminWithPolicy = (objectAttributesInt & (1 << 7)) != 0
-	pubArea.objectAttributes.noDA = (objectAttributesInt & (1 << 10)) != 0
-	pubArea.objectAttributes.encryptedDuplication = (objectAttributesInt & (1 << 11)) != 0
-	pubArea.objectAttributes.restricted = (objectAttributesInt & (1 << 16)) != 0
-	pubArea.objectAttributes.decrypt = (objectAttributesInt & (1 << 17)) != 0
-	pubArea.objectAttributes.signOrEncrypt = (objectAttributesInt & (1 << 18)) != 0
-
-	if pubArea.authPolicy, data, err = getTPM2bData(data); err != nil {
-		if err == io.ErrUnexpectedEOF {
-			return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "pubArea", Msg: "unexpected EOF"}
-		}
-		return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "pubArea.authPolicy", Msg: err.Error()}
-	}
-
-	if pubArea.typ == "TPM_ALG_RSA" {
-		if len(data) < 10 {
-			return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "pubArea", Msg: "unexpected EOF"}
-		}
-		pubArea.parameters.symmetric = tpmAlgorithms[int(binary.BigEndian.Uint16(data[:2]))]
-		pubArea.parameters.scheme = tpmAlgorithms[int(binary.BigEndian.Uint16(data[2:4]))]
-		pubArea.parameters.keyBits = binary.BigEndian.Uint16(data[4:6])
-		pubArea.parameters.exponent = binary.BigEndian.Uint32(data[6:10])
-		if pubArea.parameters.exponent == 0 {
-			pubArea.parameters.exponent = 65537 // default exponent value
-		}
-		data = data[10:]
-
-		if pubArea.rsaN, data, err = getTPM2bData(data); err != nil {
-			if err == io.ErrUnexpectedEOF {
-				return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "pubArea", Msg: "unexpected EOF"}
-			}
-			return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "pubArea.rsaN", Msg: err.Error()}
-		}
-	} else if pubArea.typ == "TPM_ALG_ECC" {
-		if len(data) < 8 {
-			return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "pubArea", Msg: "unexpected EOF"}
-		}
-		pubArea.parameters.symmetric = tpmAlgorithms[int(binary.BigEndian.Uint16(data[:2]))]
-		pubArea.parameters.scheme = tpmAlgorithms[int(binary.BigEndian.Uint16(data[2:4]))]
-		pubArea.parameters.curveID = tpmECCCurve[int(binary.BigEndian.Uint16(data[4:6]))]
-		pubArea.parameters.kdf = tpmAlgorithms[int(binary.BigEndian.Uint16(data[6:8]))]
-		data = data[8:]
-
-		if pubArea.eccX, data, err = getTPM2bData(data); err != nil {
-			if err == io.ErrUnexpectedEOF {
-				return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "pubArea", Msg: "unexpected EOF"}
-			}
-			return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "pubArea.eccX", Msg: err.Error()}
-		}
-		if pubArea.eccY, data, err = getTPM2bData(data); err != nil {
-			if err == io.ErrUnexpectedEOF {
-				return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "pubArea", Msg: "unexpected EOF"}
-			}
-			return nil, &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: "pubArea.eccY", Msg: err.Error()}
+// tpm2Error converts error returned by tpm2 package to webauthn error.
+func tpm2Error(field string, err error) error {
+	switch e := err.(type) {
+	case *tpm2.UnmarshalError:
+		if e.Field != "" {
+			field += "." + e.Field
 		}
-	} else {
-		return nil, &webauthn.UnsupportedFeatureError{Feature: "TPM attestation public key type " + pubArea.typ}
-	}
-
-	if len(data) != 0 {
-		return nil, &webauthn.UnmarshalBadDataError{Type: "TPM attestation", Msg: "trailing data after pubArea"}
-	}
-
-	return pubArea, nil
-}
-
-func getTPM2bData(data []byte) (element []byte, rest []byte, err error) {
-	if len(data) < 2 {
-		err = io.ErrUnexpectedEOF
-		return
-	}
-	elementLen := int(binary.BigEndian.Uint16(data[:2]))
-
-	if len(data) < 2+elementLen {
-		err = io.ErrUnexpectedEOF
-		return
-	}
-	element, rest = data[2:2+elementLen], data[2+elementLen:]
-	return
-}
-
-func getTPM2bName(data []byte) (hashType string, name []byte, rest []byte, err error) {
-	var element []byte
-	if element, rest, err = getTPM2bData(data); err != nil {
-		return
-	}
-	if len(element) < 2 {
-		err = io.ErrUnexpectedEOF
-		return
+		return &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: field, Msg: e.Msg}
+	case *tpm2.UnsupportedError:
+		return &webauthn.UnsupportedFeatureError{Feature: "TPM attestation " + field + " " + e.Feature}
+	default:
+		return &webauthn.UnmarshalSyntaxError{Type: "TPM attestation", Field: field, Msg: err.Error()}
 	}
-	hashType = tpmAlgorithms[int(binary.BigEndian.Uint16(element[:2]))]
-	name = element[2:]
-	return
 }
 
 // Verify implements the webauthn.AttestationStatement interface.  It follows android-key attestation statement verification procedure defined in https://w3c.github.io/webauthn/ section 8.3, also refers to https://medium.com/@herrjemand/verifying-fido-tpm2-0-attestation-fc7243847498 for clarification.
@@ -347,78 +188,68 @@ func (attStmt *tpmAttestationStatement) Verify(clientDataHash []byte, authnData
 	}
 
 	// Verify that the public key specified by the parameters and unique fields of pubArea is idential to the credentialPublicKey in the attestedCredentialData in authenticatorData.
-	if attStmt.pubArea.typ == "TPM_ALG_RSA" {
+	if attStmt.pubArea.Type == tpm2.AlgRSA {
 		credentialPubKey, ok := authnData.Credential.PublicKey.(*rsa.PublicKey)
 		if !ok {
 			err = &webauthn.VerificationError{Type: "TPM attestation", Field: "public key", Msg: "public key type specified in pubArea does not match credential public key type"}
 			return
 		}
 		// Compare RSA public key n coefficient.
-		if !bytes.Equal(attStmt.pubArea.rsaN, credentialPubKey.N.Bytes()) {
+		if !bytes.Equal(attStmt.pubArea.RSAModulus, credentialPubKey.N.Bytes()) {
 			err = &webauthn.VerificationError{Type: "TPM attestation", Field: "public key", Msg: "RSA public key n coefficient specified in pubArea does not match credential public key n coefficient"}
 			return
 		}
 		// Compare RSA public key exponent.
-		if attStmt.pubArea.parameters.exponent != uint32(credentialPubKey.E) {
+		if attStmt.pubArea.RSAParameters.PublicExponent() != uint32(credentialPubKey.E) {
 			err = &webauthn.VerificationError{Type: "TPM attestation", Field: "public key", Msg: "RSA public key exponent specified in pubArea does not match credential public key exponent"}
 			return
 		}
-	} else if attStmt.pubArea.typ == "TPM_ALG_ECC" {
+	} else if attStmt.pubArea.Type == tpm2.AlgECC {
 		credentialPubKey, ok := authnData.Credential.PublicKey.(*ecdsa.PublicKey)
 		if !ok {
 			err = &webauthn.VerificationError{Type: "TPM attestation", Field: "public key", Msg: "public key type specified in pubArea does not match credential public key type"}
 			return
 		}
 		// Compare ECDSA public key curve.
-		switch attStmt.pubArea.parameters.curveID {
-		case "TPM_ECC_NIST_P224":
-			if credentialPubKey.Curve.Params().BitSize != 224 {
-				err = &webauthn.VerificationError{Type: "TPM attestation", Field: "public key", Msg: "ECC public key curve does not match credential public key curve"}
-				return
-			}
-		case "TPM_ECC_NIST_P256":
-			if credentialPubKey.Curve.Params().BitSize != 256 {
-				err = &webauthn.VerificationError{Type: "TPM attestation", Field: "public key", Msg: "ECC public key curve does not match credential public key curve"}
-				return
-			}
-		case "TPM_ECC_NIST_P384":
-			if credentialPubKey.Curve.Params().BitSize != 384 {
-				err = &webauthn.VerificationError{Type: "TPM attestation", Field: "public key", Msg: "ECC public key curve does not match credential public key curve"}
-				return
-			}
-		case "TPM_ECC_NIST_P521":
-			if credentialPubKey.Curve.Params().BitSize != 521 {
-				err = &webauthn.VerificationError{Type: "TPM attestation", Field: "public key", Msg: "ECC public key curve does not match credential public key curve"}
-				return
-			}
-		default:
-			err = &webauthn.UnsupportedFeatureError{Feature: "TPM ECC public key curve " + attStmt.pubArea.parameters.curveID}
+		curve, ok := attStmt.pubArea.ECCParameters.CurveID.Curve()
+		if !ok {
+			err = &webauthn.UnsupportedFeatureError{Feature: "TPM ECC public key curve " + attStmt.pubArea.ECCParameters.CurveID.String()}
+			return
+		}
+		if credentialPubKey.Curve.Params().Name != curve.Params().Name {
+			err = &webauthn.VerificationError{Type: "TPM attestation", Field: "public key", Msg: "ECC public key curve does not match credential public key curve"}
 			return
 		}
 		// Compare ECDSA public key x and y coordinates.
-		if !bytes.Equal(attStmt.pubArea.eccX, credentialPubKey.X.Bytes()) {
+		if !bytes.Equal(attStmt.pubArea.ECCPoint.X, credentialPubKey.X.Bytes()) {
 			err = &webauthn.VerificationError{Type: "TPM attestation", Field: "public key", Msg: "ECC public key x coordinate specified in pubArea does not match credential public key x coordinate"}
 			return
 		}
-		if !bytes.Equal(attStmt.pubArea.eccY, credentialPubKey.Y.Bytes()) {
+		if !bytes.Equal(attStmt.pubArea.ECCPoint.Y, credentialPubKey.Y.Bytes()) {
 			err = &webauthn.VerificationError{Type: "TPM attestation", Field: "public key", Msg: "ECC public key y coordinate specified in pubArea does not match credential public key y coordinate"}
 			return
 		}
 	} else {
-		err = &webauthn.UnsupportedFeatureError{Feature: "TPM attestation public key type " + attStmt.pubArea.typ}
+		err = &webauthn.UnsupportedFeatureError{Feature: "TPM attestation public key type " + attStmt.pubArea.Type.String()}
+		return
+	}
+
+	// Verify that pubArea describes a signing key that was generated in and can't be exported from the TPM.
+	if err = verifyTPMPubArea(attStmt.pubArea, authnData.Credential.SignatureAlgorithm); err != nil {
+		err = &webauthn.VerificationError{Type: "TPM attestation", Field: "pubArea", Msg: err.Error()}
 		return
 	}
 
 	// Validate that certInfo is valid:
 	// - Verify that magic is set to TPM_GENERATED_VALUE.
-	if attStmt.certInfo.magic != 0xff544347 { // TPM_GENERATED_VALUE
-		err = &webauthn.VerificationError{Type: "TPM attestation", Field: "certInfo.magic", Msg: fmt.Sprintf("expected certInfo.magic %d, got %d", 0xff544347, attStmt.certInfo.magic)}
+	if attStmt.certInfo.Magic != tpm2.GeneratedValue {
+		err = &webauthn.VerificationError{Type: "TPM attestation", Field: "certInfo.magic", Msg: fmt.Sprintf("expected certInfo.magic %d, got %d", tpm2.GeneratedValue, attStmt.certInfo.Magic)}
 		return
 	}
 
 	// - Verify that type is set to TPM_ST_ATTEST_CERTIFY.
-	if attStmt.certInfo.typ != "TPM_ST_ATTEST_CERTIFY" {
-		err = &webauthn.VerificationError{Type: "TPM attestation", Field: "certInfo.typ", Msg: "expected certInfo.typ \"TPM_ST_ATTEST_CERTIFY\", got " + attStmt.certInfo.typ}
+	if attStmt.certInfo.Type != tpm2.TagAttestCertify {
+		err = &webauthn.VerificationError{Type: "TPM attestation", Field: "certInfo.typ", Msg: "expected certInfo.typ \"TPM_ST_ATTEST_CERTIFY\", got " + attStmt.certInfo.Type.String()}
 		return
 	}
 
@@ -431,41 +262,58 @@ func (attStmt *tpmAttestationStatement) Verify(clientDataHash []byte, authnData
 	h := attStmt.Hash.New()
 	h.Write(attToBeSigned)
 	authnClientDataHash := h.Sum(nil)
-	if !bytes.Equal(authnClientDataHash, attStmt.certInfo.extraData) {
+	if !bytes.Equal(authnClientDataHash, attStmt.certInfo.ExtraData) {
 		err = &webauthn.VerificationError{Type: "TPM attestation", Field: "certInfo.extraData", Msg: "extraData doesn't match hash of authenticator data and client data hash"}
 		return
 	}
 
 	// - Verify that attested contains a TPMS_CERTIFY_INFO structure as specified in [TPMv2-Part2] section 10.12.3, whose name field contains a valid Name for pubArea, as computed using the algorithm in the nameAlg field of pubArea using the procedure specified in [TPMv2-Part1] section 16.
-	switch attStmt.pubArea.nameAlg {
-	case "TPM_ALG_SHA256":
-		h = crypto.SHA256.New()
-	case "TPM_ALG_SHA384":
-		h = crypto.SHA384.New()
-	case "TPM_ALG_SHA512":
-		h = crypto.SHA512.New()
+	switch attStmt.pubArea.NameAlg {
+	case tpm2.AlgSHA256, tpm2.AlgSHA384, tpm2.AlgSHA512:
 	default:
-		err = &webauthn.UnsupportedFeatureError{Feature: "TPM attestation public key nameAlg " + attStmt.pubArea.nameAlg}
+		err = &webauthn.UnsupportedFeatureError{Feature: "TPM attestation public key nameAlg " + attStmt.pubArea.NameAlg.String()}
 		return
 	}
-	h.Write(attStmt.rawPubArea)
-	computedPubAreaName := h.Sum(nil)
-	if !bytes.Equal(computedPubAreaName, attStmt.certInfo.name) {
+	computedPubAreaName, err := tpm2.ComputeName(attStmt.pubArea.NameAlg, attStmt.rawPubArea)
+	if err != nil {
+		err = &webauthn.UnsupportedFeatureError{Feature: "TPM attestation public key nameAlg " + attStmt.pubArea.NameAlg.String()}
+		return
+	}
+	if !computedPubAreaName.Equal(attStmt.certInfo.Certify.Name) {
 		err = &webauthn.VerificationError{Type: "TPM attestation", Field: "certInfo.name", Msg: "pubArea name does not match computed name"}
 		return
 	}
 
-	// - Note that the remaining fields in the "Standard Attesation Structure" [TPMv2-Part2] section 31.2, i.e., qualifiedSigner, clockInfo and firmwareVersion are ignored.  These fields MAY be used as an input to risk engines.
+	// - Note that the remaining fields in the "Standard Attesation Structure" [TPMv2-Part2] section 31.2, i.e., qualifiedSigner, clockInfo and firmwareVersion are not verified.  These fields are returned in AttestationDetails as an input to risk engines.
 
 	if attStmt.aikCert != nil {
+		// Verify that sigAlg and hash of TPMT_SIGNATURE match the algorithm specified in alg.
+		sig := attStmt.rawSig
+		if attStmt.sig != nil {
+			if err = verifyTPMSignatureAlgorithm(attStmt.sig, attStmt.SignatureAlgorithm); err != nil {
+				err = &webauthn.VerificationError{Type: "TPM attestation", Field: "sig", Msg: err.Error()}
+				return
+			}
+			if sig, err = attStmt.sig.X509Signature(); err != nil {
+				err = &webauthn.VerificationError{Type: "TPM attestation", Field: "sig", Msg: err.Error()}
+				return
+			}
+		}
+
 		// Verify the sig is a valid signature over certInfo using the attestation public key in aikCert with the algorithm specified in alg.
-		if err = attStmt.aikCert.CheckSignature(attStmt.Algorithm, attStmt.rawCerInfo, attStmt.rawSig); err != nil {
+		if err = attStmt.aikCert.CheckSignature(attStmt.Algorithm, attStmt.rawCerInfo, sig); err != nil {
 			err = &webauthn.VerificationError{Type: "TPM attestation", Field: "signature", Msg: err.Error()}
 			return
 		}
 
 		// Verify that aikCert meets the certificate requirements https://w3c.github.io/webauthn/ section 8.3.1.
-		if err = verifyTPMAttestationStatementCert(attStmt.aikCert); err != nil {
+		details := &AttestationDetails{
+			ClockInfo:               attStmt.certInfo.ClockInfo,
+			FirmwareVersion:         attStmt.certInfo.FirmwareVersion,
+			QualifiedSignerHashType: attStmt.certInfo.QualifiedSigner.HashAlg.String(),
+			QualifiedSigner:         attStmt.certInfo.QualifiedSigner.Digest,
+		}
+		if details.Manufacturer, details.Model, details.Version, err = verifyTPMAttestationStatementCert(attStmt.aikCert); err != nil {
 			err = &webauthn.VerificationError{Type: "TPM attestation", Field: "certificate requirement", Msg: err.Error()}
 			return
 		}
@@ -484,34 +332,148 @@ func (attStmt *tpmAttestationStatement) Verify(clientDataHash []byte, authnData
 			}
 		}
 
-		// Verify aikCert by building certificate chain.
-		if trustPath, err = verifyAttestationCert(attStmt.aikCert, attStmt.caCerts); err != nil {
+		// Verify aikCert by building certificate chain to a trusted root of TPM manufacturer.  Self-signed
+		// certificates in x5c are not trusted.
+		var trusted bool
+		if trustPath, trusted, err = webauthn.VerifyAttestationCertificateWithRoots(attStmt.aikCert, attStmt.caCerts, DefaultRoots.Certificates(details.Manufacturer)); err != nil {
 			err = &webauthn.VerificationError{Type: "TPM attestation", Field: "certificate", Msg: err.Error()}
 			return
 		}
+		details.ManufacturerName = tpmManufacturers[normalizeManufacturerID(details.Manufacturer)]["name"]
+		attStmt.details = details
+
+		// Without a trusted root of TPM manufacturer, attestation type is uncertain.
+		if !trusted {
+			return webauthn.AttestationTypeUncertain, trustPath, nil
+		}
 
 		// If successful, return implementation-specific values representing attestation type AttCA and attestation trust path x5c.
 		return webauthn.AttestationTypeCA, trustPath, nil
 	}
 	if len(attStmt.ecdaaKeyID) > 0 {
-		return webauthn.AttestationTypeECDAA, attStmt.ecdaaKeyID, &webauthn.UnsupportedFeatureError{Feature: "Elliptic Curve based Direct Anonymous Attestation (ECDAA)"}
+		// Look up ECDAA-Issuer public key identified by ecdaaKeyId.
+		var ipk *ecdaa.IssuerPublicKey
+		if ipk, err = ecdaa.ResolveIssuerKey(attStmt.ecdaaKeyID); err != nil {
+			err = &webauthn.VerificationError{Type: "TPM attestation", Field: "ecdaaKeyId", Msg: err.Error()}
+			return
+		}
+
+		// Perform ECDAA-Verify on sig to verify that it is a valid signature over certInfo using ECDAA-Issuer public key identified by ecdaaKeyId.
+		if err = ipk.Verify(attStmt.rawSig, attStmt.rawCerInfo); err != nil {
+			err = &webauthn.VerificationError{Type: "TPM attestation", Field: "signature", Msg: err.Error()}
+			return
+		}
+		attStmt.details = &AttestationDetails{
+			ClockInfo:               attStmt.certInfo.ClockInfo,
+			FirmwareVersion:         attStmt.certInfo.FirmwareVersion,
+			QualifiedSignerHashType: attStmt.certInfo.QualifiedSigner.HashAlg.String(),
+			QualifiedSigner:         attStmt.certInfo.QualifiedSigner.Digest,
+		}
+
+		// If successful, return implementation-specific values representing attestation type ECDAA and attestation trust path ECDAA-Issuer public key.
+		return webauthn.AttestationTypeECDAA, ipk, nil
 	}
 	return
 }
 
-func verifyTPMAttestationStatementCert(c *x509.Certificate) error {
+// AttestationDetails implements the webauthn.AttestationDetailer interface.  It returns *AttestationDetails
+// after successful verification.
+func (attStmt *tpmAttestationStatement) AttestationDetails() interface{} {
+	return attStmt.details
+}
+
+// tpmSignatureScheme returns TPM signature scheme of given signature algorithm.
+func tpmSignatureScheme(alg webauthn.SignatureAlgorithm) tpm2.Algorithm {
+	switch {
+	case alg.IsRSAPSS():
+		return tpm2.AlgRSAPSS
+	case alg.IsRSA():
+		return tpm2.AlgRSASSA
+	case alg.IsECDSA():
+		return tpm2.AlgECDSA
+	default:
+		return tpm2.AlgError
+	}
+}
+
+func verifyTPMSignatureAlgorithm(sig *tpm2.Signature, alg webauthn.SignatureAlgorithm) error {
+	if scheme := tpmSignatureScheme(alg); sig.SigAlg != scheme {
+		return fmt.Errorf("expected sigAlg %s, got %s", scheme, sig.SigAlg)
+	}
+	if hash, _ := tpm2.HashAlgorithm(alg.Hash); sig.Hash != hash {
+		return fmt.Errorf("expected hash %s, got %s", hash, sig.Hash)
+	}
+	return nil
+}
+
+func verifyTPMPubArea(pubArea *tpm2.Public, credentialAlg webauthn.SignatureAlgorithm) error {
+	// The credential key MUST be generated by the TPM and MUST not be duplicated to another TPM or parent.
+	if !pubArea.ObjectAttributes.Has(tpm2.FlagFixedTPM) {
+		return errors.New("fixedTPM is not set")
+	}
+	if !pubArea.ObjectAttributes.Has(tpm2.FlagFixedParent) {
+		return errors.New("fixedParent is not set")
+	}
+	if !pubArea.ObjectAttributes.Has(tpm2.FlagSensitiveDataOrigin) {
+		return errors.New("sensitiveDataOrigin is not set")
+	}
+
+	// The credential key MUST be an unrestricted signing key because it signs authenticator data which is not generated by the TPM.
+	if !pubArea.ObjectAttributes.Has(tpm2.FlagSignOrEncrypt) {
+		return errors.New("signOrEncrypt is not set")
+	}
+	if pubArea.ObjectAttributes.Has(tpm2.FlagRestricted) {
+		return errors.New("restricted is set")
+	}
+
+	var symmetric tpm2.SymDefObject
+	var scheme tpm2.Scheme
+	switch pubArea.Type {
+	case tpm2.AlgRSA:
+		symmetric, scheme = pubArea.RSAParameters.Symmetric, pubArea.RSAParameters.Scheme
+	case tpm2.AlgECC:
+		symmetric, scheme = pubArea.ECCParameters.Symmetric, pubArea.ECCParameters.Scheme
+
+		// Key derivation scheme MUST be TPM_ALG_NULL for ECC keys.
+		if kdf := pubArea.ECCParameters.KDF.Scheme; kdf != tpm2.AlgNull {
+			return errors.New("expected kdf TPM_ALG_NULL, got " + kdf.String())
+		}
+	}
+
+	// Symmetric algorithm MUST be TPM_ALG_NULL for keys that aren't restricted decryption keys.
+	if symmetric.Algorithm != tpm2.AlgNull {
+		return errors.New("expected symmetric TPM_ALG_NULL, got " + symmetric.Algorithm.String())
+	}
+
+	// Scheme MUST be TPM_ALG_NULL or the scheme of credential public key algorithm.
+	if scheme.Scheme != tpm2.AlgNull {
+		if want := tpmSignatureScheme(credentialAlg); scheme.Scheme != want {
+			return fmt.Errorf("expected scheme TPM_ALG_NULL or %s, got %s", want, scheme.Scheme)
+		}
+		if want, _ := tpm2.HashAlgorithm(credentialAlg.Hash); scheme.HashAlg != want {
+			return fmt.Errorf("expected scheme hash %s, got %s", want, scheme.HashAlg)
+		}
+	}
+
+	return nil
+}
+
+func verifyTPMAttestationStatementCert(c *x509.Certificate) (manufacturer, model, firmwareVersion string, err error) {
 	// Version MUST be set to 3.
 	if c.Version != 3 {
-		return fmt.Errorf("expected certificate version 3, got version %d", c.Version)
+		err = fmt.Errorf("expected certificate version 3, got version %d", c.Version)
+		return
 	}
 
 	// Subject field MUST be set to empty.
 	var subjectRawValue asn1.RawValue
-	if _, err := asn1.Unmarshal(c.RawSubject, &subjectRawValue); err != nil {
-		return errors.New("failed to parse certificate subject field: " + err.Error())
+	if _, err = asn1.Unmarshal(c.RawSubject, &subjectRawValue); err != nil {
+		err = errors.New("failed to parse certificate subject field: " + err.Error())
+		return
 	}
 	if len(subjectRawValue.Bytes) != 0 {
-		return errors.New("certificate subject field is not empty")
+		err = errors.New("certificate subject field is not empty")
+		return
 	}
 
 	// Subject Alternative Name extension MUST be set as defined in https://trustedcomputinggroup.org/wp-content/uploads/Credential_Profile_EK_V2.0_R14_published.pdf section 3.2.9.
@@ -521,20 +483,32 @@ func verifyTPMAttestationStatementCert(c *x509.Certificate) error {
 	// The TPM firmware version is a manfacturer-specific implementation version of the TPM.
 	tpmManufacturer, tpmModel, tpmVersion, err := parseSANExtension(c)
 	if err != nil {
-		return err
+		return
 	}
+	var ok bool
 	if tpmManufacturer == nil {
-		return errors.New("certificate SAN extension doesn't have TPM manufacturer")
-	} else if tpmManufacturer, ok := tpmManufacturer.(string); !ok {
-		return errors.New("TPM manufacturer is of wrong type")
-	} else if _, ok := tpmManufacturers[tpmManufacturer]; !ok {
-		return errors.New("TPM manufacturer \"" + tpmManufacturer + "\" is not recognized")
+		err = errors.New("certificate SAN extension doesn't have TPM manufacturer")
+		return
+	} else if manufacturer, ok = tpmManufacturer.(string); !ok {
+		err = errors.New("TPM manufacturer is of wrong type")
+		return
+	} else if _, ok = tpmManufacturers[normalizeManufacturerID(manufacturer)]; !ok {
+		err = errors.New("TPM manufacturer \"" + manufacturer + "\" is not recognized")
+		return
 	}
 	if tpmModel == nil {
-		return errors.New("certificate SAN extension doesn't have TPM part number")
+		err = errors.New("certificate SAN extension doesn't have TPM part number")
+		return
+	} else if model, ok = tpmModel.(string); !ok {
+		err = errors.New("TPM part number is of wrong type")
+		return
 	}
 	if tpmVersion == nil {
-		return errors.New("certificate SAN extension doesn't have TPM firmware version")
+		err = errors.New("certificate SAN extension doesn't have TPM firmware version")
+		return
+	} else if firmwareVersion, ok = tpmVersion.(string); !ok {
+		err = errors.New("TPM firmware version is of wrong type")
+		return
 	}
 
 	// The Extended Key Usage extension MUST contain the "joint-iso-itu-t(2) internationalorganizations(23) 133 tcg-kp(8) tcg-kp-AIKCertificate(3)" OID.
@@ -546,17 +520,19 @@ func verifyTPMAttestationStatementCert(c *x509.Certificate) error {
 		}
 	}
 	if !foundExtKeyUsgTcgKpAikCertificate {
-		return errors.New("certificate extended key usage extension does not have " + oidTcgKpAikCertificate.String() + "(\"tcg-kp-aik-certificate\")")
+		err = errors.New("certificate extended key usage extension does not have " + oidTcgKpAikCertificate.String() + "(\"tcg-kp-aik-certificate\")")
+		return
 	}
 
 	// The Basic Constraints extension MUST have the CA component set to false.
 	if c.IsCA {
-		return errors.New("certificate's basic constraints extension does not have the CA component set to false")
+		err = errors.New("certificate's basic constraints extension does not have the CA component set to false")
+		return
 	}
 
 	// An Authority Information Access (AIA) extension with entry id-ad-ocsp and a CRL Distribution Point extension [RFC5280] are both OPTIONAL as the status of many attestation certificates is available through metadata service.
 
-	return nil
+	return
 }
 
 func matchAAGUIDWithCertificateExtensionIfExists(c *x509.Certificate, aaguid []byte) error {
@@ -642,104 +618,7 @@ func parseSANExtension(c *x509.Certificate) (tpmManufacturer, tpmModel, tpmVersi
 	return
 }
 
-func verifyAttestationCert(attestnCert *x509.Certificate, caCerts []*x509.Certificate) (trustPath []*x509.Certificate, err error) {
-	var verifyOptions x509.VerifyOptions
-
-	if len(caCerts) > 0 {
-		lastCert := caCerts[len(caCerts)-1]
-		if bytes.Equal(lastCert.RawIssuer, lastCert.RawSubject) && lastCert.IsCA {
-			caCerts = caCerts[:len(caCerts)-1]
-
-			verifyOptions.Roots = x509.NewCertPool()
-			verifyOptions.Roots.AddCert(lastCert)
-		}
-		if len(caCerts) > 0 {
-			verifyOptions.Intermediates = x509.NewCertPool()
-			for _, c := range caCerts {
-				verifyOptions.Intermediates.AddCert(c)
-			}
-		}
-	}
-
-	var chains [][]*x509.Certificate
-	chains, err = attestnCert.Verify(verifyOptions)
-	if err != nil {
-		return nil, err
-	}
-	return chains[0], nil
-}
-
-var tpmAlgorithms = map[int]string{
-	0x0000: "TPM_ALG_ERROR",
-	0x0001: "TPM_ALG_RSA",
-	// 0x0004: "TPM_ALG_SHA",
-	0x0004: "TPM_ALG_SHA1",
-	0x0005: "TPM_ALG_HMAC",
-	0x0006: "TPM_ALG_AES",
-	0x0007: "TPM_ALG_MGF1",
-	0x0008: "TPM_ALG_KEYEDHASH",
-	0x000A: "TPM_ALG_XOR",
-	0x000B: "TPM_ALG_SHA256",
-	0x000C: "TPM_ALG_SHA384",
-	0x000D: "TPM_ALG_SHA512",
-	0x0010: "TPM_ALG_NULL",
-	0x0012: "TPM_ALG_SM3_256",
-	0x0013: "TPM_ALG_SM4",
-	0x0014: "TPM_ALG_RSASSA",
-	0x0015: "TPM_ALG_RSAES",
-	0x0016: "TPM_ALG_RSAPSS",
-	0x0017: "TPM_ALG_OAEP",
-	0x0018: "TPM_ALG_ECDSA",
-	0x0019: "TPM_ALG_ECDH",
-	0x001A: "TPM_ALG_ECDAA",
-	0x001B: "TPM_ALG_SM2",
-	0x001C: "TPM_ALG_ECSCHNORR",
-	0x001D: "TPM_ALG_ECMQV",
-	0x0020: "TPM_ALG_KDF1_SP800_56A",
-	0x0021: "TPM_ALG_KDF2",
-	0x0022: "TPM_ALG_KDF1_SP800_108",
-	0x0023: "TPM_ALG_ECC",
-	0x0025: "TPM_ALG_SYMCIPHER",
-	0x0026: "TPM_ALG_CAMELLIA",
-	0x0040: "TPM_ALG_CTR",
-	0x0041: "TPM_ALG_OFB",
-	0x0042: "TPM_ALG_CBC",
-	0x0043: "TPM_ALG_CFB",
-	0x0044: "TPM_ALG_ECB",
-}
-
-var tpmECCCurve = map[int]string{
-	0x0000: "TPM_ECC_NONE",
-	0x0001: "TPM_ECC_NIST_P192",
-	0x0002: "TPM_ECC_NIST_P224",
-	0x0003: "TPM_ECC_NIST_P256",
-	0x0004: "TPM_ECC_NIST_P384",
-	0x0005: "TPM_ECC_NIST_P521",
-	0x0010: "TPM_ECC_BN_P256",
-	0x0011: "TPM_ECC_BN_P638",
-	0x0020: "TPM_ECC_SM2_P256",
-}
-
-var tpmStructureTags = map[int]string{
-	0x00C4: "TPM_ST_RSP_COMMAND",
-	0x8000: "TPM_ST_NULL",
-	0x8001: "TPM_ST_NO_SESSIONS",
-	0x8002: "TPM_ST_SESSIONS",
-	0x8014: "TPM_ST_ATTEST_NV",
-	0x8015: "TPM_ST_ATTEST_COMMAND_AUDIT",
-	0x8016: "TPM_ST_ATTEST_SESSION_AUDIT",
-	0x8017: "TPM_ST_ATTEST_CERTIFY",
-	0x8018: "TPM_ST_ATTEST_QUOTE",
-	0x8019: "TPM_ST_ATTEST_TIME",
-	0x801A: "TPM_ST_ATTEST_CREATION",
-	0x8021: "TPM_ST_CREATION",
-	0x8022: "TPM_ST_VERIFIED",
-	0x8023: "TPM_ST_AUTH_SECRET",
-	0x8024: "TPM_ST_HASHCHECK",
-	0x8025: "TPM_ST_AUTH_SIGNED",
-	0x8029: "TPM_ST_FU_MANIFEST",
-}
-
+// tpmManufacturers is keyed by manufacturer IDs normalized with normalizeManufacturerID.
 var tpmManufacturers = map[string]map[string]string{
 	"id:414D4400": {
 		"name": "AMD",
@@ -757,7 +636,7 @@ var tpmManufacturers = map[string]map[string]string{
 		"name": "HPE",
 		"id":   "HPE",
 	},
-	"id:49424d00": {
+	"id:49424D00": {
 		"name": "IBM",
 		"id":   "IBM",
 	},
@@ -22,12 +22,19 @@ package tpm
 import (
 	"bytes"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
 	"encoding/json"
 	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/fxamacker/cbor/v2"
 	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/ecdaa"
+	"github.com/kappapay/webauthn/internal/testcert"
+	"github.com/kappapay/webauthn/tpm/tpm2"
 )
 
 var (
@@ -320,7 +327,8 @@ type parseTest struct {
 	wantCertInfoQualifiedSignerHashType string
 	wantCertInfoQualifiedSigner         []byte
 	wantExtraDataLength                 int
-	wantFirmwareVersionLength           int
+	wantCertInfoClockInfo               ClockInfo
+	wantFirmwareVersion                 FirmwareVersion
 	wantCertInfoNameHashType            string
 	wantCertInfoNameLength              int
 	wantCertInfoQualifiedNameHashType   string
@@ -376,7 +384,8 @@ var parseTests = []parseTest{
 			0x82, 0x6b, 0xbf, 0x01, 0x4b, 0x67, 0xab, 0x0a, 0xd6, 0xeb, 0xb1, 0x76, 0x30, 0x6b, 0x80, 0x07,
 		},
 		wantExtraDataLength:               20,
-		wantFirmwareVersionLength:         8,
+		wantCertInfoClockInfo:             ClockInfo{Clock: 0x00000001b15a48c7, ResetCount: 0x6840f9e3, RestartCount: 0xd8f39f05, Safe: true},
+		wantFirmwareVersion:               0xa9e0c4a53fbbc413,
 		wantCertInfoNameHashType:          "TPM_ALG_SHA256",
 		wantCertInfoNameLength:            32,
 		wantCertInfoQualifiedNameHashType: "TPM_ALG_SHA256",
@@ -409,7 +418,7 @@ var parseTests = []parseTest{
 }
 
 var verifyErrorTests = []verifyErrorTest{
-	{"certificate signed by unknown authority", []byte(attestation1), "tpm_attestation: failed to verify certificate: x509: certificate signed by unknown authority"},
+	{"untrusted root certificate", []byte(attestation1), "tpm_attestation: failed to verify certificate: x509: certificate signed by unknown authority"},
 }
 
 func parseCertificate(data []byte) *x509.Certificate {
@@ -470,116 +479,130 @@ func TestParseTPMAttestation(t *testing.T) {
 			if !bytes.Equal(attStmt.rawCerInfo, tc.wantRawCertInfo) {
 				t.Errorf("attestation raw cert info %v, want %v", attStmt.rawCerInfo, tc.wantRawCertInfo)
 			}
-			if attStmt.certInfo.magic != tc.wantCertInfoMagic {
-				t.Errorf("attestation cert info magic %v, want %v", attStmt.certInfo.magic, tc.wantCertInfoMagic)
+			if attStmt.certInfo.Magic != tc.wantCertInfoMagic {
+				t.Errorf("attestation cert info magic %v, want %v", attStmt.certInfo.Magic, tc.wantCertInfoMagic)
 			}
-			if attStmt.certInfo.typ != tc.wantCertInfoType {
-				t.Errorf("attestation cert info type %s, want %s", attStmt.certInfo.typ, tc.wantCertInfoType)
+			if attStmt.certInfo.Type.String() != tc.wantCertInfoType {
+				t.Errorf("attestation cert info type %s, want %s", attStmt.certInfo.Type, tc.wantCertInfoType)
 			}
-			if attStmt.certInfo.qualifiedSignerHashType != tc.wantCertInfoQualifiedSignerHashType {
-				t.Errorf("attestation cert info qualified signer hash type %s, want %s", attStmt.certInfo.qualifiedSignerHashType, tc.wantCertInfoQualifiedSignerHashType)
+			if attStmt.certInfo.QualifiedSigner.HashAlg.String() != tc.wantCertInfoQualifiedSignerHashType {
+				t.Errorf("attestation cert info qualified signer hash type %s, want %s", attStmt.certInfo.QualifiedSigner.HashAlg, tc.wantCertInfoQualifiedSignerHashType)
 			}
-			if !bytes.Equal(attStmt.certInfo.qualifiedSigner, tc.wantCertInfoQualifiedSigner) {
-				t.Errorf("attestation cert info qualified signer %v, want %v", attStmt.certInfo.qualifiedSigner, tc.wantCertInfoQualifiedSigner)
+			if !bytes.Equal(attStmt.certInfo.QualifiedSigner.Digest, tc.wantCertInfoQualifiedSigner) {
+				t.Errorf("attestation cert info qualified signer %v, want %v", attStmt.certInfo.QualifiedSigner.Digest, tc.wantCertInfoQualifiedSigner)
 			}
-			if len(attStmt.certInfo.extraData) != tc.wantExtraDataLength {
-				t.Errorf("attestation cert info extra data length %d, want %d", len(attStmt.certInfo.extraData), tc.wantExtraDataLength)
+			if len(attStmt.certInfo.ExtraData) != tc.wantExtraDataLength {
+				t.Errorf("attestation cert info extra data length %d, want %d", len(attStmt.certInfo.ExtraData), tc.wantExtraDataLength)
 			}
-			// ignore clock field, not used
-			if len(attStmt.certInfo.firmwareVersion) != tc.wantFirmwareVersionLength {
-				t.Errorf("attestation cert info firmware version length %d, want %d", len(attStmt.certInfo.firmwareVersion), tc.wantFirmwareVersionLength)
+			if attStmt.certInfo.ClockInfo != tc.wantCertInfoClockInfo {
+				t.Errorf("attestation cert info clock info %+v, want %+v", attStmt.certInfo.ClockInfo, tc.wantCertInfoClockInfo)
 			}
-			if attStmt.certInfo.nameHashType != tc.wantCertInfoNameHashType {
-				t.Errorf("attestation cert info name hash type %s, want %s", attStmt.certInfo.nameHashType, tc.wantCertInfoNameHashType)
+			if attStmt.certInfo.FirmwareVersion != tc.wantFirmwareVersion {
+				t.Errorf("attestation cert info firmware version %s, want %s", attStmt.certInfo.FirmwareVersion, tc.wantFirmwareVersion)
 			}
-			if len(attStmt.certInfo.name) != tc.wantCertInfoNameLength {
-				t.Errorf("attestation cert info name length %d, want %d", len(attStmt.certInfo.name), tc.wantCertInfoNameLength)
+			if attStmt.certInfo.Certify == nil {
+				t.Fatalf("attestation cert info doesn't have certify info")
 			}
-			if attStmt.certInfo.qualifiedNameHashType != tc.wantCertInfoQualifiedNameHashType {
-				t.Errorf("attestation cert info qualified name hash type %s, want %s", attStmt.certInfo.qualifiedNameHashType, tc.wantCertInfoQualifiedNameHashType)
+			if attStmt.certInfo.Certify.Name.HashAlg.String() != tc.wantCertInfoNameHashType {
+				t.Errorf("attestation cert info name hash type %s, want %s", attStmt.certInfo.Certify.Name.HashAlg, tc.wantCertInfoNameHashType)
 			}
-			if len(attStmt.certInfo.qualifiedName) != tc.wantCertInfoQualifiedNameLength {
-				t.Errorf("attestation cert info qualified name length %d, want %d", len(attStmt.certInfo.qualifiedName), tc.wantCertInfoQualifiedNameLength)
+			if len(attStmt.certInfo.Certify.Name.Digest) != tc.wantCertInfoNameLength {
+				t.Errorf("attestation cert info name length %d, want %d", len(attStmt.certInfo.Certify.Name.Digest), tc.wantCertInfoNameLength)
+			}
+			if attStmt.certInfo.Certify.QualifiedName.HashAlg.String() != tc.wantCertInfoQualifiedNameHashType {
+				t.Errorf("attestation cert info qualified name hash type %s, want %s", attStmt.certInfo.Certify.QualifiedName.HashAlg, tc.wantCertInfoQualifiedNameHashType)
+			}
+			if len(attStmt.certInfo.Certify.QualifiedName.Digest) != tc.wantCertInfoQualifiedNameLength {
+				t.Errorf("attestation cert info qualified name length %d, want %d", len(attStmt.certInfo.Certify.QualifiedName.Digest), tc.wantCertInfoQualifiedNameLength)
 			}
 
 			// check attestation statement pub area
+			pubArea := attStmt.pubArea
 			if !bytes.Equal(attStmt.rawPubArea, tc.wantRawPubArea) {
 				t.Errorf("attestation raw pub area %v, want %v", attStmt.rawPubArea, tc.wantRawPubArea)
 			}
-			if attStmt.pubArea.typ != tc.wantPubAreaType {
-				t.Errorf("attestation pub area type %s, want %s", attStmt.pubArea.typ, tc.wantPubAreaType)
-			}
-			if attStmt.pubArea.nameAlg != tc.wantPubAreaNameAlg {
-				t.Errorf("attestation pub area name alg %s, want %s", attStmt.pubArea.nameAlg, tc.wantPubAreaNameAlg)
-			}
-			if attStmt.pubArea.objectAttributes.fixedTPM != tc.wantFixedTPM {
-				t.Errorf("attestation object attributes fixed TPM %t, want %t", attStmt.pubArea.objectAttributes.fixedTPM, tc.wantFixedTPM)
-			}
-			if attStmt.pubArea.objectAttributes.stClear != tc.wantStClear {
-				t.Errorf("attestation object attributes st clear %t, want %t", attStmt.pubArea.objectAttributes.stClear, tc.wantStClear)
-			}
-			if attStmt.pubArea.objectAttributes.fixedParent != tc.wantFixedParent {
-				t.Errorf("attestation object attributes fixed parent %t, want %t", attStmt.pubArea.objectAttributes.fixedParent, tc.wantFixedParent)
-			}
-			if attStmt.pubArea.objectAttributes.sensitiveDataOrigin != tc.wantSensitiveDataOrigin {
-				t.Errorf("attestation object attributes sensitive data origin %t, want %t", attStmt.pubArea.objectAttributes.sensitiveDataOrigin, tc.wantSensitiveDataOrigin)
+			if pubArea.Type.String() != tc.wantPubAreaType {
+				t.Errorf("attestation pub area type %s, want %s", pubArea.Type, tc.wantPubAreaType)
 			}
-			if attStmt.pubArea.objectAttributes.userWithAuth != tc.wantUserWithAuth {
-				t.Errorf("attestation object attributes user with auth %t, want %t", attStmt.pubArea.objectAttributes.userWithAuth, tc.wantUserWithAuth)
+			if pubArea.NameAlg.String() != tc.wantPubAreaNameAlg {
+				t.Errorf("attestation pub area name alg %s, want %s", pubArea.NameAlg, tc.wantPubAreaNameAlg)
 			}
-			if attStmt.pubArea.objectAttributes.adminWithPolicy != tc.wantAdminWithPolicy {
-				t.Errorf("attestation object attributes admin with policy %t, want %t", attStmt.pubArea.objectAttributes.adminWithPolicy, tc.wantAdminWithPolicy)
+			flagTests := []struct {
+				name string
+				flag tpm2.ObjectAttributes
+				want bool
+			}{
+				{"fixed TPM", tpm2.FlagFixedTPM, tc.wantFixedTPM},
+				{"st clear", tpm2.FlagSTClear, tc.wantStClear},
+				{"fixed parent", tpm2.FlagFixedParent, tc.wantFixedParent},
+				{"sensitive data origin", tpm2.FlagSensitiveDataOrigin, tc.wantSensitiveDataOrigin},
+				{"user with auth", tpm2.FlagUserWithAuth, tc.wantUserWithAuth},
+				{"admin with policy", tpm2.FlagAdminWithPolicy, tc.wantAdminWithPolicy},
+				{"no da", tpm2.FlagNoDA, tc.wantNoDA},
+				{"encrypted duplication", tpm2.FlagEncryptedDuplication, tc.wantEncryptedDuplication},
+				{"restricted", tpm2.FlagRestricted, tc.wantRestricted},
+				{"decrypt", tpm2.FlagDecrypt, tc.wantDecrypt},
+				{"sign or encrypt", tpm2.FlagSignOrEncrypt, tc.wantSignOrEncrypt},
 			}
-			if attStmt.pubArea.objectAttributes.noDA != tc.wantNoDA {
-				t.Errorf("attestation object attributes no da %t, want %t", attStmt.pubArea.objectAttributes.noDA, tc.wantNoDA)
-			}
-			if attStmt.pubArea.objectAttributes.encryptedDuplication != tc.wantEncryptedDuplication {
-				t.Errorf("attestation object attributes encrypted duplication %t, want %t", attStmt.pubArea.objectAttributes.encryptedDuplication, tc.wantEncryptedDuplication)
-			}
-			if attStmt.pubArea.objectAttributes.restricted != tc.wantRestricted {
-				t.Errorf("attestation object attributes restricted %t, want %t", attStmt.pubArea.objectAttributes.restricted, tc.wantRestricted)
+			for _, ft := range flagTests {
+				if got := pubArea.ObjectAttributes.Has(ft.flag); got != ft.want {
+					t.Errorf("attestation object attributes %s %t, want %t", ft.name, got, ft.want)
+				}
 			}
-			if attStmt.pubArea.objectAttributes.decrypt != tc.wantDecrypt {
-				t.Errorf("attestation object attributes decrypt %t, want %t", attStmt.pubArea.objectAttributes.decrypt, tc.wantDecrypt)
+			if len(pubArea.AuthPolicy) != tc.wantPubAreaAuthPolicyLength {
+				t.Errorf("attestation pub area auth policy length %d, want %d", len(pubArea.AuthPolicy), tc.wantPubAreaAuthPolicyLength)
 			}
-			if attStmt.pubArea.objectAttributes.signOrEncrypt != tc.wantSignOrEncrypt {
-				t.Errorf("attestation object attributes sign or encrypt %t, want %t", attStmt.pubArea.objectAttributes.signOrEncrypt, tc.wantSignOrEncrypt)
+			var symmetric, scheme, curveID, kdf string
+			var keyBits uint16
+			var exponent uint32
+			var rsaN, eccX, eccY []byte
+			if params := pubArea.RSAParameters; params != nil {
+				symmetric, scheme = params.Symmetric.Algorithm.String(), params.Scheme.Scheme.String()
+				keyBits, exponent = params.KeyBits, params.PublicExponent()
+				rsaN = pubArea.RSAModulus
 			}
-			if len(attStmt.pubArea.authPolicy) != tc.wantPubAreaAuthPolicyLength {
-				t.Errorf("attestation pub area auth policy length %d, want %d", len(attStmt.pubArea.authPolicy), tc.wantPubAreaAuthPolicyLength)
+			if params := pubArea.ECCParameters; params != nil {
+				symmetric, scheme = params.Symmetric.Algorithm.String(), params.Scheme.Scheme.String()
+				curveID, kdf = params.CurveID.String(), params.KDF.Scheme.String()
+				eccX, eccY = pubArea.ECCPoint.X, pubArea.ECCPoint.Y
 			}
-			if attStmt.pubArea.parameters.symmetric != tc.wantSymmetric {
-				t.Errorf("attestation pub area symmetric %s, want %s", attStmt.pubArea.parameters.symmetric, tc.wantSymmetric)
+			if symmetric != tc.wantSymmetric {
+				t.Errorf("attestation pub area symmetric %s, want %s", symmetric, tc.wantSymmetric)
 			}
-			if attStmt.pubArea.parameters.scheme != tc.wantScheme {
-				t.Errorf("attestation pub area scheme %s, want %s", attStmt.pubArea.parameters.scheme, tc.wantScheme)
+			if scheme != tc.wantScheme {
+				t.Errorf("attestation pub area scheme %s, want %s", scheme, tc.wantScheme)
 			}
-			if attStmt.pubArea.parameters.keyBits != tc.wantKeyBits {
-				t.Errorf("attestation pub area key bits %d, want %d", attStmt.pubArea.parameters.keyBits, tc.wantKeyBits)
+			if keyBits != tc.wantKeyBits {
+				t.Errorf("attestation pub area key bits %d, want %d", keyBits, tc.wantKeyBits)
 			}
-			if attStmt.pubArea.parameters.exponent != tc.wantExponent {
-				t.Errorf("attestation pub area exponent %d, want %d", attStmt.pubArea.parameters.exponent, tc.wantExponent)
+			if exponent != tc.wantExponent {
+				t.Errorf("attestation pub area exponent %d, want %d", exponent, tc.wantExponent)
 			}
-			if attStmt.pubArea.parameters.curveID != tc.wantCurveID {
-				t.Errorf("attestation pub area curve id %s, want %s", attStmt.pubArea.parameters.curveID, tc.wantCurveID)
+			if curveID != tc.wantCurveID {
+				t.Errorf("attestation pub area curve id %s, want %s", curveID, tc.wantCurveID)
 			}
-			if attStmt.pubArea.parameters.kdf != tc.wantKDF {
-				t.Errorf("attestation pub area kdf %s, want %s", attStmt.pubArea.parameters.kdf, tc.wantKDF)
+			if kdf != tc.wantKDF {
+				t.Errorf("attestation pub area kdf %s, want %s", kdf, tc.wantKDF)
 			}
-			if !bytes.Equal(attStmt.pubArea.rsaN, tc.wantRSAN) {
-				t.Errorf("attestation pub area rsa n 0x%x, want 0x%x", attStmt.pubArea.rsaN, tc.wantRSAN)
+			if !bytes.Equal(rsaN, tc.wantRSAN) {
+				t.Errorf("attestation pub area rsa n 0x%x, want 0x%x", rsaN, tc.wantRSAN)
 			}
-			if !bytes.Equal(attStmt.pubArea.eccX, tc.wantECCX) {
-				t.Errorf("attestation pub area ecc x 0x%x, want 0x%x", attStmt.pubArea.eccX, tc.wantECCX)
+			if !bytes.Equal(eccX, tc.wantECCX) {
+				t.Errorf("attestation pub area ecc x 0x%x, want 0x%x", eccX, tc.wantECCX)
 			}
-			if !bytes.Equal(attStmt.pubArea.eccY, tc.wantECCY) {
-				t.Errorf("attestation pub area ecc y 0x%x, want 0x%x", attStmt.pubArea.eccY, tc.wantECCY)
+			if !bytes.Equal(eccY, tc.wantECCY) {
+				t.Errorf("attestation pub area ecc y 0x%x, want 0x%x", eccY, tc.wantECCY)
 			}
 		})
 	}
 }
 
 func TestVerifyTPMAttestationError(t *testing.T) {
+	savedRoots := DefaultRoots
+	DefaultRoots = NewRoots()
+	defer func() { DefaultRoots = savedRoots }()
+	DefaultRoots.Add("id:4E544300", testcert.NewCA("Test Nuvoton Root CA", nil).Cert)
+
 	for _, tc := range verifyErrorTests {
 		t.Run(tc.name, func(t *testing.T) {
 			var credentialAttestation webauthn.PublicKeyCredentialAttestation
@@ -594,3 +617,408 @@ func TestVerifyTPMAttestationError(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyTPMSignatureAlgorithm(t *testing.T) {
+	rs256, _ := webauthn.CoseAlgToSignatureAlgorithm(webauthn.COSEAlgRS256)
+	ps256, _ := webauthn.CoseAlgToSignatureAlgorithm(webauthn.COSEAlgPS256)
+	es256, _ := webauthn.CoseAlgToSignatureAlgorithm(webauthn.COSEAlgES256)
+
+	testCases := []struct {
+		name         string
+		sig          *tpm2.Signature
+		alg          webauthn.SignatureAlgorithm
+		wantErrorMsg string
+	}{
+		{"RSASSA with RS256", &tpm2.Signature{SigAlg: tpm2.AlgRSASSA, Hash: tpm2.AlgSHA256}, rs256, ""},
+		{"RSAPSS with PS256", &tpm2.Signature{SigAlg: tpm2.AlgRSAPSS, Hash: tpm2.AlgSHA256}, ps256, ""},
+		{"ECDSA with ES256", &tpm2.Signature{SigAlg: tpm2.AlgECDSA, Hash: tpm2.AlgSHA256}, es256, ""},
+		{"RSASSA with PS256", &tpm2.Signature{SigAlg: tpm2.AlgRSASSA, Hash: tpm2.AlgSHA256}, ps256, "expected sigAlg TPM_ALG_RSAPSS, got TPM_ALG_RSASSA"},
+		{"ECDSA with RS256", &tpm2.Signature{SigAlg: tpm2.AlgECDSA, Hash: tpm2.AlgSHA256}, rs256, "expected sigAlg TPM_ALG_RSASSA, got TPM_ALG_ECDSA"},
+		{"hash mismatch", &tpm2.Signature{SigAlg: tpm2.AlgRSASSA, Hash: tpm2.AlgSHA1}, rs256, "expected hash TPM_ALG_SHA256, got TPM_ALG_SHA1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifyTPMSignatureAlgorithm(tc.sig, tc.alg)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("verifyTPMSignatureAlgorithm() returns error %q", err)
+			} else if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("verifyTPMSignatureAlgorithm() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("verifyTPMSignatureAlgorithm() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyTPMPubArea(t *testing.T) {
+	rs256, _ := webauthn.CoseAlgToSignatureAlgorithm(webauthn.COSEAlgRS256)
+	es256, _ := webauthn.CoseAlgToSignatureAlgorithm(webauthn.COSEAlgES256)
+
+	nullScheme := tpm2.Scheme{Scheme: tpm2.AlgNull}
+	nullSymmetric := tpm2.SymDefObject{Algorithm: tpm2.AlgNull}
+	newPubArea := func(typ tpm2.Algorithm, modify func(*tpm2.Public)) *tpm2.Public {
+		pubArea := &tpm2.Public{
+			Type:             typ,
+			NameAlg:          tpm2.AlgSHA256,
+			ObjectAttributes: tpm2.FlagFixedTPM | tpm2.FlagFixedParent | tpm2.FlagSensitiveDataOrigin | tpm2.FlagSignOrEncrypt,
+		}
+		if typ == tpm2.AlgRSA {
+			pubArea.RSAParameters = &tpm2.RSAParms{Symmetric: nullSymmetric, Scheme: nullScheme, KeyBits: 2048}
+		} else {
+			pubArea.ECCParameters = &tpm2.ECCParms{Symmetric: nullSymmetric, Scheme: nullScheme, CurveID: tpm2.CurveNISTP256, KDF: nullScheme}
+		}
+		if modify != nil {
+			modify(pubArea)
+		}
+		return pubArea
+	}
+
+	testCases := []struct {
+		name         string
+		pubArea      *tpm2.Public
+		alg          webauthn.SignatureAlgorithm
+		wantErrorMsg string
+	}{
+		{"attestation 1", parseTestPubArea(t, attestation1RawPubArea), rs256, ""},
+		{"RSA key", newPubArea(tpm2.AlgRSA, nil), rs256, ""},
+		{"RSA key with RSASSA scheme", newPubArea(tpm2.AlgRSA, func(p *tpm2.Public) {
+			p.RSAParameters.Scheme = tpm2.Scheme{Scheme: tpm2.AlgRSASSA, HashAlg: tpm2.AlgSHA256}
+		}), rs256, ""},
+		{"ECC key with ECDSA scheme", newPubArea(tpm2.AlgECC, func(p *tpm2.Public) {
+			p.ECCParameters.Scheme = tpm2.Scheme{Scheme: tpm2.AlgECDSA, HashAlg: tpm2.AlgSHA256}
+		}), es256, ""},
+		{"not fixedTPM", newPubArea(tpm2.AlgRSA, func(p *tpm2.Public) { p.ObjectAttributes &^= tpm2.FlagFixedTPM }), rs256, "fixedTPM is not set"},
+		{"not fixedParent", newPubArea(tpm2.AlgRSA, func(p *tpm2.Public) { p.ObjectAttributes &^= tpm2.FlagFixedParent }), rs256, "fixedParent is not set"},
+		{"not sensitiveDataOrigin", newPubArea(tpm2.AlgRSA, func(p *tpm2.Public) { p.ObjectAttributes &^= tpm2.FlagSensitiveDataOrigin }), rs256, "sensitiveDataOrigin is not set"},
+		{"not signing key", newPubArea(tpm2.AlgRSA, func(p *tpm2.Public) { p.ObjectAttributes &^= tpm2.FlagSignOrEncrypt }), rs256, "signOrEncrypt is not set"},
+		{"restricted key", newPubArea(tpm2.AlgRSA, func(p *tpm2.Public) { p.ObjectAttributes |= tpm2.FlagRestricted }), rs256, "restricted is set"},
+		{"symmetric algorithm", newPubArea(tpm2.AlgRSA, func(p *tpm2.Public) {
+			p.RSAParameters.Symmetric = tpm2.SymDefObject{Algorithm: tpm2.AlgAES, KeyBits: 128, Mode: tpm2.AlgCFB}
+		}), rs256, "expected symmetric TPM_ALG_NULL, got TPM_ALG_AES"},
+		{"incompatible scheme", newPubArea(tpm2.AlgRSA, func(p *tpm2.Public) {
+			p.RSAParameters.Scheme = tpm2.Scheme{Scheme: tpm2.AlgRSAES}
+		}), rs256, "expected scheme TPM_ALG_NULL or TPM_ALG_RSASSA, got TPM_ALG_RSAES"},
+		{"incompatible scheme hash", newPubArea(tpm2.AlgRSA, func(p *tpm2.Public) {
+			p.RSAParameters.Scheme = tpm2.Scheme{Scheme: tpm2.AlgRSASSA, HashAlg: tpm2.AlgSHA1}
+		}), rs256, "expected scheme hash TPM_ALG_SHA256, got TPM_ALG_SHA1"},
+		{"ECC key with kdf", newPubArea(tpm2.AlgECC, func(p *tpm2.Public) {
+			p.ECCParameters.KDF = tpm2.Scheme{Scheme: tpm2.AlgKDF2, HashAlg: tpm2.AlgSHA256}
+		}), es256, "expected kdf TPM_ALG_NULL, got TPM_ALG_KDF2"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifyTPMPubArea(tc.pubArea, tc.alg)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("verifyTPMPubArea() returns error %q", err)
+			} else if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("verifyTPMPubArea() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("verifyTPMPubArea() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyTPMAttestationStatementCertExtKeyUsage(t *testing.T) {
+	if _, _, _, err := verifyTPMAttestationStatementCert(parseCertificate(attestation1CredCert)); err != nil {
+		t.Errorf("verifyTPMAttestationStatementCert() returns error %q", err)
+	}
+
+	c := parseCertificate(attestation1CredCert)
+	c.UnknownExtKeyUsage = nil
+	wantErrorMsg := "certificate extended key usage extension does not have " + oidTcgKpAikCertificate.String()
+	if _, _, _, err := verifyTPMAttestationStatementCert(c); err == nil {
+		t.Errorf("verifyTPMAttestationStatementCert() returns no error, want error containing substring %q", wantErrorMsg)
+	} else if !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("verifyTPMAttestationStatementCert() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	}
+}
+
+func TestVerifyTPMAttestationStatementCertManufacturerCase(t *testing.T) {
+	testCases := []struct {
+		manufacturer string
+		wantName     string
+	}{
+		{"id:4E544300", "Nuvoton Technology"},
+		{"id:4e544300", "Nuvoton Technology"},
+		{"id:49424D00", "IBM"},
+		{"id:49424d00", "IBM"},
+		{"id:4d534654", "Microsoft"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.manufacturer, func(t *testing.T) {
+			c := parseCertificate(attestation1CredCert)
+			for i, ext := range c.Extensions {
+				if ext.Id.Equal(oidExtensionSubjectAltName) {
+					c.Extensions[i].Value = newTestSANExtensionValue(t, tc.manufacturer, "NPCT6xx", "id:13")
+				}
+			}
+			manufacturer, _, _, err := verifyTPMAttestationStatementCert(c)
+			if err != nil {
+				t.Fatalf("verifyTPMAttestationStatementCert() returns error %q", err)
+			}
+			if manufacturer != tc.manufacturer {
+				t.Errorf("manufacturer %q, want %q", manufacturer, tc.manufacturer)
+			}
+			if name := tpmManufacturers[normalizeManufacturerID(manufacturer)]["name"]; name != tc.wantName {
+				t.Errorf("manufacturer name %q, want %q", name, tc.wantName)
+			}
+		})
+	}
+}
+
+func newTestSANExtensionValue(t *testing.T, manufacturer, model, version string) []byte {
+	name, err := asn1.Marshal(pkix.RDNSequence{{
+		{Type: oidTPMManufacturer, Value: manufacturer},
+		{Type: oidTPMModel, Value: model},
+		{Type: oidTPMVersion, Value: version},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := asn1.Marshal([]asn1.RawValue{{Class: asn1.ClassContextSpecific, Tag: 4, IsCompound: true, Bytes: name}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func parseTestPubArea(t *testing.T, data []byte) *tpm2.Public {
+	pubArea, err := tpm2.DecodePublic(data)
+	if err != nil {
+		t.Fatalf("DecodePublic() returns error %q", err)
+	}
+	return pubArea
+}
+
+func TestTPM2Error(t *testing.T) {
+	_, err := tpm2.DecodeAttest(attestation1RawCertInfo[:50])
+	wantErrorMsg := "webauthn/tpm_attestation: failed to unmarshal certInfo.extraData: unexpected EOF"
+	if err = tpm2Error("certInfo", err); err.Error() != wantErrorMsg {
+		t.Errorf("tpm2Error() returns error %q, want %q", err, wantErrorMsg)
+	}
+
+	_, err = tpm2.DecodePublic([]byte{0x00, 0x08, 0x00, 0x0B, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+	wantErrorMsg = "TPM attestation pubArea type TPM_ALG_KEYEDHASH"
+	if err = tpm2Error("pubArea", err); !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("tpm2Error() returns error %q, want error containing substring %q", err, wantErrorMsg)
+	} else if _, ok := err.(*webauthn.UnsupportedFeatureError); !ok {
+		t.Errorf("tpm2Error() returns error type %T, want *webauthn.UnsupportedFeatureError", err)
+	}
+}
+
+// ECDAA-Issuer public key and ECDAA signature over attestation1RawCertInfo, generated with a test ECDAA-Issuer.
+var (
+	ecdaaIssuerX = []byte{
+		0x04, 0xf0, 0x4d, 0x3e, 0x05, 0x6b, 0x9c, 0xfe, 0xc2, 0x9e, 0x1c, 0x55, 0xd1, 0x87, 0x04, 0x35,
+		0xd6, 0x63, 0x76, 0xa5, 0xea, 0x82, 0xef, 0x73, 0xd2, 0xe4, 0xad, 0x00, 0xf2, 0xa7, 0xc2, 0xa1,
+		0x64, 0x95, 0x5b, 0xf2, 0xa0, 0x04, 0x06, 0xa1, 0x8a, 0xfa, 0x33, 0x28, 0xea, 0x0b, 0x75, 0xc4,
+		0xd4, 0x0c, 0x06, 0x87, 0x6f, 0x3f, 0xdc, 0x4f, 0x51, 0x27, 0x21, 0x50, 0x4b, 0x19, 0xcc, 0xb6,
+		0x79, 0xa9, 0x17, 0x7a, 0x78, 0xb7, 0xc4, 0xf4, 0x4a, 0x98, 0x43, 0xac, 0x4c, 0x22, 0x6f, 0x9b,
+		0x31, 0xdf, 0x85, 0xfe, 0xc8, 0x22, 0x8f, 0x66, 0x03, 0x5d, 0x4e, 0x95, 0x5b, 0x42, 0xf4, 0xc7,
+		0x89, 0x01, 0x52, 0xe3, 0x75, 0xd9, 0x19, 0x45, 0xa4, 0x9a, 0x47, 0xbb, 0xcc, 0x72, 0x4b, 0xc7,
+		0xba, 0xde, 0x1e, 0x08, 0x04, 0xb5, 0xaa, 0x1e, 0xaf, 0x25, 0xde, 0x91, 0x19, 0x54, 0xe6, 0x60,
+		0xf7,
+	}
+	ecdaaIssuerY = []byte{
+		0x04, 0x35, 0x7c, 0x37, 0xbb, 0x52, 0xea, 0xd4, 0xc1, 0x90, 0xfb, 0x58, 0x25, 0x29, 0xa2, 0xf9,
+		0x97, 0xf4, 0x21, 0xa3, 0x82, 0x06, 0x57, 0x23, 0xd9, 0xd2, 0xaa, 0x4f, 0x15, 0xef, 0xc1, 0xc3,
+		0x1f, 0x24, 0x28, 0x64, 0x30, 0x9d, 0xe9, 0xd9, 0x76, 0xd1, 0x47, 0xfa, 0xb5, 0x61, 0xd5, 0x37,
+		0x16, 0xee, 0x62, 0x7f, 0xdc, 0x58, 0x85, 0xb8, 0xcb, 0x83, 0xe5, 0xdc, 0xd5, 0x6e, 0x1a, 0xd2,
+		0x1c, 0xbf, 0xff, 0xad, 0x0b, 0x79, 0x4b, 0xbd, 0x51, 0x00, 0x76, 0xf8, 0xde, 0xef, 0xd6, 0x69,
+		0xaa, 0xab, 0xef, 0x83, 0xac, 0x4f, 0xad, 0x32, 0x7a, 0xe6, 0x71, 0xb2, 0xda, 0x06, 0xb3, 0xc3,
+		0x3d, 0x02, 0x40, 0x84, 0x75, 0xe9, 0xfd, 0x57, 0x77, 0xfc, 0xc7, 0x55, 0xe7, 0xef, 0x75, 0x1c,
+		0x1a, 0x3e, 0x49, 0x1f, 0x25, 0x42, 0x56, 0xc0, 0x51, 0xb5, 0xee, 0x7e, 0xcd, 0x34, 0x69, 0x2b,
+		0x15,
+	}
+	ecdaaIssuerC = []byte{
+		0xdd, 0x4f, 0xce, 0x53, 0xb8, 0x19, 0xb0, 0xa7, 0x15, 0x24, 0x68, 0x1f, 0x0f, 0xfa, 0x85, 0x99,
+		0x05, 0x64, 0x50, 0xe4, 0x86, 0xc2, 0xb4, 0x65, 0xda, 0x02, 0x04, 0xac, 0x87, 0x87, 0xed, 0xcb,
+	}
+	ecdaaIssuerSx = []byte{
+		0xfe, 0x7c, 0xee, 0x7a, 0xf4, 0x71, 0x7b, 0x5a, 0xb2, 0x66, 0xec, 0x9f, 0xdb, 0x3f, 0xa1, 0x1a,
+		0x8e, 0x28, 0x43, 0x72, 0xc7, 0x55, 0xbc, 0xf4, 0xb9, 0x4f, 0x4f, 0x19, 0x4f, 0x6e, 0xbd, 0x7e,
+	}
+	ecdaaIssuerSy = []byte{
+		0x50, 0xab, 0xe9, 0xc7, 0x55, 0xa8, 0x57, 0x19, 0xab, 0xad, 0xf7, 0x27, 0x6e, 0x16, 0xa9, 0xa1,
+		0x2f, 0x4a, 0x5a, 0x0d, 0xdd, 0x00, 0xa1, 0x4d, 0x75, 0x49, 0x1e, 0xa2, 0x21, 0x0f, 0x04, 0x4e,
+	}
+	attestation1ECDAASig = []byte{
+		0x5d, 0xfc, 0x58, 0xe4, 0x73, 0xad, 0x02, 0x10, 0xea, 0x54, 0x82, 0x01, 0x5f, 0x2d, 0x97, 0xed,
+		0x6d, 0x14, 0x2a, 0x60, 0x5f, 0x19, 0xb2, 0xe7, 0x11, 0x94, 0xb1, 0xae, 0x05, 0xf5, 0x4c, 0x4d,
+		0x41, 0x1f, 0xff, 0xf7, 0xec, 0x11, 0xa5, 0x7c, 0xcf, 0x1e, 0xfe, 0xf5, 0x7a, 0x42, 0x20, 0x54,
+		0xc0, 0xbc, 0x39, 0xe2, 0x8f, 0x95, 0xd1, 0x60, 0x66, 0xe9, 0xb0, 0xf3, 0x3a, 0xc6, 0x95, 0xd4,
+		0x04, 0x6d, 0x54, 0xee, 0xbe, 0xee, 0x8f, 0x1e, 0xa8, 0xda, 0x63, 0x2e, 0x42, 0xed, 0x48, 0x2f,
+		0xfa, 0x8f, 0x0a, 0xee, 0x10, 0xd2, 0x10, 0xb6, 0x8a, 0x05, 0xdb, 0x57, 0xf3, 0xf6, 0xe7, 0x43,
+		0xca, 0xb5, 0xfb, 0xc5, 0x19, 0x98, 0x1e, 0xff, 0x27, 0x59, 0x20, 0x46, 0x06, 0xad, 0xc0, 0x15,
+		0x89, 0xb5, 0x28, 0x2c, 0x0a, 0x67, 0xfc, 0x1b, 0x94, 0x71, 0x46, 0xdc, 0x2c, 0x61, 0xf4, 0x8d,
+		0x03, 0x04, 0x4a, 0x8f, 0xdd, 0x8c, 0xed, 0xc0, 0xe2, 0x41, 0x1d, 0x8d, 0xa9, 0x54, 0x2f, 0xa2,
+		0x3e, 0x01, 0x89, 0x5a, 0x0f, 0xd6, 0xf8, 0x81, 0xce, 0xac, 0xea, 0xcb, 0x88, 0xc2, 0x09, 0xb3,
+		0x3d, 0xc7, 0x2f, 0x47, 0x01, 0xbe, 0x6e, 0xb6, 0xee, 0x2b, 0x93, 0x04, 0xce, 0xef, 0xdf, 0x17,
+		0xe9, 0xa7, 0x3f, 0x15, 0x32, 0x4c, 0x64, 0xf4, 0xe4, 0xf2, 0x23, 0x29, 0xe5, 0x56, 0xfe, 0x2f,
+		0x14, 0x66, 0x04, 0xad, 0xe2, 0x09, 0x24, 0x90, 0x46, 0xe1, 0x2e, 0xc0, 0x09, 0x72, 0x2a, 0x82,
+		0xbe, 0x6b, 0xd7, 0x3a, 0x89, 0x76, 0x85, 0x3b, 0xe9, 0x0c, 0x9b, 0x20, 0x45, 0x2d, 0xe6, 0x1d,
+		0x43, 0xea, 0xf2, 0x56, 0xff, 0xa0, 0x5a, 0x08, 0xcc, 0xf5, 0x4a, 0x7b, 0xd5, 0x15, 0xfd, 0xcb,
+		0x5e, 0x69, 0xb5, 0x1e, 0x34, 0x71, 0xd5, 0x81, 0x11, 0xf3, 0x2a, 0x14, 0x42, 0x05, 0x78, 0x2e,
+		0x4f, 0x31, 0x5a, 0x04, 0x1d, 0xa2, 0x5c, 0x00, 0x35, 0x34, 0x37, 0xa4, 0x87, 0x16, 0xc8, 0xff,
+		0xec, 0x87, 0x5e, 0x56, 0x48, 0x8e, 0x0c, 0x96, 0x66, 0x88, 0xb1, 0xd0, 0xa4, 0x56, 0x9a, 0x82,
+		0x2c, 0xdf, 0x67, 0xff, 0x5b, 0xfa, 0x61, 0x51, 0x0a, 0xb2, 0x16, 0x42, 0xaa, 0x1c, 0x98, 0xf5,
+		0x21, 0xbc, 0x1b, 0x01, 0x8c, 0x30, 0xbb, 0x1b, 0x4f, 0xfc, 0x1f, 0x5e, 0xbd, 0x07, 0x96, 0x42,
+		0x19, 0x82, 0x14, 0xd4, 0xbd, 0xa4, 0xef, 0x69, 0x7e, 0x43, 0x89, 0xeb, 0xdd, 0x87, 0x14, 0x73,
+		0x8c, 0x1a, 0x9b, 0x0b, 0xd7, 0x2b, 0xb0, 0xc0, 0x70, 0x1b, 0x84, 0x23, 0xf7, 0x97, 0x68, 0x23,
+		0xf5, 0x80, 0x4d, 0x21,
+	}
+)
+
+func TestParseTPMAttestationSignature(t *testing.T) {
+	tpmtSig, err := (&tpm2.Signature{SigAlg: tpm2.AlgRSASSA, Hash: tpm2.AlgSHA256, RSA: attestation1Sig}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name         string
+		sig          []byte
+		wantDecoded  bool
+		wantErrorMsg string
+	}{
+		{"raw RSA signature", attestation1Sig, false, ""},
+		{"TPMT_SIGNATURE", tpmtSig, true, ""},
+		{"TPMT_SIGNATURE with trailing data", append(append([]byte{}, tpmtSig...), 0x00), false, "tpm_attestation: failed to unmarshal sig: trailing data"},
+		{"TPMT_SIGNATURE with unsupported sigAlg", append([]byte{0x00, 0x0b}, tpmtSig[2:]...), false, "TPM attestation sig sigAlg TPM_ALG_SHA256"},
+		{"truncated raw RSA signature", attestation1Sig[:len(attestation1Sig)-1], false, "TPM attestation sig sigAlg"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := cbor.Marshal(map[string]interface{}{
+				"ver":      "2.0",
+				"alg":      webauthn.COSEAlgRS256,
+				"x5c":      [][]byte{attestation1CredCert, attestation1CACert0},
+				"sig":      tc.sig,
+				"certInfo": attestation1RawCertInfo,
+				"pubArea":  attestation1RawPubArea,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			attStmt, err := parseAttestation(data)
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("parseAttestation() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("parseAttestation() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAttestation() returns error %q", err)
+			}
+			if decoded := attStmt.(*tpmAttestationStatement).sig != nil; decoded != tc.wantDecoded {
+				t.Errorf("attestation sig decoded as TPMT_SIGNATURE %t, want %t", decoded, tc.wantDecoded)
+			}
+		})
+	}
+}
+
+func TestParseTPMECDAAAttestation(t *testing.T) {
+	testCases := []struct {
+		name         string
+		alg          int
+		wantErrorMsg string
+	}{
+		{"ED256", webauthn.COSEAlgED256, ""},
+		{"RS256", webauthn.COSEAlgRS256, "ECDAA COSE algorithm -257 is not supported"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := cbor.Marshal(map[string]interface{}{
+				"ver":        "2.0",
+				"alg":        tc.alg,
+				"ecdaaKeyId": ecdaaIssuerC,
+				"sig":        attestation1ECDAASig,
+				"certInfo":   attestation1RawCertInfo,
+				"pubArea":    attestation1RawPubArea,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			attStmt, err := parseAttestation(data)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("parseAttestation() returns error %q", err)
+			} else if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("parseAttestation() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("parseAttestation() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			tpmAttStmt := attStmt.(*tpmAttestationStatement)
+			if !bytes.Equal(tpmAttStmt.ecdaaKeyID, ecdaaIssuerC) {
+				t.Errorf("attestation ecdaaKeyID %x, want %x", tpmAttStmt.ecdaaKeyID, ecdaaIssuerC)
+			}
+			if tpmAttStmt.sig != nil {
+				t.Errorf("attestation sig is decoded as TPMT_SIGNATURE, want raw ECDAA signature")
+			}
+		})
+	}
+}
+
+func TestVerifyTPMECDAAAttestation(t *testing.T) {
+	ipk, err := ecdaa.ParseIssuerPublicKey(ecdaaIssuerX, ecdaaIssuerY, ecdaaIssuerC, ecdaaIssuerSx, ecdaaIssuerSy)
+	if err != nil {
+		t.Fatalf("ParseIssuerPublicKey() returns error %q", err)
+	}
+	savedResolver := ecdaa.DefaultResolver
+	defer func() { ecdaa.DefaultResolver = savedResolver }()
+	keys := ecdaa.NewIssuerKeys()
+	ecdaa.DefaultResolver = keys
+
+	// Replace AIK certificate and signature of attestation 1 with ECDAA-Issuer public key ID and ECDAA signature.
+	// alg of attestation 1 is kept because certInfo.extraData is computed with its hash algorithm.
+	newAttestation := func(t *testing.T, sig []byte) *webauthn.PublicKeyCredentialAttestation {
+		var credentialAttestation webauthn.PublicKeyCredentialAttestation
+		if err := json.Unmarshal([]byte(attestation1), &credentialAttestation); err != nil {
+			t.Fatalf("failed to unmarshal attestation %s: %q", attestation1, err)
+		}
+		attStmt := credentialAttestation.AttStmt.(*tpmAttestationStatement)
+		attStmt.aikCert, attStmt.caCerts, attStmt.sig = nil, nil, nil
+		attStmt.ecdaaKeyID = ipk.KeyID()
+		attStmt.rawSig = sig
+		return &credentialAttestation
+	}
+
+	wantErrorMsg := "ecdaaKeyId: ecdaa: ECDAA-Issuer public key " + hex.EncodeToString(ecdaaIssuerC) + " is not trusted"
+	if _, _, err := newAttestation(t, attestation1ECDAASig).VerifyAttestationStatement(); err == nil || !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("VerifyAttestationStatement() returns error %v, want error containing substring %q", err, wantErrorMsg)
+	}
+
+	keys.Add(ipk)
+
+	badSig := append([]byte(nil), attestation1ECDAASig...)
+	badSig[len(badSig)-1] ^= 0x01
+	wantErrorMsg = "signature: ecdaa: signature does not match message"
+	if _, _, err := newAttestation(t, badSig).VerifyAttestationStatement(); err == nil || !strings.Contains(err.Error(), wantErrorMsg) {
+		t.Errorf("VerifyAttestationStatement() returns error %v, want error containing substring %q", err, wantErrorMsg)
+	}
+
+	credentialAttestation := newAttestation(t, attestation1ECDAASig)
+	attType, trustPath, err := credentialAttestation.VerifyAttestationStatement()
+	if err != nil {
+		t.Fatalf("VerifyAttestationStatement() returns error %q", err)
+	}
+	if attType != webauthn.AttestationTypeECDAA {
+		t.Errorf("attestation type %v, want %v", attType, webauthn.AttestationTypeECDAA)
+	}
+	if trustPath != ipk {
+		t.Errorf("trust path %v, want ECDAA-Issuer public key", trustPath)
+	}
+	details := credentialAttestation.AttStmt.(webauthn.AttestationDetailer).AttestationDetails().(*AttestationDetails)
+	if details.Manufacturer != "" || details.FirmwareVersion != 0xa9e0c4a53fbbc413 {
+		t.Errorf("attestation details %+v, want firmware version 0xa9e0c4a53fbbc413 without manufacturer", details)
+	}
+}
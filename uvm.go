@@ -0,0 +1,198 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const extensionUVM = "uvm"
+
+// UserVerificationMethod identifies how the user was verified, as defined in
+// https://fidoalliance.org/specs/common-specs/fido-registry-v2.1-ps-20191217.html#user-verification-methods
+type UserVerificationMethod uint32
+
+// User verification methods are bit flags defined in FIDO Registry of Predefined Values.
+const (
+	UVMPresenceInternal    UserVerificationMethod = 0x00000001
+	UVMFingerprintInternal UserVerificationMethod = 0x00000002
+	UVMPasscodeInternal    UserVerificationMethod = 0x00000004
+	UVMVoiceprintInternal  UserVerificationMethod = 0x00000008
+	UVMFaceprintInternal   UserVerificationMethod = 0x00000010
+	UVMLocationInternal    UserVerificationMethod = 0x00000020
+	UVMEyeprintInternal    UserVerificationMethod = 0x00000040
+	UVMPatternInternal     UserVerificationMethod = 0x00000080
+	UVMHandprintInternal   UserVerificationMethod = 0x00000100
+	UVMNone                UserVerificationMethod = 0x00000200
+	UVMAll                 UserVerificationMethod = 0x00000400
+	UVMPasscodeExternal    UserVerificationMethod = 0x00000800
+	UVMPatternExternal     UserVerificationMethod = 0x00001000
+)
+
+var userVerificationMethodNames = []flagName{
+	{uint32(UVMPresenceInternal), "presence_internal"},
+	{uint32(UVMFingerprintInternal), "fingerprint_internal"},
+	{uint32(UVMPasscodeInternal), "passcode_internal"},
+	{uint32(UVMVoiceprintInternal), "voiceprint_internal"},
+	{uint32(UVMFaceprintInternal), "faceprint_internal"},
+	{uint32(UVMLocationInternal), "location_internal"},
+	{uint32(UVMEyeprintInternal), "eyeprint_internal"},
+	{uint32(UVMPatternInternal), "pattern_internal"},
+	{uint32(UVMHandprintInternal), "handprint_internal"},
+	{uint32(UVMNone), "none"},
+	{uint32(UVMAll), "all"},
+	{uint32(UVMPasscodeExternal), "passcode_external"},
+	{uint32(UVMPatternExternal), "pattern_external"},
+}
+
+func (m UserVerificationMethod) String() string {
+	return flagsString(uint32(m), userVerificationMethodNames)
+}
+
+// KeyProtectionType identifies how the authenticator protects the credential private key, as defined in
+// https://fidoalliance.org/specs/common-specs/fido-registry-v2.1-ps-20191217.html#key-protection-types
+type KeyProtectionType uint16
+
+// Key protection types are bit flags defined in FIDO Registry of Predefined Values.
+const (
+	KeyProtectionSoftware      KeyProtectionType = 0x0001
+	KeyProtectionHardware      KeyProtectionType = 0x0002
+	KeyProtectionTEE           KeyProtectionType = 0x0004
+	KeyProtectionSecureElement KeyProtectionType = 0x0008
+	KeyProtectionRemoteHandle  KeyProtectionType = 0x0010
+)
+
+var keyProtectionTypeNames = []flagName{
+	{uint32(KeyProtectionSoftware), "software"},
+	{uint32(KeyProtectionHardware), "hardware"},
+	{uint32(KeyProtectionTEE), "tee"},
+	{uint32(KeyProtectionSecureElement), "secure_element"},
+	{uint32(KeyProtectionRemoteHandle), "remote_handle"},
+}
+
+func (t KeyProtectionType) String() string {
+	return flagsString(uint32(t), keyProtectionTypeNames)
+}
+
+// MatcherProtectionType identifies how the authenticator protects the matcher that performs user
+// verification, as defined in
+// https://fidoalliance.org/specs/common-specs/fido-registry-v2.1-ps-20191217.html#matcher-protection-types
+type MatcherProtectionType uint16
+
+// Matcher protection types are bit flags defined in FIDO Registry of Predefined Values.
+const (
+	MatcherProtectionSoftware MatcherProtectionType = 0x0001
+	MatcherProtectionTEE      MatcherProtectionType = 0x0002
+	MatcherProtectionOnChip   MatcherProtectionType = 0x0004
+)
+
+var matcherProtectionTypeNames = []flagName{
+	{uint32(MatcherProtectionSoftware), "software"},
+	{uint32(MatcherProtectionTEE), "tee"},
+	{uint32(MatcherProtectionOnChip), "on_chip"},
+}
+
+func (t MatcherProtectionType) String() string {
+	return flagsString(uint32(t), matcherProtectionTypeNames)
+}
+
+// UVMEntry represents one user verification method used by the authenticator, reported by
+// the uvm extension defined in https://www.w3.org/TR/webauthn-1/#sctn-uvm-extension
+type UVMEntry struct {
+	UserVerificationMethod UserVerificationMethod
+	KeyProtectionType      KeyProtectionType
+	MatcherProtectionType  MatcherProtectionType
+}
+
+// uvmMaxEntries is the maximum number of entries in uvm authenticator extension output.
+const uvmMaxEntries = 3
+
+// UVM returns user verification methods used by the authenticator, reported by the uvm
+// authenticator extension output in authenticator data.  The most preferred method is first.
+func (authnData *AuthenticatorData) UVM() (uvm []UVMEntry, ok bool) {
+	uvm, err := authnData.uvm()
+	return uvm, err == nil && uvm != nil
+}
+
+// verifyUVM verifies uvm authenticator extension output and returns user verification methods,
+// or nil if the extension output is absent.
+func verifyUVM(typ string, authnData *AuthenticatorData) ([]UVMEntry, error) {
+	uvm, err := authnData.uvm()
+	if err != nil {
+		return nil, &VerificationError{Type: typ, Field: "uvm extension", Msg: err.Error()}
+	}
+	return uvm, nil
+}
+
+// uvm parses and validates uvm authenticator extension output, and returns user verification methods,
+// or nil if the extension output is absent.  UVM and verifyUVM share it, so that they apply the same rules.
+func (authnData *AuthenticatorData) uvm() ([]UVMEntry, error) {
+	rawValue, ok := authnData.rawExtensions[extensionUVM]
+	if !ok {
+		return nil, nil
+	}
+	var rawEntries [][]uint32
+	if err := cbor.Unmarshal(rawValue, &rawEntries); err != nil {
+		return nil, &UnmarshalSyntaxError{Type: "authenticator data", Field: "extension " + extensionUVM, Msg: err.Error()}
+	}
+	if len(rawEntries) == 0 || len(rawEntries) > uvmMaxEntries {
+		return nil, &UnmarshalBadDataError{Type: "authenticator data", Msg: "uvm extension must have 1 to " + strconv.Itoa(uvmMaxEntries) + " entries, got " + strconv.Itoa(len(rawEntries))}
+	}
+	uvm := make([]UVMEntry, len(rawEntries))
+	for i, e := range rawEntries {
+		if len(e) != 3 {
+			return nil, &UnmarshalBadDataError{Type: "authenticator data", Msg: "uvm extension entry must have 3 elements, got " + strconv.Itoa(len(e))}
+		}
+		if e[1] > 0xffff || e[2] > 0xffff {
+			return nil, &UnmarshalBadDataError{Type: "authenticator data", Msg: "uvm extension entry protection type overflows uint16"}
+		}
+		uvm[i] = UVMEntry{
+			UserVerificationMethod: UserVerificationMethod(e[0]),
+			KeyProtectionType:      KeyProtectionType(e[1]),
+			MatcherProtectionType:  MatcherProtectionType(e[2]),
+		}
+	}
+	return uvm, nil
+}
+
+type flagName struct {
+	flag uint32
+	name string
+}
+
+// flagsString returns names of flags set in v joined by "|", and hex value of unnamed flags.
+func flagsString(v uint32, names []flagName) string {
+	if v == 0 {
+		return "Undefined"
+	}
+	var s []string
+	for _, n := range names {
+		if v&n.flag != 0 {
+			s = append(s, n.name)
+			v &^= n.flag
+		}
+	}
+	if v != 0 {
+		s = append(s, "0x"+strconv.FormatUint(uint64(v), 16))
+	}
+	return strings.Join(s, "|")
+}
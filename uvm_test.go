@@ -0,0 +1,151 @@
+/*
+Copyright 2019-present Faye Amacker.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Modified by Kappa
+*/
+
+package webauthn_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kappapay/webauthn"
+)
+
+func TestUVMString(t *testing.T) {
+	testCases := []struct {
+		name string
+		v    interface{ String() string }
+		want string
+	}{
+		{"fingerprint", webauthn.UVMFingerprintInternal, "fingerprint_internal"},
+		{"passcode", webauthn.UVMPasscodeInternal, "passcode_internal"},
+		{"face", webauthn.UVMFaceprintInternal, "faceprint_internal"},
+		{"pattern", webauthn.UVMPatternInternal, "pattern_internal"},
+		{"presence and fingerprint", webauthn.UVMPresenceInternal | webauthn.UVMFingerprintInternal, "presence_internal|fingerprint_internal"},
+		{"unknown method", webauthn.UserVerificationMethod(0x10000), "0x10000"},
+		{"undefined method", webauthn.UserVerificationMethod(0), "Undefined"},
+		{"tee key protection", webauthn.KeyProtectionTEE, "tee"},
+		{"hardware and secure element key protection", webauthn.KeyProtectionHardware | webauthn.KeyProtectionSecureElement, "hardware|secure_element"},
+		{"on chip matcher protection", webauthn.MatcherProtectionOnChip, "on_chip"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if s := tc.v.String(); s != tc.want {
+				t.Errorf("String() returns %q, want %q", s, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAuthenticationUVM(t *testing.T) {
+	authenticator := newTestAuthenticator()
+	challenge := "eaTyUNnyPDDdK8SNEgTEUvz1Q8dylkjjTimYd5X7QAo"
+	clientData := []byte(`{"type":"webauthn.get","challenge":"` + challenge + `","origin":"https://acme.com"}`)
+
+	testCases := []struct {
+		name         string
+		extensions   []byte
+		wantUVM      []webauthn.UVMEntry
+		wantErrorMsg string
+	}{
+		{
+			name:       "no uvm",
+			extensions: nil,
+		},
+		{
+			name:       "fingerprint",
+			extensions: cborMarshal(map[string]interface{}{"uvm": [][]uint32{{2, 2, 4}}}),
+			wantUVM: []webauthn.UVMEntry{
+				{webauthn.UVMFingerprintInternal, webauthn.KeyProtectionHardware, webauthn.MatcherProtectionOnChip},
+			},
+		},
+		{
+			name:       "passcode and presence",
+			extensions: cborMarshal(map[string]interface{}{"uvm": [][]uint32{{4, 1, 1}, {1, 4, 2}}}),
+			wantUVM: []webauthn.UVMEntry{
+				{webauthn.UVMPasscodeInternal, webauthn.KeyProtectionSoftware, webauthn.MatcherProtectionSoftware},
+				{webauthn.UVMPresenceInternal, webauthn.KeyProtectionTEE, webauthn.MatcherProtectionTEE},
+			},
+		},
+		{
+			name:         "no entries",
+			extensions:   cborMarshal(map[string]interface{}{"uvm": [][]uint32{}}),
+			wantErrorMsg: "assertion: failed to verify uvm extension: webauthn/authenticator_data: uvm extension must have 1 to 3 entries, got 0",
+		},
+		{
+			name:         "too many entries",
+			extensions:   cborMarshal(map[string]interface{}{"uvm": [][]uint32{{2, 2, 4}, {2, 2, 4}, {2, 2, 4}, {2, 2, 4}}}),
+			wantErrorMsg: "assertion: failed to verify uvm extension: webauthn/authenticator_data: uvm extension must have 1 to 3 entries, got 4",
+		},
+		{
+			name:         "entry with 2 elements",
+			extensions:   cborMarshal(map[string]interface{}{"uvm": [][]uint32{{2, 2}}}),
+			wantErrorMsg: "assertion: failed to verify uvm extension: webauthn/authenticator_data: uvm extension entry must have 3 elements, got 2",
+		},
+		{
+			name:         "protection type overflows",
+			extensions:   cborMarshal(map[string]interface{}{"uvm": [][]uint32{{2, 0x10000, 4}}}),
+			wantErrorMsg: "assertion: failed to verify uvm extension: webauthn/authenticator_data: uvm extension entry protection type overflows uint16",
+		},
+		{
+			name:         "not an array",
+			extensions:   cborMarshal(map[string]interface{}{"uvm": "fingerprint"}),
+			wantErrorMsg: "assertion: failed to verify uvm extension",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authnData := authenticator.authenticatorData("acme.com", 0x01, 1, tc.extensions)
+			credentialAssertion, err := webauthn.ParseAssertion(bytes.NewReader(authenticator.assertion(authnData, clientData, nil)))
+			if err != nil {
+				t.Fatalf("ParseAssertion() returns error %q", err)
+			}
+			expected := &webauthn.AssertionExpectedData{
+				Origin:           "https://acme.com",
+				RPID:             "acme.com",
+				Challenge:        challenge,
+				UserVerification: webauthn.UserVerificationPreferred,
+				Credential:       authenticator.credential(),
+			}
+			result, err := webauthn.VerifyAuthentication(credentialAssertion, expected)
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyAuthentication() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyAuthentication() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				// UVM() rejects extension outputs that fail verification.
+				if uvm, ok := credentialAssertion.AuthnData.UVM(); ok || uvm != nil {
+					t.Errorf("UVM() returns (%v, %t), want (nil, false)", uvm, ok)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyAuthentication() returns error %q", err)
+			}
+			if !reflect.DeepEqual(result.UVM, tc.wantUVM) {
+				t.Errorf("uvm %v, want %v", result.UVM, tc.wantUVM)
+			}
+			uvm, ok := credentialAssertion.AuthnData.UVM()
+			if ok != (tc.wantUVM != nil) || !reflect.DeepEqual(uvm, tc.wantUVM) {
+				t.Errorf("UVM() returns (%v, %t), want (%v, %t)", uvm, ok, tc.wantUVM, tc.wantUVM != nil)
+			}
+		})
+	}
+}
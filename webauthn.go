@@ -21,8 +21,8 @@ Package webauthn provides server-side registration and authentication for client
 using FIDO2 keys, FIDO U2F keys, tpm, etc. and is decoupled from `net/http` for
 easy integration with existing projects.
 
-It's modular so projects only import what is needed. Five attestation packages are
-available: fidou2f, androidkeystore, androidsafetynet, packed, and tpm.
+It's modular so projects only import what is needed. Seven attestation packages are
+available: fidou2f, androidkeystore, androidsafetynet, apple, appattest, packed, and tpm.
 
 It doesn't import unreliable packages. It uses fxamacker/cbor because it doesn't
 crash and it's the most well-tested CBOR library available (v1.5 has 375+ tests
@@ -37,6 +37,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -51,27 +52,76 @@ type User struct {
 	Icon          string
 	DisplayName   string
 	CredentialIDs [][]byte
+	CredBlob      []byte // Opaque data to store with the new credential on the authenticator, using the credBlob extension (optional).
 }
 
 // AttestationExpectedData represents data needed to verify attestations.
 type AttestationExpectedData struct {
-	Origin           string
-	RPID             string
-	CredentialAlgs   []int
-	Challenge        string
-	UserVerification UserVerificationRequirement
+	Origin             string
+	RPID               string
+	CredentialAlgs     []int
+	Challenge          string
+	UserVerification   UserVerificationRequirement
+	MinPinLength       int                             // Minimum PIN length required by policy, reported by the minPinLength extension (optional).
+	Mediation          CredentialMediationRequirement  // Mediation of the registration ceremony, "conditional" for conditional create (optional).
+	UserID             []byte                          // User handle of the new credential, copied to the credential record (optional).
+	TokenBinding       *TokenBinding                   // Token Binding state of the TLS connection (optional).
+	TokenBindingPolicy TokenBindingPolicy              // Verification of client data token binding without TokenBinding (optional).
+	Attestation        AttestationConveyancePreference // Attestation conveyance preference of the attestation options (optional).
+	AttestationFormats []string                        // Attestation statement formats of the attestation options, which also apply to nested attestation statements (optional).
+	AttestationPolicy  AttestationPolicy               // Handling of attestation statements not allowed by Attestation and AttestationFormats (optional).
+	Revocation         *RevocationChecker              // Revocation checking of attestation certificates in trust path and nested trust paths (optional).
 }
 
 // AssertionExpectedData represents data needed to verify assertions.
 type AssertionExpectedData struct {
-	Origin            string
-	RPID              string
-	Challenge         string
-	UserVerification  UserVerificationRequirement
-	UserID            []byte
-	UserCredentialIDs [][]byte
-	PrevCounter       uint32
-	Credential        *Credential
+	Origin             string
+	RPID               string
+	Challenge          string
+	UserVerification   UserVerificationRequirement
+	UserID             []byte
+	UserCredentialIDs  [][]byte
+	PrevCounter        uint32
+	Credential         *Credential
+	AppID              string               // FIDO AppID sent in the appid extension, if any.
+	DevicePublicKeys   []*DevicePublicKey   // Device public keys previously seen with the credential, if any.
+	Payment            *PaymentExpectedData // Transaction data of Secure Payment Confirmation, if any.
+	TokenBinding       *TokenBinding        // Token Binding state of the TLS connection (optional).
+	TokenBindingPolicy TokenBindingPolicy   // Verification of client data token binding without TokenBinding (optional).
+}
+
+// CredentialRecord represents a registered credential stored by the Relying Party,
+// as defined in https://w3c.github.io/webauthn/#credential-record
+type CredentialRecord struct {
+	ID               []byte             // Credential ID.
+	UserID           []byte             // User handle of the credential owner.
+	Credential       *Credential        // Algorithm and public key of the credential.
+	SignCount        uint32             // Latest signature counter returned by the authenticator.
+	DevicePublicKeys []*DevicePublicKey // Device public keys seen with the credential, if any.
+	AutoCreated      bool               // Credential was created automatically with conditional create.
+}
+
+// RegistrationResult represents the result of a verified attestation.
+type RegistrationResult struct {
+	AttestationFormat   string              // Format of the verified attestation statement, "none" if it was replaced.
+	AttestationReplaced bool                // Unwanted attestation statement was replaced by a "none" attestation statement.
+	AttestationType     AttestationType     // Attestation type of the credential.
+	TrustPath           interface{}         // Attestation trust path of the credential.
+	Details             interface{}         // Format specific attestation details, if the attestation statement is an AttestationDetailer.
+	UVM                 []UVMEntry          // User verification methods returned by the uvm extension, if any.
+	DevicePublicKey     *DevicePublicKey    // Device public key returned by the devicePubKey extension, if any.
+	AutoCreated         bool                // Credential was created automatically with conditional create.
+	Revocation          []*RevocationResult // Revocation status of attestation certificates in trust path, if checked.
+
+	CredentialRecord *CredentialRecord // Credential record of the new credential.
+}
+
+// AuthenticationResult represents the result of a verified assertion.
+type AuthenticationResult struct {
+	UVM                []UVMEntry        // User verification methods returned by the uvm extension, if any.
+	DevicePublicKey    *DevicePublicKey  // Device public key returned by the devicePubKey extension, if any.
+	NewDevicePublicKey bool              // DevicePublicKey isn't among expected device public keys.
+	CredentialRecord   *CredentialRecord // Credential record found by conditional mediation verification.
 }
 
 // NewAttestationOptions returns a PublicKeyCredentialCreationOptions from config and user.
@@ -125,7 +175,22 @@ func NewAttestationOptions(config *Config, user *User) (*PublicKeyCredentialCrea
 			ResidentKey:             config.ResidentKey,
 			UserVerification:        config.UserVerification,
 		},
-		Attestation: config.Attestation,
+		Attestation:        config.Attestation,
+		AttestationFormats: config.AttestationFormats,
+	}
+
+	extensions := AuthenticationExtensionsClientInputs{
+		AppIDExclude: config.AppID,            // Exclude credentials registered with the legacy FIDO U2F JavaScript API.
+		MinPinLength: config.MinPinLength > 0, // Request authenticator's minimum PIN length to enforce PIN length policy.
+		CredBlob:     user.CredBlob,
+		UVM:          config.UVM,
+		DevicePubKey: config.DevicePubKey,
+	}
+	if config.Payment {
+		extensions.Payment = &AuthenticationExtensionsPaymentInputs{IsPayment: true}
+	}
+	if !extensions.isEmpty() {
+		options.Extensions = &extensions
 	}
 
 	return options, nil
@@ -143,47 +208,51 @@ func ParseAttestation(r io.Reader) (*PublicKeyCredentialAttestation, error) {
 // VerifyAttestation verifies attestation and returns attestation type, trust path, or error,
 // as defined in http://w3c.github.io/webauthn/#sctn-registering-a-new-credential
 func VerifyAttestation(credentialAttestation *PublicKeyCredentialAttestation, expected *AttestationExpectedData) (attType AttestationType, trustPath interface{}, err error) {
+	result, err := VerifyRegistration(credentialAttestation, expected)
+	if err != nil {
+		return
+	}
+	return result.AttestationType, result.TrustPath, nil
+}
+
+// VerifyRegistration verifies attestation and returns RegistrationResult or error,
+// as defined in http://w3c.github.io/webauthn/#sctn-registering-a-new-credential
+func VerifyRegistration(credentialAttestation *PublicKeyCredentialAttestation, expected *AttestationExpectedData) (*RegistrationResult, error) {
 	// Verify that the value of C.type is webauthn.create.
 	if credentialAttestation.ClientData.Type != "webauthn.create" {
-		err = &VerificationError{Type: "attestation", Field: "client data type", Msg: "expected \"webauthn.create\", got \"" + credentialAttestation.ClientData.Type + "\""}
-		return
+		return nil, &VerificationError{Type: "attestation", Field: "client data type", Msg: "expected \"webauthn.create\", got \"" + credentialAttestation.ClientData.Type + "\""}
 	}
 
 	// Verify that the value of C.challenge equals the base64url encoding of options.challenge.
 	if credentialAttestation.ClientData.Challenge != expected.Challenge {
-		err = &VerificationError{Type: "attestation", Field: "client data challenge", Msg: "client data challenge does not match expected challenge"}
-		return
+		return nil, &VerificationError{Type: "attestation", Field: "client data challenge", Msg: "client data challenge does not match expected challenge"}
 	}
 
 	// Verify that the value of C.origin matches the Relying Party's origin.
 	if credentialAttestation.ClientData.Origin != expected.Origin {
-		err = &VerificationError{Type: "attestation", Field: "client data origin", Msg: "expected \"" + expected.Origin + "\", got \"" + credentialAttestation.ClientData.Origin + "\""}
-		return
+		return nil, &VerificationError{Type: "attestation", Field: "client data origin", Msg: "expected \"" + expected.Origin + "\", got \"" + credentialAttestation.ClientData.Origin + "\""}
 	}
 
 	// Verify that authData's credential id matches the credential's raw id.
 	if !bytes.Equal(credentialAttestation.RawID, credentialAttestation.AuthnData.CredentialID) {
-		err = &VerificationError{Type: "attestation", Field: "credential ID", Msg: "attestation's raw ID does not match credential ID"}
-		return
+		return nil, &VerificationError{Type: "attestation", Field: "credential ID", Msg: "attestation's raw ID does not match credential ID"}
 	}
 
 	// Verify that the rpIdHash in authData is the SHA-256 hash of the RP ID expected by the Relying Party.
 	computedRPIDHash := sha256.Sum256([]byte(expected.RPID))
 	if !bytes.Equal(credentialAttestation.AuthnData.RPIDHash, computedRPIDHash[:]) {
-		err = &VerificationError{Type: "attestation", Field: "rp ID", Msg: "authenticator data's rp ID hash does not match computed rp ID hash"}
-		return
+		return nil, &VerificationError{Type: "attestation", Field: "rp ID", Msg: "authenticator data's rp ID hash does not match computed rp ID hash"}
 	}
 
-	// Verify that the User Present bit of the flags in authData is set.
-	if !credentialAttestation.AuthnData.UserPresent {
-		err = &VerificationError{Type: "attestation", Field: "user present", Msg: "user wasn't present"}
-		return
+	// Unless the registration ceremony was started with conditional mediation, verify that the
+	// User Present bit of the flags in authData is set.
+	if expected.Mediation != MediationConditional && !credentialAttestation.AuthnData.UserPresent {
+		return nil, &VerificationError{Type: "attestation", Field: "user present", Msg: "user wasn't present"}
 	}
 
 	// If user verification is required for this registration, verify that the User Verified bit of the flags in authData is set.
 	if expected.UserVerification == UserVerificationRequired && !credentialAttestation.AuthnData.UserVerified {
-		err = &VerificationError{Type: "attestation", Field: "user verification", Msg: "user didn't verify"}
-		return
+		return nil, &VerificationError{Type: "attestation", Field: "user verification", Msg: "user didn't verify"}
 	}
 
 	// Verify that the "alg" parameter in the credential public key in authData matches the alg
@@ -196,19 +265,107 @@ func VerifyAttestation(credentialAttestation *PublicKeyCredentialAttestation, ex
 		}
 	}
 	if !foundAlg {
-		err = &VerificationError{Type: "attestation", Field: "credential algorithm", Msg: "credential algorithm is not among options.pubKeyCredParams."}
-		return
+		return nil, &VerificationError{Type: "attestation", Field: "credential algorithm", Msg: "credential algorithm is not among options.pubKeyCredParams."}
+	}
+
+	// If a minimum PIN length is required, verify that the minPinLength authenticator extension output
+	// reports a minimum PIN length that satisfies it.
+	if expected.MinPinLength > 0 {
+		minPinLength, ok := credentialAttestation.AuthnData.MinPinLength()
+		if !ok {
+			return nil, &VerificationError{Type: "attestation", Field: "minPinLength extension", Msg: "authenticator didn't report minimum PIN length"}
+		}
+		if minPinLength < expected.MinPinLength {
+			return nil, &VerificationError{Type: "attestation", Field: "minPinLength extension", Msg: "minimum PIN length " + strconv.Itoa(minPinLength) + " is less than required " + strconv.Itoa(expected.MinPinLength)}
+		}
+	}
+
+	// Verify that the value of C.tokenBinding.status matches the state of Token Binding for the TLS
+	// connection over which the attestation was obtained. If Token Binding was used on that TLS
+	// connection, also verify that C.tokenBinding.id matches the base64url encoding of the Token
+	// Binding ID for the connection.
+	if err := verifyTokenBinding("attestation", credentialAttestation.ClientData.TokenBinding, expected.TokenBinding, expected.TokenBindingPolicy); err != nil {
+		return nil, err
+	}
+
+	result := &RegistrationResult{AutoCreated: expected.Mediation == MediationConditional}
+
+	// Verify user verification methods returned by the uvm extension, if any.
+	var err error
+	if result.UVM, err = verifyUVM("attestation", credentialAttestation.AuthnData); err != nil {
+		return nil, err
+	}
+
+	// Verify the device public key returned by the devicePubKey extension, if any.
+	if result.DevicePublicKey, err = verifyDevicePublicKey("attestation", credentialAttestation.AuthnData, credentialAttestation.ClientData, credentialAttestation.ClientExtensionResults.DevicePubKey); err != nil {
+		return nil, err
 	}
 
-	// todo: Verify that the value of C.tokenBinding.status matches the state of Token Binding for
-	// the TLS connection over which the assertion was obtained. If Token Binding was used on that
-	// TLS connection, also verify that C.tokenBinding.id matches the base64url encoding of the
-	// Token Binding ID for the connection.
+	// Verify that the attestation statement format is allowed by the attestation conveyance preference and
+	// attestation formats of the attestation options.  An unwanted attestation statement can be replaced by
+	// a "none" attestation statement, so that no identifying information of the authenticator is kept.
+	asNone, err := verifyAttestationFormat(credentialAttestation.Fmt, expected.Attestation, expected.AttestationFormats, expected.AttestationPolicy)
+	if err != nil {
+		return nil, err
+	}
+	// Nested attestation statements, such as those of a compound attestation statement, must be allowed as well.
+	if nested, ok := credentialAttestation.AttStmt.(NestedAttestationFormatter); ok && !asNone {
+		for _, format := range nested.NestedAttestationFormats() {
+			if asNone, err = verifyAttestationFormat(format, expected.Attestation, expected.AttestationFormats, expected.AttestationPolicy); err != nil {
+				return nil, err
+			}
+			if asNone {
+				break
+			}
+		}
+	}
+	// The replacement is kept local, so the caller's attestation statement isn't modified.
+	result.AttestationFormat = credentialAttestation.Fmt
+	attStmt := credentialAttestation.AttStmt
+	if asNone {
+		result.AttestationFormat = "none"
+		result.AttestationReplaced = true
+		attStmt = &noneAttestationStatement{}
+	}
 
-	// todo: Verify that the values of the client extension outputs in clientExtensionResults and
-	// the authenticator extension outputs in the extensions in authData are as expected.
+	clientDataHash := sha256.Sum256(credentialAttestation.ClientData.Raw)
+	if result.AttestationType, result.TrustPath, err = attStmt.Verify(clientDataHash[:], credentialAttestation.AuthnData); err != nil {
+		return nil, err
+	}
 
-	return credentialAttestation.VerifyAttestationStatement()
+	// Verify that no certificate in the attestation trust path, or in trust paths of nested attestation
+	// statements, is revoked, e.g. because its attestation key leaked.
+	if expected.Revocation != nil {
+		var chains [][]*x509.Certificate
+		if trustPath, ok := result.TrustPath.([]*x509.Certificate); ok {
+			chains = append(chains, trustPath)
+		}
+		if chainer, ok := attStmt.(CertificateChainer); ok {
+			chains = append(chains, chainer.CertificateChains()...)
+		}
+		for _, chain := range chains {
+			revocation, err := expected.Revocation.Check(chain)
+			if err != nil {
+				return nil, &VerificationError{Type: "attestation", Field: "certificate revocation status", Msg: err.Error()}
+			}
+			result.Revocation = append(result.Revocation, revocation...)
+		}
+	}
+	if detailer, ok := attStmt.(AttestationDetailer); ok {
+		result.Details = detailer.AttestationDetails()
+	}
+
+	result.CredentialRecord = &CredentialRecord{
+		ID:          credentialAttestation.AuthnData.CredentialID,
+		UserID:      expected.UserID,
+		Credential:  credentialAttestation.AuthnData.Credential,
+		SignCount:   credentialAttestation.AuthnData.Counter,
+		AutoCreated: result.AutoCreated,
+	}
+	if result.DevicePublicKey != nil {
+		result.CredentialRecord.DevicePublicKeys = []*DevicePublicKey{result.DevicePublicKey}
+	}
+	return result, nil
 }
 
 // NewAssertionOptions returns a PublicKeyCredentialRequestOptions from config and user.
@@ -233,6 +390,16 @@ func NewAssertionOptions(config *Config, user *User) (*PublicKeyCredentialReques
 		UserVerification: config.UserVerification,
 	}
 
+	extensions := AuthenticationExtensionsClientInputs{
+		AppID:        config.AppID, // Allow credentials registered with the legacy FIDO U2F JavaScript API.
+		GetCredBlob:  config.GetCredBlob,
+		UVM:          config.UVM,
+		DevicePubKey: config.DevicePubKey,
+	}
+	if !extensions.isEmpty() {
+		options.Extensions = &extensions
+	}
+
 	return options, nil
 }
 
@@ -247,6 +414,13 @@ func ParseAssertion(r io.Reader) (*PublicKeyCredentialAssertion, error) {
 
 // VerifyAssertion verifies assertion and returns error, as defined in http://w3c.github.io/webauthn/#sctn-verifying-assertion
 func VerifyAssertion(credentialAssertion *PublicKeyCredentialAssertion, expected *AssertionExpectedData) error {
+	_, err := VerifyAuthentication(credentialAssertion, expected)
+	return err
+}
+
+// VerifyAuthentication verifies assertion and returns AuthenticationResult or error,
+// as defined in http://w3c.github.io/webauthn/#sctn-verifying-assertion
+func VerifyAuthentication(credentialAssertion *PublicKeyCredentialAssertion, expected *AssertionExpectedData) (*AuthenticationResult, error) {
 	// Verify that credential.id identifies one of the public key credentials listed in options.allowCredentials.
 	foundCredentialID := false
 	for _, id := range expected.UserCredentialIDs {
@@ -256,66 +430,108 @@ func VerifyAssertion(credentialAssertion *PublicKeyCredentialAssertion, expected
 		}
 	}
 	if len(expected.UserCredentialIDs) > 0 && !foundCredentialID {
-		return &VerificationError{Type: "assertion", Field: "credential ID", Msg: "credential ID is not allowed"}
+		return nil, &VerificationError{Type: "assertion", Field: "credential ID", Msg: "credential ID is not allowed"}
 	}
 
 	// Verify that userHandle also is the owner of the public key credential.
 	if len(credentialAssertion.UserHandle) > 0 {
 		if !bytes.Equal(credentialAssertion.UserHandle, expected.UserID) {
-			return &VerificationError{Type: "assertion", Field: "user handle", Msg: fmt.Sprintf("expected %02x, got %02x", expected.UserID, credentialAssertion.UserHandle)}
+			return nil, &VerificationError{Type: "assertion", Field: "user handle", Msg: fmt.Sprintf("expected %02x, got %02x", expected.UserID, credentialAssertion.UserHandle)}
 		}
 	}
 
-	// Verify that the value of C.type is the string webauthn.get.
-	if credentialAssertion.ClientData.Type != "webauthn.get" {
-		return &VerificationError{Type: "assertion", Field: "client data type", Msg: "expected \"webauthn.get\", got \"" + credentialAssertion.ClientData.Type + "\""}
+	// Verify that the value of C.type is the string webauthn.get, or payment.get for Secure Payment Confirmation.
+	clientDataType := "webauthn.get"
+	if expected.Payment != nil {
+		clientDataType = "payment.get"
+	}
+	if credentialAssertion.ClientData.Type != clientDataType {
+		return nil, &VerificationError{Type: "assertion", Field: "client data type", Msg: "expected \"" + clientDataType + "\", got \"" + credentialAssertion.ClientData.Type + "\""}
 	}
 
 	// Verify that the value of C.challenge equals the base64url encoding of options.challenge.
 	if credentialAssertion.ClientData.Challenge != expected.Challenge {
-		return &VerificationError{Type: "assertion", Field: "client data challenge", Msg: "client data challenge does not match expected challenge"}
+		return nil, &VerificationError{Type: "assertion", Field: "client data challenge", Msg: "client data challenge does not match expected challenge"}
 	}
 
 	// Verify that the value of C.origin matches the Relying Party's origin.
 	if credentialAssertion.ClientData.Origin != expected.Origin {
-		return &VerificationError{Type: "assertion", Field: "client data origin", Msg: "expected \"" + expected.Origin + "\", got \"" + credentialAssertion.ClientData.Origin + "\""}
+		return nil, &VerificationError{Type: "assertion", Field: "client data origin", Msg: "expected \"" + expected.Origin + "\", got \"" + credentialAssertion.ClientData.Origin + "\""}
+	}
+
+	// For Secure Payment Confirmation, verify that C.payment matches the expected transaction.
+	if expected.Payment != nil {
+		if err := verifyPayment(credentialAssertion.ClientData.Payment, expected.RPID, expected.Payment); err != nil {
+			return nil, err
+		}
 	}
 
 	// Verify that the rpIdHash in authData is the SHA-256 hash of the RP ID expected by the Relying Party.
-	computedRPIDHash := sha256.Sum256([]byte(expected.RPID))
+	// If the appid extension output is true, the rpIdHash is the SHA-256 hash of the FIDO AppID instead.
+	rpID := expected.RPID
+	if credentialAssertion.ClientExtensionResults.AppID {
+		if expected.AppID == "" {
+			return nil, &VerificationError{Type: "assertion", Field: "appid extension", Msg: "client used FIDO AppID that wasn't requested"}
+		}
+		rpID = expected.AppID
+	}
+	computedRPIDHash := sha256.Sum256([]byte(rpID))
 	if !bytes.Equal(credentialAssertion.AuthnData.RPIDHash, computedRPIDHash[:]) {
-		return &VerificationError{Type: "assertion", Field: "rp ID", Msg: "authenticator data's rp ID hash does not match computed rp ID hash"}
+		return nil, &VerificationError{Type: "assertion", Field: "rp ID", Msg: "authenticator data's rp ID hash does not match computed rp ID hash"}
 	}
 
 	// Verify that the User Present bit of the flags in authData is set.
 	if !credentialAssertion.AuthnData.UserPresent {
-		return &VerificationError{Type: "assertion", Field: "user present", Msg: "user wasn't present"}
+		return nil, &VerificationError{Type: "assertion", Field: "user present", Msg: "user wasn't present"}
 	}
 
 	// If user verification is required for this assertion, verify that the User Verified bit of the flags in authData is set.
 	if expected.UserVerification == UserVerificationRequired && !credentialAssertion.AuthnData.UserVerified {
-		return &VerificationError{Type: "assertion", Field: "user verification", Msg: "user didn't verify"}
+		return nil, &VerificationError{Type: "assertion", Field: "user verification", Msg: "user didn't verify"}
 	}
 
 	// Using credentialPublicKey, verify that sig is a valid signature over the binary concatenation of authData and hash.
 	if err := credentialAssertion.verifySignature(expected.Credential); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Verify that authData.signCount does not roll back.
 	if credentialAssertion.AuthnData.Counter != 0 || expected.PrevCounter != 0 {
 		if credentialAssertion.AuthnData.Counter <= expected.PrevCounter {
-			return &VerificationError{Type: "assertion", Field: "counter", Msg: "cloned authenticator is detected"}
+			return nil, &VerificationError{Type: "assertion", Field: "counter", Msg: "cloned authenticator is detected"}
 		}
 	}
 
-	// todo: Verify that the value of C.tokenBinding.status matches the state of Token Binding for
-	// the TLS connection over which the attestation was obtained. If Token Binding was used on
-	// that TLS connection, also verify that C.tokenBinding.id matches the base64url encoding of
-	// the Token Binding ID for the connection.
+	// Verify that the value of C.tokenBinding.status matches the state of Token Binding for the TLS
+	// connection over which the assertion was obtained. If Token Binding was used on that TLS
+	// connection, also verify that C.tokenBinding.id matches the base64url encoding of the Token
+	// Binding ID for the connection.
+	if err := verifyTokenBinding("assertion", credentialAssertion.ClientData.TokenBinding, expected.TokenBinding, expected.TokenBindingPolicy); err != nil {
+		return nil, err
+	}
+
+	result := &AuthenticationResult{}
+
+	// Verify user verification methods returned by the uvm extension, if any.
+	var err error
+	if result.UVM, err = verifyUVM("assertion", credentialAssertion.AuthnData); err != nil {
+		return nil, err
+	}
 
-	// todo: Verify that the values of the client extension outputs in clientExtensionResults and
-	// the authenticator extension outputs in the extensions in authData are as expected.
+	// Verify the device public key returned by the devicePubKey extension, if any, and whether it
+	// was seen before with the credential.
+	if result.DevicePublicKey, err = verifyDevicePublicKey("assertion", credentialAssertion.AuthnData, credentialAssertion.ClientData, credentialAssertion.ClientExtensionResults.DevicePubKey); err != nil {
+		return nil, err
+	}
+	if result.DevicePublicKey != nil {
+		result.NewDevicePublicKey = true
+		for _, dpk := range expected.DevicePublicKeys {
+			if result.DevicePublicKey.Equal(dpk) {
+				result.NewDevicePublicKey = false
+				break
+			}
+		}
+	}
 
-	return nil
+	return result, nil
 }
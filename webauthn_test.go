@@ -21,12 +21,25 @@ package webauthn_test
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
 	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/fxamacker/cbor/v2"
 	"github.com/kappapay/webauthn"
+	"github.com/kappapay/webauthn/internal/testcert"
 )
 
 var (
@@ -134,6 +147,23 @@ func (attStmt *mockAttestationStatement) Verify(rawClientData []byte, authnData
 	return webauthn.AttestationTypeBasic, nil, nil
 }
 
+func (attStmt *mockAttestationStatement) AttestationDetails() interface{} {
+	return "mock details"
+}
+
+// nestedMockAttestationStatement is a mock attestation statement nesting "packed" and "tpm" attestation statements.
+type nestedMockAttestationStatement struct {
+	mockAttestationStatement
+}
+
+func parseNestedMockAttestation(data []byte) (webauthn.AttestationStatement, error) {
+	return &nestedMockAttestationStatement{}, nil
+}
+
+func (attStmt *nestedMockAttestationStatement) NestedAttestationFormats() []string {
+	return []string{"packed", "tpm"}
+}
+
 type newAttestationOptionsTest struct {
 	name                string
 	cfg                 *webauthn.Config
@@ -228,6 +258,100 @@ var newAttestationOptionsTests = []newAttestationOptionsTest{
 			Attestation: webauthn.AttestationDirect,
 		},
 	},
+	{
+		name: "new attestation options with appidExclude extension",
+		cfg:  getTestConfigWithAppID(),
+		user: &webauthn.User{
+			ID:            []byte{1, 2, 3},
+			Name:          "Jane Doe",
+			DisplayName:   "Jane",
+			CredentialIDs: [][]byte{{1, 2, 3}},
+		},
+		wantCreationOptions: &webauthn.PublicKeyCredentialCreationOptions{
+			RP:   webauthn.PublicKeyCredentialRpEntity{Name: "ACME Corporation", Icon: "https://acme.com/avatar.png", ID: "acme.com"},
+			User: webauthn.PublicKeyCredentialUserEntity{Name: "Jane Doe", ID: []byte{1, 2, 3}, DisplayName: "Jane"},
+			PubKeyCredParams: []webauthn.PublicKeyCredentialParameters{
+				{Type: webauthn.PublicKeyCredentialTypePublicKey, Alg: webauthn.COSEAlgES256},
+				{Type: webauthn.PublicKeyCredentialTypePublicKey, Alg: webauthn.COSEAlgPS256},
+				{Type: webauthn.PublicKeyCredentialTypePublicKey, Alg: webauthn.COSEAlgRS256},
+			},
+			Timeout: uint64(30000),
+			ExcludeCredentials: []webauthn.PublicKeyCredentialDescriptor{
+				{Type: webauthn.PublicKeyCredentialTypePublicKey, ID: []byte{1, 2, 3}},
+			},
+			AuthenticatorSelection: webauthn.AuthenticatorSelectionCriteria{
+				AuthenticatorAttachment: webauthn.AuthenticatorPlatform,
+				RequireResidentKey:      false,
+				ResidentKey:             webauthn.ResidentKeyPreferred,
+				UserVerification:        webauthn.UserVerificationPreferred,
+			},
+			Attestation: webauthn.AttestationDirect,
+			Extensions:  &webauthn.AuthenticationExtensionsClientInputs{AppIDExclude: "https://acme.com/app-id.json"},
+		},
+	},
+	{
+		name: "new attestation options with minPinLength and credBlob extensions",
+		cfg: func() *webauthn.Config {
+			cfg := getTestConfig()
+			cfg.MinPinLength = 6
+			return cfg
+		}(),
+		user: &webauthn.User{
+			ID:          []byte{1, 2, 3},
+			Name:        "Jane Doe",
+			DisplayName: "Jane",
+			CredBlob:    []byte{4, 5, 6},
+		},
+		wantCreationOptions: &webauthn.PublicKeyCredentialCreationOptions{
+			RP:   webauthn.PublicKeyCredentialRpEntity{Name: "ACME Corporation", Icon: "https://acme.com/avatar.png", ID: "acme.com"},
+			User: webauthn.PublicKeyCredentialUserEntity{Name: "Jane Doe", ID: []byte{1, 2, 3}, DisplayName: "Jane"},
+			PubKeyCredParams: []webauthn.PublicKeyCredentialParameters{
+				{Type: webauthn.PublicKeyCredentialTypePublicKey, Alg: webauthn.COSEAlgES256},
+				{Type: webauthn.PublicKeyCredentialTypePublicKey, Alg: webauthn.COSEAlgPS256},
+				{Type: webauthn.PublicKeyCredentialTypePublicKey, Alg: webauthn.COSEAlgRS256},
+			},
+			Timeout: uint64(30000),
+			AuthenticatorSelection: webauthn.AuthenticatorSelectionCriteria{
+				AuthenticatorAttachment: webauthn.AuthenticatorPlatform,
+				RequireResidentKey:      false,
+				ResidentKey:             webauthn.ResidentKeyPreferred,
+				UserVerification:        webauthn.UserVerificationPreferred,
+			},
+			Attestation: webauthn.AttestationDirect,
+			Extensions:  &webauthn.AuthenticationExtensionsClientInputs{MinPinLength: true, CredBlob: []byte{4, 5, 6}},
+		},
+	},
+	{
+		name: "new attestation options with payment extension",
+		cfg: func() *webauthn.Config {
+			cfg := getTestConfig()
+			cfg.Payment = true
+			return cfg
+		}(),
+		user: &webauthn.User{
+			ID:          []byte{1, 2, 3},
+			Name:        "Jane Doe",
+			DisplayName: "Jane",
+		},
+		wantCreationOptions: &webauthn.PublicKeyCredentialCreationOptions{
+			RP:   webauthn.PublicKeyCredentialRpEntity{Name: "ACME Corporation", Icon: "https://acme.com/avatar.png", ID: "acme.com"},
+			User: webauthn.PublicKeyCredentialUserEntity{Name: "Jane Doe", ID: []byte{1, 2, 3}, DisplayName: "Jane"},
+			PubKeyCredParams: []webauthn.PublicKeyCredentialParameters{
+				{Type: webauthn.PublicKeyCredentialTypePublicKey, Alg: webauthn.COSEAlgES256},
+				{Type: webauthn.PublicKeyCredentialTypePublicKey, Alg: webauthn.COSEAlgPS256},
+				{Type: webauthn.PublicKeyCredentialTypePublicKey, Alg: webauthn.COSEAlgRS256},
+			},
+			Timeout: uint64(30000),
+			AuthenticatorSelection: webauthn.AuthenticatorSelectionCriteria{
+				AuthenticatorAttachment: webauthn.AuthenticatorPlatform,
+				RequireResidentKey:      false,
+				ResidentKey:             webauthn.ResidentKeyPreferred,
+				UserVerification:        webauthn.UserVerificationPreferred,
+			},
+			Attestation: webauthn.AttestationDirect,
+			Extensions:  &webauthn.AuthenticationExtensionsClientInputs{Payment: &webauthn.AuthenticationExtensionsPaymentInputs{IsPayment: true}},
+		},
+	},
 }
 
 var newAttestationOptionsErrorTests = []newAttestationOptionsErrorTest{
@@ -354,6 +478,50 @@ var newAssertionOptionsTests = []newAssertionOptionsTest{
 			UserVerification: webauthn.UserVerificationPreferred,
 		},
 	},
+	{
+		name: "new assertion options with appid extension",
+		cfg:  getTestConfigWithAppID(),
+		user: &webauthn.User{},
+		wantRequestOptions: &webauthn.PublicKeyCredentialRequestOptions{
+			Timeout:          uint64(30000),
+			RPID:             "acme.com",
+			AllowCredentials: nil,
+			UserVerification: webauthn.UserVerificationPreferred,
+			Extensions:       &webauthn.AuthenticationExtensionsClientInputs{AppID: "https://acme.com/app-id.json"},
+		},
+	},
+	{
+		name: "new assertion options with getCredBlob extension",
+		cfg: func() *webauthn.Config {
+			cfg := getTestConfig()
+			cfg.GetCredBlob = true
+			return cfg
+		}(),
+		user: &webauthn.User{},
+		wantRequestOptions: &webauthn.PublicKeyCredentialRequestOptions{
+			Timeout:          uint64(30000),
+			RPID:             "acme.com",
+			AllowCredentials: nil,
+			UserVerification: webauthn.UserVerificationPreferred,
+			Extensions:       &webauthn.AuthenticationExtensionsClientInputs{GetCredBlob: true},
+		},
+	},
+	{
+		name: "new assertion options with uvm extension",
+		cfg: func() *webauthn.Config {
+			cfg := getTestConfig()
+			cfg.UVM = true
+			return cfg
+		}(),
+		user: &webauthn.User{},
+		wantRequestOptions: &webauthn.PublicKeyCredentialRequestOptions{
+			Timeout:          uint64(30000),
+			RPID:             "acme.com",
+			AllowCredentials: nil,
+			UserVerification: webauthn.UserVerificationPreferred,
+			Extensions:       &webauthn.AuthenticationExtensionsClientInputs{UVM: true},
+		},
+	},
 }
 
 var parseAndVerifyAssertionTests = []parseAndVerifyAssertionTest{
@@ -483,6 +651,15 @@ func getTestConfig() *webauthn.Config {
 	return cfg
 }
 
+func getTestConfigWithAppID() *webauthn.Config {
+	cfg := getTestConfig()
+	cfg.AppID = "https://acme.com/app-id.json"
+	if err := cfg.Valid(); err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
 func parseCredential(data []byte) *webauthn.Credential {
 	c, _, err := webauthn.ParseCredential(data)
 	if err != nil {
@@ -644,3 +821,592 @@ func TestVerifyAssertionError(t *testing.T) {
 		})
 	}
 }
+
+// testAuthenticator generates assertions signed with an ECDSA P-256 credential key.
+type testAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte // User handle returned in assertions, if any.
+}
+
+func newTestAuthenticator() *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		panic(err)
+	}
+	return &testAuthenticator{key: key, credentialID: credentialID}
+}
+
+func (a *testAuthenticator) credential() *webauthn.Credential {
+	signatureAlgorithm, err := webauthn.CoseAlgToSignatureAlgorithm(webauthn.COSEAlgES256)
+	if err != nil {
+		panic(err)
+	}
+	return &webauthn.Credential{SignatureAlgorithm: signatureAlgorithm, PublicKey: &a.key.PublicKey}
+}
+
+// authenticatorData returns authenticator data without attested credential data.
+// extensions is CBOR encoded authenticator extension outputs, or nil.
+func (a *testAuthenticator) authenticatorData(rpID string, flags byte, counter uint32, extensions []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	var buf bytes.Buffer
+	buf.Write(rpIDHash[:])
+	if len(extensions) > 0 {
+		flags |= 0x80
+	}
+	buf.WriteByte(flags)
+	binary.Write(&buf, binary.BigEndian, counter)
+	buf.Write(extensions)
+	return buf.Bytes()
+}
+
+// coseKey returns credential public key encoded in COSE_Key format.
+func (a *testAuthenticator) coseKey() []byte {
+	byteLen := (a.key.Params().BitSize + 7) / 8
+	x := make([]byte, byteLen)
+	y := make([]byte, byteLen)
+	xb, yb := a.key.X.Bytes(), a.key.Y.Bytes()
+	copy(x[byteLen-len(xb):], xb)
+	copy(y[byteLen-len(yb):], yb)
+	return cborMarshal(map[int]interface{}{1: 2, 3: webauthn.COSEAlgES256, -1: 1, -2: x, -3: y})
+}
+
+// attestedAuthenticatorData returns authenticator data with attested credential data.
+// extensions is CBOR encoded authenticator extension outputs, or nil.
+func (a *testAuthenticator) attestedAuthenticatorData(rpID string, flags byte, extensions []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	var buf bytes.Buffer
+	buf.Write(rpIDHash[:])
+	flags |= 0x40
+	if len(extensions) > 0 {
+		flags |= 0x80
+	}
+	buf.WriteByte(flags)
+	buf.Write([]byte{0, 0, 0, 0}) // counter
+	buf.Write(make([]byte, 16))   // aaguid
+	binary.Write(&buf, binary.BigEndian, uint16(len(a.credentialID)))
+	buf.Write(a.credentialID)
+	buf.Write(a.coseKey())
+	buf.Write(extensions)
+	return buf.Bytes()
+}
+
+// attestation returns JSON encoded attestation of authnData and clientData in mock attestation format.
+func (a *testAuthenticator) attestation(authnData []byte, clientData []byte, clientExtensionResults interface{}) []byte {
+	attestationObject := cborMarshal(map[string]interface{}{"fmt": "mock", "attStmt": map[string]interface{}{}, "authData": authnData})
+	attestation := map[string]interface{}{
+		"id":    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"rawId": base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"response": map[string]string{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientData),
+			"attestationObject": base64.RawURLEncoding.EncodeToString(attestationObject),
+		},
+		"type": "public-key",
+	}
+	if clientExtensionResults != nil {
+		attestation["clientExtensionResults"] = clientExtensionResults
+	}
+	b, err := json.Marshal(attestation)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// sign returns ASN.1 encoded ECDSA signature over the concatenation of authnData and client data hash.
+func (a *testAuthenticator) sign(authnData []byte, clientData []byte) []byte {
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authnData...), clientDataHash[:]...))
+	r, s, err := ecdsa.Sign(rand.Reader, a.key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	sig, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		panic(err)
+	}
+	return sig
+}
+
+// assertion returns JSON encoded assertion of authnData and clientData signed with credential key.
+func (a *testAuthenticator) assertion(authnData []byte, clientData []byte, clientExtensionResults interface{}) []byte {
+	sig := a.sign(authnData, clientData)
+	assertion := map[string]interface{}{
+		"id":    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"rawId": base64.RawURLEncoding.EncodeToString(a.credentialID),
+		"response": map[string]string{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientData),
+			"authenticatorData": base64.RawURLEncoding.EncodeToString(authnData),
+			"signature":         base64.RawURLEncoding.EncodeToString(sig),
+			"userHandle":        base64.RawURLEncoding.EncodeToString(a.userHandle),
+		},
+		"type": "public-key",
+	}
+	if clientExtensionResults != nil {
+		assertion["clientExtensionResults"] = clientExtensionResults
+	}
+	b, err := json.Marshal(assertion)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestVerifyAssertionAppID(t *testing.T) {
+	authenticator := newTestAuthenticator()
+	appID := "https://acme.com/app-id.json"
+	challenge := "eaTyUNnyPDDdK8SNEgTEUvz1Q8dylkjjTimYd5X7QAo"
+	clientData := []byte(`{"type":"webauthn.get","challenge":"` + challenge + `","origin":"https://acme.com"}`)
+
+	testCases := []struct {
+		name                   string
+		rpID                   string
+		clientExtensionResults interface{}
+		expectedAppID          string
+		wantErrorMsg           string
+	}{
+		{"rp id hash of app id", appID, map[string]bool{"appid": true}, appID, ""},
+		{"rp id hash of rp id", "acme.com", map[string]bool{"appid": false}, appID, ""},
+		{"rp id hash of app id without appid output", appID, nil, appID, "assertion: failed to verify rp ID"},
+		{"rp id hash of rp id with appid output", "acme.com", map[string]bool{"appid": true}, appID, "assertion: failed to verify rp ID"},
+		{"appid output without requested app id", appID, map[string]bool{"appid": true}, "", "assertion: failed to verify appid extension"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authnData := authenticator.authenticatorData(tc.rpID, 0x01, 1, nil)
+			credentialAssertion, err := webauthn.ParseAssertion(bytes.NewReader(authenticator.assertion(authnData, clientData, tc.clientExtensionResults)))
+			if err != nil {
+				t.Fatalf("ParseAssertion() returns error %q", err)
+			}
+			expected := &webauthn.AssertionExpectedData{
+				Origin:           "https://acme.com",
+				RPID:             "acme.com",
+				Challenge:        challenge,
+				UserVerification: webauthn.UserVerificationPreferred,
+				Credential:       authenticator.credential(),
+				AppID:            tc.expectedAppID,
+			}
+			err = webauthn.VerifyAssertion(credentialAssertion, expected)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("VerifyAssertion() returns error %q", err)
+			} else if tc.wantErrorMsg != "" && err == nil {
+				t.Errorf("VerifyAssertion() returns no error, want error containing substring %q", tc.wantErrorMsg)
+			} else if tc.wantErrorMsg != "" && !strings.Contains(err.Error(), tc.wantErrorMsg) {
+				t.Errorf("VerifyAssertion() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+			}
+		})
+	}
+}
+
+func cborMarshal(v interface{}) []byte {
+	em, err := cbor.EncOptions{Sort: cbor.SortCanonical}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	b, err := em.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestVerifyAttestationMinPinLength(t *testing.T) {
+	// register mock attestation statement
+	webauthn.RegisterAttestationFormat("mock", parseMockAttestation)
+	defer webauthn.UnregisterAttestationFormat("mock")
+
+	authenticator := newTestAuthenticator()
+	challenge := "33EHav-jZ1v9qwH783aU-j0ARx6r5o-YHh-wd7C6jPbd7Wh6ytbIZosIIACehwf9"
+	clientData := []byte(`{"type":"webauthn.create","challenge":"` + challenge + `","origin":"https://acme.com"}`)
+
+	testCases := []struct {
+		name                 string
+		extensions           []byte
+		expectedMinPinLength int
+		wantErrorMsg         string
+	}{
+		{"no minimum PIN length policy", nil, 0, ""},
+		{"minimum PIN length is longer than required", cborMarshal(map[string]interface{}{"minPinLength": 8}), 6, ""},
+		{"minimum PIN length is required length", cborMarshal(map[string]interface{}{"minPinLength": 6}), 6, ""},
+		{"minimum PIN length is shorter than required", cborMarshal(map[string]interface{}{"minPinLength": 4}), 6, "attestation: failed to verify minPinLength extension: minimum PIN length 4 is less than required 6"},
+		{"minimum PIN length isn't reported", nil, 6, "attestation: failed to verify minPinLength extension: authenticator didn't report minimum PIN length"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authnData := authenticator.attestedAuthenticatorData("acme.com", 0x01, tc.extensions)
+			credentialAttestation, err := webauthn.ParseAttestation(bytes.NewReader(authenticator.attestation(authnData, clientData, nil)))
+			if err != nil {
+				t.Fatalf("ParseAttestation() returns error %q", err)
+			}
+			expected := &webauthn.AttestationExpectedData{
+				Origin:           "https://acme.com",
+				RPID:             "acme.com",
+				CredentialAlgs:   []int{webauthn.COSEAlgES256},
+				Challenge:        challenge,
+				UserVerification: webauthn.UserVerificationPreferred,
+				MinPinLength:     tc.expectedMinPinLength,
+			}
+			_, _, err = webauthn.VerifyAttestation(credentialAttestation, expected)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("VerifyAttestation() returns error %q", err)
+			} else if tc.wantErrorMsg != "" && err == nil {
+				t.Errorf("VerifyAttestation() returns no error, want error containing substring %q", tc.wantErrorMsg)
+			} else if tc.wantErrorMsg != "" && !strings.Contains(err.Error(), tc.wantErrorMsg) {
+				t.Errorf("VerifyAttestation() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+			}
+		})
+	}
+}
+
+func TestParseAssertionCredBlob(t *testing.T) {
+	authenticator := newTestAuthenticator()
+	challenge := "eaTyUNnyPDDdK8SNEgTEUvz1Q8dylkjjTimYd5X7QAo"
+	clientData := []byte(`{"type":"webauthn.get","challenge":"` + challenge + `","origin":"https://acme.com"}`)
+	credBlob := []byte("blob")
+
+	authnData := authenticator.authenticatorData("acme.com", 0x01, 1, cborMarshal(map[string]interface{}{"credBlob": credBlob}))
+	clientExtensionResults := map[string]interface{}{"getCredBlob": base64.RawURLEncoding.EncodeToString(credBlob)}
+	credentialAssertion, err := webauthn.ParseAssertion(bytes.NewReader(authenticator.assertion(authnData, clientData, clientExtensionResults)))
+	if err != nil {
+		t.Fatalf("ParseAssertion() returns error %q", err)
+	}
+	if b, ok := credentialAssertion.AuthnData.CredBlob(); !ok || !bytes.Equal(b, credBlob) {
+		t.Errorf("CredBlob() returns (%v, %t), want (%v, true)", b, ok, credBlob)
+	}
+	if !bytes.Equal(credentialAssertion.ClientExtensionResults.GetCredBlob, credBlob) {
+		t.Errorf("getCredBlob client extension output %v, want %v", credentialAssertion.ClientExtensionResults.GetCredBlob, credBlob)
+	}
+	expected := &webauthn.AssertionExpectedData{
+		Origin:           "https://acme.com",
+		RPID:             "acme.com",
+		Challenge:        challenge,
+		UserVerification: webauthn.UserVerificationPreferred,
+		Credential:       authenticator.credential(),
+	}
+	if err := webauthn.VerifyAssertion(credentialAssertion, expected); err != nil {
+		t.Errorf("VerifyAssertion() returns error %q", err)
+	}
+}
+
+func TestVerifyRegistrationAttestationDetails(t *testing.T) {
+	// register mock attestation statement
+	webauthn.RegisterAttestationFormat("mock", parseMockAttestation)
+	defer webauthn.UnregisterAttestationFormat("mock")
+
+	authenticator := newTestAuthenticator()
+	challenge := "33EHav-jZ1v9qwH783aU-j0ARx6r5o-YHh-wd7C6jPbd7Wh6ytbIZosIIACehwf9"
+	clientData := []byte(`{"type":"webauthn.create","challenge":"` + challenge + `","origin":"https://acme.com"}`)
+	authnData := authenticator.attestedAuthenticatorData("acme.com", 0x01, nil)
+	credentialAttestation, err := webauthn.ParseAttestation(bytes.NewReader(authenticator.attestation(authnData, clientData, nil)))
+	if err != nil {
+		t.Fatalf("ParseAttestation() returns error %q", err)
+	}
+	expected := &webauthn.AttestationExpectedData{
+		Origin:           "https://acme.com",
+		RPID:             "acme.com",
+		CredentialAlgs:   []int{webauthn.COSEAlgES256},
+		Challenge:        challenge,
+		UserVerification: webauthn.UserVerificationPreferred,
+	}
+	result, err := webauthn.VerifyRegistration(credentialAttestation, expected)
+	if err != nil {
+		t.Fatalf("VerifyRegistration() returns error %q", err)
+	}
+	if result.Details != "mock details" {
+		t.Errorf("attestation details %v, want %q", result.Details, "mock details")
+	}
+}
+
+func TestVerifyRegistrationAttestationPolicy(t *testing.T) {
+	// register mock attestation statement
+	webauthn.RegisterAttestationFormat("mock", parseMockAttestation)
+	defer webauthn.UnregisterAttestationFormat("mock")
+
+	authenticator := newTestAuthenticator()
+	challenge := "33EHav-jZ1v9qwH783aU-j0ARx6r5o-YHh-wd7C6jPbd7Wh6ytbIZosIIACehwf9"
+	clientData := []byte(`{"type":"webauthn.create","challenge":"` + challenge + `","origin":"https://acme.com"}`)
+	authnData := authenticator.attestedAuthenticatorData("acme.com", 0x01, nil)
+
+	testCases := []struct {
+		name         string
+		preference   webauthn.AttestationConveyancePreference
+		formats      []string
+		policy       webauthn.AttestationPolicy
+		wantAttType  webauthn.AttestationType
+		wantFmt      string
+		wantErrorMsg string
+	}{
+		{"allowed format", webauthn.AttestationDirect, []string{"packed", "mock"}, webauthn.AttestationPolicyReject, webauthn.AttestationTypeBasic, "mock", ""},
+		{"format not allowed", webauthn.AttestationDirect, []string{"packed"}, webauthn.AttestationPolicyReject, 0, "", "attestation: failed to verify attestation statement format: attestation statement format \"mock\" isn't allowed"},
+		{"format not allowed as none", webauthn.AttestationIndirect, []string{"packed"}, webauthn.AttestationPolicyNone, webauthn.AttestationTypeNone, "none", ""},
+		{"attestation not wanted", webauthn.AttestationNone, nil, webauthn.AttestationPolicyReject, 0, "", "attestation: failed to verify attestation statement format: attestation statement isn't wanted, got \"mock\""},
+		{"attestation not wanted as none", webauthn.AttestationNone, nil, webauthn.AttestationPolicyNone, webauthn.AttestationTypeNone, "none", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			credentialAttestation, err := webauthn.ParseAttestation(bytes.NewReader(authenticator.attestation(authnData, clientData, nil)))
+			if err != nil {
+				t.Fatalf("ParseAttestation() returns error %q", err)
+			}
+			expected := &webauthn.AttestationExpectedData{
+				Origin:             "https://acme.com",
+				RPID:               "acme.com",
+				CredentialAlgs:     []int{webauthn.COSEAlgES256},
+				Challenge:          challenge,
+				UserVerification:   webauthn.UserVerificationPreferred,
+				Attestation:        tc.preference,
+				AttestationFormats: tc.formats,
+				AttestationPolicy:  tc.policy,
+			}
+			result, err := webauthn.VerifyRegistration(credentialAttestation, expected)
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyRegistration() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyRegistration() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyRegistration() returns error %q", err)
+			}
+			if result.AttestationType != tc.wantAttType {
+				t.Errorf("attestation type %v, want %v", result.AttestationType, tc.wantAttType)
+			}
+			if result.AttestationFormat != tc.wantFmt {
+				t.Errorf("attestation statement format %q, want %q", result.AttestationFormat, tc.wantFmt)
+			}
+			if result.AttestationReplaced != (tc.wantFmt == "none") {
+				t.Errorf("attestation statement replaced %t, want %t", result.AttestationReplaced, tc.wantFmt == "none")
+			}
+			if tc.wantFmt == "none" && (result.TrustPath != nil || result.Details != nil) {
+				t.Errorf("trust path %v and details %v of unwanted attestation statement, want nil", result.TrustPath, result.Details)
+			}
+			if credentialAttestation.Fmt != "mock" {
+				t.Errorf("attestation statement format of credential attestation %q, want %q", credentialAttestation.Fmt, "mock")
+			}
+
+			// Verification doesn't modify the credential attestation, so it can be repeated with the same result.
+			again, err := webauthn.VerifyRegistration(credentialAttestation, expected)
+			if err != nil {
+				t.Fatalf("VerifyRegistration() returns error %q", err)
+			}
+			if again.AttestationFormat != result.AttestationFormat || again.AttestationType != result.AttestationType {
+				t.Errorf("repeated VerifyRegistration() returns format %q and attestation type %v, want %q and %v", again.AttestationFormat, again.AttestationType, result.AttestationFormat, result.AttestationType)
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrationNestedAttestationFormats(t *testing.T) {
+	// register mock attestation statement nesting other attestation statements
+	webauthn.RegisterAttestationFormat("mock", parseNestedMockAttestation)
+	defer webauthn.UnregisterAttestationFormat("mock")
+
+	authenticator := newTestAuthenticator()
+	challenge := "33EHav-jZ1v9qwH783aU-j0ARx6r5o-YHh-wd7C6jPbd7Wh6ytbIZosIIACehwf9"
+	clientData := []byte(`{"type":"webauthn.create","challenge":"` + challenge + `","origin":"https://acme.com"}`)
+	authnData := authenticator.attestedAuthenticatorData("acme.com", 0x01, nil)
+
+	testCases := []struct {
+		name         string
+		preference   webauthn.AttestationConveyancePreference
+		formats      []string
+		policy       webauthn.AttestationPolicy
+		wantFmt      string
+		wantErrorMsg string
+	}{
+		{"no formats", webauthn.AttestationDirect, nil, webauthn.AttestationPolicyReject, "mock", ""},
+		{"nested formats allowed", webauthn.AttestationDirect, []string{"mock", "packed", "tpm"}, webauthn.AttestationPolicyReject, "mock", ""},
+		{"nested format not allowed", webauthn.AttestationDirect, []string{"mock", "packed"}, webauthn.AttestationPolicyReject, "", "attestation statement format \"tpm\" isn't allowed"},
+		{"nested format not allowed as none", webauthn.AttestationIndirect, []string{"mock", "packed"}, webauthn.AttestationPolicyNone, "none", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			credentialAttestation, err := webauthn.ParseAttestation(bytes.NewReader(authenticator.attestation(authnData, clientData, nil)))
+			if err != nil {
+				t.Fatalf("ParseAttestation() returns error %q", err)
+			}
+			expected := &webauthn.AttestationExpectedData{
+				Origin:             "https://acme.com",
+				RPID:               "acme.com",
+				CredentialAlgs:     []int{webauthn.COSEAlgES256},
+				Challenge:          challenge,
+				UserVerification:   webauthn.UserVerificationPreferred,
+				Attestation:        tc.preference,
+				AttestationFormats: tc.formats,
+				AttestationPolicy:  tc.policy,
+			}
+			result, err := webauthn.VerifyRegistration(credentialAttestation, expected)
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyRegistration() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyRegistration() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyRegistration() returns error %q", err)
+			}
+			if result.AttestationFormat != tc.wantFmt {
+				t.Errorf("attestation statement format %q, want %q", result.AttestationFormat, tc.wantFmt)
+			}
+			if credentialAttestation.Fmt != "mock" {
+				t.Errorf("attestation statement format of credential attestation %q, want %q", credentialAttestation.Fmt, "mock")
+			}
+		})
+	}
+}
+
+type chainAttestationStatement struct {
+	trustPath []*x509.Certificate
+}
+
+func (attStmt *chainAttestationStatement) Verify(rawClientData []byte, authnData *webauthn.AuthenticatorData) (attType webauthn.AttestationType, trustPath interface{}, err error) {
+	return webauthn.AttestationTypeBasic, attStmt.trustPath, nil
+}
+
+// compoundChainAttestationStatement is a mock attestation statement nesting attestation statements with certificate chains.
+type compoundChainAttestationStatement struct {
+	chains [][]*x509.Certificate
+}
+
+func (attStmt *compoundChainAttestationStatement) Verify(rawClientData []byte, authnData *webauthn.AuthenticatorData) (attType webauthn.AttestationType, trustPath interface{}, err error) {
+	return webauthn.AttestationTypeCompound, "nested trust paths", nil
+}
+
+func (attStmt *compoundChainAttestationStatement) CertificateChains() [][]*x509.Certificate {
+	return attStmt.chains
+}
+
+type unavailableRevocationFetcher struct{}
+
+func (unavailableRevocationFetcher) FetchCRL(url string) ([]byte, error) {
+	return nil, errors.New("network is unreachable")
+}
+
+func (unavailableRevocationFetcher) FetchOCSP(url string, request []byte) ([]byte, error) {
+	return nil, errors.New("network is unreachable")
+}
+
+// newTestCertificateChain returns an attestation certificate with a CRL distribution point and its self-signed issuer.
+func newTestCertificateChain() []*x509.Certificate {
+	ca := testcert.NewCA("Test Root CA", nil)
+	leaf := testcert.New(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test Attestation"},
+		CRLDistributionPoints: []string{"http://crl.example.com/ca.crl"},
+	}, ca)
+	return []*x509.Certificate{leaf.Cert, ca.Cert}
+}
+
+func TestVerifyRegistrationRevocation(t *testing.T) {
+	chain := newTestCertificateChain()
+	defer webauthn.UnregisterAttestationFormat("mock")
+
+	authenticator := newTestAuthenticator()
+	challenge := "33EHav-jZ1v9qwH783aU-j0ARx6r5o-YHh-wd7C6jPbd7Wh6ytbIZosIIACehwf9"
+	clientData := []byte(`{"type":"webauthn.create","challenge":"` + challenge + `","origin":"https://acme.com"}`)
+	authnData := authenticator.attestedAuthenticatorData("acme.com", 0x01, nil)
+
+	testCases := []struct {
+		name         string
+		nested       bool
+		revocation   *webauthn.RevocationChecker
+		wantResults  int
+		wantErrorMsg string
+	}{
+		{"revocation not checked", false, nil, 0, ""},
+		{"revocation unavailable in soft-fail mode", false, webauthn.NewRevocationChecker(unavailableRevocationFetcher{}, webauthn.RevocationSoftFail), 1, ""},
+		{"revocation unavailable in hard-fail mode", false, webauthn.NewRevocationChecker(unavailableRevocationFetcher{}, webauthn.RevocationHardFail), 0, "attestation: failed to verify certificate revocation status: certificate \"Test Attestation\" revocation status is unknown: http://crl.example.com/ca.crl: failed to fetch CRL: network is unreachable"},
+		{"nested revocation unavailable in soft-fail mode", true, webauthn.NewRevocationChecker(unavailableRevocationFetcher{}, webauthn.RevocationSoftFail), 1, ""},
+		{"nested revocation unavailable in hard-fail mode", true, webauthn.NewRevocationChecker(unavailableRevocationFetcher{}, webauthn.RevocationHardFail), 0, "attestation: failed to verify certificate revocation status: certificate \"Test Attestation\" revocation status is unknown"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// register mock attestation statement returning certificate chain as trust path, or nesting it
+			webauthn.RegisterAttestationFormat("mock", func(data []byte) (webauthn.AttestationStatement, error) {
+				if tc.nested {
+					return &compoundChainAttestationStatement{chains: [][]*x509.Certificate{chain}}, nil
+				}
+				return &chainAttestationStatement{trustPath: chain}, nil
+			})
+
+			credentialAttestation, err := webauthn.ParseAttestation(bytes.NewReader(authenticator.attestation(authnData, clientData, nil)))
+			if err != nil {
+				t.Fatalf("ParseAttestation() returns error %q", err)
+			}
+			expected := &webauthn.AttestationExpectedData{
+				Origin:           "https://acme.com",
+				RPID:             "acme.com",
+				CredentialAlgs:   []int{webauthn.COSEAlgES256},
+				Challenge:        challenge,
+				UserVerification: webauthn.UserVerificationPreferred,
+				Revocation:       tc.revocation,
+			}
+			result, err := webauthn.VerifyRegistration(credentialAttestation, expected)
+			if tc.wantErrorMsg != "" {
+				if err == nil {
+					t.Errorf("VerifyRegistration() returns no error, want error containing substring %q", tc.wantErrorMsg)
+				} else if !strings.Contains(err.Error(), tc.wantErrorMsg) {
+					t.Errorf("VerifyRegistration() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyRegistration() returns error %q", err)
+			}
+			if len(result.Revocation) != tc.wantResults {
+				t.Fatalf("%d revocation results, want %d", len(result.Revocation), tc.wantResults)
+			}
+			if tc.wantResults > 0 && (result.Revocation[0].Status != webauthn.RevocationStatusUnknown || !result.Revocation[0].Certificate.Equal(chain[0])) {
+				t.Errorf("revocation result %+v, want unknown status of attestation certificate", result.Revocation[0])
+			}
+		})
+	}
+}
+
+func TestVerifyAuthenticationTokenBinding(t *testing.T) {
+	authenticator := newTestAuthenticator()
+	challenge := "eaTyUNnyPDDdK8SNEgTEUvz1Q8dylkjjTimYd5X7QAo"
+	clientData := []byte(`{"type":"webauthn.get","challenge":"` + challenge + `","origin":"https://acme.com","tokenBinding":{"status":"present","id":"dGJpZA"}}`)
+
+	testCases := []struct {
+		name         string
+		tokenBinding *webauthn.TokenBinding
+		wantErrorMsg string
+	}{
+		{"token binding used on connection", &webauthn.TokenBinding{Status: webauthn.TokenBindingPresent, ID: "dGJpZA"}, ""},
+		{"other token binding used on connection", &webauthn.TokenBinding{Status: webauthn.TokenBindingPresent, ID: "b3RoZXI"}, "assertion: failed to verify client data token binding: token binding ID does not match Token Binding ID of the connection"},
+		{"token binding not used on connection", &webauthn.TokenBinding{}, "assertion: failed to verify client data token binding: token binding is present, but Token Binding wasn't used on the connection"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authnData := authenticator.authenticatorData("acme.com", 0x01, 1, nil)
+			credentialAssertion, err := webauthn.ParseAssertion(bytes.NewReader(authenticator.assertion(authnData, clientData, nil)))
+			if err != nil {
+				t.Fatalf("ParseAssertion() returns error %q", err)
+			}
+			expected := &webauthn.AssertionExpectedData{
+				Origin:           "https://acme.com",
+				RPID:             "acme.com",
+				Challenge:        challenge,
+				UserVerification: webauthn.UserVerificationPreferred,
+				Credential:       authenticator.credential(),
+				TokenBinding:     tc.tokenBinding,
+			}
+			_, err = webauthn.VerifyAuthentication(credentialAssertion, expected)
+			if tc.wantErrorMsg == "" && err != nil {
+				t.Errorf("VerifyAuthentication() returns error %q", err)
+			} else if tc.wantErrorMsg != "" && err == nil {
+				t.Errorf("VerifyAuthentication() returns no error, want error containing substring %q", tc.wantErrorMsg)
+			} else if tc.wantErrorMsg != "" && !strings.Contains(err.Error(), tc.wantErrorMsg) {
+				t.Errorf("VerifyAuthentication() returns error %q, want error containing substring %q", err, tc.wantErrorMsg)
+			}
+		})
+	}
+}